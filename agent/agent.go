@@ -5,6 +5,8 @@ import (
 	"context"
 	"io"
 	"time"
+
+	"github.com/sirsjg/momentum/sandbox"
 )
 
 // Agent defines the interface for AI coding agents.
@@ -31,16 +33,43 @@ type Agent interface {
 	IsRunning() bool
 }
 
+// Interactive is implemented by agents that can take a message from the user
+// while they run. Agents return ErrInputNotSupported when they cannot, in
+// which case the caller stops the agent and resumes its session with the
+// message instead.
+type Interactive interface {
+	// SendMessage delivers a user message to the running agent
+	SendMessage(text string) error
+}
+
+// Previewable is implemented by agents that can show the command they run
+// for a prompt before they are started.
+type Previewable interface {
+	// CommandLine returns the executable and arguments started for prompt
+	CommandLine(prompt string) []string
+}
+
 // Config holds agent configuration
 type Config struct {
 	// WorkDir is the working directory for the agent
 	WorkDir string
 
+	// Repository is the git URL WorkDir is a managed checkout of, if any;
+	// remote runners check it out themselves
+	Repository string
+
 	// Env contains additional environment variables
 	Env map[string]string
 
 	// Timeout is the maximum execution time (0 = no timeout)
 	Timeout time.Duration
+
+	// Sandbox restricts the agent process (nil = unrestricted)
+	Sandbox *sandbox.Profile
+
+	// ResumeSession continues an earlier Claude Code session instead of
+	// starting a new one (empty = new session)
+	ResumeSession string
 }
 
 // Result represents the outcome of an agent execution
@@ -48,6 +77,8 @@ type Result struct {
 	ExitCode int
 	Duration time.Duration
 	Error    error
+	// SessionID is the Claude Code session the agent ran in, if it reported one
+	SessionID string
 }
 
 // OutputLine represents a single line of agent output
@@ -1,10 +1,15 @@
 package agent
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"io"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/sirsjg/momentum/sandbox"
 )
 
 func TestNewClaudeCode(t *testing.T) {
@@ -26,6 +31,11 @@ func TestNewClaudeCode(t *testing.T) {
 	if agent.IsRunning() {
 		t.Error("expected agent to not be running before Start")
 	}
+
+	want := "claude -p --output-format stream-json --verbose --dangerously-skip-permissions prompt"
+	if got := strings.Join(agent.CommandLine("prompt"), " "); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
 }
 
 func TestAgentNotStarted(t *testing.T) {
@@ -37,6 +47,22 @@ func TestAgentNotStarted(t *testing.T) {
 	}
 }
 
+func TestClaudeCodeSandboxFailsClosed(t *testing.T) {
+	// Neither claude nor the sandbox tools can be found
+	t.Setenv("PATH", t.TempDir())
+
+	agent := NewClaudeCode(Config{Sandbox: &sandbox.Profile{
+		Filesystem: sandbox.Filesystem{ReadOnly: true},
+	}})
+	err := agent.Start(context.Background(), "prompt")
+	if !errors.Is(err, sandbox.ErrUnavailable) {
+		t.Errorf("expected sandbox.ErrUnavailable, got %v", err)
+	}
+	if agent.IsRunning() {
+		t.Error("expected agent to not be running")
+	}
+}
+
 func TestNewRunner(t *testing.T) {
 	agent := NewClaudeCode(Config{})
 	runner := NewRunner(agent)
@@ -52,6 +78,49 @@ func TestNewRunner(t *testing.T) {
 	if runner.Agent() != agent {
 		t.Error("expected runner.Agent() to return the wrapped agent")
 	}
+
+	if runner.Command() != ClaudeCommand {
+		t.Errorf("expected the claude command, got %q", runner.Command())
+	}
+	if got := NewRunner(NewFake(Config{}, FakeConfig{})).Command(); got != "" {
+		t.Errorf("expected no command for an in-process agent, got %q", got)
+	}
+}
+
+func TestReadLine(t *testing.T) {
+	exact := strings.Repeat("a", MaxLineSize)
+	input := "one\r\n\n" + exact + "\n" + exact + "bc\nlast"
+	br := bufio.NewReaderSize(strings.NewReader(input), 4096)
+
+	want := []string{"one", "", exact, exact + TruncatedSuffix, "last"}
+	for i, w := range want {
+		got, err := readLine(br)
+		if got != w {
+			t.Errorf("line %d: got %d bytes, want %d", i, len(got), len(w))
+		}
+		if (err != nil) != (i == len(want)-1) {
+			t.Errorf("line %d: unexpected error %v", i, err)
+		}
+	}
+}
+
+func TestSessionID(t *testing.T) {
+	tests := map[string]string{
+		`{"type":"system","subtype":"init","session_id":"abc-123"}`: "abc-123",
+		`{"type":"assistant","message":{"content":[]}}`:             "",
+		`{"type":"result","session_id":`:                            "",
+		"plain text mentioning \"session_id\"":                      "",
+	}
+	for line, want := range tests {
+		if got := SessionID(line); got != want {
+			t.Errorf("SessionID(%s) = %q, want %q", line, got, want)
+		}
+	}
+
+	resume := Config{ResumeSession: "abc-123"}.resumeArgs()
+	if strings.Join(resume, " ") != "--resume abc-123" || (Config{}).resumeArgs() != nil {
+		t.Errorf("unexpected resume args %v", resume)
+	}
 }
 
 func TestOutputLine(t *testing.T) {
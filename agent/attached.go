@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// attachPollInterval is how often an attached process is checked for exit.
+const attachPollInterval = time.Second
+
+// Attached implements the Agent interface for an agent process started by an
+// earlier Momentum run, e.g. before a crash. Its output and exit code cannot
+// be recovered, so it can only be watched until it exits and cancelled.
+type Attached struct {
+	pid  int
+	name string
+
+	mu      sync.Mutex
+	running bool
+	done    chan struct{}
+	cancel  context.CancelFunc
+}
+
+// NewAttached creates an agent for the existing process pid.
+func NewAttached(pid int, name string) *Attached {
+	return &Attached{
+		pid:  pid,
+		name: name,
+	}
+}
+
+// Name returns the agent's display name
+func (a *Attached) Name() string {
+	return a.name
+}
+
+// Start begins watching the process. The prompt is ignored.
+func (a *Attached) Start(ctx context.Context, prompt string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.running {
+		return ErrAgentAlreadyRunning
+	}
+	if !ProcessAlive(a.pid) {
+		return ErrProcessNotRunning
+	}
+
+	ctx, a.cancel = context.WithCancel(ctx)
+	a.done = make(chan struct{})
+	a.running = true
+
+	go func() {
+		ticker := time.NewTicker(attachPollInterval)
+		defer ticker.Stop()
+		defer close(a.done)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !ProcessAlive(a.pid) {
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Stdout returns nil; the output of an attached process is not available
+func (a *Attached) Stdout() io.Reader {
+	return nil
+}
+
+// Stderr returns nil; the output of an attached process is not available
+func (a *Attached) Stderr() io.Reader {
+	return nil
+}
+
+// Wait blocks until the process exits. The exit code cannot be observed, so
+// it always returns -1 and ErrExitCodeUnknown.
+func (a *Attached) Wait() (int, error) {
+	a.mu.Lock()
+	done := a.done
+	a.mu.Unlock()
+	if done == nil {
+		return -1, ErrAgentNotStarted
+	}
+
+	<-done
+
+	a.mu.Lock()
+	a.running = false
+	a.cancel()
+	a.mu.Unlock()
+	return -1, ErrExitCodeUnknown
+}
+
+// Cancel terminates the process tree, forcing it after 3 seconds
+func (a *Attached) Cancel() error {
+	a.mu.Lock()
+	running := a.running
+	a.mu.Unlock()
+	if !running {
+		return nil
+	}
+
+	process, err := os.FindProcess(a.pid)
+	if err != nil {
+		return err
+	}
+	killProcessTree(a.pid, process, false)
+
+	go func() {
+		time.Sleep(3 * time.Second)
+		if ProcessAlive(a.pid) {
+			killProcessTree(a.pid, process, true)
+		}
+	}()
+	return nil
+}
+
+// IsRunning returns whether the process is still being watched
+func (a *Attached) IsRunning() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.running
+}
+
+// PID returns the attached process ID.
+func (a *Attached) PID() int {
+	return a.pid
+}
@@ -0,0 +1,95 @@
+//go:build !windows
+
+package agent
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func startSleep(t *testing.T) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("sleep not available: %v", err)
+	}
+	// Reap the child so it stops being reported as alive after it exits
+	go cmd.Wait()
+	t.Cleanup(func() { cmd.Process.Kill() })
+	return cmd
+}
+
+func TestProcessAlive(t *testing.T) {
+	if !ProcessAlive(os.Getpid()) {
+		t.Error("expected current process to be alive")
+	}
+	if ProcessAlive(0) || ProcessAlive(-1) {
+		t.Error("expected invalid PIDs to be reported as not alive")
+	}
+}
+
+func TestProcessMatches(t *testing.T) {
+	cmd := startSleep(t)
+	// Right after fork the child still shows the parent's command line
+	deadline := time.Now().Add(2 * time.Second)
+	for !ProcessMatches(cmd.Process.Pid, "sleep") {
+		if time.Now().After(deadline) {
+			t.Fatal("expected sleep process to match")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := os.Stat("/proc/self/cmdline"); err == nil && ProcessMatches(cmd.Process.Pid, ClaudeCommand) {
+		t.Error("expected sleep process not to match claude")
+	}
+}
+
+func TestAttached_NotRunning(t *testing.T) {
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("true not available: %v", err)
+	}
+
+	a := NewAttached(cmd.Process.Pid, "Claude")
+	if err := a.Start(context.Background(), ""); err != ErrProcessNotRunning {
+		t.Errorf("expected ErrProcessNotRunning, got %v", err)
+	}
+}
+
+func TestAttached_WaitsForExit(t *testing.T) {
+	cmd := startSleep(t)
+
+	a := NewAttached(cmd.Process.Pid, "Claude (reattached)")
+	if _, err := a.Wait(); err != ErrAgentNotStarted {
+		t.Errorf("expected ErrAgentNotStarted before Start, got %v", err)
+	}
+
+	runner := NewRunner(a)
+	if err := runner.Run(context.Background(), ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runner.PID() != cmd.Process.Pid {
+		t.Errorf("expected PID %d, got %d", cmd.Process.Pid, runner.PID())
+	}
+	if !a.IsRunning() {
+		t.Error("expected attached agent to be running")
+	}
+
+	if err := runner.Cancel(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	select {
+	case result := <-runner.Done():
+		if result.ExitCode != -1 || result.Error != ErrExitCodeUnknown {
+			t.Errorf("expected unknown exit code, got %+v", result)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for attached process to exit")
+	}
+	if a.IsRunning() {
+		t.Error("expected attached agent to stop running after exit")
+	}
+}
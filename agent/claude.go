@@ -10,6 +10,13 @@ import (
 	"time"
 )
 
+// ClaudeCommand is the Claude Code CLI executable
+const ClaudeCommand = "claude"
+
+// claudeStatePaths are written by Claude Code itself and stay writable when a
+// sandbox profile mounts the filesystem read-only.
+var claudeStatePaths = []string{"~/.claude", "~/.claude.json"}
+
 // ClaudeCode implements the Agent interface for Claude Code CLI
 type ClaudeCode struct {
 	config    Config
@@ -23,6 +30,14 @@ type ClaudeCode struct {
 	startTime time.Time
 }
 
+// ClaudeArgs returns the flags Claude Code is started with, before the
+// prompt.
+func (c Config) ClaudeArgs() []string {
+	args := []string{"-p", "--output-format", "stream-json", "--verbose"}
+	args = append(args, c.Sandbox.ClaudeArgs()...)
+	return append(args, c.resumeArgs()...)
+}
+
 // NewClaudeCode creates a new Claude Code agent instance
 func NewClaudeCode(config Config) *ClaudeCode {
 	return &ClaudeCode{
@@ -52,14 +67,10 @@ func (c *ClaudeCode) Start(ctx context.Context, prompt string) error {
 	}
 
 	// Build command: claude -p --output-format stream-json --verbose --dangerously-skip-permissions "prompt"
-	// Using stream-json for real-time output instead of --print which buffers
-	c.cmd = exec.CommandContext(c.ctx, "claude",
-		"-p",
-		"--output-format", "stream-json",
-		"--verbose",
-		"--dangerously-skip-permissions",
-		prompt,
-	)
+	// Using stream-json for real-time output instead of --print which buffers.
+	// A sandbox profile may replace the permission flag with tool allowlists.
+	args := c.config.ClaudeArgs()
+	c.cmd = exec.CommandContext(c.ctx, ClaudeCommand, append(args, prompt)...)
 
 	// Create a new process group so we can signal all children
 	setProcAttr(c.cmd)
@@ -69,12 +80,15 @@ func (c *ClaudeCode) Start(ctx context.Context, prompt string) error {
 		c.cmd.Dir = c.config.WorkDir
 	}
 
-	// Set environment
-	if len(c.config.Env) > 0 {
-		c.cmd.Env = os.Environ()
-		for k, v := range c.config.Env {
-			c.cmd.Env = append(c.cmd.Env, k+"="+v)
-		}
+	// Set environment, filtered by the sandbox profile's allowlist if any
+	if len(c.config.Env) > 0 || c.config.Sandbox != nil {
+		c.cmd.Env = c.config.Sandbox.Environ(os.Environ(), c.config.Env)
+	}
+
+	// Apply resource limits and filesystem restrictions
+	if err := c.config.Sandbox.Wrap(c.cmd, c.cmd.Dir, claudeStatePaths); err != nil {
+		c.cancel()
+		return err
 	}
 
 	// Capture stdout/stderr
@@ -171,6 +185,17 @@ func (c *ClaudeCode) IsRunning() bool {
 	return c.running
 }
 
+// Command returns the executable the agent process runs.
+func (c *ClaudeCode) Command() string {
+	return ClaudeCommand
+}
+
+// CommandLine returns the command Start runs for prompt.
+func (c *ClaudeCode) CommandLine(prompt string) []string {
+	args := append([]string{ClaudeCommand}, c.config.ClaudeArgs()...)
+	return append(args, prompt)
+}
+
 // PID returns the process ID for the running agent, or 0 if unavailable.
 func (c *ClaudeCode) PID() int {
 	c.mu.Lock()
@@ -3,8 +3,11 @@
 package agent
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"syscall"
 )
 
@@ -27,3 +30,30 @@ func killProcessTree(pid int, process *os.Process, force bool) error {
 	}
 	return nil
 }
+
+// ProcessAlive reports whether a process with the given PID exists
+func ProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// ProcessMatches reports whether the process looks like it runs command. It
+// checks /proc where available and otherwise assumes a match. Scripts run
+// through an interpreter (e.g. node), and agents started through a sandbox
+// wrapper (e.g. bwrap), carry the command as a later argument.
+func ProcessMatches(pid int, command string) bool {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return true
+	}
+	args := strings.Split(string(data), "\x00")
+	for i := 0; i < len(args); i++ {
+		if filepath.Base(args[i]) == command {
+			return true
+		}
+	}
+	return false
+}
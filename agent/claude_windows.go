@@ -6,8 +6,15 @@ import (
 	"os"
 	"os/exec"
 	"strconv"
+	"syscall"
 )
 
+// processQueryLimitedInformation is PROCESS_QUERY_LIMITED_INFORMATION
+const processQueryLimitedInformation = 0x1000
+
+// stillActive is the exit code reported for a process that has not exited
+const stillActive = 259
+
 // setProcAttr is a no-op on Windows (no process groups)
 func setProcAttr(cmd *exec.Cmd) {
 	// Windows doesn't support Setpgid
@@ -29,3 +36,27 @@ func killProcessTree(pid int, process *os.Process, force bool) error {
 	}
 	return nil
 }
+
+// ProcessAlive reports whether a process with the given PID is still running
+func ProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h)
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
+
+// ProcessMatches reports whether the process looks like it runs command.
+// Windows offers no cheap way to check, so it always assumes a match.
+func ProcessMatches(pid int, command string) bool {
+	return true
+}
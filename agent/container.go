@@ -0,0 +1,378 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContainerAgent is the registry name of the container backend
+const ContainerAgent = "container"
+
+// containerStopTimeout is how long a cancelled container gets to exit before
+// the runtime kills it.
+const containerStopTimeout = 3 * time.Second
+
+// containerRuntimes are tried in order when no runtime is configured; a
+// variable so tests can substitute them.
+var containerRuntimes = []string{"docker", "podman"}
+
+// ContainerConfig configures agents that run inside a disposable container.
+type ContainerConfig struct {
+	// Runtime is the container CLI, e.g. "docker" or "podman" (default:
+	// whichever is installed, preferring docker)
+	Runtime string `json:"runtime,omitempty"`
+	// Image provides the Claude Code CLI and the task's toolchain
+	Image string `json:"image,omitempty"`
+	// Command is the agent CLI inside the image (default "claude")
+	Command string `json:"command,omitempty"`
+	// Network is passed to --network: "none", "bridge", "host" or a named
+	// network (default: the runtime's default)
+	Network string `json:"network,omitempty"`
+	// CPUs limits the number of CPUs, e.g. "2" or "1.5"
+	CPUs string `json:"cpus,omitempty"`
+	// Memory limits memory, e.g. "4g"
+	Memory string `json:"memory,omitempty"`
+	// PidsLimit bounds the number of processes in the container
+	PidsLimit int `json:"pids-limit,omitempty"`
+	// Env lists host environment variables passed into the container.
+	// Variables set explicitly for the agent always pass.
+	Env []string `json:"env,omitempty"`
+	// Mounts are extra volumes in the runtime's -v syntax; a leading "~/"
+	// in the host path is expanded
+	Mounts []string `json:"mounts,omitempty"`
+	// User runs the agent as this user, e.g. "1000:1000" (default: the
+	// image's user)
+	User string `json:"user,omitempty"`
+}
+
+// Validate checks the container settings.
+func (c ContainerConfig) Validate() error {
+	if c.Image == "" {
+		return errors.New("container agent needs an image")
+	}
+	if c.PidsLimit < 0 {
+		return errors.New("pids-limit must not be negative")
+	}
+	if c.CPUs != "" {
+		if n, err := strconv.ParseFloat(c.CPUs, 64); err != nil || n <= 0 {
+			return fmt.Errorf("invalid cpus %q", c.CPUs)
+		}
+	}
+	for _, name := range c.Env {
+		if name == "" || strings.Contains(name, "=") {
+			return fmt.Errorf("invalid env name %q", name)
+		}
+	}
+	return nil
+}
+
+// Describe summarises the container settings for logs and status output.
+func (c ContainerConfig) Describe() string {
+	parts := []string{"container " + c.Image}
+	if c.Network != "" {
+		parts = append(parts, "network "+c.Network)
+	}
+	if c.CPUs != "" {
+		parts = append(parts, "cpus "+c.CPUs)
+	}
+	if c.Memory != "" {
+		parts = append(parts, "memory "+c.Memory)
+	}
+	if c.PidsLimit > 0 {
+		parts = append(parts, "pids "+strconv.Itoa(c.PidsLimit))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Container implements the Agent interface by running the agent CLI in a
+// fresh container with the working directory bind-mounted at the same path.
+// The container is removed when the agent exits.
+type Container struct {
+	config  Config
+	spec    ContainerConfig
+	runtime string
+	name    string
+	cmd     *exec.Cmd
+	stdout  io.ReadCloser
+	stderr  io.ReadCloser
+	ctx     context.Context
+	cancel  context.CancelFunc
+	mu      sync.Mutex
+	running bool
+}
+
+// NewContainer creates a container agent instance
+func NewContainer(config Config, spec ContainerConfig) *Container {
+	return &Container{
+		config: config,
+		spec:   spec,
+	}
+}
+
+// Name returns the agent's display name
+func (c *Container) Name() string {
+	return "Claude Code (container)"
+}
+
+// Start creates the container and runs the agent with the given prompt
+func (c *Container) Start(ctx context.Context, prompt string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.running {
+		return ErrAgentAlreadyRunning
+	}
+	if err := c.spec.Validate(); err != nil {
+		return err
+	}
+
+	runtime, err := c.findRuntime()
+	if err != nil {
+		return err
+	}
+	dir, err := filepath.Abs(c.config.WorkDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve workdir: %w", err)
+	}
+	name, err := containerName()
+	if err != nil {
+		return err
+	}
+	c.runtime, c.name = runtime, name
+
+	if c.config.Timeout > 0 {
+		c.ctx, c.cancel = context.WithTimeout(ctx, c.config.Timeout)
+	} else {
+		c.ctx, c.cancel = context.WithCancel(ctx)
+	}
+
+	c.cmd = exec.CommandContext(c.ctx, runtime, c.runArgs(name, dir, prompt)...)
+	// Killing the CLI client alone would leave the container running
+	c.cmd.Cancel = func() error {
+		exec.Command(runtime, "kill", name).Run()
+		return c.cmd.Process.Kill()
+	}
+	c.cmd.WaitDelay = containerStopTimeout
+	setProcAttr(c.cmd)
+
+	// "-e NAME" reads values from the client's environment, keeping them
+	// off the command line
+	c.cmd.Env = os.Environ()
+	for k, v := range c.config.Env {
+		c.cmd.Env = append(c.cmd.Env, k+"="+v)
+	}
+
+	c.stdout, err = c.cmd.StdoutPipe()
+	if err != nil {
+		c.cancel()
+		return fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	c.stderr, err = c.cmd.StderrPipe()
+	if err != nil {
+		c.cancel()
+		return fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
+
+	if err := c.cmd.Start(); err != nil {
+		c.cancel()
+		return fmt.Errorf("failed to start %s: %w", runtime, err)
+	}
+
+	c.running = true
+	return nil
+}
+
+// findRuntime resolves the configured container CLI, or the first installed one.
+func (c *Container) findRuntime() (string, error) {
+	candidates := containerRuntimes
+	if c.spec.Runtime != "" {
+		candidates = []string{c.spec.Runtime}
+	}
+	for _, candidate := range candidates {
+		if path, err := exec.LookPath(candidate); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%w: no container runtime (%s)", ErrAgentNotFound, strings.Join(candidates, ", "))
+}
+
+// runArgs builds the `run` invocation for the prompt.
+func (c *Container) runArgs(name, dir, prompt string) []string {
+	args := []string{"run", "--rm", "--init", "--name", name,
+		"--label", "momentum.agent=true",
+		"-v", dir + ":" + dir, "-w", dir,
+	}
+	if c.spec.Network != "" {
+		args = append(args, "--network", c.spec.Network)
+	}
+	if c.spec.CPUs != "" {
+		args = append(args, "--cpus", c.spec.CPUs)
+	}
+	if c.spec.Memory != "" {
+		args = append(args, "--memory", c.spec.Memory)
+	}
+	if c.spec.PidsLimit > 0 {
+		args = append(args, "--pids-limit", strconv.Itoa(c.spec.PidsLimit))
+	}
+	if c.spec.User != "" {
+		args = append(args, "--user", c.spec.User)
+	}
+	for _, mount := range c.spec.Mounts {
+		args = append(args, "-v", expandMount(mount))
+	}
+
+	// Pass variables by name only
+	names := append([]string(nil), c.spec.Env...)
+	for k := range c.config.Env {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		if i == 0 || name != names[i-1] {
+			args = append(args, "-e", name)
+		}
+	}
+
+	command := c.spec.Command
+	if command == "" {
+		command = ClaudeCommand
+	}
+	args = append(args, c.spec.Image, command)
+	args = append(args, c.config.ClaudeArgs()...)
+	return append(args, prompt)
+}
+
+// CommandLine returns the command Start runs for prompt. The container's
+// name is only chosen when it starts.
+func (c *Container) CommandLine(prompt string) []string {
+	runtime := strings.Join(containerRuntimes, "|")
+	if path, err := c.findRuntime(); err == nil {
+		runtime = filepath.Base(path)
+	} else if c.spec.Runtime != "" {
+		runtime = c.spec.Runtime
+	}
+	dir, err := filepath.Abs(c.config.WorkDir)
+	if err != nil {
+		dir = c.config.WorkDir
+	}
+	return append([]string{runtime}, c.runArgs("momentum-<id>", dir, prompt)...)
+}
+
+// Stdout returns a reader for the container's stdout
+func (c *Container) Stdout() io.Reader {
+	return c.stdout
+}
+
+// Stderr returns a reader for the container's stderr
+func (c *Container) Stderr() io.Reader {
+	return c.stderr
+}
+
+// Wait blocks until the container exits and returns the agent's exit code
+func (c *Container) Wait() (int, error) {
+	if c.cmd == nil {
+		return -1, ErrAgentNotStarted
+	}
+
+	err := c.cmd.Wait()
+
+	c.mu.Lock()
+	c.running = false
+	c.mu.Unlock()
+	c.cancel()
+
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return exitErr.ExitCode(), nil
+		}
+		return -1, err
+	}
+	return 0, nil
+}
+
+// Cancel stops the container, killing it if it does not exit in time
+func (c *Container) Cancel() error {
+	c.mu.Lock()
+	if !c.running {
+		c.mu.Unlock()
+		return nil
+	}
+	runtime, name, cancel := c.runtime, c.name, c.cancel
+	c.mu.Unlock()
+
+	// Don't call Wait() here - the Runner's Wait() goroutine handles that
+	go func() {
+		seconds := strconv.Itoa(int(containerStopTimeout.Seconds()))
+		exec.Command(runtime, "stop", "--time", seconds, name).Run()
+		// Kills the container and client if they are still running
+		cancel()
+	}()
+	return nil
+}
+
+// IsRunning returns whether the container is running
+func (c *Container) IsRunning() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.running
+}
+
+// Command returns the container CLI the agent process runs, e.g. "docker",
+// or "" before it has started.
+func (c *Container) Command() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.runtime == "" {
+		return ""
+	}
+	return filepath.Base(c.runtime)
+}
+
+// PID returns the container CLI client's process ID, or 0 if unavailable.
+func (c *Container) PID() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cmd == nil || c.cmd.Process == nil {
+		return 0
+	}
+	return c.cmd.Process.Pid
+}
+
+// ContainerName returns the name of the agent's container, or "" before Start.
+func (c *Container) ContainerName() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.name
+}
+
+// containerName returns a unique container name.
+func containerName() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to name container: %w", err)
+	}
+	return "momentum-" + hex.EncodeToString(b), nil
+}
+
+// expandMount expands a leading "~/" in a -v mount's host path.
+func expandMount(mount string) string {
+	if strings.HasPrefix(mount, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + mount[1:]
+		}
+	}
+	return mount
+}
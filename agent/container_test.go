@@ -0,0 +1,183 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirsjg/momentum/sandbox"
+)
+
+// fakeRuntime creates a container CLI stand-in that logs its arguments to
+// the returned file. `run` prints $TOKEN and then executes script.
+func fakeRuntime(t *testing.T, script string) (string, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake runtime is a shell script")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls.log")
+	path := filepath.Join(dir, "docker")
+	body := "#!/bin/sh\necho \"$@\" >> " + log + "\n" +
+		"if [ \"$1\" = run ]; then\n  echo \"token=$TOKEN\"\n  echo warning >&2\n  " + script + "\nfi\n"
+	if err := os.WriteFile(path, []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path, log
+}
+
+func readCalls(t *testing.T, log string) []string {
+	t.Helper()
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestContainerConfigValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		spec  ContainerConfig
+		valid bool
+	}{
+		{"minimal", ContainerConfig{Image: "momentum-agent"}, true},
+		{"limits", ContainerConfig{Image: "i", CPUs: "1.5", Memory: "4g", PidsLimit: 256}, true},
+		{"no image", ContainerConfig{}, false},
+		{"bad cpus", ContainerConfig{Image: "i", CPUs: "lots"}, false},
+		{"negative pids", ContainerConfig{Image: "i", PidsLimit: -1}, false},
+		{"env assignment", ContainerConfig{Image: "i", Env: []string{"TOKEN=x"}}, false},
+	} {
+		err := tc.spec.Validate()
+		if (err == nil) != tc.valid {
+			t.Errorf("%s: valid=%v, got %v", tc.name, tc.valid, err)
+		}
+	}
+}
+
+func TestContainerRun(t *testing.T) {
+	docker, log := fakeRuntime(t, "exit 3")
+	work := t.TempDir()
+
+	c := NewContainer(Config{
+		WorkDir:       work,
+		Env:           map[string]string{"TOKEN": "secret"},
+		Sandbox:       &sandbox.Profile{Permissions: sandbox.Permissions{AllowedTools: []string{"Read"}}},
+		ResumeSession: "abc-123",
+	}, ContainerConfig{
+		Runtime:   docker,
+		Image:     "momentum-agent:latest",
+		Network:   "none",
+		CPUs:      "2",
+		Memory:    "4g",
+		PidsLimit: 128,
+		Env:       []string{"ANTHROPIC_API_KEY"},
+		User:      "1000:1000",
+	})
+	preview := strings.Join(c.CommandLine("do the task"), " ")
+	if err := c.Start(context.Background(), "do the task"); err != nil {
+		t.Fatal(err)
+	}
+	stdout, _ := io.ReadAll(c.Stdout())
+	stderr, _ := io.ReadAll(c.Stderr())
+	code, err := c.Wait()
+	if err != nil || code != 3 {
+		t.Fatalf("expected the container's exit code 3, got %d (%v)", code, err)
+	}
+	if string(stdout) != "token=secret\n" || string(stderr) != "warning\n" {
+		t.Errorf("unexpected streams: stdout %q, stderr %q", stdout, stderr)
+	}
+
+	want := "run --rm --init --name " + c.ContainerName() + " --label momentum.agent=true" +
+		" -v " + work + ":" + work + " -w " + work +
+		" --network none --cpus 2 --memory 4g --pids-limit 128 --user 1000:1000" +
+		" -e ANTHROPIC_API_KEY -e TOKEN momentum-agent:latest" +
+		" claude -p --output-format stream-json --verbose --allowedTools Read --resume abc-123 do the task"
+	if calls := readCalls(t, log); len(calls) != 1 || calls[0] != want {
+		t.Errorf("unexpected invocation:\n got %v\nwant %s", calls, want)
+	}
+	// The preview shows the same invocation, before a name is chosen
+	if got := strings.Replace(preview, "momentum-<id>", c.ContainerName(), 1); got != "docker "+want {
+		t.Errorf("unexpected preview:\n got %s\nwant docker %s", got, want)
+	}
+	if strings.Contains(want, "secret") {
+		t.Error("expected env values to stay off the command line")
+	}
+	// Crash recovery matches the container CLI's process, not claude
+	if got := NewRunner(c).Command(); got != "docker" {
+		t.Errorf("expected the runtime as the command, got %q", got)
+	}
+}
+
+func TestContainerCancelStopsContainer(t *testing.T) {
+	docker, log := fakeRuntime(t, "exec sleep 30")
+
+	c := NewContainer(Config{WorkDir: t.TempDir()}, ContainerConfig{Runtime: docker, Image: "i"})
+	if err := c.Start(context.Background(), "prompt"); err != nil {
+		t.Fatal(err)
+	}
+	go io.Copy(io.Discard, c.Stdout())
+	go io.Copy(io.Discard, c.Stderr())
+
+	if err := c.Cancel(); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Wait()
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the agent to exit after Cancel")
+	}
+	if c.IsRunning() {
+		t.Error("expected agent to not be running")
+	}
+
+	// The fake ignores stop, so the container is killed as well
+	calls := strings.Join(readCalls(t, log), "\n")
+	if !strings.Contains(calls, "stop --time 3 "+c.ContainerName()) || !strings.Contains(calls, "kill "+c.ContainerName()) {
+		t.Errorf("expected the container to be stopped and killed, got:\n%s", calls)
+	}
+}
+
+func TestContainerTimeoutKillsContainer(t *testing.T) {
+	docker, log := fakeRuntime(t, "exec sleep 30")
+
+	c := NewContainer(Config{WorkDir: t.TempDir(), Timeout: 100 * time.Millisecond}, ContainerConfig{Runtime: docker, Image: "i"})
+	if err := c.Start(context.Background(), "prompt"); err != nil {
+		t.Fatal(err)
+	}
+	go io.Copy(io.Discard, c.Stdout())
+	go io.Copy(io.Discard, c.Stderr())
+	c.Wait()
+
+	if calls := strings.Join(readCalls(t, log), "\n"); !strings.Contains(calls, "kill "+c.ContainerName()) {
+		t.Errorf("expected the container to be killed on timeout, got:\n%s", calls)
+	}
+}
+
+func TestContainerNoRuntime(t *testing.T) {
+	old := containerRuntimes
+	defer func() { containerRuntimes = old }()
+	containerRuntimes = []string{"momentum-no-such-docker"}
+
+	c := NewContainer(Config{}, ContainerConfig{Image: "i"})
+	if err := c.Start(context.Background(), "prompt"); !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("expected ErrAgentNotFound, got %v", err)
+	}
+	if err := NewContainer(Config{}, ContainerConfig{}).Start(context.Background(), "prompt"); err == nil {
+		t.Error("expected an error without an image")
+	}
+	if c.IsRunning() {
+		t.Error("expected agent to not be running")
+	}
+}
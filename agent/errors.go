@@ -17,4 +17,13 @@ var (
 
 	// ErrAgentCancelled is returned when the agent execution was cancelled
 	ErrAgentCancelled = errors.New("agent execution was cancelled")
+
+	// ErrExitCodeUnknown is returned when waiting on an attached process whose exit status cannot be observed
+	ErrExitCodeUnknown = errors.New("agent exit code is unknown")
+
+	// ErrInputNotSupported is returned when an agent cannot take messages while it runs
+	ErrInputNotSupported = errors.New("agent does not accept input while running")
+
+	// ErrProcessNotRunning is returned when attaching to a process that has already exited
+	ErrProcessNotRunning = errors.New("agent process is not running")
 )
@@ -0,0 +1,327 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeAgent is the registry name of the scripted agent
+const FakeAgent = "fake"
+
+// Failure modes simulated by the fake agent
+const (
+	// FakeHang replays the transcript and then never exits on its own
+	FakeHang = "hang"
+	// FakeCrash stops halfway through the transcript with an error on stderr
+	FakeCrash = "crash"
+	// FakePartial stops halfway through a transcript line
+	FakePartial = "partial"
+	// FakeHugeLine emits a line larger than the runner's line limit before
+	// the last transcript line
+	FakeHugeLine = "huge-line"
+)
+
+// fakeHugeLineSize is the length of the line emitted by FakeHugeLine
+const fakeHugeLineSize = 2 * MaxLineSize
+
+// defaultTranscript is replayed when no transcript file is configured: a
+// minimal successful Claude Code run in stream-json format.
+var defaultTranscript = []string{
+	`{"type":"system","subtype":"init","session_id":"fake-session","model":"fake","tools":[]}`,
+	`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Working on the task."}]},"session_id":"fake-session"}`,
+	`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"The task is complete."}]},"session_id":"fake-session"}`,
+	`{"type":"result","subtype":"success","is_error":false,"result":"The task is complete.","session_id":"fake-session"}`,
+}
+
+// FakeConfig scripts the fake agent, which stands in for Claude Code in
+// tests and CI.
+type FakeConfig struct {
+	// Transcript is a stream-json file replayed line by line to stdout
+	// (default: a short successful run)
+	Transcript string `json:"transcript,omitempty"`
+	// Delay is the pause before each line, as a Go duration string
+	Delay string `json:"delay,omitempty"`
+	// ExitCode is returned once the transcript has been replayed
+	ExitCode int `json:"exit-code,omitempty"`
+	// Failure simulates a misbehaving agent: "hang", "crash", "partial" or
+	// "huge-line"
+	Failure string `json:"failure,omitempty"`
+	// Files are written to the working directory, relative paths mapped to
+	// contents, after the transcript has been replayed
+	Files map[string]string `json:"files,omitempty"`
+	// Interactive accepts messages while running; otherwise the agent is
+	// stopped and resumed like Claude Code
+	Interactive bool `json:"interactive,omitempty"`
+}
+
+// Validate checks the script's settings.
+func (c FakeConfig) Validate() error {
+	if c.Delay != "" {
+		if d, err := time.ParseDuration(c.Delay); err != nil || d < 0 {
+			return fmt.Errorf("invalid delay %q", c.Delay)
+		}
+	}
+	switch c.Failure {
+	case "", FakeHang, FakeCrash, FakePartial, FakeHugeLine:
+	default:
+		return fmt.Errorf("unknown failure mode %q (hang, crash, partial or huge-line)", c.Failure)
+	}
+	for path := range c.Files {
+		if !filepath.IsLocal(path) {
+			return fmt.Errorf("file %q must be relative to the working directory", path)
+		}
+	}
+	return nil
+}
+
+// transcript returns the lines to replay.
+func (c FakeConfig) transcript() ([]string, error) {
+	if c.Transcript == "" {
+		return defaultTranscript, nil
+	}
+	data, err := os.ReadFile(c.Transcript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fake transcript: %w", err)
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("fake transcript %s is empty", c.Transcript)
+	}
+	return lines, nil
+}
+
+// Fake implements the Agent interface by replaying a scripted transcript
+// in-process instead of running Claude Code.
+type Fake struct {
+	config  Config
+	script  FakeConfig
+	stdout  *io.PipeReader
+	stderr  *io.PipeReader
+	outW    *io.PipeWriter
+	errW    *io.PipeWriter
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	mu      sync.Mutex
+	running bool
+	prompt  string
+	code    int
+	// messages are the user messages received while running
+	messages []string
+}
+
+// NewFake creates a scripted agent instance
+func NewFake(config Config, script FakeConfig) *Fake {
+	return &Fake{
+		config: config,
+		script: script,
+	}
+}
+
+// Name returns the agent's display name
+func (f *Fake) Name() string {
+	return "Fake"
+}
+
+// Start begins replaying the transcript; prompt is only recorded
+func (f *Fake) Start(ctx context.Context, prompt string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.running {
+		return ErrAgentAlreadyRunning
+	}
+	if err := f.script.Validate(); err != nil {
+		return err
+	}
+	lines, err := f.script.transcript()
+	if err != nil {
+		return err
+	}
+	delay, _ := time.ParseDuration(f.script.Delay)
+
+	var cancel context.CancelFunc
+	if f.config.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, f.config.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	f.stdout, f.outW = io.Pipe()
+	f.stderr, f.errW = io.Pipe()
+	f.stop = make(chan struct{})
+	f.done = make(chan struct{})
+	f.prompt = prompt
+	f.running = true
+
+	go func() {
+		defer cancel()
+		code := f.play(ctx, lines, delay)
+		f.outW.Close()
+		f.errW.Close()
+
+		f.mu.Lock()
+		f.code = code
+		f.running = false
+		f.mu.Unlock()
+		close(f.done)
+	}()
+	return nil
+}
+
+// play replays the transcript and returns the exit code.
+func (f *Fake) play(ctx context.Context, lines []string, delay time.Duration) int {
+	half := len(lines) / 2
+	for i, line := range lines {
+		if !f.pause(ctx, delay) {
+			return -1
+		}
+		switch {
+		case f.script.Failure == FakeCrash && i == half:
+			io.WriteString(f.errW, "Error: fake agent crashed\n    at replay (fake.js:1:1)\n")
+			if f.script.ExitCode != 0 {
+				return f.script.ExitCode
+			}
+			return 1
+		case f.script.Failure == FakePartial && i == half:
+			io.WriteString(f.outW, line[:len(line)/2])
+			return f.script.ExitCode
+		case f.script.Failure == FakeHugeLine && i == len(lines)-1:
+			huge := `{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"` +
+				strings.Repeat("x", fakeHugeLineSize) + `"}]}}` + "\n"
+			if _, err := io.WriteString(f.outW, huge); err != nil {
+				return -1
+			}
+		}
+		if _, err := io.WriteString(f.outW, line+"\n"); err != nil {
+			return -1
+		}
+	}
+
+	if f.script.Failure == FakeHang {
+		select {
+		case <-ctx.Done():
+		case <-f.stop:
+		}
+		return -1
+	}
+
+	for path, content := range f.script.Files {
+		full := filepath.Join(f.config.WorkDir, path)
+		err := os.MkdirAll(filepath.Dir(full), 0o755)
+		if err == nil {
+			err = os.WriteFile(full, []byte(content), 0o644)
+		}
+		if err != nil {
+			fmt.Fprintf(f.errW, "Error: %v\n", err)
+			return 1
+		}
+	}
+	return f.script.ExitCode
+}
+
+// pause waits for d, reporting false if the agent was stopped meanwhile.
+func (f *Fake) pause(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-f.stop:
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// Stdout returns a reader for the replayed transcript
+func (f *Fake) Stdout() io.Reader {
+	return f.stdout
+}
+
+// Stderr returns a reader for the agent's error output
+func (f *Fake) Stderr() io.Reader {
+	return f.stderr
+}
+
+// Wait blocks until the script completes and returns the exit code
+func (f *Fake) Wait() (int, error) {
+	f.mu.Lock()
+	done := f.done
+	f.mu.Unlock()
+	if done == nil {
+		return -1, ErrAgentNotStarted
+	}
+
+	<-done
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.code, nil
+}
+
+// Cancel stops the replay; the agent exits with code -1 like a killed process
+func (f *Fake) Cancel() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.running {
+		return nil
+	}
+	f.once.Do(func() {
+		close(f.stop)
+		// Unblock a write the runner is not reading
+		f.outW.CloseWithError(ErrAgentCancelled)
+		f.errW.CloseWithError(ErrAgentCancelled)
+	})
+	return nil
+}
+
+// SendMessage records a user message when the script is interactive
+func (f *Fake) SendMessage(text string) error {
+	if !f.script.Interactive {
+		return ErrInputNotSupported
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.running {
+		return ErrProcessNotRunning
+	}
+	f.messages = append(f.messages, text)
+	return nil
+}
+
+// Messages returns the user messages the agent received while running.
+func (f *Fake) Messages() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.messages...)
+}
+
+// IsRunning returns whether the script is still playing
+func (f *Fake) IsRunning() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.running
+}
+
+// Prompt returns the prompt the agent was started with.
+func (f *Fake) Prompt() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.prompt
+}
+
+// ResumedSession returns the session the agent was asked to resume, if any.
+func (f *Fake) ResumedSession() string {
+	return f.config.ResumeSession
+}
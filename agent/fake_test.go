@@ -0,0 +1,203 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runFake runs a scripted agent to completion and collects its output.
+func runFake(t *testing.T, cfg Config, script FakeConfig) ([]OutputLine, Result) {
+	t.Helper()
+	runner := NewRunner(NewFake(cfg, script))
+	if err := runner.Run(context.Background(), "prompt"); err != nil {
+		t.Fatal(err)
+	}
+	var lines []OutputLine
+	for line := range runner.Output() {
+		lines = append(lines, line)
+	}
+	select {
+	case result := <-runner.Done():
+		return lines, result
+	case <-time.After(5 * time.Second):
+		t.Fatal("fake agent did not finish")
+		return nil, Result{}
+	}
+}
+
+func TestFake_DefaultTranscript(t *testing.T) {
+	lines, result := runFake(t, Config{}, FakeConfig{})
+	if result.ExitCode != 0 || result.Error != nil {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if result.SessionID != "fake-session" {
+		t.Errorf("expected the transcript's session ID, got %q", result.SessionID)
+	}
+	if len(lines) != len(defaultTranscript) {
+		t.Fatalf("expected %d lines, got %d", len(defaultTranscript), len(lines))
+	}
+	for i, line := range lines {
+		if line.Text != defaultTranscript[i] || line.IsStderr {
+			t.Errorf("line %d: unexpected %+v", i, line)
+		}
+	}
+}
+
+func TestFake_TranscriptFileAndFiles(t *testing.T) {
+	dir := t.TempDir()
+	transcript := filepath.Join(dir, "run.jsonl")
+	if err := os.WriteFile(transcript, []byte("{\"type\":\"system\"}\r\n\n{\"type\":\"result\"}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	work := t.TempDir()
+
+	lines, result := runFake(t, Config{WorkDir: work}, FakeConfig{
+		Transcript: transcript,
+		Delay:      "1ms",
+		ExitCode:   3,
+		Files:      map[string]string{"src/main.go": "package main\n"},
+	})
+	if result.ExitCode != 3 {
+		t.Errorf("expected exit code 3, got %d", result.ExitCode)
+	}
+	if len(lines) != 2 || lines[1].Text != `{"type":"result"}` {
+		t.Errorf("unexpected output %+v", lines)
+	}
+	if data, err := os.ReadFile(filepath.Join(work, "src", "main.go")); err != nil || string(data) != "package main\n" {
+		t.Errorf("expected the scripted file to be written, got %q (%v)", data, err)
+	}
+}
+
+func TestFake_Crash(t *testing.T) {
+	lines, result := runFake(t, Config{}, FakeConfig{Failure: FakeCrash})
+	if result.ExitCode != 1 {
+		t.Errorf("expected exit code 1, got %d", result.ExitCode)
+	}
+	var stdout, stderr int
+	for _, line := range lines {
+		if line.IsStderr {
+			stderr++
+		} else {
+			stdout++
+		}
+	}
+	if stdout != len(defaultTranscript)/2 || stderr == 0 {
+		t.Errorf("expected half the transcript and a stderr trace, got %+v", lines)
+	}
+}
+
+func TestFake_Partial(t *testing.T) {
+	lines, result := runFake(t, Config{}, FakeConfig{Failure: FakePartial})
+	if result.ExitCode != 0 {
+		t.Errorf("expected exit code 0, got %d", result.ExitCode)
+	}
+	last := lines[len(lines)-1].Text
+	if len(lines) != len(defaultTranscript)/2+1 || !strings.HasPrefix(defaultTranscript[len(lines)-1], last) || last == defaultTranscript[len(lines)-1] {
+		t.Errorf("expected output cut off mid-line, got %+v", lines)
+	}
+}
+
+func TestFake_HugeLine(t *testing.T) {
+	lines, result := runFake(t, Config{}, FakeConfig{Failure: FakeHugeLine})
+	if result.ExitCode != 0 {
+		t.Errorf("expected exit code 0, got %d", result.ExitCode)
+	}
+	if len(lines) != len(defaultTranscript)+1 {
+		t.Fatalf("expected the transcript plus one huge line, got %d lines", len(lines))
+	}
+	huge := lines[len(lines)-2].Text
+	if len(huge) != MaxLineSize+len(TruncatedSuffix) || !strings.HasSuffix(huge, TruncatedSuffix) {
+		t.Errorf("expected the huge line to be truncated, got %d bytes", len(huge))
+	}
+	if lines[len(lines)-1].Text != defaultTranscript[len(defaultTranscript)-1] {
+		t.Errorf("expected output after the huge line to survive, got %q", lines[len(lines)-1].Text)
+	}
+}
+
+func TestFake_HangUntilCancelled(t *testing.T) {
+	f := NewFake(Config{}, FakeConfig{Failure: FakeHang})
+	runner := NewRunner(f)
+	if err := runner.Run(context.Background(), "prompt"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(defaultTranscript); i++ {
+		<-runner.Output()
+	}
+	select {
+	case <-runner.Done():
+		t.Fatal("expected the agent to hang")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	runner.Cancel()
+	select {
+	case result := <-runner.Done():
+		if result.ExitCode != -1 {
+			t.Errorf("expected exit code -1, got %d", result.ExitCode)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Cancel to stop the agent")
+	}
+	if f.IsRunning() || f.Prompt() != "prompt" {
+		t.Errorf("unexpected state: running=%v prompt=%q", f.IsRunning(), f.Prompt())
+	}
+}
+
+func TestFake_SendMessage(t *testing.T) {
+	if err := NewFake(Config{}, FakeConfig{}).SendMessage("hi"); !errors.Is(err, ErrInputNotSupported) {
+		t.Errorf("expected ErrInputNotSupported by default, got %v", err)
+	}
+
+	f := NewFake(Config{}, FakeConfig{Failure: FakeHang, Interactive: true})
+	if err := f.SendMessage("early"); !errors.Is(err, ErrProcessNotRunning) {
+		t.Errorf("expected ErrProcessNotRunning before Start, got %v", err)
+	}
+	if err := f.Start(context.Background(), "prompt"); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Cancel()
+	go io.Copy(io.Discard, f.Stdout())
+	if err := f.SendMessage("focus on the tests"); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Messages(); len(got) != 1 || got[0] != "focus on the tests" {
+		t.Errorf("unexpected messages %v", got)
+	}
+}
+
+func TestFake_HangUntilTimeout(t *testing.T) {
+	_, result := runFake(t, Config{Timeout: 20 * time.Millisecond}, FakeConfig{Failure: FakeHang})
+	if result.ExitCode != -1 {
+		t.Errorf("expected exit code -1, got %d", result.ExitCode)
+	}
+}
+
+func TestFakeConfigValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		script FakeConfig
+		valid  bool
+	}{
+		{"empty", FakeConfig{}, true},
+		{"scripted", FakeConfig{Delay: "10ms", Failure: FakeHang, Files: map[string]string{"a/b.txt": ""}}, true},
+		{"bad delay", FakeConfig{Delay: "soon"}, false},
+		{"unknown failure", FakeConfig{Failure: "explode"}, false},
+		{"escaping file", FakeConfig{Files: map[string]string{"../x": ""}}, false},
+		{"absolute file", FakeConfig{Files: map[string]string{"/etc/x": ""}}, false},
+	} {
+		err := tc.script.Validate()
+		if (err == nil) != tc.valid {
+			t.Errorf("%s: valid=%v, got %v", tc.name, tc.valid, err)
+		}
+	}
+
+	if err := NewFake(Config{}, FakeConfig{Transcript: filepath.Join(t.TempDir(), "missing")}).Start(context.Background(), "p"); err == nil {
+		t.Error("expected an error for a missing transcript")
+	}
+}
@@ -24,6 +24,9 @@ func NewRegistry() *Registry {
 	r.Register("claude", func(cfg Config) Agent {
 		return NewClaudeCode(cfg)
 	})
+	r.Register(FakeAgent, func(cfg Config) Agent {
+		return NewFake(cfg, FakeConfig{})
+	})
 
 	return r
 }
@@ -2,12 +2,20 @@ package agent
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"io"
 	"sync"
 	"time"
 )
 
+// MaxLineSize is the longest output line passed on whole; longer lines are
+// truncated and marked with TruncatedSuffix.
+const MaxLineSize = 1024 * 1024
+
+// TruncatedSuffix marks an output line cut at MaxLineSize
+const TruncatedSuffix = " [truncated]"
+
 // Runner manages agent execution and output streaming
 type Runner struct {
 	agent      Agent
@@ -16,12 +24,17 @@ type Runner struct {
 	mu         sync.Mutex
 	running    bool
 	startTime  time.Time
+	sessionID  string
 }
 
 type pidProvider interface {
 	PID() int
 }
 
+type commandProvider interface {
+	Command() string
+}
+
 // NewRunner creates a new agent runner
 func NewRunner(agent Agent) *Runner {
 	return &Runner{
@@ -76,12 +89,14 @@ func (r *Runner) Run(ctx context.Context, prompt string) error {
 		r.mu.Lock()
 		duration := time.Since(r.startTime)
 		r.running = false
+		sessionID := r.sessionID
 		r.mu.Unlock()
 
 		r.doneChan <- Result{
-			ExitCode: exitCode,
-			Duration: duration,
-			Error:    err,
+			ExitCode:  exitCode,
+			Duration:  duration,
+			Error:     err,
+			SessionID: sessionID,
 		}
 		close(r.outputChan)
 		close(r.doneChan)
@@ -95,14 +110,17 @@ func (r *Runner) streamOutput(reader io.Reader, isStderr bool) {
 		return
 	}
 
-	scanner := bufio.NewScanner(reader)
-	// Increase buffer size for long lines
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
-
-	for scanner.Scan() {
+	br := bufio.NewReaderSize(reader, 64*1024)
+	for {
+		text, err := readLine(br)
+		if err != nil && text == "" {
+			return
+		}
+		if !isStderr {
+			r.noteSession(text)
+		}
 		line := OutputLine{
-			Text:      scanner.Text(),
+			Text:      text,
 			IsStderr:  isStderr,
 			Timestamp: time.Now(),
 		}
@@ -117,6 +135,47 @@ func (r *Runner) streamOutput(reader io.Reader, isStderr bool) {
 			default:
 			}
 		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// noteSession records the first session ID the agent reports.
+func (r *Runner) noteSession(text string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sessionID == "" {
+		r.sessionID = SessionID(text)
+	}
+}
+
+// readLine reads a line without its line ending. Lines longer than
+// MaxLineSize are truncated, and the rest is discarded so the agent never
+// blocks on a full pipe. A final line without a newline is returned along
+// with the read error.
+func readLine(br *bufio.Reader) (string, error) {
+	var (
+		line      []byte
+		truncated bool
+	)
+	for {
+		chunk, err := br.ReadSlice('\n')
+		content := bytes.TrimSuffix(chunk, []byte("\n"))
+		if room := MaxLineSize - len(line); len(content) > room {
+			line = append(line, content[:max(room, 0)]...)
+			truncated = true
+		} else {
+			line = append(line, content...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if truncated {
+			return string(line) + TruncatedSuffix, err
+		}
+		return string(line), err
 	}
 }
 
@@ -142,6 +201,14 @@ func (r *Runner) IsRunning() bool {
 	return r.running
 }
 
+// SessionID returns the Claude Code session the agent reported, or "" if it
+// has not reported one yet.
+func (r *Runner) SessionID() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sessionID
+}
+
 // Agent returns the underlying agent
 func (r *Runner) Agent() Agent {
 	return r.agent
@@ -157,3 +224,15 @@ func (r *Runner) PID() int {
 	}
 	return 0
 }
+
+// Command returns the executable the agent's process runs, for matching the
+// PID after a restart, or "" if the agent has no process of its own.
+func (r *Runner) Command() string {
+	if r == nil {
+		return ""
+	}
+	if provider, ok := r.agent.(commandProvider); ok {
+		return provider.Command()
+	}
+	return ""
+}
@@ -0,0 +1,30 @@
+package agent
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// SessionID returns the Claude Code session ID reported by a stream-json
+// output line, or "" if the line carries none.
+func SessionID(line string) string {
+	if !strings.Contains(line, `"session_id"`) {
+		return ""
+	}
+	var msg struct {
+		SessionID string `json:"session_id"`
+	}
+	if err := json.Unmarshal([]byte(line), &msg); err != nil {
+		return ""
+	}
+	return msg.SessionID
+}
+
+// resumeArgs returns the Claude Code flags that continue the configured
+// session, if any.
+func (c Config) resumeArgs() []string {
+	if c.ResumeSession == "" {
+		return nil
+	}
+	return []string{"--resume", c.ResumeSession}
+}
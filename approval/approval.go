@@ -0,0 +1,118 @@
+// Package approval decides which successful agent runs need a human review
+// before their task is marked done.
+//
+// A run needs approval when its task belongs to a listed epic or project, or
+// when it changed a file matching one of the protected path patterns.
+package approval
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sirsjg/momentum/client"
+)
+
+// DefaultStatus is where tasks wait for a reviewer.
+const DefaultStatus = "review"
+
+// Config selects the tasks that need approval.
+type Config struct {
+	// Epics are epic IDs whose tasks always need approval
+	Epics []string `json:"epics,omitempty"`
+	// Projects are project IDs whose tasks always need approval
+	Projects []string `json:"projects,omitempty"`
+	// ProtectedPaths are glob patterns; a run changing a matching file needs
+	// approval. "dir/**" matches everything under dir, and a pattern without
+	// a slash also matches base names in any directory.
+	ProtectedPaths []string `json:"protected-paths,omitempty"`
+	// Status is where tasks wait for a reviewer (default "review")
+	Status string `json:"status,omitempty"`
+}
+
+// Policy applies a Config. A nil *Policy never requires approval.
+type Policy struct {
+	cfg    Config
+	status string
+}
+
+// New validates cfg and returns its policy.
+func New(cfg Config) (*Policy, error) {
+	for _, pattern := range cfg.ProtectedPaths {
+		if _, err := path.Match(strings.TrimSuffix(pattern, "/**"), ""); err != nil || strings.TrimSpace(pattern) == "" {
+			return nil, fmt.Errorf("invalid protected path %q", pattern)
+		}
+	}
+	status := strings.TrimSpace(cfg.Status)
+	if status == "" {
+		status = DefaultStatus
+	}
+	return &Policy{cfg: cfg, status: status}, nil
+}
+
+// Enabled reports whether any task can need approval.
+func (p *Policy) Enabled() bool {
+	return p != nil && (len(p.cfg.Epics) > 0 || len(p.cfg.Projects) > 0 || len(p.cfg.ProtectedPaths) > 0)
+}
+
+// ProtectsPaths reports whether approval can depend on the files a run
+// changed, so failing to list them must be treated as needing approval.
+func (p *Policy) ProtectsPaths() bool {
+	return p != nil && len(p.cfg.ProtectedPaths) > 0
+}
+
+// Status returns the status tasks wait in for a reviewer.
+func (p *Policy) Status() string {
+	if p == nil {
+		return DefaultStatus
+	}
+	return p.status
+}
+
+// Check returns why a successful run of task needs approval, or "" if it
+// does not. changed lists the files the run changed.
+func (p *Policy) Check(task *client.Task, changed []string) string {
+	if !p.Enabled() {
+		return ""
+	}
+	if task.EpicID != "" && slices.Contains(p.cfg.Epics, task.EpicID) {
+		return fmt.Sprintf("tasks in epic %s need approval", task.EpicID)
+	}
+	if slices.Contains(p.cfg.Projects, task.ProjectID) {
+		return fmt.Sprintf("tasks in project %s need approval", task.ProjectID)
+	}
+	var protected []string
+	for _, file := range changed {
+		if p.protects(file) {
+			protected = append(protected, file)
+		}
+	}
+	if len(protected) > 0 {
+		return "changes protected paths: " + strings.Join(protected, ", ")
+	}
+	return ""
+}
+
+// protects reports whether file matches a protected path pattern.
+func (p *Policy) protects(file string) bool {
+	file = filepath.ToSlash(file)
+	for _, pattern := range p.cfg.ProtectedPaths {
+		if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+			if file == dir || strings.HasPrefix(file, dir+"/") {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(pattern, file); ok {
+			return true
+		}
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(file)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package approval
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sirsjg/momentum/client"
+)
+
+func TestNew_InvalidPattern(t *testing.T) {
+	if _, err := New(Config{ProtectedPaths: []string{"[unclosed"}}); err == nil {
+		t.Error("expected an error for a malformed pattern")
+	}
+}
+
+func TestPolicy_Nil(t *testing.T) {
+	var p *Policy
+	if p.Enabled() {
+		t.Error("nil policy should be disabled")
+	}
+	if p.Status() != DefaultStatus {
+		t.Errorf("expected default status, got %q", p.Status())
+	}
+	if reason := p.Check(&client.Task{ID: "t1"}, []string{"main.go"}); reason != "" {
+		t.Errorf("nil policy should not require approval, got %q", reason)
+	}
+}
+
+func TestPolicy_Status(t *testing.T) {
+	p, _ := New(Config{Status: "needs-review", Epics: []string{"e1"}})
+	if p.Status() != "needs-review" {
+		t.Errorf("expected configured status, got %q", p.Status())
+	}
+}
+
+func TestPolicy_CheckEpicAndProject(t *testing.T) {
+	p, _ := New(Config{Epics: []string{"epic-1"}, Projects: []string{"proj-1"}})
+
+	if reason := p.Check(&client.Task{EpicID: "epic-1", ProjectID: "other"}, nil); !strings.Contains(reason, "epic-1") {
+		t.Errorf("expected epic reason, got %q", reason)
+	}
+	if reason := p.Check(&client.Task{ProjectID: "proj-1"}, nil); !strings.Contains(reason, "proj-1") {
+		t.Errorf("expected project reason, got %q", reason)
+	}
+	if reason := p.Check(&client.Task{EpicID: "epic-2", ProjectID: "proj-2"}, []string{"main.go"}); reason != "" {
+		t.Errorf("expected no approval, got %q", reason)
+	}
+}
+
+func TestPolicy_CheckProtectedPaths(t *testing.T) {
+	p, _ := New(Config{ProtectedPaths: []string{"migrations/**", "*.sql", "deploy/prod.yaml"}})
+	task := &client.Task{ProjectID: "p"}
+
+	tests := []struct {
+		file      string
+		protected bool
+	}{
+		{"migrations/001_init.go", true},
+		{"migrations", true},
+		{"db/schema.sql", true},
+		{"deploy/prod.yaml", true},
+		{"deploy/staging.yaml", false},
+		{"migrationsx/a.go", false},
+		{"main.go", false},
+	}
+	for _, tt := range tests {
+		reason := p.Check(task, []string{tt.file})
+		if (reason != "") != tt.protected {
+			t.Errorf("%s: expected protected=%v, got reason %q", tt.file, tt.protected, reason)
+		}
+	}
+
+	reason := p.Check(task, []string{"main.go", "db/schema.sql"})
+	if !strings.Contains(reason, "db/schema.sql") || strings.Contains(reason, "main.go") {
+		t.Errorf("expected only the protected file in the reason, got %q", reason)
+	}
+}
@@ -0,0 +1,114 @@
+// Package audit keeps an append-only log of the task status changes Momentum
+// makes, so a change on the board can be traced back to the instance, run
+// and reason behind it.
+//
+// Each change is one JSON line. Several instances can share a log file:
+// every line is written with a single append.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/sirsjg/momentum/workflow"
+)
+
+// Log appends workflow events to a file. A nil *Log records nothing.
+type Log struct {
+	mu      sync.Mutex
+	f       *os.File
+	onError func(error)
+}
+
+// DefaultPath returns the default audit log location, or "" if the user
+// config directory cannot be determined.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "momentum", "audit.log")
+}
+
+// Open opens the log at path for appending, creating it and its directory if
+// needed. Failed writes are passed to onError, which may be nil.
+func Open(path string, onError func(error)) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &Log{f: f, onError: onError}, nil
+}
+
+// Observe appends e to the log; it makes a *Log a workflow.Observer.
+func (l *Log) Observe(e workflow.Event) {
+	if err := l.Write(e); err != nil && l.onError != nil {
+		l.onError(err)
+	}
+}
+
+// Write appends e to the log.
+func (l *Log) Write(e workflow.Event) error {
+	if l == nil {
+		return nil
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.f.Close()
+}
+
+// Read returns the events in the log at path, oldest first, keeping those
+// for taskID or all of them when taskID is empty. A missing log has no
+// events; lines that cannot be parsed, such as one cut short by a crash,
+// are skipped.
+func Read(path, taskID string) ([]workflow.Event, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var events []workflow.Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e workflow.Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if taskID == "" || e.TaskID == taskID {
+			events = append(events, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return events, nil
+}
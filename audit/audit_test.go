@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirsjg/momentum/workflow"
+)
+
+func TestLog_WriteAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "audit.log")
+	log, err := Open(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 3, 4, 3, 0, 0, 0, time.UTC)
+	log.Observe(workflow.Event{Time: at, TaskID: "task-1", From: "in_progress", To: "done", Reason: "agent finished", Actor: "momentum host:1"})
+	log.Observe(workflow.Event{Time: at, TaskID: "task-2", To: "todo"})
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening appends rather than truncating
+	log, err = Open(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Observe(workflow.Event{Time: at, TaskID: "task-1", From: "done", To: "todo"})
+	log.Close()
+
+	events, err := Read(path, "task-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Actor != "momentum host:1" || !events[0].Time.Equal(at) || events[1].From != "done" {
+		t.Errorf("unexpected events %+v", events)
+	}
+	if all, _ := Read(path, ""); len(all) != 3 {
+		t.Errorf("expected 3 events in total, got %d", len(all))
+	}
+}
+
+func TestRead_SkipsBadLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	data := `{"time":"2026-03-04T03:00:00Z","task_id":"task-1","to":"done"}
+{"time":"2026-03-04T03:01:00Z","task_id":"ta`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	events, err := Read(path, "")
+	if err != nil || len(events) != 1 {
+		t.Errorf("expected the complete line only, got %+v (%v)", events, err)
+	}
+}
+
+func TestRead_Missing(t *testing.T) {
+	events, err := Read(filepath.Join(t.TempDir(), "audit.log"), "")
+	if err != nil || events != nil {
+		t.Errorf("expected no events for a missing log, got %+v (%v)", events, err)
+	}
+}
+
+func TestLog_Nil(t *testing.T) {
+	var log *Log
+	log.Observe(workflow.Event{TaskID: "task-1"})
+	if err := log.Close(); err != nil {
+		t.Errorf("expected nil log to be a no-op, got %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,14 +11,31 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/sirsjg/momentum/tracing"
 )
 
 // Client is a REST client for the Flux API.
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
+	hooks      []RequestHook
+	ctx        context.Context
+}
+
+// RequestInfo describes a completed Flux API request.
+type RequestInfo struct {
+	Method string
+	Path   string
+	// StatusCode is the HTTP status, or 0 if no response was received
+	StatusCode int
+	Duration   time.Duration
+	Err        error
 }
 
+// RequestHook is called after every Flux API request completes.
+type RequestHook func(RequestInfo)
+
 // NewClient creates a new Flux API client with the given base URL.
 func NewClient(baseURL string) *Client {
 	return &Client{
@@ -100,8 +118,60 @@ func (e *APIError) Error() string {
 	return fmt.Sprintf("flux api error (status %d): %s", e.StatusCode, e.Message)
 }
 
+// AddRequestHook registers a hook that is called after every request, e.g.
+// to record latency metrics. Hooks must be added before the client is shared
+// between goroutines.
+func (c *Client) AddRequestHook(hook RequestHook) {
+	c.hooks = append(c.hooks, hook)
+}
+
+// WithContext returns a shallow copy of the client whose requests use ctx for
+// cancellation and trace propagation. The original client is unchanged.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	cc := *c
+	cc.ctx = ctx
+	return &cc
+}
+
+func (c *Client) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
 // doRequest performs an HTTP request and handles the response.
-func (c *Client) doRequest(method, path string, body interface{}, result interface{}) error {
+func (c *Client) doRequest(method, path string, body interface{}, result interface{}) (err error) {
+	start := time.Now()
+	statusCode := 0
+
+	ctx, span := tracing.Start(c.context(), "flux "+method,
+		tracing.String("http.method", method),
+		tracing.String("http.path", path),
+	)
+	defer func() {
+		if statusCode != 0 {
+			span.SetAttributes(tracing.Int("http.status_code", statusCode))
+		}
+		span.SetError(err)
+		span.End()
+	}()
+
+	if len(c.hooks) > 0 {
+		defer func() {
+			info := RequestInfo{
+				Method:     method,
+				Path:       path,
+				StatusCode: statusCode,
+				Duration:   time.Since(start),
+				Err:        err,
+			}
+			for _, hook := range c.hooks {
+				hook(info)
+			}
+		}()
+	}
+
 	var bodyReader io.Reader
 	if body != nil {
 		jsonBody, err := json.Marshal(body)
@@ -111,7 +181,7 @@ func (c *Client) doRequest(method, path string, body interface{}, result interfa
 		bodyReader = bytes.NewReader(jsonBody)
 	}
 
-	req, err := http.NewRequest(method, c.baseURL+path, bodyReader)
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bodyReader)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -124,6 +194,7 @@ func (c *Client) doRequest(method, path string, body interface{}, result interfa
 		return fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
+	statusCode = resp.StatusCode
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -324,6 +395,118 @@ func (c *Client) MoveTaskStatus(taskID, status string) (*Task, error) {
 	return c.UpdateTask(taskID, updates)
 }
 
+// BatchMoveResult is the outcome of one task in a MoveTasksStatus request.
+type BatchMoveResult struct {
+	ID string `json:"id"`
+	// Task is the updated task, or nil if the move failed
+	Task *Task `json:"task,omitempty"`
+	// Code and Error describe a failed move, as an HTTP status and message
+	Code  int    `json:"code,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Err returns the result's failure as an *APIError, or nil if the task moved.
+func (r BatchMoveResult) Err() error {
+	if r.Error == "" && r.Code == 0 {
+		return nil
+	}
+	return &APIError{StatusCode: r.Code, Message: r.Error}
+}
+
+// MoveTasksStatus moves several tasks to status in one request. Flux servers
+// without the batch endpoint answer with an *APIError of status 404 or 405;
+// otherwise there is one result per task, in the order given.
+func (c *Client) MoveTasksStatus(taskIDs []string, status string) ([]BatchMoveResult, error) {
+	body := map[string]any{
+		"ids":    taskIDs,
+		"status": status,
+	}
+	var resp struct {
+		Results []BatchMoveResult `json:"results"`
+	}
+	if err := c.doRequest(http.MethodPost, "/api/tasks/status", body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to move tasks: %w", err)
+	}
+	if len(resp.Results) != len(taskIDs) {
+		return nil, fmt.Errorf("failed to move tasks: expected %d results, got %d", len(taskIDs), len(resp.Results))
+	}
+	return resp.Results, nil
+}
+
+// FindTask returns the current state of a task, or nil if it does not exist.
+// Flux has no single-task endpoint, so this lists the task's project, or
+// every project when projectID is empty.
+func (c *Client) FindTask(projectID, taskID string) (*Task, error) {
+	tasks, err := c.FindTasks(projectID, []string{taskID})
+	if err != nil {
+		return nil, err
+	}
+	if task, ok := tasks[taskID]; ok {
+		return &task, nil
+	}
+	return nil, nil
+}
+
+// FindTasks returns the current state of each task that exists, keyed by ID,
+// listing projects like FindTask but only once for all of them.
+func (c *Client) FindTasks(projectID string, taskIDs []string) (map[string]Task, error) {
+	projectIDs := []string{projectID}
+	if projectID == "" {
+		projects, err := c.ListProjects()
+		if err != nil {
+			return nil, err
+		}
+		projectIDs = projectIDs[:0]
+		for _, project := range projects {
+			projectIDs = append(projectIDs, project.ID)
+		}
+	}
+
+	wanted := make(map[string]bool, len(taskIDs))
+	for _, id := range taskIDs {
+		wanted[id] = true
+	}
+	found := make(map[string]Task, len(taskIDs))
+	for _, id := range projectIDs {
+		if len(found) == len(wanted) {
+			break
+		}
+		tasks, err := c.ListTasks(id, TaskFilters{})
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			if wanted[task.ID] {
+				found[task.ID] = task
+			}
+		}
+	}
+	return found, nil
+}
+
+// --- Comment Operations ---
+
+// Comment represents a comment on a Flux task.
+type Comment struct {
+	ID        string `json:"id,omitempty"`
+	TaskID    string `json:"task_id,omitempty"`
+	Body      string `json:"body"`
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+// AddComment adds a comment to a task.
+func (c *Client) AddComment(taskID, body string) (*Comment, error) {
+	reqBody := map[string]string{
+		"body": body,
+	}
+	var comment Comment
+	path := fmt.Sprintf("/api/tasks/%s/comments", url.PathEscape(taskID))
+	if err := c.doRequest(http.MethodPost, path, reqBody, &comment); err != nil {
+		return nil, fmt.Errorf("failed to add comment to task %s: %w", taskID, err)
+	}
+	return &comment, nil
+}
+
 // --- Helper Functions ---
 
 // StringPtr returns a pointer to the given string. Useful for optional fields in updates.
@@ -1,10 +1,14 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/sirsjg/momentum/tracing"
 )
 
 // setupTestServer creates a test server with the given handler.
@@ -752,3 +756,264 @@ func TestUpdateEpicWithDependsOn(t *testing.T) {
 		t.Errorf("expected 1 dependency, got %d", len(epic.DependsOn))
 	}
 }
+
+// --- Comment Tests ---
+
+func TestAddComment(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST method, got %s", r.Method)
+		}
+		if r.URL.Path != "/api/tasks/task-1/comments" {
+			t.Errorf("expected path /api/tasks/task-1/comments, got %s", r.URL.Path)
+		}
+
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode request body: %v", err)
+		}
+		if body["body"] != "Tests failed" {
+			t.Errorf("expected body 'Tests failed', got '%s'", body["body"])
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(Comment{ID: "c-1", TaskID: "task-1", Body: body["body"]})
+	})
+
+	server, client := setupTestServer(handler)
+	defer server.Close()
+
+	comment, err := client.AddComment("task-1", "Tests failed")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if comment.ID != "c-1" || comment.Body != "Tests failed" {
+		t.Errorf("unexpected comment: %+v", comment)
+	}
+}
+
+// --- Request Hook Tests ---
+
+func TestRequestHook(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/projects" {
+			w.Write([]byte("[]"))
+			return
+		}
+		http.Error(w, "missing", http.StatusNotFound)
+	})
+
+	server, client := setupTestServer(handler)
+	defer server.Close()
+
+	var infos []RequestInfo
+	client.AddRequestHook(func(info RequestInfo) {
+		infos = append(infos, info)
+	})
+
+	client.ListProjects()
+	client.DeleteTask("task-1")
+
+	if len(infos) != 2 {
+		t.Fatalf("expected 2 hook calls, got %d", len(infos))
+	}
+	if infos[0].Method != http.MethodGet || infos[0].Path != "/api/projects" || infos[0].StatusCode != http.StatusOK || infos[0].Err != nil {
+		t.Errorf("unexpected info for successful request: %+v", infos[0])
+	}
+	if infos[1].StatusCode != http.StatusNotFound || infos[1].Err == nil {
+		t.Errorf("expected failed request to report status and error: %+v", infos[1])
+	}
+}
+
+func TestRequestHook_ConnectionError(t *testing.T) {
+	client := NewClient("http://127.0.0.1:1")
+
+	var got RequestInfo
+	client.AddRequestHook(func(info RequestInfo) { got = info })
+
+	client.ListProjects()
+	if got.StatusCode != 0 || got.Err == nil {
+		t.Errorf("expected status 0 and an error for connection failure, got %+v", got)
+	}
+}
+
+// --- Tracing Tests ---
+
+func TestWithContext_RecordsRequestSpans(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/projects" {
+			w.Write([]byte("[]"))
+			return
+		}
+		http.Error(w, "missing", http.StatusNotFound)
+	})
+
+	server, client := setupTestServer(handler)
+	defer server.Close()
+
+	exp := tracing.NewMemoryExporter()
+	ctx, parent := tracing.NewTracer(exp).Start(context.Background(), "parent")
+
+	traced := client.WithContext(ctx)
+	traced.ListProjects()
+	traced.DeleteTask("task-1")
+	parent.End()
+
+	// The original client is untouched and records nothing
+	client.ListProjects()
+
+	spans := exp.Spans()
+	if len(spans) != 3 {
+		t.Fatalf("expected 2 request spans and the parent, got %d", len(spans))
+	}
+	ok, failed := spans[0], spans[1]
+	if ok.Name != "flux GET" || ok.ParentSpanID != parent.SpanID() || ok.Status != tracing.StatusUnset {
+		t.Errorf("unexpected span for successful request: %+v", ok)
+	}
+	if failed.Name != "flux DELETE" || failed.Status != tracing.StatusError {
+		t.Errorf("expected failed request span to carry an error: %+v", failed)
+	}
+	var status interface{}
+	for _, attr := range failed.Attributes {
+		if attr.Key == "http.status_code" {
+			status = attr.Value
+		}
+	}
+	if status != int64(http.StatusNotFound) {
+		t.Errorf("expected http.status_code 404, got %v", status)
+	}
+}
+
+func TestWithContext_Cancelled(t *testing.T) {
+	server, client := setupTestServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := client.WithContext(ctx).ListProjects(); err == nil {
+		t.Error("expected cancelled context to fail the request")
+	}
+}
+
+func TestFindTask(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/projects":
+			json.NewEncoder(w).Encode([]Project{{ID: "proj-1"}, {ID: "proj-2"}})
+		case "/api/projects/proj-1/tasks":
+			json.NewEncoder(w).Encode([]Task{{ID: "task-1", Status: "todo"}})
+		case "/api/projects/proj-2/tasks":
+			json.NewEncoder(w).Encode([]Task{{ID: "task-2", Status: "in_progress"}})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	server, client := setupTestServer(handler)
+	defer server.Close()
+
+	task, err := client.FindTask("proj-1", "task-1")
+	if err != nil || task == nil || task.Status != "todo" {
+		t.Fatalf("expected task-1, got %+v, %v", task, err)
+	}
+
+	// Without a project every project is searched
+	task, err = client.FindTask("", "task-2")
+	if err != nil || task == nil || task.Status != "in_progress" {
+		t.Fatalf("expected task-2 from project search, got %+v, %v", task, err)
+	}
+
+	task, err = client.FindTask("proj-1", "task-missing")
+	if err != nil || task != nil {
+		t.Errorf("expected nil for a missing task, got %+v, %v", task, err)
+	}
+
+	if _, err := client.FindTask("proj-missing", "task-1"); err == nil {
+		t.Error("expected error for a missing project")
+	}
+}
+
+func TestFindTasks(t *testing.T) {
+	requests := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/projects":
+			json.NewEncoder(w).Encode([]Project{{ID: "proj-1"}, {ID: "proj-2"}, {ID: "proj-3"}})
+		case "/api/projects/proj-1/tasks":
+			json.NewEncoder(w).Encode([]Task{{ID: "task-1", Status: "todo"}})
+		case "/api/projects/proj-2/tasks":
+			json.NewEncoder(w).Encode([]Task{{ID: "task-2", Status: "in_progress"}, {ID: "task-3"}})
+		default:
+			t.Errorf("unexpected request for %s", r.URL.Path)
+		}
+	})
+
+	server, client := setupTestServer(handler)
+	defer server.Close()
+
+	tasks, err := client.FindTasks("", []string{"task-1", "task-2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tasks) != 2 || tasks["task-1"].Status != "todo" || tasks["task-2"].Status != "in_progress" {
+		t.Errorf("unexpected tasks %+v", tasks)
+	}
+	// The search stops once every task is found
+	if requests != 3 {
+		t.Errorf("expected 3 requests, got %d", requests)
+	}
+}
+
+func TestMoveTasksStatus(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/tasks/status" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var body struct {
+			IDs    []string `json:"ids"`
+			Status string   `json:"status"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if len(body.IDs) != 2 || body.Status != "done" {
+			t.Errorf("unexpected body %+v", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"results": []map[string]any{
+			{"id": "task-1", "task": Task{ID: "task-1", Status: "done"}},
+			{"id": "task-2", "code": 404, "error": "task not found"},
+		}})
+	})
+
+	server, client := setupTestServer(handler)
+	defer server.Close()
+
+	results, err := client.MoveTasksStatus([]string{"task-1", "task-2"}, "done")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results[0].Err() != nil || results[0].Task == nil || results[0].Task.Status != "done" {
+		t.Errorf("expected task-1 to move, got %+v", results[0])
+	}
+	var apiErr *APIError
+	if !errors.As(results[1].Err(), &apiErr) || apiErr.StatusCode != 404 {
+		t.Errorf("expected a 404 APIError for task-2, got %v", results[1].Err())
+	}
+}
+
+func TestMoveTasksStatus_Unsupported(t *testing.T) {
+	server, client := setupTestServer(http.HandlerFunc(http.NotFound))
+	defer server.Close()
+
+	_, err := client.MoveTasksStatus([]string{"task-1"}, "done")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected a 404 APIError, got %v", err)
+	}
+}
@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/control"
+	"github.com/sirsjg/momentum/hooks"
+	"github.com/sirsjg/momentum/internal/git"
+	"github.com/sirsjg/momentum/notify"
+	"github.com/sirsjg/momentum/ui"
+	"github.com/sirsjg/momentum/workflow"
+)
+
+// pendingReview is a successful agent run waiting for a reviewer.
+type pendingReview struct {
+	task      *client.Task
+	reason    string
+	changes   []string
+	requested time.Time
+}
+
+// held returns the task as it waits for review in status.
+func (r *pendingReview) held(status string) client.Task {
+	task := *r.task
+	task.Status = status
+	return task
+}
+
+// reviewBoard tracks runs awaiting review, and the comments of rejected
+// runs waiting to be passed to their task's next run.
+type reviewBoard struct {
+	mu       sync.Mutex
+	pending  map[string]*pendingReview
+	order    []string
+	feedback map[string]string
+}
+
+func newReviewBoard() *reviewBoard {
+	return &reviewBoard{
+		pending:  make(map[string]*pendingReview),
+		feedback: make(map[string]string),
+	}
+}
+
+func (b *reviewBoard) add(review *pendingReview) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.pending[review.task.ID]; !ok {
+		b.order = append(b.order, review.task.ID)
+	}
+	b.pending[review.task.ID] = review
+}
+
+// take removes and returns a task's pending review, or nil if it has none.
+func (b *reviewBoard) take(taskID string) *pendingReview {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	review, ok := b.pending[taskID]
+	if !ok {
+		return nil
+	}
+	delete(b.pending, taskID)
+	for i, id := range b.order {
+		if id == taskID {
+			b.order = append(b.order[:i], b.order[i+1:]...)
+			break
+		}
+	}
+	return review
+}
+
+// list describes the pending reviews, oldest first.
+func (b *reviewBoard) list() []control.ReviewStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var reviews []control.ReviewStatus
+	for _, id := range b.order {
+		r := b.pending[id]
+		reviews = append(reviews, control.ReviewStatus{
+			ID:          r.task.ID,
+			Title:       r.task.Title,
+			Reason:      r.reason,
+			Changes:     r.changes,
+			RequestedAt: r.requested,
+		})
+	}
+	return reviews
+}
+
+func (b *reviewBoard) setFeedback(taskID, comment string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.feedback[taskID] = comment
+}
+
+// takeFeedback returns and clears the instructions a rejected task's next
+// run should follow, or "" if there are none.
+func (b *reviewBoard) takeFeedback(taskID string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	comment := b.feedback[taskID]
+	delete(b.feedback, taskID)
+	return feedbackInstructions(comment)
+}
+
+// peekFeedback returns the instructions takeFeedback would, leaving them for
+// the task's next run.
+func (b *reviewBoard) peekFeedback(taskID string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return feedbackInstructions(b.feedback[taskID])
+}
+
+// feedbackInstructions passes a reviewer's comment on to the agent.
+func feedbackInstructions(comment string) string {
+	if comment == "" {
+		return ""
+	}
+	return "A reviewer rejected your previous attempt at this task:\n" + comment
+}
+
+// checkApproval decides whether a successful run of task needs a review. If
+// it does, the reason and a summary of the agent's changes are shown in the
+// task's panel.
+func (env *workerEnv) checkApproval(ctx context.Context, task *client.Task, workDir string, base git.Baseline) *pendingReview {
+	if !env.approval.Enabled() {
+		return nil
+	}
+	diff, err := git.Changes(ctx, workDir, base)
+	reason := env.approval.Check(task, diff.Paths())
+	if err != nil && reason == "" && env.approval.ProtectsPaths() {
+		// Without the list of changes, a protected path may have been touched
+		reason = fmt.Sprintf("could not list the changed files: %v", err)
+	}
+	if reason == "" {
+		return nil
+	}
+
+	changes := diff.Summary()
+	if err != nil {
+		changes = []string{fmt.Sprintf("changes unavailable: %v", err)}
+	}
+	output := env.taskOutput(task.ID)
+	output("Awaiting review: " + reason)
+	for _, line := range changes {
+		output(line)
+	}
+	return &pendingReview{
+		task:      task,
+		reason:    reason,
+		changes:   changes,
+		requested: time.Now(),
+	}
+}
+
+// holdForReview moves a task to the review status instead of done and
+// waits for a reviewer.
+func (env *workerEnv) holdForReview(wf *workflow.Workflow, review *pendingReview) error {
+	if _, err := wf.WithReason("held for review: "+review.reason).MoveTo([]string{review.task.ID}, env.approval.Status()); err != nil {
+		return fmt.Errorf("hold task %s for review: %w", review.task.ID, err)
+	}
+	wf.Comment(review.task.ID, reviewRequestComment(review))
+	// Only accept decisions once the task has reached the review status, so
+	// an early approval cannot be overwritten by the move
+	env.reviews.add(review)
+	env.p.Send(ui.ReviewRequestedMsg{TaskID: review.task.ID, Reason: review.reason})
+	env.notifyTask(notify.ReviewRequested, review.task, review.reason, nil)
+	return nil
+}
+
+// restoreReviews puts tasks that earlier runs left in the review status back
+// on the review board, so they can still be approved or rejected after a
+// restart. Only tasks this instance could have picked up are restored.
+func restoreReviews(env *workerEnv, c *client.Client) {
+	if !env.approval.Enabled() {
+		return
+	}
+	projectIDs := []string{projectID}
+	if projectID == "" {
+		projects, err := c.ListProjects()
+		if err != nil {
+			env.reportError(err)
+			return
+		}
+		projectIDs = projectIDs[:0]
+		for _, project := range projects {
+			projectIDs = append(projectIDs, project.ID)
+		}
+	}
+
+	for _, id := range projectIDs {
+		tasks, err := c.ListTasks(id, client.TaskFilters{Status: client.StringPtr(env.approval.Status())})
+		if err != nil {
+			env.reportError(err)
+			continue
+		}
+		for _, task := range tasks {
+			if (taskID != "" && task.ID != taskID) || (epicID != "" && task.EpicID != epicID) {
+				continue
+			}
+			env.reviews.add(&pendingReview{
+				task:      &task,
+				reason:    "held for review before Momentum restarted",
+				requested: time.Now(),
+			})
+		}
+	}
+}
+
+// approveReview marks a task awaiting review as done and runs the success hooks
+// its run skipped.
+func approveReview(ctx context.Context, env *workerEnv, wf *workflow.Workflow, taskID, comment string) error {
+	review := env.reviews.take(taskID)
+	if review == nil {
+		return fmt.Errorf("%w: task %s is not awaiting review", control.ErrTaskNotFound, taskID)
+	}
+	wf = wf.WithContext(ctx).WithFrom(review.held(env.approval.Status()))
+	if _, err := wf.WithReason("approved in review").MarkComplete([]string{taskID}); err != nil {
+		env.reviews.add(review)
+		return err
+	}
+	wf.Comment(taskID, reviewDecisionComment("approved", comment))
+	env.p.Send(ui.ReviewResolvedMsg{TaskID: taskID, Approved: true})
+	env.notifyTask(notify.TaskCompleted, review.task, "approved in review", nil)
+
+	exitCode := 0
+	return env.runHook(ctx, hooks.OnSuccess, review.task, &exitCode)
+}
+
+// rejectReview sends a task awaiting review back to todo. The reviewer's
+// comment is passed to the task's next run.
+func rejectReview(ctx context.Context, env *workerEnv, wf *workflow.Workflow, taskID, comment string) error {
+	comment = strings.TrimSpace(comment)
+	if comment == "" {
+		return errors.New("a rejection needs a comment telling the agent what to change")
+	}
+	review := env.reviews.take(taskID)
+	if review == nil {
+		return fmt.Errorf("%w: task %s is not awaiting review", control.ErrTaskNotFound, taskID)
+	}
+	// Record the feedback before the task can be picked up again
+	env.reviews.setFeedback(taskID, comment)
+	wf = wf.WithContext(ctx).WithFrom(review.held(env.approval.Status()))
+	if _, err := wf.WithReason("rejected in review").ResetTask([]string{taskID}); err != nil {
+		env.reviews.takeFeedback(taskID)
+		env.reviews.add(review)
+		return err
+	}
+	wf.Comment(taskID, reviewDecisionComment("rejected", comment))
+	env.p.Send(ui.ReviewResolvedMsg{TaskID: taskID, Approved: false})
+	return nil
+}
+
+// reviewRequestComment explains why a task is waiting for review.
+func reviewRequestComment(review *pendingReview) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Momentum is holding this task for review: %s.\n", review.reason))
+	b.WriteString("\n```\n")
+	b.WriteString(strings.Join(review.changes, "\n"))
+	b.WriteString("\n```\n")
+	return b.String()
+}
+
+// reviewDecisionComment records a reviewer's decision on a task.
+func reviewDecisionComment(decision, comment string) string {
+	if comment = strings.TrimSpace(comment); comment == "" {
+		return fmt.Sprintf("A reviewer %s this task.", decision)
+	}
+	return fmt.Sprintf("A reviewer %s this task: %s", decision, comment)
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sirsjg/momentum/approval"
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/control"
+	"github.com/sirsjg/momentum/fluxtest"
+)
+
+func TestReviewBoard(t *testing.T) {
+	board := newReviewBoard()
+	board.add(&pendingReview{task: &client.Task{ID: "task-1", Title: "One"}, reason: "epic"})
+	board.add(&pendingReview{task: &client.Task{ID: "task-2", Title: "Two"}, reason: "paths"})
+
+	if reviews := board.list(); len(reviews) != 2 || reviews[0].ID != "task-1" || reviews[1].Reason != "paths" {
+		t.Errorf("unexpected reviews %+v", reviews)
+	}
+	if review := board.take("task-1"); review == nil || review.task.ID != "task-1" {
+		t.Errorf("expected task-1's review, got %+v", review)
+	}
+	if board.take("task-1") != nil {
+		t.Error("expected a taken review to be gone")
+	}
+	if reviews := board.list(); len(reviews) != 1 || reviews[0].ID != "task-2" {
+		t.Errorf("unexpected reviews %+v", reviews)
+	}
+
+	board.setFeedback("task-1", "add tests")
+	if got := board.takeFeedback("task-1"); !contains(got, "rejected") || !contains(got, "add tests") {
+		t.Errorf("unexpected feedback %q", got)
+	}
+	if got := board.takeFeedback("task-1"); got != "" {
+		t.Errorf("expected feedback to be passed on once, got %q", got)
+	}
+}
+
+func TestReviewDecisionComment(t *testing.T) {
+	if got := reviewDecisionComment("approved", ""); got != "A reviewer approved this task." {
+		t.Errorf("unexpected comment %q", got)
+	}
+	if got := reviewDecisionComment("rejected", " keep the old API "); got != "A reviewer rejected this task: keep the old API" {
+		t.Errorf("unexpected comment %q", got)
+	}
+}
+
+func TestPrintStatus_Reviews(t *testing.T) {
+	var buf bytes.Buffer
+	printStatus(&buf, &control.Status{
+		Mode: "async",
+		Reviews: []control.ReviewStatus{
+			{ID: "task-1", Title: "Migrate", Reason: "changes protected paths: db/1.sql", Changes: []string{"1 file changed, +3 -0", "  db/1.sql +3 -0"}},
+		},
+	})
+	out := buf.String()
+	if !contains(out, "Awaiting review (1):") || !contains(out, "task-1  Migrate  changes protected paths") || !contains(out, "db/1.sql +3 -0") {
+		t.Errorf("expected review listing, got:\n%s", out)
+	}
+}
+
+func TestRestoreReviews(t *testing.T) {
+	flux := fluxtest.NewServer()
+	defer flux.Close()
+	project := flux.AddProject(client.Project{Name: "demo"})
+	other := flux.AddProject(client.Project{Name: "other"})
+	held := flux.AddTask(client.Task{Title: "Held", ProjectID: project.ID, Status: "review"})
+	flux.AddTask(client.Task{Title: "Queued", ProjectID: project.ID, Status: "todo"})
+	flux.AddTask(client.Task{Title: "Elsewhere", ProjectID: other.ID, Status: "review"})
+
+	oldProject := projectID
+	projectID = project.ID
+	defer func() { projectID = oldProject }()
+
+	policy, _ := approval.New(approval.Config{ProtectedPaths: []string{"go.mod"}})
+	env := &workerEnv{approval: policy, reviews: newReviewBoard()}
+	restoreReviews(env, client.NewClient(flux.URL))
+
+	reviews := env.reviews.list()
+	if len(reviews) != 1 || reviews[0].ID != held.ID {
+		t.Fatalf("expected only the held task to be restored, got %+v", reviews)
+	}
+	if review := env.reviews.take(held.ID); review == nil || review.task.Status != "review" {
+		t.Errorf("expected the restored review to be decidable, got %+v", review)
+	}
+}
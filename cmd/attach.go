@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/control"
+	"github.com/sirsjg/momentum/ui"
+	"github.com/spf13/cobra"
+)
+
+// attachPollInterval is how often `momentum attach` checks the coordinator
+// for newly started tasks.
+const attachPollInterval = 2 * time.Second
+
+// remoteAgentName labels panels for agents followed through a coordinator.
+const remoteAgentName = "Claude (remote)"
+
+var attachCmd = &cobra.Command{
+	Use:   "attach",
+	Short: "Watch a coordinator's agents in the TUI",
+	Long: `Open the TUI against a coordinator started with "momentum serve". Each
+running task gets a panel streaming its output; stopping a panel stops the
+remote agent.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAttach()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(attachCmd)
+}
+
+// runAttach runs the TUI against a coordinator.
+func runAttach() error {
+	if coordinatorURL == "" {
+		return errors.New("--coordinator is required")
+	}
+	log.SetOutput(io.Discard)
+
+	c := control.NewRemoteClient(coordinatorURL, poolTokenValue())
+	status, err := c.Status()
+	if err != nil {
+		return err
+	}
+	mode, err := parseExecutionMode(status.Mode)
+	if err != nil {
+		return err
+	}
+
+	// Mode and workdir belong to the coordinator, so the TUI's updates are not wired up
+	model := ui.NewModel(fmt.Sprintf("%s @ %s", status.Criteria, coordinatorURL), mode, status.WorkDir, nil, nil, nil)
+	p := tea.NewProgram(&model, tea.WithAltScreen())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchCoordinator(ctx, c, p)
+
+	if _, err := p.Run(); err != nil {
+		return fmt.Errorf("error running UI: %w", err)
+	}
+	return nil
+}
+
+// watchCoordinator polls the coordinator and opens a panel for every task
+// that starts running, until ctx is cancelled.
+func watchCoordinator(ctx context.Context, c *control.Client, p messenger) {
+	attached := make(map[string]bool)
+	var mu sync.Mutex
+
+	ticker := time.NewTicker(attachPollInterval)
+	defer ticker.Stop()
+	for {
+		status, err := c.Status()
+		if err != nil {
+			p.Send(ui.ListenerErrorMsg{Err: err})
+		} else {
+			p.Send(ui.ListenerConnectedMsg{})
+			p.Send(ui.PausedMsg{Paused: status.Paused})
+
+			for _, t := range status.Running {
+				mu.Lock()
+				seen := attached[t.ID]
+				attached[t.ID] = true
+				mu.Unlock()
+				if seen {
+					continue
+				}
+
+				taskID := t.ID
+				if err := followRemoteTask(ctx, c, p, t, func() {
+					mu.Lock()
+					delete(attached, taskID)
+					mu.Unlock()
+				}); err != nil {
+					p.Send(ui.ListenerErrorMsg{Err: err})
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// followRemoteTask adds a panel for a task running on the coordinator and
+// relays its output until it finishes, then calls done.
+func followRemoteTask(ctx context.Context, c *control.Client, p messenger, t control.TaskStatus, done func()) error {
+	runner := agent.NewRunner(newRemoteTask(c, t.ID))
+	if err := runner.Run(ctx, ""); err != nil {
+		done()
+		return err
+	}
+
+	p.Send(ui.AddAgentMsg{
+		TaskID:    t.ID,
+		TaskTitle: t.Title,
+		AgentName: remoteAgentName,
+		Runner:    runner,
+	})
+	go func() {
+		for line := range runner.Output() {
+			p.Send(ui.AgentOutputMsg{TaskID: t.ID, Line: line})
+		}
+	}()
+	go func() {
+		result := <-runner.Done()
+		p.Send(ui.AgentCompletedMsg{TaskID: t.ID, Result: result})
+		done()
+	}()
+	return nil
+}
+
+// remoteTask implements agent.Agent for a task running on a coordinator: it
+// tails the task's output, stops it through the control API and takes the
+// exit code from the coordinator's list of finished tasks.
+type remoteTask struct {
+	client *control.Client
+	taskID string
+
+	mu       sync.Mutex
+	stdoutR  *io.PipeReader
+	stderrR  *io.PipeReader
+	done     chan struct{}
+	exitCode int
+	err      error
+}
+
+func newRemoteTask(c *control.Client, taskID string) *remoteTask {
+	return &remoteTask{client: c, taskID: taskID}
+}
+
+// Name returns the agent's display name
+func (r *remoteTask) Name() string {
+	return remoteAgentName
+}
+
+// Start begins tailing the task; the prompt is ignored
+func (r *remoteTask) Start(ctx context.Context, prompt string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.done != nil {
+		return agent.ErrAgentAlreadyRunning
+	}
+
+	stdoutR, stdoutW := io.Pipe()
+	stderrR, stderrW := io.Pipe()
+	r.stdoutR, r.stderrR = stdoutR, stderrR
+	r.done = make(chan struct{})
+
+	go func() {
+		err := r.client.Tail(ctx, r.taskID, func(line agent.OutputLine) {
+			w := stdoutW
+			if line.IsStderr {
+				w = stderrW
+			}
+			io.WriteString(w, line.Text+"\n")
+		})
+		stdoutW.Close()
+		stderrW.Close()
+
+		exitCode, waitErr := -1, err
+		switch {
+		case ctx.Err() != nil:
+			waitErr = agent.ErrAgentCancelled
+		case err == nil || errors.Is(err, control.ErrTaskNotFound):
+			exitCode, waitErr = r.finishedExitCode()
+		}
+
+		r.mu.Lock()
+		r.exitCode, r.err = exitCode, waitErr
+		r.mu.Unlock()
+		close(r.done)
+	}()
+	return nil
+}
+
+// finishedExitCode looks the task up among the coordinator's finished tasks.
+func (r *remoteTask) finishedExitCode() (int, error) {
+	status, err := r.client.Status()
+	if err != nil {
+		return -1, err
+	}
+	for i := len(status.Finished) - 1; i >= 0; i-- {
+		if t := status.Finished[i]; t.ID == r.taskID && t.ExitCode != nil {
+			return *t.ExitCode, nil
+		}
+	}
+	return -1, agent.ErrExitCodeUnknown
+}
+
+// Stdout returns the task's relayed stdout
+func (r *remoteTask) Stdout() io.Reader {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stdoutR == nil {
+		return nil
+	}
+	return r.stdoutR
+}
+
+// Stderr returns the task's relayed stderr
+func (r *remoteTask) Stderr() io.Reader {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stderrR == nil {
+		return nil
+	}
+	return r.stderrR
+}
+
+// Wait blocks until the task finishes on the coordinator
+func (r *remoteTask) Wait() (int, error) {
+	r.mu.Lock()
+	done := r.done
+	r.mu.Unlock()
+	if done == nil {
+		return -1, agent.ErrAgentNotStarted
+	}
+	<-done
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.exitCode, r.err
+}
+
+// Cancel asks the coordinator to stop the task
+func (r *remoteTask) Cancel() error {
+	return r.client.Stop(r.taskID)
+}
+
+// IsRunning returns whether the task is still being followed
+func (r *remoteTask) IsRunning() bool {
+	r.mu.Lock()
+	done := r.done
+	r.mu.Unlock()
+	if done == nil {
+		return false
+	}
+	select {
+	case <-done:
+		return false
+	default:
+		return true
+	}
+}
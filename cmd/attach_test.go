@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/control"
+	"github.com/sirsjg/momentum/pool"
+	"github.com/sirsjg/momentum/ui"
+)
+
+// recordingMessenger collects the messages sent to the TUI.
+type recordingMessenger struct {
+	msgs chan tea.Msg
+}
+
+func (r *recordingMessenger) Send(msg tea.Msg) {
+	r.msgs <- msg
+}
+
+// startCoordinatorAPI serves a coordinator's control API for the given state.
+func startCoordinatorAPI(t *testing.T, state *instanceState, agents *runningAgents) *control.Client {
+	t.Helper()
+	backend := newControlBackend(state, agents, &recordingMessenger{msgs: make(chan tea.Msg, 10)})
+	srv := httptest.NewServer(pool.Authorize("secret", control.NewServer("", backend).Handler()))
+	t.Cleanup(srv.Close)
+	return control.NewRemoteClient(srv.URL, "secret")
+}
+
+func TestRemoteTask_FollowsOutputAndExitCode(t *testing.T) {
+	state := newInstanceState("All projects", ui.ExecutionModeAsync)
+	c := startCoordinatorAPI(t, state, newRunningAgents())
+
+	task := &client.Task{ID: "task-1", Title: "Remote work"}
+	state.taskStarted(task, 0)
+	state.appendOutput(task.ID, agent.OutputLine{Text: "before attach"})
+
+	runner := agent.NewRunner(newRemoteTask(c, task.ID))
+	if err := runner.Run(context.Background(), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if line := <-runner.Output(); line.Text != "before attach" {
+		t.Errorf("expected history line, got %q", line.Text)
+	}
+	state.appendOutput(task.ID, agent.OutputLine{Text: "warning", IsStderr: true})
+	if line := <-runner.Output(); line.Text != "warning" || !line.IsStderr {
+		t.Errorf("expected live stderr line, got %+v", line)
+	}
+
+	state.taskFinished(task.ID, 4, false)
+	select {
+	case result := <-runner.Done():
+		if result.ExitCode != 4 || result.Error != nil {
+			t.Errorf("expected exit 4, got %d (%v)", result.ExitCode, result.Error)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("remote task did not finish")
+	}
+}
+
+func TestRemoteTask_AlreadyFinished(t *testing.T) {
+	state := newInstanceState("All projects", ui.ExecutionModeAsync)
+	c := startCoordinatorAPI(t, state, newRunningAgents())
+
+	state.taskStarted(&client.Task{ID: "task-1"}, 0)
+	state.taskFinished("task-1", 0, true)
+
+	r := newRemoteTask(c, "task-1")
+	if err := r.Start(context.Background(), ""); err != nil {
+		t.Fatal(err)
+	}
+	if code, err := r.Wait(); code != 0 || err != nil {
+		t.Errorf("expected exit 0 from finished list, got %d (%v)", code, err)
+	}
+
+	r = newRemoteTask(c, "task-never-ran")
+	r.Start(context.Background(), "")
+	if _, err := r.Wait(); !errors.Is(err, agent.ErrExitCodeUnknown) {
+		t.Errorf("expected ErrExitCodeUnknown, got %v", err)
+	}
+}
+
+func TestRemoteTask_CancelStopsCoordinatorAgent(t *testing.T) {
+	state := newInstanceState("All projects", ui.ExecutionModeAsync)
+	agents := newRunningAgents()
+	c := startCoordinatorAPI(t, state, agents)
+
+	// An unstarted pool agent stands in for the coordinator's running agent
+	agents.markRunning("task-1", agent.NewRunner(pool.NewCoordinator().NewAgent(agent.Config{})))
+
+	if err := newRemoteTask(c, "task-1").Cancel(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !agents.wasStoppedByUser("task-1") {
+		t.Error("expected the coordinator to record a user stop")
+	}
+	if err := newRemoteTask(c, "task-9").Cancel(); !errors.Is(err, control.ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestWatchCoordinator_OpensPanelsForRunningTasks(t *testing.T) {
+	state := newInstanceState("All projects", ui.ExecutionModeAsync)
+	c := startCoordinatorAPI(t, state, newRunningAgents())
+	state.taskStarted(&client.Task{ID: "task-1", Title: "Remote work"}, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := &recordingMessenger{msgs: make(chan tea.Msg, 100)}
+	go watchCoordinator(ctx, c, p)
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-p.msgs:
+			if add, ok := msg.(ui.AddAgentMsg); ok {
+				if add.TaskID != "task-1" || add.AgentName != remoteAgentName || add.Runner == nil {
+					t.Errorf("unexpected panel: %+v", add)
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatal("expected a panel for the running task")
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sirsjg/momentum/audit"
+	"github.com/sirsjg/momentum/lease"
+	"github.com/sirsjg/momentum/ui"
+	"github.com/sirsjg/momentum/workflow"
+	"github.com/spf13/cobra"
+)
+
+var auditJSON bool
+
+var auditCmd = &cobra.Command{
+	Use:   "audit [task]",
+	Short: "Show the status changes Momentum has made",
+	Long: `Show the task status changes recorded in the audit log, oldest first: when
+each task moved, from and to which status, why, and which Momentum instance
+moved it. Give a task ID to see only that task's history.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if auditLogPath == "" {
+			return fmt.Errorf("no audit log: --audit-log is empty")
+		}
+		taskID := ""
+		if len(args) > 0 {
+			taskID = args[0]
+		}
+		events, err := audit.Read(auditLogPath, taskID)
+		if err != nil {
+			return err
+		}
+		if auditJSON {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			for _, e := range events {
+				if err := enc.Encode(e); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		printAudit(cmd.OutOrStdout(), events)
+		return nil
+	},
+}
+
+func init() {
+	auditCmd.Flags().StringVar(&auditLogPath, "audit-log", audit.DefaultPath(), "Audit log file to read")
+	auditCmd.Flags().BoolVar(&auditJSON, "json", false, "Print events as JSON lines")
+
+	rootCmd.AddCommand(auditCmd)
+}
+
+// printAudit writes one line per event.
+func printAudit(w io.Writer, events []workflow.Event) {
+	if len(events) == 0 {
+		fmt.Fprintln(w, "No status changes recorded")
+		return
+	}
+	for _, e := range events {
+		move := e.To
+		if e.From != "" {
+			move = e.From + " -> " + e.To
+		}
+		line := fmt.Sprintf("%s  %s  %s", e.Time.Local().Format("2006-01-02 15:04:05"), e.TaskID, move)
+		if e.Error != "" {
+			line += "  FAILED: " + e.Error
+		} else if e.Reason != "" {
+			line += "  " + e.Reason
+		}
+		if e.Actor != "" {
+			line += "  [" + e.Actor + "]"
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// openAuditLog opens the --audit-log file, or returns nil if it is disabled.
+// Failed writes are shown in the TUI.
+func openAuditLog(p messenger) (*audit.Log, error) {
+	if auditLogPath == "" {
+		return nil, nil
+	}
+	return audit.Open(auditLogPath, func(err error) {
+		p.Send(ui.ListenerErrorMsg{Err: err})
+	})
+}
+
+// observeTransitions attributes wf's transitions to this instance and sends
+// them to the audit log and the TUI's activity feed.
+func (env *workerEnv) observeTransitions(wf *workflow.Workflow) {
+	wf.SetActor("momentum " + env.owner())
+
+	if env.audit != nil {
+		wf.AddObserver(env.audit)
+	}
+	wf.AddObserver(workflow.ObserverFunc(func(e workflow.Event) {
+		env.p.Send(ui.TransitionMsg{
+			Time:   e.Time,
+			TaskID: e.TaskID,
+			Title:  e.Title,
+			From:   e.From,
+			To:     e.To,
+			Reason: e.Reason,
+			Error:  e.Error,
+		})
+	}))
+}
+
+// owner names this instance: its lease owner, or host:pid without leases.
+func (env *workerEnv) owner() string {
+	if owner := env.leases.Owner(); owner != "" {
+		return owner
+	}
+	return lease.DefaultOwner()
+}
+
+// firstLine returns the first line of s, for reasons recorded on one line.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/sirsjg/momentum/workflow"
+)
+
+func TestPrintAudit(t *testing.T) {
+	at := time.Date(2026, 3, 4, 3, 0, 0, 0, time.Local)
+	var out bytes.Buffer
+	printAudit(&out, []workflow.Event{
+		{Time: at, TaskID: "task-1", From: "in_progress", To: "done", Reason: "agent finished", Actor: "momentum host:1"},
+		{Time: at, TaskID: "task-2", To: "todo", Error: "flux api error (status 500)"},
+	})
+
+	got := out.String()
+	if !contains(got, "2026-03-04 03:00:00  task-1  in_progress -> done  agent finished  [momentum host:1]") {
+		t.Errorf("unexpected output:\n%s", got)
+	}
+	if !contains(got, "task-2  todo  FAILED: flux api error (status 500)") {
+		t.Errorf("expected the failed move, got:\n%s", got)
+	}
+
+	out.Reset()
+	printAudit(&out, nil)
+	if !contains(out.String(), "No status changes recorded") {
+		t.Errorf("unexpected output for no events: %q", out.String())
+	}
+}
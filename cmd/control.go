@@ -0,0 +1,343 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/control"
+	"github.com/sirsjg/momentum/pool"
+	"github.com/sirsjg/momentum/ui"
+)
+
+// maxTailHistory is the number of recent output lines kept per running task
+// so that `momentum tail` can show some context before streaming live output.
+const maxTailHistory = 200
+
+// maxFinished is the number of recently finished tasks reported in status.
+const maxFinished = 20
+
+// instanceState mirrors the worker state that is exposed over the control socket.
+type instanceState struct {
+	mu        sync.Mutex
+	criteria  string
+	mode      ui.ExecutionMode
+	paused    bool
+	connected bool
+	lastErr   error
+	completed int
+	running   map[string]*trackedTask
+	order     []string
+	queued    []*client.Task
+	finished  []control.TaskStatus
+	// sandbox describes the agents' sandbox profile; set before the worker starts
+	sandbox string
+}
+
+// trackedTask holds metadata and recent output for a running task.
+type trackedTask struct {
+	task    *client.Task
+	pid     int
+	started time.Time
+	lines   []agent.OutputLine
+	subs    map[chan agent.OutputLine]struct{}
+}
+
+func newInstanceState(criteria string, mode ui.ExecutionMode) *instanceState {
+	return &instanceState{
+		criteria: criteria,
+		mode:     mode,
+		running:  make(map[string]*trackedTask),
+	}
+}
+
+func (s *instanceState) setMode(mode ui.ExecutionMode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mode = mode
+}
+
+func (s *instanceState) setPaused(paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = paused
+}
+
+func (s *instanceState) isPaused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+func (s *instanceState) setConnected() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connected = true
+	s.lastErr = nil
+}
+
+func (s *instanceState) setError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErr = err
+}
+
+func (s *instanceState) setQueued(tasks []*client.Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queued = append(s.queued[:0], tasks...)
+}
+
+func (s *instanceState) queuedCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queued)
+}
+
+func (s *instanceState) taskStarted(task *client.Task, pid int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.running[task.ID]; !ok {
+		s.order = append(s.order, task.ID)
+	}
+	s.running[task.ID] = &trackedTask{
+		task:    task,
+		pid:     pid,
+		started: time.Now(),
+		subs:    make(map[chan agent.OutputLine]struct{}),
+	}
+}
+
+func (s *instanceState) appendOutput(taskID string, line agent.OutputLine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.running[taskID]
+	if !ok {
+		return
+	}
+	t.lines = append(t.lines, line)
+	if len(t.lines) > maxTailHistory {
+		t.lines = t.lines[len(t.lines)-maxTailHistory:]
+	}
+	for ch := range t.subs {
+		select {
+		case ch <- line:
+		default:
+			// Slow subscriber, drop the line rather than block the agent
+		}
+	}
+}
+
+// taskFinished records the end of a task's run. completed is set when the
+// run finished the task: it exited with code 0 and was not stopped,
+// redirected or rejected.
+func (s *instanceState) taskFinished(taskID string, exitCode int, completed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.running[taskID]
+	if !ok {
+		return
+	}
+	s.finished = append(s.finished, control.TaskStatus{
+		ID:        t.task.ID,
+		Title:     t.task.Title,
+		PID:       t.pid,
+		StartedAt: t.started,
+		ExitCode:  &exitCode,
+	})
+	if len(s.finished) > maxFinished {
+		s.finished = s.finished[len(s.finished)-maxFinished:]
+	}
+	for ch := range t.subs {
+		close(ch)
+	}
+	delete(s.running, taskID)
+	for i, id := range s.order {
+		if id == taskID {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	if completed {
+		s.completed++
+	}
+}
+
+// subscribe registers a tail subscriber for the task and returns its recent
+// history. The returned channel is closed when the task finishes.
+func (s *instanceState) subscribe(taskID string) ([]agent.OutputLine, chan agent.OutputLine, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.running[taskID]
+	if !ok {
+		return nil, nil, false
+	}
+	ch := make(chan agent.OutputLine, 100)
+	t.subs[ch] = struct{}{}
+	history := append([]agent.OutputLine(nil), t.lines...)
+	return history, ch, true
+}
+
+func (s *instanceState) unsubscribe(taskID string, ch chan agent.OutputLine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.running[taskID]; ok {
+		if _, subscribed := t.subs[ch]; subscribed {
+			delete(t.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+func (s *instanceState) snapshot() control.Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := control.Status{
+		PID:            os.Getpid(),
+		Criteria:       s.criteria,
+		Mode:           s.mode.String(),
+		Paused:         s.paused,
+		Connected:      s.connected,
+		WorkDir:        GetWorkDir(),
+		Sandbox:        s.sandbox,
+		TasksCompleted: s.completed,
+		Running:        make([]control.TaskStatus, 0, len(s.order)),
+		Queued:         make([]control.TaskStatus, 0, len(s.queued)),
+	}
+	if s.lastErr != nil {
+		status.LastError = s.lastErr.Error()
+	}
+	for _, id := range s.order {
+		t := s.running[id]
+		status.Running = append(status.Running, control.TaskStatus{
+			ID:        t.task.ID,
+			Title:     t.task.Title,
+			PID:       t.pid,
+			StartedAt: t.started,
+		})
+	}
+	for _, task := range s.queued {
+		status.Queued = append(status.Queued, control.TaskStatus{
+			ID:    task.ID,
+			Title: task.Title,
+		})
+	}
+	status.Finished = append(status.Finished, s.finished...)
+	return status
+}
+
+// controlBackend answers control socket requests for the running instance.
+type controlBackend struct {
+	state  *instanceState
+	agents *runningAgents
+	p      messenger
+	// pool lists remote runners when running as a coordinator
+	pool *pool.Coordinator
+	// continueTask resumes a task's agent session; nil until the worker
+	// has connected to Flux
+	continueTask func(taskID, instructions string) error
+	// reviews lists the tasks awaiting review
+	reviews *reviewBoard
+	// approveReview and rejectReview settle a task awaiting review; nil
+	// until the worker has connected to Flux
+	approveReview func(taskID, comment string) error
+	rejectReview  func(taskID, comment string) error
+}
+
+func newControlBackend(state *instanceState, agents *runningAgents, p messenger) *controlBackend {
+	return &controlBackend{
+		state:  state,
+		agents: agents,
+		p:      p,
+	}
+}
+
+// Status returns a snapshot of the instance state.
+func (b *controlBackend) Status() control.Status {
+	status := b.state.snapshot()
+	status.Runners = b.pool.Runners()
+	if b.reviews != nil {
+		status.Reviews = b.reviews.list()
+	}
+	return status
+}
+
+// StopTask stops a running agent the same way the TUI's stop key does.
+func (b *controlBackend) StopTask(taskID string) error {
+	runner := b.agents.runner(taskID)
+	if runner == nil {
+		return fmt.Errorf("%w: no running agent for task %s", control.ErrTaskNotFound, taskID)
+	}
+	b.agents.markStoppedByUser(taskID)
+	if err := runner.Cancel(); err != nil {
+		return fmt.Errorf("failed to stop task %s: %w", taskID, err)
+	}
+	b.p.Send(ui.AgentStoppingMsg{TaskID: taskID})
+	return nil
+}
+
+// ContinueTask resumes the agent session of a task that is not running.
+func (b *controlBackend) ContinueTask(taskID, instructions string) error {
+	if b.continueTask == nil {
+		return errors.New("this instance cannot continue tasks")
+	}
+	return b.continueTask(taskID, instructions)
+}
+
+// ApproveTask marks a task awaiting review as done.
+func (b *controlBackend) ApproveTask(taskID, comment string) error {
+	if b.approveReview == nil {
+		return errors.New("this instance cannot review tasks")
+	}
+	return b.approveReview(taskID, comment)
+}
+
+// RejectTask sends a task awaiting review back to the queue with the
+// reviewer's comment.
+func (b *controlBackend) RejectTask(taskID, comment string) error {
+	if b.rejectReview == nil {
+		return errors.New("this instance cannot review tasks")
+	}
+	return b.rejectReview(taskID, comment)
+}
+
+// SetPaused pauses or resumes task selection.
+func (b *controlBackend) SetPaused(paused bool) {
+	b.state.setPaused(paused)
+	b.p.Send(ui.PausedMsg{Paused: paused})
+}
+
+// Tail streams recent and live output for a running task.
+func (b *controlBackend) Tail(ctx context.Context, taskID string, send func(agent.OutputLine) error) error {
+	history, ch, ok := b.state.subscribe(taskID)
+	if !ok {
+		return fmt.Errorf("%w: no running agent for task %s", control.ErrTaskNotFound, taskID)
+	}
+	defer b.state.unsubscribe(taskID, ch)
+
+	for _, line := range history {
+		if err := send(line); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if err := send(line); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/control"
+	"github.com/sirsjg/momentum/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statusJSON bool
+	tailRaw    bool
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of a running momentum instance",
+	Long: `Show running tasks, the queue, execution mode and Flux connectivity
+of a momentum instance running in another terminal.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		status, err := controlClient().Status()
+		if err != nil {
+			return err
+		}
+		if statusJSON {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(status)
+		}
+		printStatus(cmd.OutOrStdout(), status)
+		return nil
+	},
+}
+
+var stopCmd = &cobra.Command{
+	Use:   "stop <task>",
+	Short: "Stop the agent working on a task",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := controlClient().Stop(args[0]); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Stopping task %s\n", args[0])
+		return nil
+	},
+}
+
+var continueCmd = &cobra.Command{
+	Use:   "continue <task> [instructions...]",
+	Short: "Resume the agent session of a finished or stopped task",
+	Long: `Resume the Claude Code session a task last ran in, optionally with extra
+instructions, instead of starting the task from scratch.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		instructions := strings.Join(args[1:], " ")
+		if err := controlClient().Continue(args[0], instructions); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Continuing task %s\n", args[0])
+		return nil
+	},
+}
+
+var approveCmd = &cobra.Command{
+	Use:   "approve <task> [comment...]",
+	Short: "Approve a task awaiting review and mark it done",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		comment := strings.Join(args[1:], " ")
+		if err := controlClient().Approve(args[0], comment); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Approved task %s\n", args[0])
+		return nil
+	},
+}
+
+var rejectCmd = &cobra.Command{
+	Use:   "reject <task> <comment...>",
+	Short: "Reject a task awaiting review and re-queue it with a comment",
+	Long: `Reject the work on a task awaiting review. The task goes back to todo, and
+its next run is told the reviewer's comment.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		comment := strings.Join(args[1:], " ")
+		if err := controlClient().Reject(args[0], comment); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Rejected task %s\n", args[0])
+		return nil
+	},
+}
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause",
+	Short: "Stop picking up new tasks (running agents continue)",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := controlClient().Pause(); err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), "Paused")
+		return nil
+	},
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume picking up new tasks",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := controlClient().Resume(); err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), "Resumed")
+		return nil
+	},
+}
+
+var tailCmd = &cobra.Command{
+	Use:   "tail <task>",
+	Short: "Stream the output of the agent working on a task",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		out := cmd.OutOrStdout()
+		return controlClient().Tail(ctx, args[0], func(line agent.OutputLine) {
+			text := line.Text
+			if !tailRaw {
+				text = ui.ParseClaudeOutput(text)
+				if text == "" {
+					return
+				}
+			}
+			fmt.Fprintln(out, text)
+		})
+	},
+}
+
+func init() {
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "Print status as JSON")
+	tailCmd.Flags().BoolVar(&tailRaw, "raw", false, "Print raw agent output instead of parsed text")
+
+	rootCmd.AddCommand(statusCmd, stopCmd, continueCmd, approveCmd, rejectCmd, pauseCmd, resumeCmd, tailCmd)
+}
+
+// controlClient returns a client for the local control socket, or for the
+// coordinator given with --coordinator.
+func controlClient() *control.Client {
+	if coordinatorURL != "" {
+		return control.NewRemoteClient(coordinatorURL, poolTokenValue())
+	}
+	return control.NewClient(controlSocket)
+}
+
+// printStatus writes a human-readable summary of an instance status.
+func printStatus(w io.Writer, status *control.Status) {
+	mode := status.Mode
+	if status.Paused {
+		mode += " (paused)"
+	}
+	flux := "connected"
+	if !status.Connected {
+		flux = "connecting"
+	}
+	if status.LastError != "" {
+		flux += " - last error: " + status.LastError
+	}
+
+	fmt.Fprintf(w, "%-11s %d\n", "PID:", status.PID)
+	fmt.Fprintf(w, "%-11s %s\n", "Filter:", status.Criteria)
+	fmt.Fprintf(w, "%-11s %s\n", "Mode:", mode)
+	fmt.Fprintf(w, "%-11s %s\n", "Flux:", flux)
+	fmt.Fprintf(w, "%-11s %s\n", "WorkDir:", status.WorkDir)
+	if status.Sandbox != "" {
+		fmt.Fprintf(w, "%-11s %s\n", "Sandbox:", status.Sandbox)
+	}
+	fmt.Fprintf(w, "%-11s %d\n", "Completed:", status.TasksCompleted)
+
+	fmt.Fprintf(w, "\nRunning (%d):\n", len(status.Running))
+	for _, t := range status.Running {
+		elapsed := time.Since(t.StartedAt).Round(time.Second)
+		fmt.Fprintf(w, "  %s  %s  pid:%d  %s\n", t.ID, t.Title, t.PID, elapsed)
+	}
+
+	fmt.Fprintf(w, "\nQueued (%d):\n", len(status.Queued))
+	for _, t := range status.Queued {
+		fmt.Fprintf(w, "  %s  %s\n", t.ID, strings.TrimSpace(t.Title))
+	}
+
+	if len(status.Reviews) > 0 {
+		fmt.Fprintf(w, "\nAwaiting review (%d):\n", len(status.Reviews))
+		for _, r := range status.Reviews {
+			fmt.Fprintf(w, "  %s  %s  %s\n", r.ID, strings.TrimSpace(r.Title), r.Reason)
+			for _, line := range r.Changes {
+				fmt.Fprintf(w, "      %s\n", strings.TrimSpace(line))
+			}
+		}
+	}
+
+	if len(status.Runners) > 0 {
+		fmt.Fprintf(w, "\nRunners (%d):\n", len(status.Runners))
+		for _, r := range status.Runners {
+			seen := time.Since(r.LastSeen).Round(time.Second)
+			fmt.Fprintf(w, "  %s  %s  %d/%d busy  seen %s ago\n", r.ID, r.Name, r.Active, r.Capacity, seen)
+		}
+	}
+}
@@ -0,0 +1,849 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/audit"
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/control"
+	"github.com/sirsjg/momentum/fluxtest"
+	"github.com/sirsjg/momentum/notify"
+	"github.com/sirsjg/momentum/session"
+	"github.com/sirsjg/momentum/ui"
+)
+
+// messageLog collects TUI messages without ever blocking the worker.
+type messageLog struct {
+	mu   sync.Mutex
+	msgs []tea.Msg
+}
+
+func (l *messageLog) Send(msg tea.Msg) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.msgs = append(l.msgs, msg)
+}
+
+func (l *messageLog) completed(taskID string) (agent.Result, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, msg := range l.msgs {
+		if done, ok := msg.(ui.AgentCompletedMsg); ok && done.TaskID == taskID {
+			return done.Result, true
+		}
+	}
+	return agent.Result{}, false
+}
+
+// changes returns the changes reported with a task's completion.
+func (l *messageLog) changes(taskID string) *ui.Changes {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, msg := range l.msgs {
+		if done, ok := msg.(ui.AgentCompletedMsg); ok && done.TaskID == taskID {
+			return done.Changes
+		}
+	}
+	return nil
+}
+
+// workDir returns the directory shown on a task's agent panel.
+func (l *messageLog) workDir(taskID string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, msg := range l.msgs {
+		if add, ok := msg.(ui.AddAgentMsg); ok && add.TaskID == taskID {
+			return add.WorkDir
+		}
+	}
+	return ""
+}
+
+// fakeAgents keeps the agents a worker started so tests can inspect the
+// prompts they received.
+type fakeAgents struct {
+	mu   sync.Mutex
+	list []*agent.Fake
+}
+
+func (f *fakeAgents) add(a *agent.Fake) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.list = append(f.list, a)
+}
+
+func (f *fakeAgents) all() []*agent.Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.list)
+}
+
+// endToEnd is a worker running against a fake Flux server.
+type endToEnd struct {
+	flux *fluxtest.Server
+	// task is the task the worker should pick up
+	task client.Task
+	// held is a task an earlier run left awaiting review; it is only added
+	// when approvals are configured, so the review status is on the board
+	held  client.Task
+	msgs  *messageLog
+	fakes *fakeAgents
+	env   *workerEnv
+}
+
+// startEndToEnd runs the worker loop against a fake Flux server with the
+// given config file contents. The worker stops when the test ends.
+func startEndToEnd(t *testing.T, config map[string]any) *endToEnd {
+	t.Helper()
+	return startEndToEndIn(t, config, nil)
+}
+
+// startEndToEndIn is startEndToEnd with prepare run on the working
+// directory before the worker starts.
+func startEndToEndIn(t *testing.T, config map[string]any, prepare func(dir string)) *endToEnd {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks run through sh")
+	}
+
+	flux := fluxtest.NewServer()
+	t.Cleanup(flux.Close)
+	project := flux.AddProject(client.Project{Name: "demo"})
+	epic := flux.AddEpic(client.Epic{Title: "auto", ProjectID: project.ID, Auto: true})
+	task := flux.AddTask(client.Task{Title: "Add a README", ProjectID: project.ID, EpicID: epic.ID})
+	var held client.Task
+	if _, ok := config["approval"]; ok {
+		held = flux.AddTask(client.Task{Title: "Add a licence", ProjectID: project.ID, EpicID: epic.ID, Status: "review"})
+	}
+
+	dir := t.TempDir()
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldBase, oldConfig, oldSocket, oldJournal, oldSessions, oldWork, oldProject, oldAudit := baseURL, configPath, controlSocket, journalDir, sessionDir, workDir, projectID, auditLogPath
+	t.Cleanup(func() {
+		baseURL, configPath, controlSocket, journalDir, sessionDir, workDir, projectID, auditLogPath = oldBase, oldConfig, oldSocket, oldJournal, oldSessions, oldWork, oldProject, oldAudit
+	})
+	baseURL, configPath, journalDir, projectID = flux.URL, path, "", project.ID
+	auditLogPath = filepath.Join(dir, "audit.log")
+	sessionDir = filepath.Join(dir, "sessions")
+	controlSocket = filepath.Join(dir, "control.sock")
+	workDir = filepath.Join(dir, "work")
+	if err := os.Mkdir(workDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if prepare != nil {
+		prepare(workDir)
+	}
+
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	msgs := &messageLog{}
+	env, closeEnv, err := newWorkerEnv("Project: demo", ui.ExecutionModeAsync, msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(closeEnv)
+
+	fakes := &fakeAgents{}
+	newAgent := env.newAgent
+	env.newAgent = func(cfg agent.Config) agent.Agent {
+		a := newAgent(cfg)
+		if fake, ok := a.(*agent.Fake); ok {
+			fakes.add(fake)
+		}
+		return a
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runWorker(ctx, env, ui.ExecutionModeAsync, nil, nil, nil, nil, nil, nil)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		env.agents.cancelAll()
+	})
+	return &endToEnd{flux: flux, task: task, held: held, msgs: msgs, fakes: fakes, env: env}
+}
+
+func waitUntil(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEndToEnd_FakeAgentCompletesTask(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "success")
+	e := startEndToEnd(t, map[string]any{
+		"agent": "fake",
+		"fake":  map[string]any{"delay": "1ms", "files": map[string]string{"README.md": "# Demo\n"}},
+		"hooks": map[string]any{"on-success": []string{`echo "$MOMENTUM_TASK_ID" > ` + marker}},
+	})
+
+	waitUntil(t, "the task to be done", func() bool {
+		current, _ := e.flux.Task(e.task.ID)
+		return current.Status == "done"
+	})
+	if got := e.flux.StatusHistory(e.task.ID); !slices.Equal(got, []string{"todo", "in_progress", "done"}) {
+		t.Errorf("unexpected status history %v", got)
+	}
+	waitUntil(t, "the on-success hook", func() bool {
+		data, _ := os.ReadFile(marker)
+		return string(data) == e.task.ID+"\n"
+	})
+	if _, err := os.Stat(filepath.Join(workDir, "README.md")); err != nil {
+		t.Errorf("expected the agent's file in the workdir: %v", err)
+	}
+	if agents := e.fakes.all(); len(agents) != 1 || !contains(agents[0].Prompt(), e.task.ID) || !contains(agents[0].Prompt(), "Add a README") {
+		t.Errorf("expected one prompt naming the task, got %d agents", len(agents))
+	}
+	if result, ok := e.msgs.completed(e.task.ID); !ok || result.ExitCode != 0 {
+		t.Errorf("expected a successful completion message, got %+v", result)
+	}
+	if changes := e.msgs.changes(e.task.ID); changes == nil || !contains(changes.Err, "not a git repository") {
+		t.Errorf("expected the changes to be unavailable outside git, got %+v", changes)
+	}
+
+	// Both moves are in the audit log, attributed to this instance
+	events, err := audit.Read(auditLogPath, e.task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].From != "todo" || events[0].To != "in_progress" || events[1].From != "in_progress" || events[1].To != "done" ||
+		events[1].Reason != "agent finished" || !strings.HasPrefix(events[1].Actor, "momentum ") {
+		t.Errorf("unexpected audit events %+v", events)
+	}
+}
+
+func TestEndToEnd_ProjectWorkDir(t *testing.T) {
+	e := startEndToEndIn(t, map[string]any{
+		"agent":    "fake",
+		"fake":     map[string]any{"files": map[string]string{"README.md": "# Demo\n"}},
+		"workdirs": map[string]any{"projects": map[string]string{"demo": "repos/demo"}},
+	}, func(dir string) {
+		if err := os.MkdirAll(filepath.Join(dir, "repos", "demo"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	waitUntil(t, "the task to be done", func() bool {
+		current, _ := e.flux.Task(e.task.ID)
+		return current.Status == "done"
+	})
+	repo := filepath.Join(workDir, "repos", "demo")
+	if _, err := os.Stat(filepath.Join(repo, "README.md")); err != nil {
+		t.Errorf("expected the agent to work in the project's directory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "README.md")); err == nil {
+		t.Error("expected nothing written to the default workdir")
+	}
+	if got := e.msgs.workDir(e.task.ID); got != repo {
+		t.Errorf("expected the panel to show %s, got %q", repo, got)
+	}
+}
+
+func TestEndToEnd_MissingProjectWorkDir(t *testing.T) {
+	e := startEndToEnd(t, map[string]any{
+		"agent":    "fake",
+		"workdirs": map[string]any{"projects": map[string]string{"demo": "repos/missing"}},
+	})
+
+	waitUntil(t, "the task to be moved back", func() bool {
+		current, _ := e.flux.Task(e.task.ID)
+		return current.Status == "planning"
+	})
+	if len(e.fakes.all()) != 0 {
+		t.Error("expected no agent to start")
+	}
+	comments := e.flux.Comments(e.task.ID)
+	if len(comments) != 1 || !contains(comments[0].Body, "repos/missing") {
+		t.Errorf("expected a comment naming the missing directory, got %+v", comments)
+	}
+}
+
+func TestEndToEnd_RepositoryCheckout(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	src := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"-c", "user.email=test@example.com", "-c", "user.name=Test", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", src}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	cache := t.TempDir()
+
+	e := startEndToEnd(t, map[string]any{
+		"agent": "fake",
+		"fake":  map[string]any{"files": map[string]string{"README.md": "# Demo\n"}},
+		"workdirs": map[string]any{
+			"repositories": map[string]string{"demo": "file://" + filepath.ToSlash(src)},
+			"cache-dir":    cache,
+		},
+	})
+
+	waitUntil(t, "the task to be done", func() bool {
+		current, _ := e.flux.Task(e.task.ID)
+		return current.Status == "done"
+	})
+	checkout := e.msgs.workDir(e.task.ID)
+	if filepath.Dir(checkout) != cache {
+		t.Fatalf("expected the agent to run in a checkout in %s, got %q", cache, checkout)
+	}
+	if _, err := os.Stat(filepath.Join(checkout, ".git")); err != nil {
+		t.Errorf("expected a clone of the repository: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(checkout, "README.md")); err != nil {
+		t.Errorf("expected the agent's file in the checkout: %v", err)
+	}
+}
+
+func TestEndToEnd_GitBranch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	git := func(dir string, args ...string) string {
+		t.Helper()
+		out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	e := startEndToEndIn(t, map[string]any{
+		"agent": "fake",
+		"fake":  map[string]any{"files": map[string]string{"README.md": "# Demo\n"}},
+		"git":   map[string]any{"enabled": true, "remote": remote},
+	}, func(dir string) {
+		git(dir, "init", "-q", "--bare", remote)
+		git(dir, "init", "-q", "-b", "main")
+		git(dir, "config", "user.email", "test@example.com")
+		git(dir, "config", "user.name", "Test")
+		git(dir, "commit", "-q", "--allow-empty", "-m", "init")
+	})
+
+	waitUntil(t, "the task to be done", func() bool {
+		current, _ := e.flux.Task(e.task.ID)
+		return current.Status == "done"
+	})
+	branch := "momentum/" + e.task.ID + "-add-a-readme"
+	sha := git(remote, "rev-parse", "refs/heads/"+branch)
+	if got := git(remote, "log", "-1", "--format=%s%n%b", sha); !contains(got, "Add a README") || !contains(got, "Flux task: "+e.task.ID) {
+		t.Errorf("unexpected commit message %q", got)
+	}
+	if got := git(remote, "show", "--format=", "--name-only", sha); got != "README.md" {
+		t.Errorf("expected the agent's file in the commit, got %q", got)
+	}
+	if git(workDir, "branch", "--show-current") != "main" {
+		t.Error("expected the workdir back on main")
+	}
+	// The panel keeps the run's changes after they were committed
+	changes := e.msgs.changes(e.task.ID)
+	if changes == nil || len(changes.Files) != 1 || changes.Files[0].Path != "README.md" || !contains(changes.Patch, "+# Demo") {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+	comments := e.flux.Comments(e.task.ID)
+	if len(comments) != 1 || !contains(comments[0].Body, branch) || !contains(comments[0].Body, sha) {
+		t.Errorf("expected a comment naming the branch and commit, got %+v", comments)
+	}
+}
+
+func TestEndToEnd_GitSharedWorkDir(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	git := func(dir string, args ...string) string {
+		t.Helper()
+		out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	e := startEndToEndIn(t, map[string]any{
+		"agent": "fake",
+		"fake":  map[string]any{"delay": "50ms", "files": map[string]string{"README.md": "# Demo\n"}},
+		"git":   map[string]any{"enabled": true},
+	}, func(dir string) {
+		git(dir, "init", "-q", "-b", "main")
+		git(dir, "config", "user.email", "test@example.com")
+		git(dir, "config", "user.name", "Test")
+		git(dir, "commit", "-q", "--allow-empty", "-m", "init")
+	})
+	waitUntil(t, "the first task to start", func() bool {
+		current, _ := e.flux.Task(e.task.ID)
+		return current.Status == "in_progress"
+	})
+	second := e.flux.AddTask(client.Task{Title: "Add a licence", ProjectID: e.task.ProjectID, EpicID: e.task.EpicID})
+
+	waitUntil(t, "both tasks to be done", func() bool {
+		first, _ := e.flux.Task(e.task.ID)
+		current, _ := e.flux.Task(second.ID)
+		return first.Status == "done" && current.Status == "done"
+	})
+	// The second task waited for the checkout instead of running on the
+	// first task's branch, so each commit holds only its own run's work
+	if got := e.flux.StatusHistory(second.ID); !slices.Equal(got, []string{"todo", "in_progress", "done"}) {
+		t.Errorf("unexpected status history %v", got)
+	}
+	for _, task := range []client.Task{e.task, second} {
+		branch := git(workDir, "branch", "--list", "--format=%(refname:short)", "momentum/"+task.ID+"-*")
+		if branch == "" {
+			t.Fatalf("expected a branch for %s", task.ID)
+		}
+		if got := git(workDir, "log", "--format=%s", "main.."+branch); got != task.Title {
+			t.Errorf("expected only %s's commit on %s, got %q", task.ID, branch, got)
+		}
+		if got := git(workDir, "show", "--format=", "--name-only", branch); got != "README.md" {
+			t.Errorf("expected the agent's file in %s's commit, got %q", task.ID, got)
+		}
+	}
+	if git(workDir, "branch", "--show-current") != "main" {
+		t.Error("expected the workdir back on main")
+	}
+}
+
+func TestEndToEnd_CrashLeavesTaskInProgress(t *testing.T) {
+	e := startEndToEnd(t, map[string]any{
+		"agent": "fake",
+		"fake":  map[string]any{"failure": "crash"},
+	})
+
+	waitUntil(t, "the agent to finish", func() bool {
+		_, ok := e.msgs.completed(e.task.ID)
+		return ok
+	})
+	if result, _ := e.msgs.completed(e.task.ID); result.ExitCode != 1 {
+		t.Errorf("expected exit code 1, got %d", result.ExitCode)
+	}
+	if current, _ := e.flux.Task(e.task.ID); current.Status != "in_progress" {
+		t.Errorf("expected a crashed run to leave the task in_progress, got %s", current.Status)
+	}
+}
+
+func TestEndToEnd_RetryResumesSession(t *testing.T) {
+	e := startEndToEnd(t, map[string]any{
+		"agent": "fake",
+		"fake":  map[string]any{"failure": "crash"},
+	})
+
+	waitUntil(t, "the agent to finish", func() bool {
+		_, ok := e.msgs.completed(e.task.ID)
+		return ok
+	})
+	if result, _ := e.msgs.completed(e.task.ID); result.SessionID != "fake-session" {
+		t.Fatalf("expected the crashed run's session, got %q", result.SessionID)
+	}
+
+	// Retry the task; its agent should pick the session back up
+	if _, err := client.NewClient(e.flux.URL).MoveTaskStatus(e.task.ID, "todo"); err != nil {
+		t.Fatal(err)
+	}
+	waitUntil(t, "the retry", func() bool { return len(e.fakes.all()) == 2 })
+	retry := e.fakes.all()[1]
+	if retry.ResumedSession() != "fake-session" {
+		t.Errorf("expected the retry to resume fake-session, got %q", retry.ResumedSession())
+	}
+	if !contains(retry.Prompt(), "resuming your earlier session") || !contains(retry.Prompt(), "exited with code 1") {
+		t.Errorf("expected a resume prompt explaining the crash, got:\n%s", retry.Prompt())
+	}
+}
+
+func TestEndToEnd_ContinueWithInstructions(t *testing.T) {
+	e := startEndToEnd(t, map[string]any{
+		"agent": "fake",
+		"fake":  map[string]any{"delay": "1ms"},
+	})
+
+	waitUntil(t, "the task to be done", func() bool {
+		current, _ := e.flux.Task(e.task.ID)
+		return current.Status == "done"
+	})
+	ctl := control.NewClient(controlSocket)
+	waitUntil(t, "the session to be recorded", func() bool {
+		rec, _ := openSessionsForTest(t).Load(e.task.ID)
+		return rec != nil && rec.Outcome == outcomeCompleted
+	})
+
+	if err := ctl.Continue(e.task.ID, "Also add a licence."); err != nil {
+		t.Fatal(err)
+	}
+	waitUntil(t, "the continued agent", func() bool { return len(e.fakes.all()) == 2 })
+	next := e.fakes.all()[1]
+	if next.ResumedSession() != "fake-session" || !contains(next.Prompt(), "Also add a licence.") {
+		t.Errorf("expected the session to resume with the instructions, got %q:\n%s", next.ResumedSession(), next.Prompt())
+	}
+	waitUntil(t, "the task to be done again", func() bool {
+		history := e.flux.StatusHistory(e.task.ID)
+		return len(history) == 5 && history[4] == "done"
+	})
+
+	if err := ctl.Continue("missing", ""); !errors.Is(err, control.ErrTaskNotFound) {
+		t.Errorf("expected an unknown task to be rejected, got %v", err)
+	}
+}
+
+// waitForSession waits until the task's agent has reported its session.
+func waitForSession(t *testing.T, e *endToEnd) {
+	t.Helper()
+	waitUntil(t, "the agent's session", func() bool {
+		runner := e.env.agents.runner(e.task.ID)
+		return runner != nil && runner.SessionID() != ""
+	})
+}
+
+func TestEndToEnd_MessageResumesSession(t *testing.T) {
+	e := startEndToEnd(t, map[string]any{
+		"agent": "fake",
+		"fake":  map[string]any{"failure": "hang"},
+	})
+	waitForSession(t, e)
+
+	if err := messageAgent(e.env, e.task.ID, "Focus on the failing test first."); err != nil {
+		t.Fatal(err)
+	}
+	waitUntil(t, "the resumed agent", func() bool {
+		return len(e.fakes.all()) == 2 && e.env.agents.runner(e.task.ID) != nil
+	})
+	next := e.fakes.all()[1]
+	if next.ResumedSession() != "fake-session" {
+		t.Errorf("expected the session to be resumed, got %q", next.ResumedSession())
+	}
+	if !contains(next.Prompt(), outcomeMessaged) || !contains(next.Prompt(), "Focus on the failing test first.") {
+		t.Errorf("expected the message in the resume prompt, got:\n%s", next.Prompt())
+	}
+	if got := e.flux.StatusHistory(e.task.ID); !slices.Equal(got, []string{"todo", "in_progress"}) {
+		t.Errorf("expected the task to stay in_progress, got %v", got)
+	}
+	if _, ok := e.msgs.completed(e.task.ID); ok {
+		t.Error("expected the panel to stay open for the resumed session")
+	}
+}
+
+func TestEndToEnd_MessageInteractiveAgent(t *testing.T) {
+	e := startEndToEnd(t, map[string]any{
+		"agent": "fake",
+		"fake":  map[string]any{"failure": "hang", "interactive": true},
+	})
+	waitForSession(t, e)
+
+	if err := messageAgent(e.env, e.task.ID, "Use the new API."); err != nil {
+		t.Fatal(err)
+	}
+	agents := e.fakes.all()
+	if len(agents) != 1 || !slices.Equal(agents[0].Messages(), []string{"Use the new API."}) {
+		t.Errorf("expected the running agent to receive the message, got %d agents", len(agents))
+	}
+	if err := messageAgent(e.env, "missing", "hello"); !errors.Is(err, control.ErrTaskNotFound) {
+		t.Errorf("expected an unknown task to be rejected, got %v", err)
+	}
+}
+
+func openSessionsForTest(t *testing.T) *session.Store {
+	t.Helper()
+	store, err := session.Open(sessionDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// reviewConfig holds every change for review: the workdir is not a git
+// repository, so the changed files cannot be listed and protected paths
+// fail safe.
+func reviewConfig(extra map[string]any) map[string]any {
+	config := map[string]any{
+		"agent":    "fake",
+		"fake":     map[string]any{"delay": "1ms", "files": map[string]string{"README.md": "# Demo\n"}},
+		"approval": map[string]any{"protected-paths": []string{"README.md"}},
+	}
+	for k, v := range extra {
+		config[k] = v
+	}
+	return config
+}
+
+// waitForReview waits until the task is held for review and accepts
+// decisions.
+func waitForReview(t *testing.T, e *endToEnd) {
+	t.Helper()
+	waitUntil(t, "the task to await review", func() bool {
+		current, _ := e.flux.Task(e.task.ID)
+		return current.Status == "review" && awaitingReview(e, e.task.ID)
+	})
+}
+
+// awaitingReview reports whether taskID is on the worker's review board.
+func awaitingReview(e *endToEnd, taskID string) bool {
+	return slices.ContainsFunc(e.env.reviews.list(), func(r control.ReviewStatus) bool { return r.ID == taskID })
+}
+
+func TestEndToEnd_ApprovalHoldsTaskForReview(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "success")
+	e := startEndToEnd(t, reviewConfig(map[string]any{
+		"hooks": map[string]any{"on-success": []string{`echo "$MOMENTUM_TASK_ID" > ` + marker}},
+	}))
+
+	waitForReview(t, e)
+	if agents := e.fakes.all(); !contains(agents[0].Prompt(), `Do not move the task to "done" yourself`) {
+		t.Errorf("expected the agent to be told to leave the status alone:\n%s", agents[0].Prompt())
+	}
+	if comments := e.flux.Comments(e.task.ID); len(comments) == 0 || !contains(comments[0].Body, "holding this task for review") {
+		t.Errorf("expected a comment explaining the review, got %+v", comments)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("expected the success hooks to wait for the approval")
+	}
+
+	ctl := control.NewClient(controlSocket)
+	status, err := ctl.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := slices.IndexFunc(status.Reviews, func(r control.ReviewStatus) bool { return r.ID == e.task.ID })
+	if len(status.Reviews) != 2 || i < 0 || !contains(status.Reviews[i].Reason, "could not list the changed files") {
+		t.Errorf("expected the task to be listed for review, got %+v", status.Reviews)
+	}
+	if err := ctl.Approve(e.held.ID, ""); err != nil {
+		t.Errorf("expected the task held by an earlier run to be restored for review: %v", err)
+	}
+
+	if err := ctl.Approve(e.task.ID, "ship it"); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.flux.StatusHistory(e.task.ID); !slices.Equal(got, []string{"todo", "in_progress", "review", "done"}) {
+		t.Errorf("unexpected status history %v", got)
+	}
+	comments := e.flux.Comments(e.task.ID)
+	if last := comments[len(comments)-1]; last.Body != "A reviewer approved this task: ship it" {
+		t.Errorf("expected the approval to be recorded, got %q", last.Body)
+	}
+	waitUntil(t, "the on-success hook", func() bool {
+		data, _ := os.ReadFile(marker)
+		return string(data) == e.task.ID+"\n"
+	})
+	if err := ctl.Approve(e.task.ID, ""); !errors.Is(err, control.ErrTaskNotFound) {
+		t.Errorf("expected a settled review to be gone, got %v", err)
+	}
+}
+
+func TestEndToEnd_ReviewHoldFails(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "failure")
+	e := startEndToEnd(t, reviewConfig(map[string]any{
+		"fake":  map[string]any{"delay": "100ms", "files": map[string]string{"README.md": "# Demo\n"}},
+		"hooks": map[string]any{"on-failure": []string{`echo "$MOMENTUM_TASK_ID" > ` + marker}},
+	}))
+
+	waitUntil(t, "the task to start", func() bool {
+		current, _ := e.flux.Task(e.task.ID)
+		return current.Status == "in_progress"
+	})
+	e.flux.Fail("PATCH", "/api/tasks/"+e.task.ID, http.StatusInternalServerError)
+
+	waitUntil(t, "the on-failure hook", func() bool {
+		data, _ := os.ReadFile(marker)
+		return string(data) == e.task.ID+"\n"
+	})
+	if awaitingReview(e, e.task.ID) {
+		t.Error("expected the task not to await review")
+	}
+	if comments := e.flux.Comments(e.task.ID); len(comments) != 0 {
+		t.Errorf("expected no review comment, got %+v", comments)
+	}
+	e.msgs.mu.Lock()
+	defer e.msgs.mu.Unlock()
+	reported := false
+	for _, msg := range e.msgs.msgs {
+		switch msg := msg.(type) {
+		case ui.ReviewRequestedMsg:
+			t.Error("expected no review to be requested")
+		case ui.ListenerErrorMsg:
+			reported = reported || contains(msg.Err.Error(), "hold task "+e.task.ID+" for review")
+		}
+	}
+	if !reported {
+		t.Error("expected the failed hold to be reported")
+	}
+}
+
+func TestEndToEnd_RejectRequeuesWithComment(t *testing.T) {
+	e := startEndToEnd(t, reviewConfig(nil))
+
+	waitForReview(t, e)
+	ctl := control.NewClient(controlSocket)
+	if err := ctl.Reject(e.task.ID, " "); err == nil || errors.Is(err, control.ErrTaskNotFound) {
+		t.Errorf("expected a rejection without a comment to be refused, got %v", err)
+	}
+	if err := ctl.Reject(e.task.ID, "Use British spelling."); err != nil {
+		t.Fatal(err)
+	}
+
+	waitUntil(t, "the task to be run again", func() bool {
+		agents := e.fakes.all()
+		return len(agents) == 2 && agents[1].Prompt() != ""
+	})
+	next := e.fakes.all()[1]
+	if !contains(next.Prompt(), "A reviewer rejected your previous attempt") || !contains(next.Prompt(), "Use British spelling.") {
+		t.Errorf("expected the reviewer's comment in the next prompt:\n%s", next.Prompt())
+	}
+	waitUntil(t, "the task to await review again", func() bool {
+		history := e.flux.StatusHistory(e.task.ID)
+		return len(history) == 6 && history[5] == "review"
+	})
+	if got := e.flux.StatusHistory(e.task.ID); !slices.Equal(got[:4], []string{"todo", "in_progress", "review", "todo"}) {
+		t.Errorf("unexpected status history %v", got)
+	}
+}
+
+func TestEndToEnd_Notifications(t *testing.T) {
+	var mu sync.Mutex
+	var received []notify.Notification
+	var signed bool
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var n notify.Notification
+		if err := json.Unmarshal(body, &n); err != nil {
+			t.Errorf("webhook payload is not a notification: %s", body)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, n)
+		signed = r.Header.Get(notify.SignatureHeader) == notify.Sign([]byte("s3cret"), body)
+	}))
+	defer webhook.Close()
+	notifications := func() []notify.Notification {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(received)
+	}
+
+	e := startEndToEnd(t, map[string]any{
+		"agent": "fake",
+		"fake":  map[string]any{"failure": "crash"},
+		"notify": map[string]any{"webhooks": []map[string]any{{
+			"url":    webhook.URL,
+			"secret": "s3cret",
+			"events": []string{"agent-started", "agent-failed"},
+		}}},
+	})
+
+	waitUntil(t, "the failure notification", func() bool { return len(notifications()) == 2 })
+	got := notifications()
+	if got[0].Event != notify.AgentStarted || got[1].Event != notify.AgentFailed {
+		t.Fatalf("unexpected notifications %+v", got)
+	}
+	failed := got[1]
+	if failed.TaskID != e.task.ID || failed.Summary != "Agent failed: Add a README" || failed.ExitCode == nil || *failed.ExitCode == 0 ||
+		!strings.Contains(failed.Message, "agent exited with code") || failed.Instance == "" {
+		t.Errorf("unexpected failure notification %+v", failed)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !signed {
+		t.Error("expected signed webhook requests")
+	}
+}
+
+func TestEndToEnd_FailureStatus(t *testing.T) {
+	e := startEndToEnd(t, map[string]any{
+		"agent":    "fake",
+		"fake":     map[string]any{"failure": "crash"},
+		"workflow": map[string]any{"failure": "planning"},
+	})
+
+	waitUntil(t, "the task to move to the failure status", func() bool {
+		current, _ := e.flux.Task(e.task.ID)
+		return current.Status == "planning"
+	})
+	if got := e.flux.StatusHistory(e.task.ID); !slices.Equal(got, []string{"todo", "in_progress", "planning"}) {
+		t.Errorf("unexpected status history %v", got)
+	}
+}
+
+func TestEndToEnd_AgentFailsToStart(t *testing.T) {
+	journalPath := t.TempDir()
+	e := startEndToEndIn(t, map[string]any{
+		"agent": "fake",
+		"fake":  map[string]any{"transcript": filepath.Join(t.TempDir(), "missing.jsonl")},
+	}, func(string) { journalDir = journalPath })
+
+	waitUntil(t, "the task to be put back in the queue", func() bool {
+		return slices.Equal(e.flux.StatusHistory(e.task.ID), []string{"todo", "in_progress", "todo"})
+	})
+	if e.env.agents.count() != 0 {
+		t.Error("expected no agent to be left running")
+	}
+	if entries, _ := e.env.journal.Entries(); len(entries) != 0 {
+		t.Errorf("expected the journal entry to be released, got %+v", entries)
+	}
+}
+
+func TestEndToEnd_CompletionMoveFails(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "success")
+	e := startEndToEnd(t, map[string]any{
+		"agent": "fake",
+		"fake":  map[string]any{"delay": "100ms"},
+		"hooks": map[string]any{"on-success": []string{`echo "$MOMENTUM_TASK_ID" > ` + marker}},
+	})
+
+	waitUntil(t, "the task to start", func() bool {
+		current, _ := e.flux.Task(e.task.ID)
+		return current.Status == "in_progress"
+	})
+	e.flux.Fail("PATCH", "/api/tasks/"+e.task.ID, http.StatusInternalServerError)
+
+	waitUntil(t, "the failed move to be reported", func() bool {
+		e.msgs.mu.Lock()
+		defer e.msgs.mu.Unlock()
+		for _, msg := range e.msgs.msgs {
+			if failed, ok := msg.(ui.ListenerErrorMsg); ok && contains(failed.Err.Error(), e.task.ID) {
+				return true
+			}
+		}
+		return false
+	})
+	if current, _ := e.flux.Task(e.task.ID); current.Status != "in_progress" {
+		t.Errorf("expected the task to stay in_progress, got %s", current.Status)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("expected the on-success hook not to run: %v", err)
+	}
+}
@@ -14,11 +14,27 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/approval"
+	"github.com/sirsjg/momentum/audit"
 	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/config"
+	"github.com/sirsjg/momentum/control"
+	"github.com/sirsjg/momentum/hooks"
+	"github.com/sirsjg/momentum/internal/git"
+	"github.com/sirsjg/momentum/journal"
+	"github.com/sirsjg/momentum/lease"
+	"github.com/sirsjg/momentum/notify"
+	"github.com/sirsjg/momentum/pool"
+	"github.com/sirsjg/momentum/sandbox"
 	"github.com/sirsjg/momentum/selection"
+	"github.com/sirsjg/momentum/session"
 	"github.com/sirsjg/momentum/sse"
+	"github.com/sirsjg/momentum/tracing"
 	"github.com/sirsjg/momentum/ui"
+	"github.com/sirsjg/momentum/vcs"
+	"github.com/sirsjg/momentum/verify"
 	"github.com/sirsjg/momentum/workflow"
+	"github.com/sirsjg/momentum/workspace"
 )
 
 // sseEventData represents the structure of SSE event payloads
@@ -34,7 +50,12 @@ type runningAgents struct {
 	tasks         map[string]bool
 	runners       map[string]*agent.Runner
 	stoppedByUser map[string]bool
-	doneCh        chan string
+	// redirects holds messages for agents being stopped so their session
+	// can resume with the message
+	redirects map[string]string
+	doneCh    chan string
+	// shuttingDown is set once Momentum starts cancelling agents to exit
+	shuttingDown bool
 }
 
 func newRunningAgents() *runningAgents {
@@ -42,6 +63,7 @@ func newRunningAgents() *runningAgents {
 		tasks:         make(map[string]bool),
 		runners:       make(map[string]*agent.Runner),
 		stoppedByUser: make(map[string]bool),
+		redirects:     make(map[string]string),
 		doneCh:        make(chan string, 100),
 	}
 }
@@ -59,12 +81,26 @@ func (r *runningAgents) markRunning(taskID string, runner *agent.Runner) {
 	r.runners[taskID] = runner
 }
 
+// reserve marks a task as running before its agent exists, reporting false
+// if the task already has one.
+func (r *runningAgents) reserve(taskID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tasks[taskID] {
+		return false
+	}
+	r.tasks[taskID] = true
+	r.runners[taskID] = nil
+	return true
+}
+
 func (r *runningAgents) markDone(taskID string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	delete(r.tasks, taskID)
 	delete(r.runners, taskID)
 	delete(r.stoppedByUser, taskID)
+	delete(r.redirects, taskID)
 	select {
 	case r.doneCh <- taskID:
 	default:
@@ -83,9 +119,41 @@ func (r *runningAgents) wasStoppedByUser(taskID string) bool {
 	return r.stoppedByUser[taskID]
 }
 
+// redirect records a message to resume a task's session with once its agent
+// has stopped, reporting false if the user has already stopped the agent.
+// Messages sent while the agent is stopping are combined.
+func (r *runningAgents) redirect(taskID, message string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stoppedByUser[taskID] {
+		return false
+	}
+	if previous, ok := r.redirects[taskID]; ok {
+		message = previous + "\n\n" + message
+	}
+	r.redirects[taskID] = message
+	return true
+}
+
+// redirection returns the message a stopped agent's session should resume
+// with, if any.
+func (r *runningAgents) redirection(taskID string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	message, ok := r.redirects[taskID]
+	return message, ok
+}
+
+func (r *runningAgents) runner(taskID string) *agent.Runner {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.runners[taskID]
+}
+
 func (r *runningAgents) cancelAll() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.shuttingDown = true
 	for _, runner := range r.runners {
 		if runner != nil {
 			runner.Cancel()
@@ -93,16 +161,92 @@ func (r *runningAgents) cancelAll() {
 	}
 }
 
+func (r *runningAgents) isShuttingDown() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.shuttingDown
+}
+
 func (r *runningAgents) hasRunning() bool {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	return len(r.tasks) > 0
 }
 
+func (r *runningAgents) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.tasks)
+}
+
 func (r *runningAgents) done() <-chan string {
 	return r.doneCh
 }
 
+// messenger receives the worker's UI messages: the TUI program, or a line
+// logger when running as `momentum serve`.
+type messenger interface {
+	Send(msg tea.Msg)
+}
+
+// workerEnv bundles the long-lived collaborators shared by the worker loop
+// and the agents it spawns.
+type workerEnv struct {
+	p       messenger
+	agents  *runningAgents
+	state   *instanceState
+	metrics *workerMetrics
+	// tracer is nil unless --trace-file is set
+	tracer *tracing.Tracer
+	// hooks runs the configured lifecycle hooks
+	hooks *hooks.Runner
+	// verifier checks agent work before tasks are marked done
+	verifier *verify.Verifier
+	// approval decides which successful runs wait for a reviewer
+	approval *approval.Policy
+	// reviews holds runs awaiting review and rejected runs' feedback
+	reviews *reviewBoard
+	// journal records claimed tasks for crash recovery; nil if disabled
+	journal *journal.Journal
+	// sessions remembers each task's agent session for resuming; nil if disabled
+	sessions *session.Store
+	// leases coordinate claims with other instances; nil if disabled
+	leases *lease.Manager
+	// states names the statuses tasks move through; nil means the built-ins
+	states *workflow.StateMachine
+	// audit records every status change; nil if disabled
+	audit *audit.Log
+	// workspaces picks the directory each task runs in; nil runs every
+	// task in the workdir setting
+	workspaces *workspace.Resolver
+	// git commits each task's changes to its own branch; nil if disabled
+	git *vcs.Manager
+	// notifier sends lifecycle events to webhooks and the desktop; nil if
+	// no notifications are configured
+	notifier *notify.Notifier
+	// newAgent creates task agents for the backend chosen with --agent; nil
+	// means a local Claude Code agent
+	newAgent agent.AgentFactory
+	// pool hands agents to remote runners under `momentum serve`; nil otherwise
+	pool *pool.Coordinator
+	// sandbox restricts local agent processes; nil runs them unrestricted
+	sandbox *sandbox.Profile
+}
+
+// createAgent creates the agent for a task.
+func (env *workerEnv) createAgent(cfg agent.Config) agent.Agent {
+	if env.newAgent != nil {
+		return env.newAgent(cfg)
+	}
+	return agent.NewClaudeCode(cfg)
+}
+
+// reportError shows an error in the TUI and records it for the control socket.
+func (env *workerEnv) reportError(err error) {
+	env.p.Send(ui.ListenerErrorMsg{Err: err})
+	env.state.setError(err)
+}
+
 // isAutoEpicEvent checks if the SSE event contains an epic with auto=true
 func isAutoEpicEvent(event sse.Event) bool {
 	var data sseEventData
@@ -138,19 +282,37 @@ func runHeadless() error {
 	modeUpdates := make(chan ui.ExecutionMode, 10)
 	stopUpdates := make(chan string, 10)
 	workDirUpdates := make(chan string, 10)
+	messageUpdates := make(chan ui.AgentMessage, 10)
+	reviewUpdates := make(chan ui.ReviewDecision, 10)
+	previewUpdates := make(chan string, 1)
 	model := ui.NewModel(criteria, mode, GetWorkDir(), modeUpdates, stopUpdates, workDirUpdates)
+	model.SetMessageUpdates(messageUpdates)
+	model.SetReviewUpdates(reviewUpdates)
+	model.SetPromptPreviewUpdates(previewUpdates)
+	workDirs := newWorkDirStore()
+	workDirs.Remember(GetWorkDir())
+	model.SetWorkDirStore(workDirs)
 
 	// Create the bubbletea program
 	p := tea.NewProgram(&model, tea.WithAltScreen())
 
+	env, closeEnv, err := newWorkerEnv(criteria, mode, p)
+	if err != nil {
+		return err
+	}
+	defer closeEnv()
+	agents := env.agents
+
 	// Create context for cancellation
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// Track running agents for cleanup
-	agents := newRunningAgents()
+	// Expose metrics for scraping if requested
+	if metricsAddr != "" {
+		go serveMetrics(ctx, metricsAddr, env)
+	}
 
 	// Start the background worker
-	go runWorker(ctx, p, agents, mode, modeUpdates, stopUpdates, workDirUpdates)
+	go runWorker(ctx, env, mode, modeUpdates, stopUpdates, workDirUpdates, messageUpdates, reviewUpdates, previewUpdates)
 
 	// Run the TUI
 	_, err = p.Run()
@@ -166,6 +328,213 @@ func runHeadless() error {
 	return nil
 }
 
+// newWorkerEnv loads the config file and builds the worker's collaborators,
+// sending UI messages to p. The returned func flushes the tracer on exit.
+func newWorkerEnv(criteria string, mode ui.ExecutionMode, p messenger) (*workerEnv, func(), error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	hookRunner, err := hooks.NewRunner(cfg.Hooks)
+	if err != nil {
+		return nil, nil, err
+	}
+	profile, err := cfg.Sandbox.Select(profileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	newAgent, err := selectAgent(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	verifier, err := verify.New(cfg.Verify, func(c agent.Config) agent.Agent {
+		c.Sandbox = profile
+		return newAgent(c)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	approvals, err := approval.New(cfg.Approval)
+	if err != nil {
+		return nil, nil, err
+	}
+	workspaces, err := workspace.New(cfg.Workdirs, client.NewClient(GetBaseURL()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid workdirs config: %w", err)
+	}
+	gitManager, err := vcs.New(cfg.Git)
+	if err != nil {
+		return nil, nil, err
+	}
+	claims, err := openJournal()
+	if err != nil {
+		return nil, nil, err
+	}
+	sessions, err := openSessions()
+	if err != nil {
+		return nil, nil, err
+	}
+	leases, err := lease.New(cfg.Leases)
+	if err != nil {
+		return nil, nil, err
+	}
+	states, err := workflow.NewStateMachine(cfg.Workflow)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := states.Require(extraMoves(states, verifier, approvals)...); err != nil {
+		return nil, nil, fmt.Errorf("invalid workflow config: %w", err)
+	}
+	if err := checkStatuses(states); err != nil {
+		return nil, nil, err
+	}
+	leases.SetStates(states)
+	auditLog, err := openAuditLog(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	notifier, err := openNotifier(cfg.Notify, p)
+	if err != nil {
+		auditLog.Close()
+		return nil, nil, err
+	}
+
+	// Open the trace exporter before the TUI takes over the terminal
+	var tracer *tracing.Tracer
+	if traceFile != "" {
+		exporter, err := tracing.NewFileExporter(traceFile)
+		if err != nil {
+			auditLog.Close()
+			notifier.Close()
+			return nil, nil, err
+		}
+		tracer = tracing.NewTracer(exporter)
+	}
+
+	// Track running agents for cleanup
+	agents := newRunningAgents()
+
+	// Mirror worker state for the control socket
+	state := newInstanceState(criteria, mode)
+	state.sandbox = describeAgents(cfg, profile)
+
+	env := &workerEnv{
+		p:          p,
+		agents:     agents,
+		state:      state,
+		metrics:    newWorkerMetrics(state, agents),
+		tracer:     tracer,
+		hooks:      hookRunner,
+		verifier:   verifier,
+		approval:   approvals,
+		git:        gitManager,
+		workspaces: workspaces,
+		reviews:    newReviewBoard(),
+		journal:    claims,
+		sessions:   sessions,
+		leases:     leases,
+		states:     states,
+		audit:      auditLog,
+		notifier:   notifier,
+		newAgent:   newAgent,
+		sandbox:    profile,
+	}
+	return env, func() {
+		tracer.Shutdown()
+		auditLog.Close()
+		notifier.Close()
+	}, nil
+}
+
+// extraMoves returns the moves verification and approvals make beyond the
+// workflow config's own statuses.
+func extraMoves(states *workflow.StateMachine, verifier *verify.Verifier, approvals *approval.Policy) []workflow.Move {
+	var moves []workflow.Move
+	if verifier.Enabled() {
+		moves = append(moves, workflow.Move{From: states.Running(), To: verifyFailedStatus(verifier, states)})
+	}
+	if approvals.Enabled() {
+		review := approvals.Status()
+		moves = append(moves,
+			workflow.Move{From: states.Running(), To: review},
+			workflow.Move{From: review, To: states.Success()},
+			workflow.Move{From: review, To: states.Reset()},
+		)
+	}
+	return moves
+}
+
+// checkStatuses makes sure every status the workflow config names exists on
+// the board. Flux has no list of statuses, so anything beyond the built-ins
+// must already be used by a task. A workflow with custom statuses is not
+// accepted unchecked: if Flux cannot be reached, starting fails.
+func checkStatuses(states *workflow.StateMachine) error {
+	if states.Check(workflow.BuiltinStatuses) == nil {
+		return nil
+	}
+	present, err := workflow.BoardStatuses(client.NewClient(GetBaseURL()), projectID)
+	if err != nil {
+		return fmt.Errorf("cannot check the workflow config's custom statuses against Flux: %w", err)
+	}
+	if err := states.Check(present); err != nil {
+		return fmt.Errorf("invalid workflow config: %w; move a task to each custom status in Flux first", err)
+	}
+	return nil
+}
+
+// selectAgent returns the factory for the agent backend named by --agent or
+// the config file, after registering the configurable backends.
+func selectAgent(cfg *config.Config) (agent.AgentFactory, error) {
+	name := agentName
+	if name == "" {
+		name = cfg.Agent
+	}
+	if name == "" {
+		name = "claude"
+	}
+
+	agent.RegisterAgent(agent.ContainerAgent, func(c agent.Config) agent.Agent {
+		return agent.NewContainer(c, cfg.Container)
+	})
+	agent.RegisterAgent(agent.FakeAgent, func(c agent.Config) agent.Agent {
+		return agent.NewFake(c, cfg.Fake)
+	})
+	if !agent.DefaultRegistry.Has(name) {
+		available := agent.AvailableAgents()
+		slices.Sort(available)
+		return nil, fmt.Errorf("unknown agent %q (available: %s)", name, strings.Join(available, ", "))
+	}
+	switch name {
+	case agent.ContainerAgent:
+		if err := cfg.Container.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid container config: %w", err)
+		}
+	case agent.FakeAgent:
+		if err := cfg.Fake.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid fake agent config: %w", err)
+		}
+	}
+
+	return func(c agent.Config) agent.Agent {
+		a, _ := agent.CreateAgent(name, c)
+		return a
+	}, nil
+}
+
+// describeAgents summarises how agents are run for logs and status output.
+// Containers replace the profile's limits and filesystem rules, but its tool
+// permissions still apply.
+func describeAgents(cfg *config.Config, profile *sandbox.Profile) string {
+	name := agentName
+	if name == "" {
+		name = cfg.Agent
+	}
+	if name == agent.ContainerAgent {
+		return cfg.Container.Describe() + "; " + profile.Describe()
+	}
+	return profile.Describe()
+}
+
 func buildCriteriaString() string {
 	if taskID != "" {
 		return fmt.Sprintf("Task: %s", taskID)
@@ -191,26 +560,63 @@ func parseExecutionMode(value string) (ui.ExecutionMode, error) {
 }
 
 // runWorker runs the background task selection and agent spawning
-func runWorker(ctx context.Context, p *tea.Program, agents *runningAgents, mode ui.ExecutionMode, modeUpdates <-chan ui.ExecutionMode, stopUpdates <-chan string, workDirUpdates <-chan string) {
+func runWorker(ctx context.Context, env *workerEnv, mode ui.ExecutionMode, modeUpdates <-chan ui.ExecutionMode, stopUpdates <-chan string, workDirUpdates <-chan string, messageUpdates <-chan ui.AgentMessage, reviewUpdates <-chan ui.ReviewDecision, previewUpdates <-chan string) {
+	p, agents, state := env.p, env.agents, env.state
+
 	// Create the REST client
 	c := client.NewClient(GetBaseURL())
+	env.metrics.observeClient(c)
 
 	// Create workflow for status updates
 	wf := workflow.NewWorkflow(c)
 	wf.SetOutput(io.Discard)
+	wf.SetStates(env.states)
+	env.observeTransitions(wf)
 
-	// Create the selector
-	selector := selection.NewSelector(c, projectID, epicID, taskID)
+	// Create the selector; with leases, abandoned tasks can be taken over
+	selector := selection.NewSelector(c, projectID, epicID, taskID).WithStates(env.states)
+	if env.leases.Enabled() {
+		selector = selector.WithExpiredLeases()
+	}
 
 	// Start SSE subscriber
 	subscriber := sse.NewSubscriber(GetBaseURL())
 	sseEvents := subscriber.Start(ctx)
 	defer subscriber.Stop()
+	env.metrics.observeSubscriber(subscriber)
+	go watchConnection(ctx, func() bool { return subscriber.Stats().Connected }, fluxConnectionGrace, time.Second, env.notifyConnection)
 
 	// Signal connected
 	p.Send(ui.ListenerConnectedMsg{})
+	state.setConnected()
+
+	// Serve the control socket so other terminals can inspect this instance
+	backend := newControlBackend(state, agents, p)
+	backend.pool = env.pool
+	backend.reviews = env.reviews
+	backend.continueTask = func(taskID, instructions string) error {
+		return continueTask(ctx, env, c, wf, taskID, instructions)
+	}
+	backend.approveReview = func(taskID, comment string) error {
+		return approveReview(ctx, env, wf, taskID, comment)
+	}
+	backend.rejectReview = func(taskID, comment string) error {
+		return rejectReview(ctx, env, wf, taskID, comment)
+	}
+	ctl := control.NewServer(controlSocket, backend)
+	if err := ctl.Start(); err != nil {
+		p.Send(ui.ListenerErrorMsg{Err: err})
+	} else {
+		defer ctl.Close()
+	}
+
+	// Settle tasks left in_progress by earlier runs that crashed or were killed
+	reconcileJournal(ctx, env, c, wf)
+	restoreReviews(env, c)
 
-	// Process stop requests and workdir updates even when the main loop blocks waiting for SSE.
+	// Process stop requests, workdir updates, agent messages, review
+	// decisions and prompt previews even when the main loop blocks waiting
+	// for SSE.
 	go func() {
 		for {
 			select {
@@ -220,6 +626,20 @@ func runWorker(ctx context.Context, p *tea.Program, agents *runningAgents, mode
 				agents.markStoppedByUser(taskID)
 			case newWorkDir := <-workDirUpdates:
 				SetWorkDir(newWorkDir)
+			case msg := <-messageUpdates:
+				if err := messageAgent(env, msg.TaskID, msg.Text); err != nil {
+					env.reportError(err)
+				}
+			case d := <-reviewUpdates:
+				review := rejectReview
+				if d.Approve {
+					review = approveReview
+				}
+				if err := review(ctx, env, wf, d.TaskID, d.Comment); err != nil {
+					env.reportError(err)
+				}
+			case taskID := <-previewUpdates:
+				go previewPrompt(ctx, env, c, selector, taskID)
 			}
 		}
 	}()
@@ -227,13 +647,59 @@ func runWorker(ctx context.Context, p *tea.Program, agents *runningAgents, mode
 	pending := make([]*client.Task, 0)
 	queued := make(map[string]bool)
 
+	// iterCtx carries the current iteration's trace span, if any
+	iterCtx := ctx
+	var iterSpan *tracing.Span
+	defer func() { iterSpan.End() }()
+
+	// Pre-select hooks run once per selection round rather than on every
+	// pass of the loop, and again whenever a task was found or became available
+	preSelectDone := false
+
 	startTask := func(task *client.Task) {
 		delete(queued, task.ID)
-		if err := wf.StartWorking([]string{task.ID}); err != nil {
-			p.Send(ui.ListenerErrorMsg{Err: err})
+		task, err := env.claimTask(iterCtx, c, wf, task)
+		if err != nil {
+			// Losing a claim to another instance is expected, not an error
+			if !errors.Is(err, lease.ErrHeld) {
+				env.reportError(err)
+			}
 			return
 		}
-		spawnAgent(ctx, p, task, wf, agents)
+		// A task mapped to a missing directory, or whose repository cannot
+		// be cloned, goes back to planning rather than running in the
+		// wrong place
+		ws, err := env.workspaceFor(task)
+		if err == nil {
+			err = env.prepareWorkspace(iterCtx, ws)
+		}
+		if err != nil {
+			env.reportError(err)
+			if err := rejectTask(wf.WithContext(iterCtx).WithFrom(*task), task.ID, err); err != nil {
+				env.reportError(err)
+			}
+			env.leases.Release(iterCtx, c, task)
+			return
+		}
+		env.journalClaim(task, ws.Dir, 0, "", time.Now())
+		if err := env.runHook(iterCtx, hooks.PreRun, task, nil); err != nil {
+			env.reportError(err)
+			if err := rejectTask(wf.WithContext(iterCtx).WithFrom(*task), task.ID, err); err != nil {
+				env.reportError(err)
+			}
+			env.leases.Release(iterCtx, c, task)
+			env.journalRelease(task.ID)
+			return
+		}
+		// A task rejected in review resumes with the reviewer's comment.
+		// A task whose agent could not start is back in the queue; wait
+		// before picking it up again rather than fail in a tight loop
+		if err := spawnAgent(iterCtx, env, c, task, wf, env.resumableSession(task.ID, ws.Dir), env.reviews.takeFeedback(task.ID)); err != nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
+			}
+		}
 	}
 
 	queueTask := func(task *client.Task) {
@@ -242,6 +708,7 @@ func runWorker(ctx context.Context, p *tea.Program, agents *runningAgents, mode
 		}
 		queued[task.ID] = true
 		pending = append(pending, task)
+		state.setQueued(pending)
 	}
 
 	startNextPending := func() {
@@ -250,6 +717,7 @@ func runWorker(ctx context.Context, p *tea.Program, agents *runningAgents, mode
 		}
 		next := pending[0]
 		pending = pending[1:]
+		state.setQueued(pending)
 		startTask(next)
 	}
 
@@ -260,32 +728,74 @@ func runWorker(ctx context.Context, p *tea.Program, agents *runningAgents, mode
 		tasks := pending
 		pending = nil
 		for _, task := range tasks {
+			// Still waiting for another task's run to release its checkout
+			if env.gitBusy(task) {
+				pending = append(pending, task)
+				continue
+			}
 			startTask(task)
 		}
+		state.setQueued(pending)
 	}
 
 	// Main loop
 	for {
+		iterSpan.End()
+		iterCtx = ctx
+
 		select {
 		case <-ctx.Done():
 			return
 		case <-agents.done():
 		case newMode := <-modeUpdates:
 			mode = newMode
-			if mode == ui.ExecutionModeAsync {
+			state.setMode(mode)
+			if mode == ui.ExecutionModeAsync && !state.isPaused() {
 				startAllPending()
 			}
 		default:
 		}
 
+		// While paused, running agents continue but nothing new is started
+		if state.isPaused() {
+			time.Sleep(250 * time.Millisecond)
+			continue
+		}
+
+		// A coordinator only starts tasks that a connected runner can take
+		if !env.pool.HasCapacity() {
+			time.Sleep(250 * time.Millisecond)
+			continue
+		}
+
+		iterCtx, iterSpan = env.tracer.Start(ctx, "worker.iteration",
+			tracing.String("worker.mode", mode.String()),
+			tracing.Int("worker.pending", len(pending)),
+			tracing.Int("worker.running", agents.count()),
+		)
+
+		if mode == ui.ExecutionModeAsync && len(pending) > 0 {
+			startAllPending()
+		}
+
 		if mode == ui.ExecutionModeSync && len(pending) > 0 && !agents.hasRunning() {
 			startNextPending()
 			time.Sleep(250 * time.Millisecond)
 			continue
 		}
 
+		if !preSelectDone {
+			if err := env.runHook(iterCtx, hooks.PreSelect, nil, nil); err != nil {
+				iterSpan.SetError(err)
+				env.reportError(err)
+				time.Sleep(5 * time.Second)
+				continue
+			}
+			preSelectDone = true
+		}
+
 		// Try to select a task
-		task, err := selector.SelectTaskExcluding(queued)
+		task, err := selector.WithContext(iterCtx).SelectTaskExcluding(queued)
 		if err != nil {
 			if errors.Is(err, selection.ErrNoTaskAvailable) {
 				if len(pending) > 0 {
@@ -293,19 +803,29 @@ func runWorker(ctx context.Context, p *tea.Program, agents *runningAgents, mode
 					continue
 				}
 				// Wait for a task to become available (only from auto epics)
-				if err := waitForTaskWithSSE(ctx, sseEvents, selector); err != nil {
+				err := waitForTaskWithSSE(ctx, sseEvents, selector.WithContext(iterCtx))
+				preSelectDone = false
+				if err != nil {
 					if errors.Is(err, context.Canceled) {
 						return
 					}
-					p.Send(ui.ListenerErrorMsg{Err: err})
+					env.reportError(err)
 					time.Sleep(5 * time.Second)
 				}
 				continue
 			}
-			p.Send(ui.ListenerErrorMsg{Err: err})
+			// Selection is cut short when Momentum is exiting
+			if ctx.Err() != nil {
+				return
+			}
+			iterSpan.SetError(err)
+			env.reportError(err)
 			time.Sleep(5 * time.Second)
 			continue
 		}
+		env.metrics.tasksSelected.Inc()
+		preSelectDone = false
+		iterSpan.SetAttributes(tracing.String("task.id", task.ID))
 
 		if mode == ui.ExecutionModeSync && agents.hasRunning() {
 			queueTask(task)
@@ -325,6 +845,14 @@ func runWorker(ctx context.Context, p *tea.Program, agents *runningAgents, mode
 			continue
 		}
 
+		// With git, one run at a time owns a working directory; a task
+		// sharing it waits in the queue rather than mixing its changes into
+		// that run's commit
+		if env.gitBusy(task) {
+			queueTask(task)
+			continue
+		}
+
 		startTask(task)
 	}
 }
@@ -365,39 +893,119 @@ func waitForTaskWithSSE(ctx context.Context, sseEvents <-chan sse.Event, selecto
 	}
 }
 
-// spawnAgent spawns a new agent for the given task
-func spawnAgent(ctx context.Context, p *tea.Program, task *client.Task, wf *workflow.Workflow, agents *runningAgents) {
+// spawnAgent spawns a new agent for the given task. A non-nil resume
+// continues that session instead of starting a new one. Instructions from
+// the user, if given, are added to the prompt. The returned error, already
+// reported, says why no agent was started.
+func spawnAgent(ctx context.Context, env *workerEnv, c *client.Client, task *client.Task, wf *workflow.Workflow, resume *session.Record, instructions string) error {
+	p, agents, state := env.p, env.agents, env.state
+
+	ws, err := env.workspaceFor(task)
+	if err != nil {
+		agents.markDone(task.ID)
+		env.reportError(err)
+		if err := rejectTask(wf.WithContext(ctx).WithFrom(*task), task.ID, err); err != nil {
+			env.reportError(err)
+		}
+		env.leases.Release(ctx, c, task)
+		env.journalRelease(task.ID)
+		return err
+	}
+
+	workDir := ws.Dir
+	cfg, prompt := env.agentInput(task, ws, resume, instructions)
+	verified := env.verifier.Enabled()
+
+	// Work on the task's own branch, so its changes can be committed to it.
+	// The worker loop holds back tasks whose checkout is in use, but a
+	// resumed or continued task can still find it taken: it goes back to be
+	// picked up again rather than run alongside the other task
+	gitRun, err := env.startGit(ctx, workDir, task)
+	if err != nil {
+		agents.markDone(task.ID)
+		env.reportError(err)
+		if _, err := wf.WithContext(ctx).WithFrom(*task).WithReason("working directory in use by another task").ResetTask([]string{task.ID}); err != nil {
+			env.reportError(err)
+		}
+		env.leases.Release(ctx, c, task)
+		env.journalRelease(task.ID)
+		return err
+	}
+
 	// Create agent
-	ag := agent.NewClaudeCode(agent.Config{
-		WorkDir: GetWorkDir(),
-	})
+	ag := env.createAgent(cfg)
 
 	runner := agent.NewRunner(ag)
 
 	// Mark task as having a running agent (with runner reference for cleanup)
 	agents.markRunning(task.ID, runner)
 
-	// Build prompt
-	prompt := buildHeadlessPrompt(task)
+	// Fingerprint the workdir so verification can tell whether the agent changed anything
+	var baseline verify.Baseline
+	if verified {
+		baseline = verify.TakeBaseline(ctx, workDir)
+	}
+	// Record the commit the run starts from, to show and review its changes
+	changesBase := git.TakeBaseline(ctx, workDir)
+
+	// Trace the agent run, including the final status transition
+	ctx, span := tracing.Start(ctx, "agent.run",
+		tracing.String("task.id", task.ID),
+		tracing.String("task.title", task.Title),
+		tracing.String("agent.name", ag.Name()),
+	)
 
 	// Start the agent
 	if err := runner.Run(ctx, prompt); err != nil {
 		agents.markDone(task.ID)
-		p.Send(ui.ListenerErrorMsg{Err: err})
-		return
+		env.reportError(err)
+		env.notifyTask(notify.AgentFailed, task, firstLine(err.Error()), nil)
+		env.settleGit(ctx, wf, task, gitRun, false)
+		// Nothing ran, so put the task back in the queue
+		if _, err := wf.WithContext(ctx).WithFrom(*task).WithReason("agent failed to start").ResetTask([]string{task.ID}); err != nil {
+			env.reportError(err)
+		}
+		env.leases.Release(ctx, c, task)
+		env.journalRelease(task.ID)
+		span.SetError(err)
+		span.End()
+		return err
 	}
+	span.SetAttributes(tracing.Int("agent.pid", runner.PID()))
+	env.journalClaim(task, workDir, runner.PID(), runner.Command(), time.Now())
+	state.taskStarted(task, runner.PID())
+	env.notifyTask(notify.AgentStarted, task, "working in "+workDir, nil)
+
+	// Renew the lease while the agent runs; if another instance takes the
+	// task over, stop this agent rather than race it
+	heartbeat := env.leases.Heartbeat(ctx, c, task, func(err error) {
+		env.reportError(err)
+		runner.Cancel()
+	}, env.reportError)
+	env.metrics.tasksStarted.Inc()
 
 	// Add panel to UI via message
 	p.Send(ui.AddAgentMsg{
 		TaskID:    task.ID,
 		TaskTitle: task.Title,
-		AgentName: "Claude",
+		AgentName: ag.Name(),
 		Runner:    runner,
+		Resumed:   resume != nil,
+		WorkDir:   workDir,
 	})
 
-	// Stream output in background
+	// Stream output in background, remembering the agent's session as soon
+	// as it is reported so a crash or restart can resume it
+	outputDone := make(chan struct{})
 	go func() {
+		defer close(outputDone)
+		saved := ""
 		for line := range runner.Output() {
+			if id := runner.SessionID(); id != saved {
+				saved = id
+				env.saveSession(task, id, workDir, outcomeInterrupted)
+			}
+			state.appendOutput(task.ID, line)
 			p.Send(ui.AgentOutputMsg{
 				TaskID: task.ID,
 				Line:   line,
@@ -408,34 +1016,241 @@ func spawnAgent(ctx context.Context, p *tea.Program, task *client.Task, wf *work
 	// Wait for completion in background
 	go func() {
 		result := <-runner.Done()
+		heartbeat.Stop()
+		leaseLost := heartbeat.Lost()
 
 		// Check if stopped by user before marking done (which clears the flag)
 		stoppedByUser := agents.wasStoppedByUser(task.ID)
+		message, redirected := agents.redirection(task.ID)
+		redirected = redirected && leaseLost == nil && !agents.isShuttingDown()
+		exitCode := result.ExitCode
+
+		// Post-run hooks check the work while the panel still shows the agent
+		// as running, so their output lands alongside the agent's
+		var vetoErr error
+		if !stoppedByUser && !redirected && leaseLost == nil {
+			vetoErr = env.runHook(ctx, hooks.PostRun, task, &exitCode)
+		}
+
+		// Independently verify successful runs before the task can be marked done
+		var verifyErr error
+		if !stoppedByUser && !redirected && leaseLost == nil && result.ExitCode == 0 && vetoErr == nil && verified {
+			verifyErr = env.verifier.Verify(ctx, task, workDir, baseline, env.taskOutput(task.ID))
+			if verifyErr == nil {
+				env.verifier.RecordSuccess(task.ID)
+			}
+		}
+
+		// Risky work waits for a reviewer instead of being marked done
+		var review *pendingReview
+		if !stoppedByUser && !redirected && leaseLost == nil && result.ExitCode == 0 && vetoErr == nil && verifyErr == nil {
+			review = env.checkApproval(ctx, task, workDir, changesBase)
+		}
+
+		// Capture the run's changes before they are committed and the
+		// branch is switched
+		var changes *ui.Changes
+		if !redirected {
+			changes = captureChanges(ctx, workDir, changesBase)
+		}
 
 		// Mark agent as done
 		agents.markDone(task.ID)
+		completed := !stoppedByUser && !redirected && leaseLost == nil && result.ExitCode == 0 && vetoErr == nil && verifyErr == nil
+		state.taskFinished(task.ID, result.ExitCode, completed)
+
+		// A redirected agent's panel stays open for the resumed session
+		if !redirected {
+			p.Send(ui.AgentCompletedMsg{
+				TaskID:  task.ID,
+				Result:  result,
+				Changes: changes,
+			})
+		}
+		env.metrics.observeResult(result, stoppedByUser || redirected)
+
+		span.SetAttributes(
+			tracing.Int("agent.exit_code", result.ExitCode),
+			tracing.Bool("agent.stopped_by_user", stoppedByUser),
+			tracing.Bool("agent.redirected", redirected),
+		)
+		if result.Error != nil {
+			span.SetError(result.Error)
+		} else if result.ExitCode != 0 && !stoppedByUser && !redirected {
+			span.SetStatus(tracing.StatusError, fmt.Sprintf("agent exited with code %d", result.ExitCode))
+		}
+		defer span.End()
+
+		// Commit successful work before the task moves on; anything else is
+		// parked on the task's branch
+		wf := wf.WithContext(ctx).WithFrom(*task)
+		succeeded := !stoppedByUser && !redirected && leaseLost == nil && result.ExitCode == 0 && vetoErr == nil && verifyErr == nil
+		env.settleGit(ctx, wf, task, gitRun, succeeded)
+
+		// Update task status and run the matching outcome hooks
+		var hookErr error
+		switch {
+		case leaseLost != nil:
+			// Another instance owns the task now; leave its status alone
+			span.SetError(leaseLost)
+		case redirected:
+			// The agent is resumed with the user's message below
+		case stoppedByUser:
+			// User stopped the agent, reset task to planning
+			if _, err := wf.WithReason("stopped by user").ResetToPlanning([]string{task.ID}); err != nil {
+				env.reportError(err)
+			}
+			env.notifyTask(notify.AgentStopped, task, "stopped by user", &exitCode)
+			hookErr = env.runHook(ctx, hooks.OnStop, task, &exitCode)
+		case result.ExitCode == 0 && vetoErr == nil && verifyErr == nil && review != nil:
+			// The success hooks run once a reviewer approves; a task that
+			// cannot be held is settled as a failed run
+			if err := env.holdForReview(wf, review); err != nil {
+				span.SetError(err)
+				env.reportError(err)
+				if _, err := wf.WithReason("could not hold for review").MarkFailed([]string{task.ID}); err != nil {
+					env.reportError(err)
+				}
+				env.notifyTask(notify.AgentFailed, task, "could not hold for review", &exitCode)
+				hookErr = env.runHook(ctx, hooks.OnFailure, task, &exitCode)
+			}
+		case result.ExitCode == 0 && vetoErr == nil && verifyErr == nil:
+			// The task is only completed, and the success hooks run, once
+			// it has moved
+			if _, err := wf.WithReason("agent finished").MarkComplete([]string{task.ID}); err != nil {
+				span.SetError(err)
+				env.reportError(err)
+				break
+			}
+			env.notifyTask(notify.TaskCompleted, task, "agent finished", &exitCode)
+			hookErr = env.runHook(ctx, hooks.OnSuccess, task, &exitCode)
+		case result.ExitCode == 0 && vetoErr != nil:
+			// A post-run hook vetoed completion
+			span.SetError(vetoErr)
+			env.reportError(vetoErr)
+			if err := rejectTask(wf, task.ID, vetoErr); err != nil {
+				env.reportError(err)
+			}
+			env.notifyTask(notify.AgentFailed, task, firstLine(vetoErr.Error()), &exitCode)
+			hookErr = env.runHook(ctx, hooks.OnFailure, task, &exitCode)
+		case result.ExitCode == 0:
+			// Verification failed
+			span.SetError(verifyErr)
+			env.reportError(verifyErr)
+			status, parked := env.recordVerifyFailure(task.ID)
+			if err := failVerification(wf, task.ID, status, parked, verifyErr); err != nil {
+				env.reportError(err)
+			}
+			env.notifyTask(notify.AgentFailed, task, "verification failed: "+firstLine(verifyErr.Error()), &exitCode)
+			hookErr = env.runHook(ctx, hooks.OnFailure, task, &exitCode)
+		case timedOut(cfg, result):
+			// Moved to the workflow's timeout status, if it has one
+			reason := fmt.Sprintf("agent timed out after %s", cfg.Timeout)
+			if _, err := wf.WithReason(reason).MarkTimedOut([]string{task.ID}); err != nil {
+				env.reportError(err)
+			}
+			env.notifyTask(notify.AgentFailed, task, reason, &exitCode)
+			hookErr = env.runHook(ctx, hooks.OnFailure, task, &exitCode)
+		default:
+			// Moved to the workflow's failure status, if it has one, and
+			// otherwise left running for investigation
+			reason := fmt.Sprintf("agent exited with code %d", result.ExitCode)
+			if _, err := wf.WithReason(reason).MarkFailed([]string{task.ID}); err != nil {
+				env.reportError(err)
+			}
+			env.notifyTask(notify.AgentFailed, task, reason, &exitCode)
+			hookErr = env.runHook(ctx, hooks.OnFailure, task, &exitCode)
+		}
+		if hookErr != nil {
+			env.reportError(hookErr)
+		}
 
-		p.Send(ui.AgentCompletedMsg{
-			TaskID: task.ID,
-			Result: result,
-		})
+		// Record how the run ended for the next resume; a run cut short by
+		// shutdown stays recorded as interrupted
+		<-outputDone
+		switch {
+		case leaseLost != nil || agents.isShuttingDown():
+		case redirected:
+			// Resume right away with the user's message; the task stays
+			// claimed and in_progress
+			rec := session.Record{SessionID: result.SessionID, Outcome: outcomeMessaged}
+			if rec.SessionID == "" {
+				rec.SessionID = runner.SessionID()
+			}
+			env.saveSession(task, rec.SessionID, workDir, rec.Outcome)
+			spawnAgent(ctx, env, c, task, wf, &rec, message)
+			return
+		case !sessionCanResume(cfg, result, stoppedByUser):
+			env.forgetSession(task.ID)
+			env.reportError(fmt.Errorf("could not resume agent session %s for task %s; its next run starts a new session", cfg.ResumeSession, task.ID))
+		case result.SessionID == "":
+		case stoppedByUser:
+			env.saveSession(task, result.SessionID, workDir, outcomeStopped)
+		default:
+			env.saveSession(task, result.SessionID, workDir, runOutcome(result.ExitCode, vetoErr, verifyErr))
+		}
 
-		// Update task status
-		if stoppedByUser {
-			// User stopped the agent, reset task to planning
-			wf.ResetToPlanning([]string{task.ID})
-		} else if result.ExitCode == 0 {
-			wf.MarkComplete([]string{task.ID})
+		// Agents killed because Momentum is exiting leave their tasks
+		// in_progress; keep the entry so the next start reconciles them
+		if leaseLost == nil && !agents.isShuttingDown() {
+			env.leases.Release(ctx, c, task)
+		}
+		if !agents.isShuttingDown() {
+			env.journalRelease(task.ID)
 		}
-		// On failure (not stopped by user), leave as in_progress for investigation
 	}()
+	return nil
+}
+
+// agentInput builds the configuration and prompt task's agent is started
+// with in ws. A non-nil resume continues that session; instructions from the
+// user or a reviewer are added to the prompt.
+func (env *workerEnv) agentInput(task *client.Task, ws workspace.Workspace, resume *session.Record, instructions string) (agent.Config, string) {
+	cfg := agent.Config{
+		WorkDir:    ws.Dir,
+		Repository: ws.Repository,
+		Timeout:    agentTimeout,
+		Sandbox:    env.sandbox,
+	}
+	// With verification or approvals enabled Momentum marks the task done
+	// itself
+	settled := env.verifier.Enabled() || env.approval.Enabled()
+	prompt := buildAgentPrompt(task, settled, env.states)
+	if resume != nil {
+		cfg.ResumeSession = resume.SessionID
+		prompt = buildResumePrompt(task, settled, env.states, resume.Outcome, instructions)
+	} else if instructions != "" {
+		prompt += "\nInstructions from the user, which take priority over the task description:\n" + strings.TrimSpace(instructions) + "\n"
+	}
+	return cfg, prompt
+}
+
+// timedOut reports whether a failed run was stopped by --agent-timeout.
+func timedOut(cfg agent.Config, result agent.Result) bool {
+	return cfg.Timeout > 0 && result.ExitCode != 0 && result.Duration >= cfg.Timeout
 }
 
 // buildHeadlessPrompt constructs the prompt for the agent
 func buildHeadlessPrompt(task *client.Task) string {
+	return buildAgentPrompt(task, false, nil)
+}
+
+// buildAgentPrompt constructs the prompt for the agent. When verified is true,
+// Momentum checks the work itself before marking the task done, so the agent
+// is told to leave the task status alone. states names the statuses the agent
+// is told to use; nil means the built-in ones.
+func buildAgentPrompt(task *client.Task, verified bool, states *workflow.StateMachine) string {
 	var b strings.Builder
 
-	b.WriteString(`Goal: complete a single Flux task end-to-end, verify it works, and mark the task as done in Flux.
+	goal := "Goal: complete a single Flux task end-to-end, verify it works, and mark the task as done in Flux."
+	finish := fmt.Sprintf("5) Mark the task as done using Flux MCP (mcp__flux__move_task_status with status %q) and mention the task ID in your final message.", states.Success())
+	if verified {
+		goal = "Goal: complete a single Flux task end-to-end and verify it works. Momentum independently verifies your work and marks the task as done."
+		finish = fmt.Sprintf("5) Do not move the task to %q yourself; Momentum does that once its own checks pass. Mention the task ID in your final message.", states.Success())
+	}
+
+	b.WriteString(goal)
+	b.WriteString(`
 
 Process:
 1) Find the task to work on (use the given task ID/title, or select the highest-priority todo in the target project).
@@ -446,17 +1261,17 @@ Process:
    - Report what you ran and the result.
    - Add a comment to the task via MCP using mcp__flux__add_task_comment.
      Example: {"task_id":"<id>","body":"What you did + verification results + any notes."}
-5) Mark the task as done using Flux MCP (mcp__flux__move_task_status with status "done") and mention the task ID in your final message.
+`)
+	b.WriteString(finish)
+	b.WriteString(`
 
 Constraints:
 - Do not modify unrelated files.
 - Do not reset/revert unrelated git changes.
 - Be concise in explanations.
 
-If anything blocks completion, stop and report the blocker instead of guessing, and set the task status back to "planning", and add a comment explaining the issue.
-
-Task context:
 `)
+	b.WriteString(fmt.Sprintf("If anything blocks completion, stop and report the blocker instead of guessing, and set the task status back to %q, and add a comment explaining the issue.\n\nTask context:\n", states.Stopped()))
 
 	b.WriteString(fmt.Sprintf("- Task ID: %s\n", task.ID))
 	b.WriteString(fmt.Sprintf("- Task: %s\n", task.Title))
@@ -1,13 +1,21 @@
 package cmd
 
 import (
+	"slices"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/approval"
 	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/config"
+	"github.com/sirsjg/momentum/fluxtest"
 	"github.com/sirsjg/momentum/sse"
+	"github.com/sirsjg/momentum/ui"
+	"github.com/sirsjg/momentum/verify"
+	"github.com/sirsjg/momentum/workflow"
 )
 
 func TestNewRunningAgents(t *testing.T) {
@@ -54,6 +62,29 @@ func TestRunningAgents_MarkDone(t *testing.T) {
 	}
 }
 
+func TestRunningAgents_Redirect(t *testing.T) {
+	agents := newRunningAgents()
+	agents.markRunning("task-1", nil)
+
+	if !agents.redirect("task-1", "first") || !agents.redirect("task-1", "second") {
+		t.Fatal("expected redirects to be recorded")
+	}
+	if message, ok := agents.redirection("task-1"); !ok || message != "first\n\nsecond" {
+		t.Errorf("expected combined messages, got %q", message)
+	}
+
+	agents.markDone("task-1")
+	if _, ok := agents.redirection("task-1"); ok {
+		t.Error("expected markDone to clear the redirect")
+	}
+
+	agents.markRunning("task-2", nil)
+	agents.markStoppedByUser("task-2")
+	if agents.redirect("task-2", "too late") {
+		t.Error("expected no redirect for an agent the user stopped")
+	}
+}
+
 func TestRunningAgents_MarkDoneNonExistent(t *testing.T) {
 	agents := newRunningAgents()
 
@@ -282,6 +313,122 @@ func TestBuildHeadlessPrompt_BasicTask(t *testing.T) {
 	}
 }
 
+func TestBuildAgentPrompt_Verified(t *testing.T) {
+	task := &client.Task{
+		ID:    "task-123",
+		Title: "Fix the bug",
+	}
+
+	unverified := buildAgentPrompt(task, false, nil)
+	if unverified != buildHeadlessPrompt(task) {
+		t.Error("unverified prompt should match the headless prompt")
+	}
+	if !contains(unverified, `mcp__flux__move_task_status with status "done"`) {
+		t.Error("unverified prompt should tell the agent to mark the task done")
+	}
+
+	verified := buildAgentPrompt(task, true, nil)
+	if contains(verified, `mcp__flux__move_task_status with status "done"`) {
+		t.Error("verified prompt should not tell the agent to mark the task done")
+	}
+	if !contains(verified, `Do not move the task to "done" yourself`) {
+		t.Error("verified prompt should tell the agent to leave the status alone")
+	}
+	if !contains(verified, "Task ID: task-123") {
+		t.Error("verified prompt should still contain the task context")
+	}
+}
+
+func TestBuildAgentPrompt_CustomStatuses(t *testing.T) {
+	task := &client.Task{ID: "task-123", Title: "Fix the bug"}
+	states, err := workflow.NewStateMachine(workflow.States{Success: "qa", Stopped: "blocked"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prompt := buildAgentPrompt(task, false, states)
+	if !contains(prompt, `with status "qa"`) || contains(prompt, `with status "done"`) {
+		t.Error("prompt should name the workflow's success status")
+	}
+	if !contains(prompt, `set the task status back to "blocked"`) {
+		t.Error("prompt should name the workflow's stopped status")
+	}
+}
+
+func TestTimedOut(t *testing.T) {
+	cfg := agent.Config{Timeout: time.Minute}
+	tests := []struct {
+		cfg    agent.Config
+		result agent.Result
+		want   bool
+	}{
+		{cfg, agent.Result{ExitCode: -1, Duration: time.Minute}, true},
+		{cfg, agent.Result{ExitCode: 1, Duration: time.Second}, false},
+		{cfg, agent.Result{ExitCode: 0, Duration: 2 * time.Minute}, false},
+		{agent.Config{}, agent.Result{ExitCode: 1, Duration: time.Hour}, false},
+	}
+	for _, tt := range tests {
+		if got := timedOut(tt.cfg, tt.result); got != tt.want {
+			t.Errorf("timedOut(%v, %+v) = %v, want %v", tt.cfg.Timeout, tt.result, got, tt.want)
+		}
+	}
+}
+
+func TestCheckStatuses(t *testing.T) {
+	flux := fluxtest.NewServer()
+	defer flux.Close()
+	project := flux.AddProject(client.Project{Name: "demo"})
+	flux.AddTask(client.Task{Title: "Waiting", ProjectID: project.ID, Status: "qa"})
+
+	oldBase, oldProject := baseURL, projectID
+	defer func() { baseURL, projectID = oldBase, oldProject }()
+	baseURL, projectID = flux.URL, project.ID
+
+	present, err := workflow.NewStateMachine(workflow.States{Success: "qa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkStatuses(present); err != nil {
+		t.Errorf("expected a status used by a task to be accepted: %v", err)
+	}
+
+	missing, err := workflow.NewStateMachine(workflow.States{Failure: "blocked"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkStatuses(missing); err == nil || !contains(err.Error(), "blocked") {
+		t.Errorf("expected an error naming the missing status, got %v", err)
+	}
+
+	// Custom statuses are not accepted unchecked while Flux is failing
+	flux.Fail("GET", "/api/projects/"+project.ID+"/tasks", 503)
+	if err := checkStatuses(present); err == nil || !contains(err.Error(), "cannot check") {
+		t.Errorf("expected an error when the board cannot be read, got %v", err)
+	}
+}
+
+func TestExtraMoves(t *testing.T) {
+	states, err := workflow.NewStateMachine(workflow.States{Pickup: []string{"ready"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moves := extraMoves(states, nil, nil); len(moves) != 0 {
+		t.Errorf("expected no extra moves without verification or approvals, got %v", moves)
+	}
+
+	verifier, _ := verify.New(verify.Config{RequireDiff: true}, nil)
+	approvals, _ := approval.New(approval.Config{ProtectedPaths: []string{"go.mod"}})
+	want := []workflow.Move{
+		{From: "in_progress", To: "ready"},
+		{From: "in_progress", To: "review"},
+		{From: "review", To: "done"},
+		{From: "review", To: "ready"},
+	}
+	if moves := extraMoves(states, verifier, approvals); !slices.Equal(moves, want) {
+		t.Errorf("extraMoves() = %v, want %v", moves, want)
+	}
+}
+
 func TestBuildHeadlessPrompt_WithNotes(t *testing.T) {
 	task := &client.Task{
 		ID:    "task-123",
@@ -878,3 +1025,143 @@ func timeAfter(seconds int) <-chan struct{} {
 	}()
 	return ch
 }
+
+// =============================================================================
+// Control Socket State Tests
+// =============================================================================
+
+func TestInstanceState_SnapshotTracksRunningAndQueued(t *testing.T) {
+	state := newInstanceState("Project: demo", ui.ExecutionModeSync)
+
+	state.taskStarted(&client.Task{ID: "task-1", Title: "First"}, 100)
+	state.taskStarted(&client.Task{ID: "task-2", Title: "Second"}, 200)
+	state.setQueued([]*client.Task{{ID: "task-3", Title: "Third"}})
+
+	status := state.snapshot()
+	if status.Mode != "sync" {
+		t.Errorf("expected mode sync, got %q", status.Mode)
+	}
+	if len(status.Running) != 2 || status.Running[0].ID != "task-1" || status.Running[1].PID != 200 {
+		t.Errorf("unexpected running tasks: %+v", status.Running)
+	}
+	if len(status.Queued) != 1 || status.Queued[0].ID != "task-3" {
+		t.Errorf("unexpected queued tasks: %+v", status.Queued)
+	}
+
+	state.taskFinished("task-1", 2, false)
+	status = state.snapshot()
+	if len(status.Running) != 1 || status.Running[0].ID != "task-2" {
+		t.Errorf("expected only task-2 running, got %+v", status.Running)
+	}
+	if len(status.Finished) != 1 || status.Finished[0].ID != "task-1" || *status.Finished[0].ExitCode != 2 {
+		t.Errorf("expected task-1 finished with exit 2, got %+v", status.Finished)
+	}
+	if status.TasksCompleted != 0 {
+		t.Errorf("expected a failed run not to count as completed, got %d", status.TasksCompleted)
+	}
+
+	state.taskFinished("task-2", 0, true)
+	if status = state.snapshot(); status.TasksCompleted != 1 {
+		t.Errorf("expected 1 completed task, got %d", status.TasksCompleted)
+	}
+}
+
+func TestInstanceState_Paused(t *testing.T) {
+	state := newInstanceState("All projects", ui.ExecutionModeAsync)
+	if state.isPaused() {
+		t.Error("expected state to start unpaused")
+	}
+	state.setPaused(true)
+	if !state.isPaused() || !state.snapshot().Paused {
+		t.Error("expected state to be paused")
+	}
+}
+
+func TestInstanceState_SubscribeReceivesHistoryAndLiveOutput(t *testing.T) {
+	state := newInstanceState("All projects", ui.ExecutionModeAsync)
+	state.taskStarted(&client.Task{ID: "task-1"}, 0)
+	state.appendOutput("task-1", agent.OutputLine{Text: "before"})
+
+	history, ch, ok := state.subscribe("task-1")
+	if !ok {
+		t.Fatal("expected subscription to running task")
+	}
+	if len(history) != 1 || history[0].Text != "before" {
+		t.Errorf("unexpected history: %+v", history)
+	}
+
+	state.appendOutput("task-1", agent.OutputLine{Text: "after"})
+	if line := <-ch; line.Text != "after" {
+		t.Errorf("expected live line 'after', got %q", line.Text)
+	}
+
+	state.taskFinished("task-1", 0, true)
+	if _, open := <-ch; open {
+		t.Error("expected subscriber channel to close when task finishes")
+	}
+	// Unsubscribing after the task finished must not panic
+	state.unsubscribe("task-1", ch)
+}
+
+func TestInstanceState_SubscribeUnknownTask(t *testing.T) {
+	state := newInstanceState("All projects", ui.ExecutionModeAsync)
+	if _, _, ok := state.subscribe("missing"); ok {
+		t.Error("expected subscribe to fail for unknown task")
+	}
+}
+
+func TestInstanceState_HistoryIsBounded(t *testing.T) {
+	state := newInstanceState("All projects", ui.ExecutionModeAsync)
+	state.taskStarted(&client.Task{ID: "task-1"}, 0)
+	for i := 0; i < maxTailHistory+50; i++ {
+		state.appendOutput("task-1", agent.OutputLine{Text: "line"})
+	}
+
+	history, _, _ := state.subscribe("task-1")
+	if len(history) != maxTailHistory {
+		t.Errorf("expected %d lines of history, got %d", maxTailHistory, len(history))
+	}
+}
+
+func TestSelectAgent(t *testing.T) {
+	defer func(old string) { agentName = old }(agentName)
+
+	cfg := &config.Config{Container: agent.ContainerConfig{Image: "momentum-agent"}}
+	newAgent, err := selectAgent(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := newAgent(agent.Config{}).(*agent.ClaudeCode); !ok {
+		t.Error("expected Claude Code by default")
+	}
+
+	cfg.Agent = agent.ContainerAgent
+	newAgent, err = selectAgent(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := newAgent(agent.Config{}).(*agent.Container); !ok {
+		t.Error("expected the config file to select the container backend")
+	}
+	if got := describeAgents(cfg, nil); got != "container momentum-agent; unrestricted" {
+		t.Errorf("unexpected description %q", got)
+	}
+
+	agentName = "claude"
+	if newAgent, err = selectAgent(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := newAgent(agent.Config{}).(*agent.ClaudeCode); !ok {
+		t.Error("expected --agent to override the config file")
+	}
+
+	agentName = "codex"
+	if _, err := selectAgent(cfg); err == nil || !contains(err.Error(), "available: claude, container, fake") {
+		t.Errorf("expected an unknown agent error listing backends, got %v", err)
+	}
+
+	agentName = agent.ContainerAgent
+	if _, err := selectAgent(&config.Config{}); err == nil {
+		t.Error("expected an error for a container backend without an image")
+	}
+}
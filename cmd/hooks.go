@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/config"
+	"github.com/sirsjg/momentum/hooks"
+	"github.com/sirsjg/momentum/ui"
+	"github.com/sirsjg/momentum/verify"
+	"github.com/sirsjg/momentum/workflow"
+)
+
+// loadConfig reads the --config file, or the default config file if present.
+func loadConfig() (*config.Config, error) {
+	if configPath != "" {
+		return config.Load(configPath)
+	}
+	return config.LoadDefault()
+}
+
+// runHook runs the hooks for event. When task is non-nil its metadata is
+// exposed to the hook and output is streamed to the task's agent panel.
+func (env *workerEnv) runHook(ctx context.Context, event hooks.Event, task *client.Task, exitCode *int) error {
+	if !env.hooks.Has(event) {
+		return nil
+	}
+
+	info := hooks.TaskInfo{
+		WorkDir:  GetWorkDir(),
+		ExitCode: exitCode,
+	}
+	var output func(string)
+	if task != nil {
+		// A task whose directory cannot be resolved is stopped before its
+		// hooks could matter, so the workdir setting is good enough
+		if ws, err := env.workspaceFor(task); err == nil {
+			info.WorkDir = ws.Dir
+		}
+		info.ID = task.ID
+		info.Title = task.Title
+		info.ProjectID = task.ProjectID
+		info.EpicID = task.EpicID
+		toPanel := env.taskOutput(task.ID)
+		output = func(text string) {
+			toPanel(fmt.Sprintf("[%s] %s", event, text))
+		}
+	}
+
+	return env.hooks.Run(ctx, event, info, output)
+}
+
+// taskOutput returns a function that appends lines to a task's agent panel
+// and its tail history.
+func (env *workerEnv) taskOutput(taskID string) func(string) {
+	return func(text string) {
+		line := agent.OutputLine{
+			Text:      text,
+			Timestamp: time.Now(),
+		}
+		env.state.appendOutput(taskID, line)
+		env.p.Send(ui.AgentOutputMsg{TaskID: taskID, Line: line})
+	}
+}
+
+// rejectTask moves a task back to planning and records why as a task
+// comment. The comment is only added once the task has moved.
+func rejectTask(wf *workflow.Workflow, taskID string, reason error) error {
+	if _, err := wf.WithReason(firstLine(reason.Error())).ResetToPlanning([]string{taskID}); err != nil {
+		return err
+	}
+	wf.Comment(taskID, hookFailureComment(reason))
+	return nil
+}
+
+// failVerification moves a task that failed verification to status and
+// records the failure as a task comment. parked is the number of failed
+// attempts when the task is being parked rather than queued again, else 0.
+// The comment is only added once the task has moved.
+func failVerification(wf *workflow.Workflow, taskID, status string, parked int, reason error) error {
+	if _, err := wf.WithReason("verification failed: "+firstLine(reason.Error())).MoveTo([]string{taskID}, status); err != nil {
+		return err
+	}
+	comment := verifyFailureComment(reason)
+	if parked > 0 {
+		comment += fmt.Sprintf("\nThe task failed verification %d times in a row, so Momentum moved it to %q instead of queueing it again.\n", parked, status)
+	}
+	wf.Comment(taskID, comment)
+	return nil
+}
+
+// verifyFailedStatus returns where tasks that fail verification go: the
+// configured failure status, or the workflow's reset status.
+func verifyFailedStatus(verifier *verify.Verifier, states *workflow.StateMachine) string {
+	if status := verifier.FailureStatus(); status != "" {
+		return status
+	}
+	return states.Reset()
+}
+
+// recordVerifyFailure counts a verification failure for taskID and returns
+// the status the task moves to: the configured failure status, the
+// workflow's reset status if none is configured, or the stopped status once
+// a task that would be queued again has used up its attempts. parked is the
+// number of attempts in that last case, else 0.
+func (env *workerEnv) recordVerifyFailure(taskID string) (status string, parked int) {
+	status = verifyFailedStatus(env.verifier, env.states)
+	if !env.states.IsPickup(status) {
+		return status, 0
+	}
+	if attempts, exhausted := env.verifier.RecordFailure(taskID); exhausted {
+		return env.states.Stopped(), attempts
+	}
+	return status, 0
+}
+
+// verifyFailureComment formats a verification failure for a Flux task comment.
+func verifyFailureComment(err error) string {
+	var failure *verify.Failure
+	if !errors.As(err, &failure) {
+		return fmt.Sprintf("Momentum could not verify this task: %v", err)
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Momentum did not mark this task done: %s check failed: %s\n", failure.Check, failure.Reason))
+	if failure.Output != "" {
+		b.WriteString("\n```\n")
+		b.WriteString(failure.Output)
+		b.WriteString("\n```\n")
+	}
+	return b.String()
+}
+
+// hookFailureComment formats a hook failure for a Flux task comment.
+func hookFailureComment(err error) string {
+	var hookErr *hooks.Error
+	if !errors.As(err, &hookErr) {
+		return fmt.Sprintf("Momentum moved this task back: %v", err)
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Momentum moved this task back: %v.\n", hookErr))
+	if hookErr.Output != "" {
+		b.WriteString("\n```\n")
+		b.WriteString(hookErr.Output)
+		b.WriteString("\n```\n")
+	}
+	return b.String()
+}
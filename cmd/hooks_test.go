@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/fluxtest"
+	"github.com/sirsjg/momentum/hooks"
+	"github.com/sirsjg/momentum/verify"
+	"github.com/sirsjg/momentum/workflow"
+)
+
+func TestHookFailureComment(t *testing.T) {
+	err := &hooks.Error{
+		Event:    hooks.PostRun,
+		Command:  "make test",
+		ExitCode: 2,
+		Output:   "FAIL: TestThing",
+	}
+
+	comment := hookFailureComment(err)
+	if !strings.Contains(comment, `post-run hook "make test" failed with exit code 2`) {
+		t.Errorf("comment should describe the failing hook:\n%s", comment)
+	}
+	if !strings.Contains(comment, "```\nFAIL: TestThing\n```") {
+		t.Errorf("comment should include the hook output:\n%s", comment)
+	}
+}
+
+func TestHookFailureComment_OtherError(t *testing.T) {
+	comment := hookFailureComment(errors.New("boom"))
+	if comment != "Momentum moved this task back: boom" {
+		t.Errorf("unexpected comment: %q", comment)
+	}
+}
+
+func TestLoadConfig_ExplicitPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "momentum.json")
+	os.WriteFile(path, []byte(`{"hooks": {"post-run": ["make test"]}}`), 0o644)
+
+	old := configPath
+	configPath = path
+	defer func() { configPath = old }()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Hooks.PostRun) != 1 {
+		t.Errorf("expected post-run hook from config, got %v", cfg.Hooks.PostRun)
+	}
+
+	configPath = filepath.Join(t.TempDir(), "missing.json")
+	if _, err := loadConfig(); err == nil {
+		t.Error("expected error when an explicit config file is missing")
+	}
+}
+
+func TestVerifyFailureComment(t *testing.T) {
+	comment := verifyFailureComment(&verify.Failure{
+		Check:  verify.CheckCommand,
+		Reason: `"make test" failed with exit code 1`,
+		Output: "--- FAIL: TestX",
+	})
+	if !strings.Contains(comment, `command check failed: "make test" failed with exit code 1`) {
+		t.Errorf("comment should give the failing check and reason:\n%s", comment)
+	}
+	if !strings.Contains(comment, "```\n--- FAIL: TestX\n```") {
+		t.Errorf("comment should include the check output:\n%s", comment)
+	}
+
+	comment = verifyFailureComment(&verify.Failure{Check: verify.CheckDiff, Reason: "no changes"})
+	if strings.Contains(comment, "```") {
+		t.Errorf("comment without output should have no code block:\n%s", comment)
+	}
+}
+
+func TestVerifyFailureStatus(t *testing.T) {
+	states, err := workflow.NewStateMachine(workflow.States{Pickup: []string{"backlog"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, _ := verify.New(verify.Config{RequireDiff: true, MaxAttempts: 2}, nil)
+	env := &workerEnv{verifier: verifier, states: states}
+
+	if status, parked := env.recordVerifyFailure("task-1"); status != "backlog" || parked != 0 {
+		t.Errorf("expected the reset status by default, got %q %d", status, parked)
+	}
+	if status, parked := env.recordVerifyFailure("task-1"); status != workflow.StatusPlanning || parked != 2 {
+		t.Errorf("expected the task to be parked, got %q %d", status, parked)
+	}
+
+	verifier, _ = verify.New(verify.Config{RequireDiff: true, FailureStatus: "review", MaxAttempts: 1}, nil)
+	env.verifier = verifier
+	if status, parked := env.recordVerifyFailure("task-1"); status != "review" || parked != 0 {
+		t.Errorf("expected tasks not queued again to never be parked, got %q %d", status, parked)
+	}
+}
+
+func TestRejectTask(t *testing.T) {
+	flux := fluxtest.NewServer()
+	defer flux.Close()
+	project := flux.AddProject(client.Project{Name: "demo"})
+	task := flux.AddTask(client.Task{Title: "Add a README", ProjectID: project.ID, Status: "in_progress"})
+	wf := workflow.NewWorkflow(client.NewClient(flux.URL))
+	wf.SetOutput(io.Discard)
+	reason := &hooks.Error{Event: hooks.PreRun, Command: "make lint", ExitCode: 1}
+
+	flux.Fail("PATCH", "/api/tasks/"+task.ID, http.StatusInternalServerError)
+	if err := rejectTask(wf, task.ID, reason); err == nil {
+		t.Error("expected the failed move to be returned")
+	}
+	if comments := flux.Comments(task.ID); len(comments) != 0 {
+		t.Errorf("expected no comment when the task did not move, got %+v", comments)
+	}
+
+	flux.Fail("PATCH", "/api/tasks/"+task.ID, 0)
+	if err := rejectTask(wf, task.ID, reason); err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := flux.Task(task.ID); current.Status != workflow.StatusPlanning {
+		t.Errorf("expected the task moved to planning, got %s", current.Status)
+	}
+	if comments := flux.Comments(task.ID); len(comments) != 1 || !strings.Contains(comments[0].Body, "Momentum moved this task back") {
+		t.Errorf("expected a comment explaining the move, got %+v", comments)
+	}
+}
+
+func TestFailVerification(t *testing.T) {
+	flux := fluxtest.NewServer()
+	defer flux.Close()
+	project := flux.AddProject(client.Project{Name: "demo"})
+	task := flux.AddTask(client.Task{Title: "Add a README", ProjectID: project.ID, Status: "in_progress"})
+	wf := workflow.NewWorkflow(client.NewClient(flux.URL))
+	wf.SetOutput(io.Discard)
+	reason := &verify.Failure{Check: verify.CheckDiff, Reason: "no changes"}
+
+	flux.Fail("PATCH", "/api/tasks/"+task.ID, http.StatusInternalServerError)
+	if err := failVerification(wf, task.ID, "todo", 0, reason); err == nil {
+		t.Error("expected the failed move to be returned")
+	}
+	if comments := flux.Comments(task.ID); len(comments) != 0 {
+		t.Errorf("expected no comment when the task did not move, got %+v", comments)
+	}
+
+	flux.Fail("PATCH", "/api/tasks/"+task.ID, 0)
+	if err := failVerification(wf, task.ID, workflow.StatusPlanning, 3, reason); err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := flux.Task(task.ID); current.Status != workflow.StatusPlanning {
+		t.Errorf("expected the task parked in planning, got %s", current.Status)
+	}
+	if comments := flux.Comments(task.ID); len(comments) != 1 || !strings.Contains(comments[0].Body, "failed verification 3 times in a row") {
+		t.Errorf("expected a comment explaining the parked task, got %+v", comments)
+	}
+}
@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/journal"
+	"github.com/sirsjg/momentum/ui"
+	"github.com/sirsjg/momentum/workflow"
+)
+
+// reattachedAgentName labels panels for agents adopted from an earlier run.
+const reattachedAgentName = "Claude (reattached)"
+
+// openJournal opens the --journal-dir journal, or returns nil if it is disabled.
+func openJournal() (*journal.Journal, error) {
+	if journalDir == "" {
+		return nil, nil
+	}
+	return journal.Open(journalDir)
+}
+
+// journalClaim records that this instance has claimed task. pid is the agent
+// process ID and command the executable it runs, or 0 and "" before the
+// agent has started.
+func (env *workerEnv) journalClaim(task *client.Task, workDir string, pid int, command string, startedAt time.Time) {
+	err := env.journal.Record(journal.Entry{
+		TaskID:    task.ID,
+		Title:     task.Title,
+		ProjectID: task.ProjectID,
+		BaseURL:   GetBaseURL(),
+		OwnerPID:  os.Getpid(),
+		PID:       pid,
+		Command:   command,
+		WorkDir:   workDir,
+		StartedAt: startedAt,
+	})
+	if err != nil {
+		env.reportError(err)
+	}
+}
+
+// journalRelease forgets a claimed task once its final status is settled.
+func (env *workerEnv) journalRelease(taskID string) {
+	if err := env.journal.Remove(taskID); err != nil {
+		env.reportError(err)
+	}
+}
+
+// reconcileAction is what startup reconciliation does with a journal entry.
+type reconcileAction int
+
+const (
+	// reconcileForget drops the entry; the task has moved on since it was claimed
+	reconcileForget reconcileAction = iota
+	// reconcileReattach adopts the still-running agent process
+	reconcileReattach
+	// reconcileReset moves the orphaned task back to the reset status
+	reconcileReset
+)
+
+// classifyEntry decides how to reconcile e. task is the task's current state
+// in Flux, or nil if it no longer exists, and running is the workflow's
+// running status.
+func classifyEntry(e journal.Entry, task *client.Task, running string) reconcileAction {
+	if task == nil || task.Status != running {
+		return reconcileForget
+	}
+	if e.PID > 0 && agent.ProcessAlive(e.PID) && agent.ProcessMatches(e.PID, e.Command) {
+		return reconcileReattach
+	}
+	return reconcileReset
+}
+
+// ownedByLiveInstance reports whether e was claimed by another Momentum
+// process that is still running, which reconciles its own tasks.
+func ownedByLiveInstance(e journal.Entry) bool {
+	if e.OwnerPID == os.Getpid() {
+		return true
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return agent.ProcessAlive(e.OwnerPID)
+	}
+	return agent.ProcessAlive(e.OwnerPID) && agent.ProcessMatches(e.OwnerPID, filepath.Base(self))
+}
+
+// reconcileJournal settles tasks claimed by earlier runs that did not exit
+// cleanly: agents that are still running are reattached, and tasks left
+// in_progress without an agent are moved back to the workflow's reset
+// status with a comment.
+func reconcileJournal(ctx context.Context, env *workerEnv, c *client.Client, wf *workflow.Workflow) {
+	entries, err := env.journal.Entries()
+	if err != nil {
+		env.reportError(err)
+	}
+
+	for _, e := range entries {
+		if e.BaseURL != "" && e.BaseURL != GetBaseURL() {
+			continue
+		}
+		if ownedByLiveInstance(e) {
+			continue
+		}
+
+		task, err := c.FindTask(e.ProjectID, e.TaskID)
+		if err != nil {
+			// Keep the entry and try again on the next start
+			env.reportError(err)
+			continue
+		}
+
+		action := classifyEntry(e, task, env.states.Running())
+		if action != reconcileForget && env.leases.HeldElsewhere(*task) {
+			// Another instance has taken the task over under a lease
+			action = reconcileForget
+		}
+
+		switch action {
+		case reconcileForget:
+			env.journalRelease(e.TaskID)
+		case reconcileReattach:
+			if err := reattachAgent(ctx, env, c, wf, task, e); err != nil {
+				env.reportError(err)
+				resetOrphan(env, wf.WithContext(ctx).WithFrom(*task), e)
+			}
+		case reconcileReset:
+			resetOrphan(env, wf.WithContext(ctx).WithFrom(*task), e)
+		}
+	}
+}
+
+// resetOrphan moves an orphaned task back to the workflow's reset status and
+// explains why.
+func resetOrphan(env *workerEnv, wf *workflow.Workflow, e journal.Entry) {
+	if _, err := wf.WithReason("the Momentum process running it exited unexpectedly").ResetTask([]string{e.TaskID}); err != nil {
+		env.reportError(err)
+		return
+	}
+	wf.Comment(e.TaskID, orphanComment(e, env.states.Reset()))
+	env.journalRelease(e.TaskID)
+}
+
+// orphanComment explains to Flux users why a claimed task moved back to
+// status.
+func orphanComment(e journal.Entry, status string) string {
+	agentInfo := "before its agent started"
+	if e.PID > 0 {
+		agentInfo = fmt.Sprintf("and its agent (PID %d) is no longer running", e.PID)
+	}
+	return fmt.Sprintf("Momentum moved this task back to %s: the Momentum process (PID %d) that started it at %s exited unexpectedly %s. Any partial changes are left in %s.",
+		status, e.OwnerPID, e.StartedAt.Local().Format(time.RFC1123), agentInfo, workDirOrDefault(e.WorkDir))
+}
+
+func workDirOrDefault(dir string) string {
+	if dir == "" {
+		return "the working directory"
+	}
+	return dir
+}
+
+// reattachAgent adopts an agent process started by an earlier run. Its
+// output and exit code are lost, so when it exits the task is left alone if
+// the agent settled it in Flux, and otherwise moved back to the workflow's
+// reset status.
+func reattachAgent(ctx context.Context, env *workerEnv, c *client.Client, wf *workflow.Workflow, task *client.Task, e journal.Entry) error {
+	p, agents, state := env.p, env.agents, env.state
+
+	runner := agent.NewRunner(agent.NewAttached(e.PID, reattachedAgentName))
+	agents.markRunning(task.ID, runner)
+	if err := runner.Run(ctx, ""); err != nil {
+		agents.markDone(task.ID)
+		return fmt.Errorf("failed to reattach to agent for task %s: %w", task.ID, err)
+	}
+
+	// Take over the entry so a later crash of this instance is reconciled too
+	env.journalClaim(task, e.WorkDir, e.PID, e.Command, e.StartedAt)
+	state.taskStarted(task, e.PID)
+
+	p.Send(ui.AddAgentMsg{
+		TaskID:    task.ID,
+		TaskTitle: task.Title,
+		AgentName: reattachedAgentName,
+		Runner:    runner,
+	})
+	env.taskOutput(task.ID)(fmt.Sprintf("Reattached to agent process %d started at %s. Earlier output is not available.",
+		e.PID, e.StartedAt.Local().Format(time.Kitchen)))
+
+	go func() {
+		result := <-runner.Done()
+		stoppedByUser := agents.wasStoppedByUser(task.ID)
+
+		agents.markDone(task.ID)
+		state.taskFinished(task.ID, result.ExitCode, result.ExitCode == 0 && !stoppedByUser)
+		p.Send(ui.AgentCompletedMsg{
+			TaskID: task.ID,
+			Result: result,
+		})
+
+		if agents.isShuttingDown() {
+			// Leave the entry for the next start to reconcile
+			return
+		}
+
+		wf := wf.WithContext(ctx).WithFrom(*task)
+		if stoppedByUser {
+			if _, err := wf.WithReason("stopped by user").ResetToPlanning([]string{task.ID}); err != nil {
+				// Keep the entry so the next start settles the task
+				env.reportError(err)
+				return
+			}
+			env.journalRelease(task.ID)
+			return
+		}
+
+		current, err := c.FindTask(task.ProjectID, task.ID)
+		if err != nil {
+			env.reportError(err)
+			return
+		}
+		if current != nil && current.Status == env.states.Running() {
+			resetOrphan(env, wf.WithFrom(*current), e)
+			return
+		}
+		env.journalRelease(task.ID)
+	}()
+	return nil
+}
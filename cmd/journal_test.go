@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/journal"
+	"github.com/sirsjg/momentum/workflow"
+)
+
+func TestClassifyEntry(t *testing.T) {
+	inProgress := &client.Task{ID: "task-1", Status: "in_progress"}
+
+	tests := []struct {
+		name  string
+		entry journal.Entry
+		task  *client.Task
+		want  reconcileAction
+	}{
+		{"task deleted", journal.Entry{TaskID: "task-1"}, nil, reconcileForget},
+		{"task moved on", journal.Entry{TaskID: "task-1"}, &client.Task{ID: "task-1", Status: "done"}, reconcileForget},
+		{"agent never started", journal.Entry{TaskID: "task-1"}, inProgress, reconcileReset},
+		{"agent gone", journal.Entry{TaskID: "task-1", PID: 999999999, Command: "claude"}, inProgress, reconcileReset},
+	}
+	for _, tt := range tests {
+		if got := classifyEntry(tt.entry, tt.task, workflow.StatusInProgress); got != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestOwnedByLiveInstance(t *testing.T) {
+	if !ownedByLiveInstance(journal.Entry{OwnerPID: os.Getpid()}) {
+		t.Error("entries owned by this process should be left alone")
+	}
+	if ownedByLiveInstance(journal.Entry{OwnerPID: 999999999}) {
+		t.Error("entries owned by a dead process should be reconciled")
+	}
+}
+
+func TestOrphanComment(t *testing.T) {
+	started := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	comment := orphanComment(journal.Entry{OwnerPID: 100, PID: 200, WorkDir: "/src/app", StartedAt: started}, "ready")
+	for _, want := range []string{"back to ready", "PID 100", "agent (PID 200) is no longer running", "/src/app"} {
+		if !contains(comment, want) {
+			t.Errorf("comment should contain %q:\n%s", want, comment)
+		}
+	}
+
+	comment = orphanComment(journal.Entry{OwnerPID: 100, StartedAt: started}, "todo")
+	if !contains(comment, "before its agent started") {
+		t.Errorf("comment should say the agent never started:\n%s", comment)
+	}
+}
+
+// fluxRecorder is a minimal Flux server that serves one project's tasks and
+// records status changes and comments.
+type fluxRecorder struct {
+	mu       sync.Mutex
+	tasks    []client.Task
+	statuses map[string]string
+	comments map[string]string
+}
+
+func newFluxRecorder(tasks ...client.Task) (*fluxRecorder, *httptest.Server) {
+	f := &fluxRecorder{tasks: tasks, statuses: map[string]string{}, comments: map[string]string{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/projects":
+			json.NewEncoder(w).Encode([]client.Project{{ID: "proj-1"}})
+		case r.Method == http.MethodGet && r.URL.Path == "/api/projects/proj-1/tasks":
+			json.NewEncoder(w).Encode(f.tasks)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/comments"):
+			id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/tasks/"), "/comments")
+			f.comments[id] = body["body"]
+			json.NewEncoder(w).Encode(map[string]string{"id": "c-1", "body": body["body"]})
+		case r.Method == http.MethodPatch:
+			id := strings.TrimPrefix(r.URL.Path, "/api/tasks/")
+			f.statuses[id] = body["status"]
+			json.NewEncoder(w).Encode(client.Task{ID: id, Status: body["status"]})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return f, server
+}
+
+func TestResetOrphan(t *testing.T) {
+	flux, server := newFluxRecorder()
+	defer server.Close()
+
+	j, _ := journal.Open(t.TempDir())
+	entry := journal.Entry{TaskID: "task-1", OwnerPID: 100, StartedAt: time.Now()}
+	j.Record(entry)
+
+	wf := workflow.NewWorkflow(client.NewClient(server.URL))
+	wf.SetOutput(io.Discard)
+	resetOrphan(&workerEnv{journal: j}, wf, entry)
+
+	if flux.statuses["task-1"] != "todo" {
+		t.Errorf("expected task moved to todo, got %q", flux.statuses["task-1"])
+	}
+	if !contains(flux.comments["task-1"], "exited unexpectedly") {
+		t.Errorf("expected crash comment, got %q", flux.comments["task-1"])
+	}
+	if entries, _ := j.Entries(); len(entries) != 0 {
+		t.Errorf("expected journal entry removed, got %+v", entries)
+	}
+}
+
+func TestReconcileJournal(t *testing.T) {
+	flux, server := newFluxRecorder(
+		client.Task{ID: "task-orphan", Status: "in_progress", ProjectID: "proj-1"},
+		client.Task{ID: "task-done", Status: "done", ProjectID: "proj-1"},
+		client.Task{ID: "task-mine", Status: "in_progress", ProjectID: "proj-1"},
+	)
+	defer server.Close()
+
+	old := baseURL
+	baseURL = server.URL
+	defer func() { baseURL = old }()
+
+	j, _ := journal.Open(t.TempDir())
+	dead := 999999999
+	j.Record(journal.Entry{TaskID: "task-orphan", ProjectID: "proj-1", BaseURL: server.URL, OwnerPID: dead, PID: dead, Command: "claude"})
+	j.Record(journal.Entry{TaskID: "task-done", ProjectID: "proj-1", BaseURL: server.URL, OwnerPID: dead})
+	j.Record(journal.Entry{TaskID: "task-mine", ProjectID: "proj-1", BaseURL: server.URL, OwnerPID: os.Getpid()})
+	j.Record(journal.Entry{TaskID: "task-elsewhere", BaseURL: "http://other:3000", OwnerPID: dead})
+
+	c := client.NewClient(server.URL)
+	wf := workflow.NewWorkflow(c)
+	wf.SetOutput(io.Discard)
+	reconcileJournal(t.Context(), &workerEnv{journal: j}, c, wf)
+
+	if flux.statuses["task-orphan"] != "todo" {
+		t.Errorf("expected orphan moved to todo, got %q", flux.statuses["task-orphan"])
+	}
+	if _, ok := flux.statuses["task-done"]; ok {
+		t.Error("tasks that moved on should not be touched")
+	}
+	if _, ok := flux.statuses["task-mine"]; ok {
+		t.Error("tasks claimed by a running instance should not be touched")
+	}
+
+	entries, _ := j.Entries()
+	var left []string
+	for _, e := range entries {
+		left = append(left, e.TaskID)
+	}
+	if strings.Join(left, ",") != "task-mine,task-elsewhere" && strings.Join(left, ",") != "task-elsewhere,task-mine" {
+		t.Errorf("expected only other instances' entries to remain, got %v", left)
+	}
+}
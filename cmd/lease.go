@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/lease"
+	"github.com/sirsjg/momentum/workflow"
+)
+
+// claimTask moves a selected task to the running status. With leases enabled the
+// claim is made under a lease, and an error wrapping lease.ErrHeld means
+// another instance has the task.
+func (env *workerEnv) claimTask(ctx context.Context, c *client.Client, wf *workflow.Workflow, task *client.Task) (*client.Task, error) {
+	if !env.leases.Enabled() {
+		if _, err := wf.WithContext(ctx).WithFrom(*task).WithReason("picked up").StartWorking([]string{task.ID}); err != nil {
+			return task, err
+		}
+		claimed := *task
+		claimed.Status = env.states.Running()
+		return &claimed, nil
+	}
+
+	claimed, err := env.leases.Claim(ctx, c, task)
+	if err != nil {
+		return nil, err
+	}
+
+	// Leave a trail when taking over a task abandoned by another instance
+	reason := "picked up under a lease"
+	if previous := lease.Parse(task.Notes); previous != nil && task.Status == env.states.Running() && previous.Owner != env.leases.Owner() {
+		wf.WithContext(ctx).Comment(task.ID, takeoverComment(env.leases.Owner(), previous))
+		reason = "took over the expired lease of " + previous.Owner
+	}
+	// The lease claim moves the task itself, so report the move for the audit log
+	wf.WithReason(reason).Record(workflow.Event{TaskID: task.ID, Title: task.Title, From: task.Status, To: claimed.Status})
+
+	// The lease marker is bookkeeping, not part of the task description
+	claimed.Notes = lease.Strip(claimed.Notes)
+	return claimed, nil
+}
+
+// takeoverComment explains to Flux users why another instance picked up a
+// task that was already in progress.
+func takeoverComment(owner string, previous *lease.Lease) string {
+	return fmt.Sprintf("Momentum (%s) took over this task: the lease held by %s expired at %s without being renewed.",
+		owner, previous.Owner, previous.Expires.Local().Format("2006-01-02 15:04:05 MST"))
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/lease"
+	"github.com/sirsjg/momentum/workflow"
+)
+
+func TestTakeoverComment(t *testing.T) {
+	comment := takeoverComment("host-a:1", &lease.Lease{Owner: "host-b:2", Expires: time.Now()})
+	for _, want := range []string{"Momentum (host-a:1) took over", "lease held by host-b:2 expired"} {
+		if !contains(comment, want) {
+			t.Errorf("comment should contain %q:\n%s", want, comment)
+		}
+	}
+}
+
+func TestClaimTask_WithoutLeases(t *testing.T) {
+	var status string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var update client.TaskUpdate
+		json.NewDecoder(r.Body).Decode(&update)
+		if update.Status != nil {
+			status = *update.Status
+		}
+		if update.Notes != nil {
+			t.Errorf("notes should not be touched without leases, got %q", *update.Notes)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(client.Task{ID: "task-1", Status: status})
+	}))
+	defer server.Close()
+
+	c := client.NewClient(server.URL)
+	wf := workflow.NewWorkflow(c)
+	wf.SetOutput(io.Discard)
+
+	task := &client.Task{ID: "task-1", Notes: "Details"}
+	claimed, err := (&workerEnv{}).claimTask(context.Background(), c, wf, task)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status != "in_progress" {
+		t.Errorf("expected the task moved to in_progress, got status %q", status)
+	}
+	if claimed.Status != "in_progress" || claimed.Notes != "Details" {
+		t.Errorf("expected the task as claimed, got %+v", claimed)
+	}
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/metrics"
+	"github.com/sirsjg/momentum/sse"
+)
+
+// agentDurationBuckets are histogram buckets for agent run time, in seconds.
+// Agents typically run for minutes, so the default sub-second buckets are useless.
+var agentDurationBuckets = []float64{10, 30, 60, 120, 300, 600, 1200, 1800, 3600, 7200}
+
+// workerMetrics holds the metrics recorded by the worker.
+type workerMetrics struct {
+	registry *metrics.Registry
+
+	tasksSelected  *metrics.Counter
+	tasksStarted   *metrics.Counter
+	tasksCompleted *metrics.Counter
+	tasksFailed    *metrics.Counter
+	tasksStopped   *metrics.Counter
+	agentDuration  *metrics.Histogram
+	agentExitCodes *metrics.Counter
+	fluxLatency    *metrics.Histogram
+	fluxErrors     *metrics.Counter
+}
+
+func newWorkerMetrics(state *instanceState, agents *runningAgents) *workerMetrics {
+	reg := metrics.NewRegistry()
+	m := &workerMetrics{
+		registry:       reg,
+		tasksSelected:  reg.NewCounter("momentum_tasks_selected_total", "Tasks returned by the selector."),
+		tasksStarted:   reg.NewCounter("momentum_tasks_started_total", "Tasks an agent was started for."),
+		tasksCompleted: reg.NewCounter("momentum_tasks_completed_total", "Tasks whose agent exited successfully."),
+		tasksFailed:    reg.NewCounter("momentum_tasks_failed_total", "Tasks whose agent exited with an error."),
+		tasksStopped:   reg.NewCounter("momentum_tasks_stopped_total", "Tasks whose agent was stopped by the user."),
+		agentDuration: reg.NewHistogram("momentum_agent_duration_seconds", "Agent run time by outcome.",
+			agentDurationBuckets, "outcome"),
+		agentExitCodes: reg.NewCounter("momentum_agent_exit_codes_total", "Agent exits by exit code.", "code"),
+		fluxLatency: reg.NewHistogram("momentum_flux_request_duration_seconds", "Flux API request latency.",
+			nil, "method"),
+		fluxErrors: reg.NewCounter("momentum_flux_request_errors_total", "Failed Flux API requests.", "method"),
+	}
+
+	reg.NewGaugeFunc("momentum_queue_depth", "Tasks queued waiting for an agent.", func() float64 {
+		return float64(state.queuedCount())
+	})
+	reg.NewGaugeFunc("momentum_running_agents", "Agents currently running.", func() float64 {
+		return float64(agents.count())
+	})
+
+	return m
+}
+
+// observeClient records latency and errors for every Flux API request.
+func (m *workerMetrics) observeClient(c *client.Client) {
+	c.AddRequestHook(func(info client.RequestInfo) {
+		m.fluxLatency.Observe(info.Duration.Seconds(), info.Method)
+		if info.Err != nil {
+			m.fluxErrors.Inc(info.Method)
+		}
+	})
+}
+
+// observeSubscriber exposes the SSE connection health.
+func (m *workerMetrics) observeSubscriber(s *sse.Subscriber) {
+	m.registry.NewCounterFunc("momentum_sse_reconnects_total", "SSE connections that failed or were closed.", func() float64 {
+		return float64(s.Stats().Reconnects)
+	})
+	m.registry.NewGaugeFunc("momentum_sse_connected", "1 while the SSE stream is open.", func() float64 {
+		return boolToFloat(s.Stats().Connected)
+	})
+	m.registry.NewGaugeFunc("momentum_sse_polling", "1 while SSE has fallen back to polling.", func() float64 {
+		return boolToFloat(s.Stats().Polling)
+	})
+}
+
+// observeResult records the outcome of an agent run.
+func (m *workerMetrics) observeResult(result agent.Result, stoppedByUser bool) {
+	outcome := "completed"
+	switch {
+	case stoppedByUser:
+		outcome = "stopped"
+		m.tasksStopped.Inc()
+	case result.ExitCode == 0:
+		m.tasksCompleted.Inc()
+	default:
+		outcome = "failed"
+		m.tasksFailed.Inc()
+	}
+	m.agentDuration.Observe(result.Duration.Seconds(), outcome)
+	m.agentExitCodes.Inc(strconv.Itoa(result.ExitCode))
+}
+
+// serveMetrics serves /metrics on addr until ctx is cancelled.
+func serveMetrics(ctx context.Context, addr string, env *workerEnv) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", env.metrics.registry.Handler())
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		env.reportError(fmt.Errorf("metrics server: %w", err))
+	}
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/ui"
+)
+
+func renderMetrics(t *testing.T, m *workerMetrics) string {
+	t.Helper()
+	var b strings.Builder
+	if err := m.registry.WriteText(&b); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	return b.String()
+}
+
+func TestWorkerMetrics_ObserveResult(t *testing.T) {
+	m := newWorkerMetrics(newInstanceState("All projects", ui.ExecutionModeAsync), newRunningAgents())
+
+	m.observeResult(agent.Result{ExitCode: 0, Duration: 45 * time.Second}, false)
+	m.observeResult(agent.Result{ExitCode: 2, Duration: time.Minute}, false)
+	m.observeResult(agent.Result{ExitCode: 130, Duration: time.Second}, true)
+
+	if m.tasksCompleted.Value() != 1 || m.tasksFailed.Value() != 1 || m.tasksStopped.Value() != 1 {
+		t.Errorf("unexpected outcome counts: completed=%v failed=%v stopped=%v",
+			m.tasksCompleted.Value(), m.tasksFailed.Value(), m.tasksStopped.Value())
+	}
+	if m.agentExitCodes.Value("2") != 1 {
+		t.Error("expected exit code 2 to be counted")
+	}
+	if m.agentDuration.Count("completed") != 1 {
+		t.Error("expected one completed duration observation")
+	}
+
+	out := renderMetrics(t, m)
+	if !strings.Contains(out, `momentum_agent_duration_seconds_bucket{outcome="completed",le="60"} 1`) {
+		t.Errorf("expected completed duration in 60s bucket:\n%s", out)
+	}
+}
+
+func TestWorkerMetrics_QueueAndRunningGauges(t *testing.T) {
+	state := newInstanceState("All projects", ui.ExecutionModeSync)
+	agents := newRunningAgents()
+	m := newWorkerMetrics(state, agents)
+
+	state.setQueued([]*client.Task{{ID: "task-1"}, {ID: "task-2"}})
+	agents.markRunning("task-3", nil)
+
+	out := renderMetrics(t, m)
+	if !strings.Contains(out, "momentum_queue_depth 2\n") {
+		t.Errorf("expected queue depth 2:\n%s", out)
+	}
+	if !strings.Contains(out, "momentum_running_agents 1\n") {
+		t.Errorf("expected 1 running agent:\n%s", out)
+	}
+}
+
+func TestWorkerMetrics_ObserveClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	m := newWorkerMetrics(newInstanceState("All projects", ui.ExecutionModeAsync), newRunningAgents())
+	c := client.NewClient(server.URL)
+	m.observeClient(c)
+
+	c.ListProjects()
+	c.MoveTaskStatus("task-1", "done")
+
+	if m.fluxLatency.Count("GET") != 1 || m.fluxLatency.Count("PATCH") != 1 {
+		t.Error("expected one latency observation per method")
+	}
+	if m.fluxErrors.Value("PATCH") != 1 || m.fluxErrors.Value("GET") != 0 {
+		t.Error("expected only the PATCH request to count as an error")
+	}
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/notify"
+	"github.com/sirsjg/momentum/ui"
+)
+
+// fluxConnectionGrace is how long the Flux event stream may be down before
+// the connection counts as lost, so a Flux restart does not page anyone.
+const fluxConnectionGrace = 30 * time.Second
+
+// notifySummaries label each task event's one-line summary.
+var notifySummaries = map[notify.Event]string{
+	notify.AgentStarted:    "Agent started",
+	notify.AgentFailed:     "Agent failed",
+	notify.AgentStopped:    "Agent stopped",
+	notify.TaskCompleted:   "Task completed",
+	notify.ReviewRequested: "Review requested",
+}
+
+// openNotifier starts the notifier for cfg, or returns nil if no
+// notifications are configured. Failed deliveries are shown in the TUI.
+func openNotifier(cfg notify.Config, p messenger) (*notify.Notifier, error) {
+	return notify.New(cfg, func(err error) {
+		p.Send(ui.ListenerErrorMsg{Err: err})
+	})
+}
+
+// notifyTask sends a notification about task. exitCode is the agent's exit
+// code, for events after it ran.
+func (env *workerEnv) notifyTask(event notify.Event, task *client.Task, message string, exitCode *int) {
+	env.notifier.Notify(notify.Notification{
+		Event:     event,
+		Summary:   fmt.Sprintf("%s: %s", notifySummaries[event], task.Title),
+		Message:   message,
+		TaskID:    task.ID,
+		TaskTitle: task.Title,
+		ProjectID: task.ProjectID,
+		EpicID:    task.EpicID,
+		ExitCode:  exitCode,
+		Instance:  env.owner(),
+	})
+}
+
+// watchConnection reports when connected has been false for longer than
+// grace, and again when the connection is back, checking every interval
+// until ctx is done.
+func watchConnection(ctx context.Context, connected func() bool, grace, interval time.Duration, report func(event notify.Event, down time.Duration)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var downSince time.Time
+	lost := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		now := time.Now()
+		switch {
+		case connected():
+			if lost {
+				report(notify.FluxReconnected, now.Sub(downSince))
+			}
+			downSince, lost = time.Time{}, false
+		case downSince.IsZero():
+			downSince = now
+		case !lost && now.Sub(downSince) >= grace:
+			lost = true
+			report(notify.FluxDisconnected, now.Sub(downSince))
+		}
+	}
+}
+
+// notifyConnection sends a notification about the Flux connection.
+func (env *workerEnv) notifyConnection(event notify.Event, down time.Duration) {
+	n := notify.Notification{Event: event, Instance: env.owner()}
+	down = down.Round(time.Second)
+	if event == notify.FluxDisconnected {
+		n.Summary = "Lost connection to Flux"
+		n.Message = fmt.Sprintf("no event stream from %s for %s", GetBaseURL(), down)
+	} else {
+		n.Summary = "Reconnected to Flux"
+		n.Message = fmt.Sprintf("the event stream from %s is back after %s", GetBaseURL(), down)
+	}
+	env.notifier.Notify(n)
+}
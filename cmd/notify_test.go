@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirsjg/momentum/notify"
+)
+
+func TestWatchConnection(t *testing.T) {
+	var connected atomic.Bool
+	var mu sync.Mutex
+	var events []notify.Event
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watchConnection(ctx, connected.Load, 50*time.Millisecond, 5*time.Millisecond, func(e notify.Event, _ time.Duration) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, e)
+		})
+	}()
+	reported := func() []notify.Event {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(events)
+	}
+
+	// Down at start, then lost once the grace period is over, and only once
+	waitUntil(t, "the lost connection", func() bool { return len(reported()) > 0 })
+	time.Sleep(30 * time.Millisecond)
+	connected.Store(true)
+	waitUntil(t, "the reconnection", func() bool { return len(reported()) > 1 })
+
+	// A short blip is not reported
+	connected.Store(false)
+	time.Sleep(20 * time.Millisecond)
+	connected.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	cancel()
+	<-done
+
+	if got := reported(); !slices.Equal(got, []notify.Event{notify.FluxDisconnected, notify.FluxReconnected}) {
+		t.Errorf("unexpected events %v", got)
+	}
+}
@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/control"
+	"github.com/sirsjg/momentum/pool"
+	"github.com/sirsjg/momentum/ui"
+	"github.com/sirsjg/momentum/workspace"
+	"github.com/spf13/cobra"
+)
+
+// shutdownGrace is how long `momentum serve` waits on exit for runners to
+// stop their agents and report back.
+const shutdownGrace = 10 * time.Second
+
+var (
+	coordinatorURL string
+	poolToken      string
+	poolListen     string
+	runnerName     string
+	runnerCapacity int
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Coordinate remote runners that execute agents for the Flux queue",
+	Long: `Run as a coordinator: watch Flux and claim tasks like the TUI does, but
+hand each agent run to a remote runner started with "momentum runner".
+
+Runners and "momentum attach" connect over HTTP and authenticate with a
+shared token (--token or $MOMENTUM_POOL_TOKEN), which is required unless
+the coordinator only listens on loopback.
+
+Examples:
+  MOMENTUM_POOL_TOKEN=secret momentum serve --listen :7420 --project myproject
+  MOMENTUM_POOL_TOKEN=secret momentum runner --coordinator http://build-box:7420`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runServe(cmd.OutOrStdout())
+	},
+}
+
+var runnerCmd = &cobra.Command{
+	Use:   "runner",
+	Short: "Run agents handed out by a coordinator",
+	Long: `Connect to a coordinator started with "momentum serve", run the agents
+it assigns in --workdir and stream their output back.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRunner(cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&coordinatorURL, "coordinator", "", "Coordinator URL for runner/attach/status/stop/pause/resume/tail (e.g. http://build-box:7420)")
+	rootCmd.PersistentFlags().StringVar(&poolToken, "token", "", "Shared coordinator token (default $"+pool.TokenEnv+")")
+
+	addWorkerFlags(serveCmd)
+	serveCmd.Flags().StringVar(&poolListen, "listen", pool.DefaultListenAddr, "Address to serve runners and attached clients on")
+
+	runnerCmd.Flags().StringVar(&runnerName, "name", "", "Runner name shown by the coordinator (default: hostname)")
+	runnerCmd.Flags().IntVar(&runnerCapacity, "capacity", 1, "Number of agents to run at once")
+	runnerCmd.Flags().StringVar(&workDir, "workdir", "", "Working directory for agents (inherits CLAUDE.md)")
+	runnerCmd.Flags().StringVar(&profileName, "profile", "", "Sandbox profile from the config file to run agents under")
+	runnerCmd.Flags().StringVar(&agentName, "agent", "", "Agent backend: claude, container or fake (default: config file, then claude)")
+
+	rootCmd.AddCommand(serveCmd, runnerCmd)
+}
+
+// poolTokenValue returns the --token flag, falling back to the environment.
+func poolTokenValue() string {
+	if poolToken != "" {
+		return poolToken
+	}
+	return os.Getenv(pool.TokenEnv)
+}
+
+// runServe runs the worker loop as a coordinator until interrupted.
+func runServe(out io.Writer) error {
+	log.SetOutput(io.Discard)
+	InitWorkDir()
+
+	mode, err := parseExecutionMode(executionMode)
+	if err != nil {
+		return err
+	}
+	token := poolTokenValue()
+	if token == "" && !pool.IsLoopback(poolListen) {
+		return fmt.Errorf("a pool token (--token or $%s) is required to listen on %s", pool.TokenEnv, poolListen)
+	}
+
+	criteria := buildCriteriaString()
+	logger := newLogMessenger(out)
+	env, closeEnv, err := newWorkerEnv(criteria, mode, logger)
+	if err != nil {
+		return err
+	}
+	defer closeEnv()
+
+	coord := pool.NewCoordinator()
+	env.pool = coord
+	env.newAgent = coord.NewAgent
+	// Sessions live on the runners' machines, so there is nothing to resume here
+	env.sessions = nil
+	// Agents run under each runner's own --agent and --profile
+	env.state.sandbox = "chosen by each runner"
+	// Reviews, verification, git and hooks need the agents' changes, which
+	// live on the runners' machines
+	if err := checkServeConfig(env); err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", poolListen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", poolListen, err)
+	}
+
+	// Runners use /pool/; attached clients use the control API
+	backend := newControlBackend(env.state, env.agents, logger)
+	backend.pool = coord
+	mux := http.NewServeMux()
+	mux.Handle("/pool/", coord.Handler())
+	mux.Handle("/", control.NewServer("", backend).Handler())
+	server := &http.Server{Handler: pool.Authorize(token, mux)}
+	go server.Serve(ln)
+	defer server.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// The coordinator outlives the worker so runners can report agents
+	// stopped during shutdown
+	coordCtx, stopCoord := context.WithCancel(context.Background())
+	defer stopCoord()
+	go coord.Run(coordCtx)
+
+	if metricsAddr != "" {
+		go serveMetrics(ctx, metricsAddr, env)
+	}
+
+	fmt.Fprintf(out, "Coordinating %s on http://%s\n", criteria, ln.Addr())
+	go runWorker(ctx, env, mode, nil, nil, nil, nil, nil, nil)
+
+	<-ctx.Done()
+	fmt.Fprintln(out, "Shutting down, stopping remote agents")
+	env.agents.cancelAll()
+	deadline := time.Now().Add(shutdownGrace)
+	for env.agents.hasRunning() && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+	return nil
+}
+
+// checkServeConfig rejects the config sections that act on a task's working
+// tree, which under serve is on a runner rather than the coordinator.
+func checkServeConfig(env *workerEnv) error {
+	sections := []struct {
+		name    string
+		what    string
+		enabled bool
+	}{
+		{"approval", "approval gates are", env.approval.Enabled()},
+		{"verify", "verification is", env.verifier.Enabled()},
+		{"git", "the git integration is", env.git.Enabled()},
+		{"hooks", "lifecycle hooks are", env.hooks.Enabled()},
+	}
+	for _, s := range sections {
+		if s.enabled {
+			return fmt.Errorf("%s not supported by momentum serve; remove the %s section from the config", s.what, s.name)
+		}
+	}
+	return nil
+}
+
+// runRunner executes agents for a coordinator until interrupted.
+func runRunner(out io.Writer) error {
+	if coordinatorURL == "" {
+		return errors.New("--coordinator is required")
+	}
+	InitWorkDir()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	profile, err := cfg.Sandbox.Select(profileName)
+	if err != nil {
+		return err
+	}
+	newAgent, err := selectAgent(cfg)
+	if err != nil {
+		return err
+	}
+	// Assignments naming a repository run in a checkout in the runner's cache
+	workspaces, err := workspace.New(cfg.Workdirs, nil)
+	if err != nil {
+		return fmt.Errorf("invalid workdirs config: %w", err)
+	}
+
+	logger := newLogMessenger(out)
+	logger.printf("Agents run in %s (%s)", GetWorkDir(), describeAgents(cfg, profile))
+	worker, err := pool.NewWorker(pool.WorkerConfig{
+		Coordinator: coordinatorURL,
+		Token:       poolTokenValue(),
+		Name:        runnerName,
+		Capacity:    runnerCapacity,
+		WorkDir:     GetWorkDir(),
+		Checkout: func(ctx context.Context, repository string) (string, error) {
+			dir, err := workspaces.Checkout(ctx, repository)
+			if errors.Is(err, workspace.ErrNotUpdated) {
+				logger.printf("%v", err)
+				return dir, nil
+			}
+			return dir, err
+		},
+		Agent: newAgent(agent.Config{WorkDir: GetWorkDir()}).Name(),
+		NewAgent: func(cfg agent.Config) agent.Agent {
+			cfg.Sandbox = profile
+			return newAgent(cfg)
+		},
+		Logf: logger.printf,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return worker.Run(ctx)
+}
+
+// logMessenger prints worker events as timestamped lines, standing in for
+// the TUI under `momentum serve`.
+type logMessenger struct {
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+}
+
+func newLogMessenger(w io.Writer) *logMessenger {
+	return &logMessenger{w: w, now: time.Now}
+}
+
+// Send logs the messages worth a line; agent output is left to attached clients.
+func (l *logMessenger) Send(msg tea.Msg) {
+	switch msg := msg.(type) {
+	case ui.ListenerConnectedMsg:
+		l.printf("Watching Flux at %s", GetBaseURL())
+	case ui.ListenerErrorMsg:
+		l.printf("Error: %v", msg.Err)
+	case ui.AddAgentMsg:
+		l.printf("Started %s: %s", msg.TaskID, msg.TaskTitle)
+	case ui.AgentCompletedMsg:
+		if msg.Result.Error != nil {
+			l.printf("Finished %s: exit %d (%v)", msg.TaskID, msg.Result.ExitCode, msg.Result.Error)
+		} else {
+			l.printf("Finished %s: exit %d", msg.TaskID, msg.Result.ExitCode)
+		}
+	case ui.AgentStoppingMsg:
+		l.printf("Stopping %s", msg.TaskID)
+	case ui.PausedMsg:
+		if msg.Paused {
+			l.printf("Paused")
+		} else {
+			l.printf("Resumed")
+		}
+	}
+}
+
+func (l *logMessenger) printf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintf(l.w, "%s %s\n", l.now().Format("15:04:05"), fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/control"
+	"github.com/sirsjg/momentum/hooks"
+	"github.com/sirsjg/momentum/pool"
+	"github.com/sirsjg/momentum/ui"
+	"github.com/sirsjg/momentum/vcs"
+	"github.com/sirsjg/momentum/verify"
+)
+
+func TestLogMessenger(t *testing.T) {
+	var buf bytes.Buffer
+	l := newLogMessenger(&buf)
+	l.now = func() time.Time { return time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC) }
+
+	l.Send(ui.AddAgentMsg{TaskID: "task-1", TaskTitle: "Fix bug"})
+	l.Send(ui.AgentOutputMsg{TaskID: "task-1", Line: agent.OutputLine{Text: "noise"}})
+	l.Send(ui.AgentCompletedMsg{TaskID: "task-1", Result: agent.Result{ExitCode: -1, Error: pool.ErrRunnerLost}})
+	l.Send(ui.ListenerErrorMsg{Err: errors.New("flux down")})
+	l.Send(ui.PausedMsg{Paused: true})
+
+	want := "15:04:05 Started task-1: Fix bug\n" +
+		"15:04:05 Finished task-1: exit -1 (remote runner stopped responding)\n" +
+		"15:04:05 Error: flux down\n" +
+		"15:04:05 Paused\n"
+	if buf.String() != want {
+		t.Errorf("unexpected log:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestPrintStatus_Runners(t *testing.T) {
+	var buf bytes.Buffer
+	printStatus(&buf, &control.Status{
+		Mode: "async",
+		Runners: []pool.RunnerStatus{
+			{ID: "abc123", Name: "build-box", Capacity: 2, Active: 1, LastSeen: time.Now()},
+		},
+	})
+	out := buf.String()
+	if !contains(out, "Runners (1):") || !contains(out, "abc123  build-box  1/2 busy") {
+		t.Errorf("expected runner listing, got:\n%s", out)
+	}
+
+	buf.Reset()
+	printStatus(&buf, &control.Status{Mode: "async"})
+	if contains(buf.String(), "Runners") {
+		t.Errorf("expected no runner section without runners, got:\n%s", buf.String())
+	}
+}
+
+func TestCheckServeConfig(t *testing.T) {
+	noHooks, err := hooks.NewRunner(hooks.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkServeConfig(&workerEnv{hooks: noHooks}); err != nil {
+		t.Fatalf("expected a plain config to be accepted, got %v", err)
+	}
+
+	hookRunner, err := hooks.NewRunner(hooks.Config{PostRun: []string{"make test"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gitManager, err := vcs.New(vcs.Config{Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := verify.New(verify.Config{Commands: []string{"make test"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for section, env := range map[string]*workerEnv{
+		"hooks":  {hooks: hookRunner},
+		"git":    {git: gitManager},
+		"verify": {verifier: verifier},
+	} {
+		if err := checkServeConfig(env); err == nil || !contains(err.Error(), "remove the "+section+" section") {
+			t.Errorf("expected the %s section to be rejected, got %v", section, err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/selection"
+	"github.com/sirsjg/momentum/ui"
+)
+
+// previewPrompt composes what the agent for taskID is given, or for the
+// task that would be picked up next when taskID is "", and sends it to the
+// TUI.
+func previewPrompt(ctx context.Context, env *workerEnv, c *client.Client, selector *selection.Selector, taskID string) {
+	preview, err := composePreview(ctx, env, c, selector, taskID)
+	env.p.Send(ui.PromptPreviewMsg{Preview: preview, Err: err})
+}
+
+func composePreview(ctx context.Context, env *workerEnv, c *client.Client, selector *selection.Selector, taskID string) (*ui.PromptPreview, error) {
+	var task *client.Task
+	var err error
+	if taskID != "" {
+		task, err = c.WithContext(ctx).FindTask(projectID, taskID)
+		if err == nil && task == nil {
+			err = fmt.Errorf("task %s no longer exists", taskID)
+		}
+	} else {
+		task, err = selector.WithContext(ctx).SelectTask()
+		if errors.Is(err, selection.ErrNoTaskAvailable) {
+			err = errors.New("no task is ready to be picked up")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	ws, err := env.workspaceFor(task)
+	if err != nil {
+		return nil, err
+	}
+
+	// Exactly what the worker loop would start: a recorded session is
+	// resumed, and a reviewer's feedback is passed on without using it up
+	resume := env.resumableSession(task.ID, ws.Dir)
+	cfg, prompt := env.agentInput(task, ws, resume, env.reviews.peekFeedback(task.ID))
+	preview := &ui.PromptPreview{TaskID: task.ID, TaskTitle: task.Title, WorkDir: ws.Dir}
+	title := "Task prompt"
+	if resume != nil {
+		title = "Task prompt (resuming session " + resume.SessionID + ")"
+	}
+	preview.Sections = append(preview.Sections, ui.PromptSection{
+		Title:   title,
+		Content: prompt,
+	})
+	preview.Sections = append(preview.Sections, ui.MemorySections(ws.Dir)...)
+	preview.Sections = append(preview.Sections, ui.PromptSection{
+		Title:    "Agent settings",
+		Content:  describeAgentConfig(env.createAgent(cfg), cfg, env.state.sandbox),
+		Settings: true,
+	})
+	return preview, nil
+}
+
+// describeAgentConfig lists how ag is started, one setting per line.
+// Agents without a local command, such as remote ones, are only named.
+func describeAgentConfig(ag agent.Agent, cfg agent.Config, sandbox string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Agent:      %s\n", ag.Name())
+	if p, ok := ag.(agent.Previewable); ok {
+		fmt.Fprintf(&b, "Command:    %s\n", strings.Join(p.CommandLine("<task prompt>"), " "))
+	}
+	fmt.Fprintf(&b, "Workdir:    %s\n", cfg.WorkDir)
+	if cfg.Repository != "" {
+		fmt.Fprintf(&b, "Repository: %s\n", cfg.Repository)
+	}
+	timeout := "none"
+	if cfg.Timeout > 0 {
+		timeout = cfg.Timeout.String()
+	}
+	fmt.Fprintf(&b, "Timeout:    %s\n", timeout)
+	if sandbox != "" {
+		fmt.Fprintf(&b, "Sandbox:    %s\n", sandbox)
+	}
+	return b.String()
+}
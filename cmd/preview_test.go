@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/fluxtest"
+	"github.com/sirsjg/momentum/selection"
+	"github.com/sirsjg/momentum/session"
+	"github.com/sirsjg/momentum/ui"
+)
+
+func TestComposePreview(t *testing.T) {
+	flux := fluxtest.NewServer()
+	defer flux.Close()
+	project := flux.AddProject(client.Project{Name: "demo"})
+	epic := flux.AddEpic(client.Epic{Title: "auto", ProjectID: project.ID, Auto: true})
+	task := flux.AddTask(client.Task{Title: "Add a README", ProjectID: project.ID, EpicID: epic.ID})
+
+	oldWork, oldProject, oldTimeout := workDir, projectID, agentTimeout
+	defer func() { workDir, projectID, agentTimeout = oldWork, oldProject, oldTimeout }()
+	workDir, projectID, agentTimeout = t.TempDir(), project.ID, 30*time.Minute
+	if err := os.WriteFile(filepath.Join(workDir, "CLAUDE.md"), []byte("Run make test.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := client.NewClient(flux.URL)
+	selector := selection.NewSelector(c, project.ID, "", "")
+	env := &workerEnv{state: newInstanceState("Project: demo", ui.ExecutionModeAsync), reviews: newReviewBoard()}
+	ctx := context.Background()
+
+	// Without a panel, the next task is previewed
+	preview, err := composePreview(ctx, env, c, selector, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.TaskID != task.ID || preview.WorkDir != workDir {
+		t.Errorf("unexpected preview of %s in %s", preview.TaskID, preview.WorkDir)
+	}
+	sections := map[string]ui.PromptSection{}
+	for _, s := range preview.Sections {
+		sections[s.Title] = s
+	}
+	if s := sections["Task prompt"]; s.Settings || !contains(s.Content, "Add a README") {
+		t.Errorf("expected the rendered task prompt, got %+v", s)
+	}
+	if s := sections["Agent settings"]; !s.Settings || !contains(s.Content, "Claude Code") || !contains(s.Content, "claude -p") ||
+		!contains(s.Content, "--dangerously-skip-permissions") || !contains(s.Content, "30m0s") {
+		t.Errorf("expected the agent's settings, got %+v", s)
+	}
+	found := false
+	for _, s := range preview.Sections {
+		found = found || s.Content == "Run make test.\n"
+	}
+	if !found {
+		t.Errorf("expected the workdir's CLAUDE.md in %+v", preview.Sections)
+	}
+
+	// A recorded session is resumed with the reviewer's feedback, which is
+	// left for the run itself
+	store, err := session.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.sessions = store
+	if err := store.Save(session.Record{TaskID: task.ID, SessionID: "sess-1", BaseURL: GetBaseURL(), WorkDir: workDir}); err != nil {
+		t.Fatal(err)
+	}
+	env.reviews.setFeedback(task.ID, "Add a licence section too.")
+	env.newAgent = func(cfg agent.Config) agent.Agent { return agent.NewFake(cfg, agent.FakeConfig{}) }
+	preview, err = composePreview(ctx, env, c, selector, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prompt, settings := preview.Sections[0], preview.Sections[len(preview.Sections)-1]
+	if !contains(prompt.Title, "sess-1") || !contains(prompt.Content, "resuming your earlier session") || !contains(prompt.Content, "Add a licence section too.") {
+		t.Errorf("expected the resume prompt with the reviewer's feedback, got %+v", prompt)
+	}
+	if !contains(settings.Content, "Fake") || contains(settings.Content, "Command:") {
+		t.Errorf("expected the fake backend without a command, got %q", settings.Content)
+	}
+	if env.reviews.takeFeedback(task.ID) == "" {
+		t.Error("expected the preview to leave the feedback for the next run")
+	}
+
+	if _, err := composePreview(ctx, env, c, selector, "missing"); err == nil || !contains(err.Error(), "no longer exists") {
+		t.Errorf("expected an error for a missing task, got %v", err)
+	}
+	if _, err := c.MoveTaskStatus(task.ID, "done"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := composePreview(ctx, env, c, selector, ""); err == nil || !contains(err.Error(), "no task is ready") {
+		t.Errorf("expected an error without a task to pick up, got %v", err)
+	}
+}
@@ -5,8 +5,13 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/sirsjg/momentum/audit"
+	"github.com/sirsjg/momentum/control"
+	"github.com/sirsjg/momentum/journal"
+	"github.com/sirsjg/momentum/session"
 	"github.com/sirsjg/momentum/version"
 )
 
@@ -15,6 +20,16 @@ var (
 	baseURL       string
 	executionMode string
 	workDir       string
+	controlSocket string
+	metricsAddr   string
+	traceFile     string
+	configPath    string
+	journalDir    string
+	sessionDir    string
+	profileName   string
+	agentName     string
+	agentTimeout  time.Duration
+	auditLogPath  string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -53,13 +68,29 @@ func Execute() error {
 func init() {
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&baseURL, "base-url", "http://localhost:3000", "Flux server base URL")
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Config file path (default: <user config dir>/momentum/config.json)")
+	rootCmd.PersistentFlags().StringVar(&controlSocket, "control-socket", control.DefaultSocketPath(), "Control socket path used by status/stop/pause/resume/tail")
 
 	// Task selection flags (on root command now)
-	rootCmd.Flags().StringVar(&taskID, "task", "", "Specific task ID to work with")
-	rootCmd.Flags().StringVar(&epicID, "epic", "", "Filter tasks by epic ID")
-	rootCmd.Flags().StringVar(&projectID, "project", "", "Filter tasks by project ID")
-	rootCmd.Flags().StringVar(&executionMode, "execution-mode", "async", "Task execution mode: async or sync")
-	rootCmd.Flags().StringVar(&workDir, "workdir", "", "Working directory for agents (inherits CLAUDE.md)")
+	addWorkerFlags(rootCmd)
+}
+
+// addWorkerFlags registers the task selection and worker flags shared by the
+// root command and `momentum serve`.
+func addWorkerFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&taskID, "task", "", "Specific task ID to work with")
+	cmd.Flags().StringVar(&epicID, "epic", "", "Filter tasks by epic ID")
+	cmd.Flags().StringVar(&projectID, "project", "", "Filter tasks by project ID")
+	cmd.Flags().StringVar(&executionMode, "execution-mode", "async", "Task execution mode: async or sync")
+	cmd.Flags().StringVar(&workDir, "workdir", "", "Working directory for agents (inherits CLAUDE.md)")
+	cmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "Serve Prometheus metrics on this address (e.g. :9464)")
+	cmd.Flags().StringVar(&traceFile, "trace-file", "", "Append OTLP/JSON trace spans to this file")
+	cmd.Flags().StringVar(&journalDir, "journal-dir", journal.DefaultDir(), "Directory recording claimed tasks for crash recovery (empty to disable)")
+	cmd.Flags().StringVar(&sessionDir, "session-dir", session.DefaultDir(), "Directory remembering agent sessions so retries can resume them (empty to disable)")
+	cmd.Flags().StringVar(&profileName, "profile", "", "Sandbox profile from the config file to run agents under")
+	cmd.Flags().StringVar(&agentName, "agent", "", "Agent backend: claude, container or fake (default: config file, then claude)")
+	cmd.Flags().DurationVar(&agentTimeout, "agent-timeout", 0, "Stop agents that run longer than this (e.g. 30m; 0 for no limit)")
+	cmd.Flags().StringVar(&auditLogPath, "audit-log", audit.DefaultPath(), "Append every task status change to this file (empty to disable)")
 }
 
 // GetBaseURL returns the configured base URL for the Flux server
@@ -83,7 +114,7 @@ func SetWorkDir(dir string) {
 	workDir = dir
 }
 
-// InitWorkDir sets initial workdir from CLI flag > env var > "."
+// InitWorkDir sets initial workdir from CLI flag > env var > config file > "."
 func InitWorkDir() {
 	if workDir != "" {
 		return // CLI flag already set
@@ -92,6 +123,11 @@ func InitWorkDir() {
 		workDir = expandHome(dir)
 		return
 	}
+	// An invalid config file is reported once the worker loads it
+	if cfg, err := loadConfig(); err == nil && cfg.WorkDir != "" {
+		workDir = expandHome(cfg.WorkDir)
+		return
+	}
 	workDir = "."
 }
 
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/control"
+	"github.com/sirsjg/momentum/hooks"
+	"github.com/sirsjg/momentum/lease"
+	"github.com/sirsjg/momentum/session"
+	"github.com/sirsjg/momentum/vcs"
+	"github.com/sirsjg/momentum/workflow"
+)
+
+// Outcomes recorded with a task's session and repeated to the agent when it
+// resumes.
+const (
+	outcomeInterrupted = "Your last run was interrupted before it finished"
+	outcomeStopped     = "Your last run was stopped by the user"
+	outcomeCompleted   = "Your last run completed the task"
+	outcomeMessaged    = "Your last run was paused so the user could send you a message"
+)
+
+// openSessions opens the --session-dir store, or returns nil if it is disabled.
+func openSessions() (*session.Store, error) {
+	if sessionDir == "" {
+		return nil, nil
+	}
+	return session.Open(sessionDir)
+}
+
+// resumableSession returns the recorded session for a task if it can be
+// resumed from workDir, or nil to start a new one.
+func (env *workerEnv) resumableSession(taskID, workDir string) *session.Record {
+	rec, err := env.sessions.Load(taskID)
+	if err != nil {
+		env.reportError(err)
+		return nil
+	}
+	if !rec.Matches(GetBaseURL(), workDir) {
+		return nil
+	}
+	return rec
+}
+
+// saveSession records the session a task's agent is running in.
+func (env *workerEnv) saveSession(task *client.Task, sessionID, workDir, outcome string) {
+	err := env.sessions.Save(session.Record{
+		TaskID:    task.ID,
+		ProjectID: task.ProjectID,
+		SessionID: sessionID,
+		BaseURL:   GetBaseURL(),
+		WorkDir:   workDir,
+		Outcome:   outcome,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		env.reportError(err)
+	}
+}
+
+// forgetSession drops a task's session so its next run starts afresh.
+func (env *workerEnv) forgetSession(taskID string) {
+	if err := env.sessions.Delete(taskID); err != nil {
+		env.reportError(err)
+	}
+}
+
+// continueTask resumes the session of a task that is not running, passing
+// the user's extra instructions to the agent.
+func continueTask(ctx context.Context, env *workerEnv, c *client.Client, wf *workflow.Workflow, taskID, instructions string) error {
+	rec, err := env.sessions.Load(taskID)
+	if err != nil {
+		return err
+	}
+	if rec == nil || rec.BaseURL != GetBaseURL() {
+		return fmt.Errorf("%w: no agent session recorded for task %s", control.ErrTaskNotFound, taskID)
+	}
+	task, err := c.WithContext(ctx).FindTask(rec.ProjectID, taskID)
+	if err != nil {
+		return err
+	}
+	if task == nil {
+		return fmt.Errorf("%w: task %s no longer exists", control.ErrTaskNotFound, taskID)
+	}
+	ws, err := env.workspaceFor(task)
+	if err != nil {
+		return err
+	}
+	if !rec.Matches(GetBaseURL(), ws.Dir) {
+		return fmt.Errorf("%w: no agent session recorded for task %s in %s", control.ErrTaskNotFound, taskID, ws.Dir)
+	}
+
+	if env.git.Busy(ws.Dir) {
+		return fmt.Errorf("task %s cannot continue while another task runs in %s: %w", task.ID, ws.Dir, vcs.ErrBusy)
+	}
+
+	// Hold the task so the worker loop cannot start it at the same time
+	if !env.agents.reserve(task.ID) {
+		return fmt.Errorf("task %s is already running", task.ID)
+	}
+
+	// Leases are only taken on tasks in a pickup status
+	if env.leases.Enabled() && !env.states.IsPickup(task.Status) {
+		if _, err := wf.WithContext(ctx).WithFrom(*task).WithReason("continued by user").ResetTask([]string{task.ID}); err != nil {
+			env.agents.markDone(task.ID)
+			return err
+		}
+		reset := *task
+		reset.Status = env.states.Reset()
+		task = &reset
+	}
+	claimed, err := env.claimTask(ctx, c, wf, task)
+	if err != nil {
+		env.agents.markDone(task.ID)
+		if errors.Is(err, lease.ErrHeld) {
+			return fmt.Errorf("task %s is held by another instance: %w", task.ID, err)
+		}
+		return err
+	}
+	task = claimed
+	if err := env.prepareWorkspace(ctx, ws); err != nil {
+		env.agents.markDone(task.ID)
+		if err := rejectTask(wf.WithContext(ctx).WithFrom(*task), task.ID, err); err != nil {
+			env.reportError(err)
+		}
+		env.leases.Release(ctx, c, task)
+		return err
+	}
+	env.journalClaim(task, ws.Dir, 0, "", time.Now())
+	if err := env.runHook(ctx, hooks.PreRun, task, nil); err != nil {
+		env.agents.markDone(task.ID)
+		if err := rejectTask(wf.WithContext(ctx).WithFrom(*task), task.ID, err); err != nil {
+			env.reportError(err)
+		}
+		env.leases.Release(ctx, c, task)
+		env.journalRelease(task.ID)
+		return err
+	}
+	// Pass on the comment of a reviewer who rejected the last run too
+	if feedback := env.reviews.takeFeedback(task.ID); feedback != "" {
+		instructions = strings.TrimSpace(feedback + "\n\n" + instructions)
+	}
+	return spawnAgent(ctx, env, c, task, wf, rec, instructions)
+}
+
+// messageAgent passes a user's message to the agent running a task. Agents
+// that cannot take input while running are stopped, and their session is
+// resumed with the message.
+func messageAgent(env *workerEnv, taskID, text string) error {
+	runner := env.agents.runner(taskID)
+	if runner == nil {
+		return fmt.Errorf("%w: no running agent for task %s", control.ErrTaskNotFound, taskID)
+	}
+	if in, ok := runner.Agent().(agent.Interactive); ok {
+		if err := in.SendMessage(text); !errors.Is(err, agent.ErrInputNotSupported) {
+			return err
+		}
+	}
+
+	if runner.SessionID() == "" {
+		return fmt.Errorf("the agent for task %s has not started its session yet; try again in a moment", taskID)
+	}
+	if !env.agents.redirect(taskID, text) {
+		return fmt.Errorf("the agent for task %s is being stopped", taskID)
+	}
+	if err := runner.Cancel(); err != nil {
+		return fmt.Errorf("failed to stop task %s to pass on the message: %w", taskID, err)
+	}
+	return nil
+}
+
+// buildResumePrompt constructs the prompt for an agent resuming its earlier
+// session on task. outcome says how the last run ended, and instructions are
+// any extra guidance from the user.
+func buildResumePrompt(task *client.Task, verified bool, states *workflow.StateMachine, outcome, instructions string) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("You are resuming your earlier session on Flux task %s (%s).\n", task.ID, task.Title))
+	if outcome == "" {
+		outcome = outcomeInterrupted
+	}
+	b.WriteString(outcome + ".\n\n")
+
+	if instructions != "" {
+		b.WriteString("Instructions from the user, which take priority over your earlier plan:\n")
+		b.WriteString(strings.TrimSpace(instructions))
+		b.WriteString("\n\n")
+	}
+
+	b.WriteString("Continue from where you left off: check what you already changed in the working directory rather than starting over, finish the task and verify it works, and add a comment to the task via mcp__flux__add_task_comment describing what you did.\n")
+	if verified {
+		b.WriteString(fmt.Sprintf("Do not move the task to %q yourself; Momentum does that once its own checks pass.", states.Success()))
+	} else {
+		b.WriteString(fmt.Sprintf("Then mark the task as done using mcp__flux__move_task_status with status %q.", states.Success()))
+	}
+	b.WriteString(fmt.Sprintf("\n\nIf anything blocks completion, stop and report the blocker, set the task status back to %q, and add a comment explaining the issue.\n", states.Stopped()))
+	return b.String()
+}
+
+// runOutcome describes how an agent run ended for the task's session record.
+func runOutcome(exitCode int, vetoErr, verifyErr error) string {
+	switch {
+	case exitCode == 0 && vetoErr != nil:
+		return fmt.Sprintf("Your last run was rejected by a post-run check: %v", vetoErr)
+	case exitCode == 0 && verifyErr != nil:
+		return fmt.Sprintf("Your last run failed Momentum's verification: %v", verifyErr)
+	case exitCode == 0:
+		return outcomeCompleted
+	default:
+		return fmt.Sprintf("Your last run exited with code %d before finishing", exitCode)
+	}
+}
+
+// sessionCanResume reports whether a run that was asked to resume a session
+// actually did; Claude Code exits without reporting a session when the one
+// requested no longer exists.
+func sessionCanResume(cfg agent.Config, result agent.Result, stoppedByUser bool) bool {
+	return cfg.ResumeSession == "" || stoppedByUser || result.SessionID != "" || result.ExitCode == 0
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/client"
+)
+
+func TestBuildResumePrompt(t *testing.T) {
+	task := &client.Task{ID: "task-1", Title: "Add login"}
+
+	prompt := buildResumePrompt(task, false, nil, outcomeStopped, "  Use the existing session middleware.\n")
+	for _, want := range []string{
+		"resuming your earlier session on Flux task task-1 (Add login)",
+		outcomeStopped + ".",
+		"Instructions from the user, which take priority over your earlier plan:\nUse the existing session middleware.\n",
+		`status "done"`,
+	} {
+		if !contains(prompt, want) {
+			t.Errorf("expected prompt to contain %q:\n%s", want, prompt)
+		}
+	}
+
+	verified := buildResumePrompt(task, true, nil, "", "")
+	if !contains(verified, outcomeInterrupted) || contains(verified, "Instructions from the user") {
+		t.Errorf("expected the default outcome and no instructions:\n%s", verified)
+	}
+	if !contains(verified, `Do not move the task to "done" yourself`) {
+		t.Errorf("expected verified prompts to leave the status alone:\n%s", verified)
+	}
+}
+
+func TestRunOutcome(t *testing.T) {
+	veto := errors.New("lint failed")
+	tests := []struct {
+		name      string
+		exitCode  int
+		veto, ver error
+		want      string
+	}{
+		{"completed", 0, nil, nil, outcomeCompleted},
+		{"vetoed", 0, veto, nil, "Your last run was rejected by a post-run check: lint failed"},
+		{"unverified", 0, nil, errors.New("tests failed"), "Your last run failed Momentum's verification: tests failed"},
+		{"failed", 2, nil, nil, "Your last run exited with code 2 before finishing"},
+	}
+	for _, tt := range tests {
+		if got := runOutcome(tt.exitCode, tt.veto, tt.ver); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSessionCanResume(t *testing.T) {
+	resumed := agent.Config{ResumeSession: "s1"}
+	if !sessionCanResume(agent.Config{}, agent.Result{ExitCode: 1}, false) {
+		t.Error("expected a new session never to be discarded")
+	}
+	if !sessionCanResume(resumed, agent.Result{ExitCode: 1, SessionID: "s1"}, false) {
+		t.Error("expected a failed run that reported its session to keep it")
+	}
+	if !sessionCanResume(resumed, agent.Result{ExitCode: -1}, true) {
+		t.Error("expected a run stopped by the user to keep its session")
+	}
+	if sessionCanResume(resumed, agent.Result{ExitCode: 1}, false) {
+		t.Error("expected a resume that failed without a session to be discarded")
+	}
+}
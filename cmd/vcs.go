@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/internal/git"
+	"github.com/sirsjg/momentum/ui"
+	"github.com/sirsjg/momentum/vcs"
+	"github.com/sirsjg/momentum/workflow"
+)
+
+// startGit puts a task's run on a branch of its own. When the working
+// directory is not a clean repository the task runs without one and the
+// reason is shown; when another run owns it, the error wraps vcs.ErrBusy
+// and the task must not run.
+func (env *workerEnv) startGit(ctx context.Context, workDir string, task *client.Task) (*vcs.Run, error) {
+	run, err := env.git.Start(ctx, workDir, task)
+	switch {
+	case errors.Is(err, vcs.ErrBusy):
+		return nil, fmt.Errorf("task %s cannot start: %w", task.ID, err)
+	case err != nil:
+		env.reportError(fmt.Errorf("task %s runs without a git branch: %w", task.ID, err))
+	}
+	return run, nil
+}
+
+// gitBusy reports whether task has to wait for another task's run to
+// release the working directory before it can start.
+func (env *workerEnv) gitBusy(task *client.Task) bool {
+	ws, err := env.workspaceFor(task)
+	return err == nil && env.git.Busy(ws.Dir)
+}
+
+// settleGit ends the git side of a run. A successful run's changes are
+// committed, pushed and linked from the task; anything else is parked on
+// the task's branch for its next run.
+func (env *workerEnv) settleGit(ctx context.Context, wf *workflow.Workflow, task *client.Task, run *vcs.Run, succeeded bool) {
+	if run == nil {
+		return
+	}
+	// The work is saved even when the run ends because Momentum is exiting
+	ctx = context.WithoutCancel(ctx)
+	if !succeeded {
+		if _, err := run.Park(ctx); err != nil {
+			env.reportError(fmt.Errorf("failed to save task %s's work on %s: %w", task.ID, run.Branch, err))
+		}
+		return
+	}
+
+	result, err := run.Finish(ctx)
+	if err != nil {
+		env.reportError(fmt.Errorf("failed to commit task %s's work on %s: %w", task.ID, run.Branch, err))
+	}
+	if result != nil && result.Changed() {
+		wf.Comment(task.ID, gitComment(result, err))
+	}
+}
+
+// gitComment links a task to the branch and commit holding its changes.
+// pushErr is why the branch could not be pushed, if it was not.
+func gitComment(result *vcs.Result, pushErr error) string {
+	msg := fmt.Sprintf("Momentum committed the changes for this task to branch `%s` (commit %s", result.Branch, result.Commit)
+	if result.Base != "" {
+		msg += fmt.Sprintf(", based on %s", shortSHA(result.Base))
+	}
+	msg += ")"
+	switch {
+	case result.Remote != "":
+		msg += fmt.Sprintf(" and pushed it to %s.", result.Remote)
+	case pushErr != nil:
+		msg += fmt.Sprintf(", but could not push it: %s.", firstLine(pushErr.Error()))
+	default:
+		msg += "."
+	}
+	return msg
+}
+
+// shortSHA abbreviates a commit hash for display.
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
+
+// maxPatchBytes bounds the diff kept for each finished agent panel.
+const maxPatchBytes = 1 << 20
+
+// captureChanges lists what a run changed in workDir since base, with the
+// diff, for the agent panel.
+func captureChanges(ctx context.Context, workDir string, base git.Baseline) *ui.Changes {
+	diff, err := git.Changes(ctx, workDir, base)
+	if err != nil {
+		return &ui.Changes{Err: err.Error()}
+	}
+	changes := &ui.Changes{Files: make([]ui.FileChange, len(diff.Files))}
+	for i, f := range diff.Files {
+		changes.Files[i] = ui.FileChange{Path: f.Path, Added: f.Added, Deleted: f.Deleted, Binary: f.Binary}
+	}
+	if len(diff.Files) == 0 {
+		return changes
+	}
+	changes.Patch, changes.Truncated, err = git.Patch(ctx, workDir, base, maxPatchBytes)
+	if err != nil {
+		changes.Err = err.Error()
+	}
+	return changes
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sirsjg/momentum/vcs"
+)
+
+func TestGitComment(t *testing.T) {
+	result := &vcs.Result{Branch: "momentum/task-1-fix", Base: "0123456789abcdef", Commit: "fedcba9876543210"}
+
+	if got := gitComment(result, nil); got != "Momentum committed the changes for this task to branch `momentum/task-1-fix` (commit fedcba9876543210, based on 0123456789ab)." {
+		t.Errorf("unexpected comment %q", got)
+	}
+	result.Remote = "origin"
+	if got := gitComment(result, nil); !contains(got, "and pushed it to origin.") {
+		t.Errorf("expected the remote in %q", got)
+	}
+	result.Remote = ""
+	if got := gitComment(result, errors.New("git push: exit status 128\nmore")); !contains(got, "but could not push it: git push: exit status 128.") {
+		t.Errorf("expected the push error in %q", got)
+	}
+}
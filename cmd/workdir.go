@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/sirsjg/momentum/config"
+	"github.com/sirsjg/momentum/shell"
+)
+
+// workDirEnv is the environment variable holding the default workdir.
+const workDirEnv = "MOMENTUM_WORKDIR"
+
+// workDirStore saves the workdirs chosen in the TUI to the config file, the
+// recent workdirs list and the user's shell startup file.
+type workDirStore struct {
+	configPath string
+	recentPath string
+}
+
+func newWorkDirStore() *workDirStore {
+	path := configPath
+	if path == "" {
+		path = config.DefaultPath()
+	}
+	return &workDirStore{configPath: path, recentPath: config.DefaultRecentWorkDirsPath()}
+}
+
+// Recent returns the workdirs used lately, most recent first.
+func (s *workDirStore) Recent() []string {
+	if s.recentPath == "" {
+		return nil
+	}
+	dirs, _ := config.LoadRecentWorkDirs(s.recentPath)
+	return dirs
+}
+
+// Remember records dir, made absolute, as the most recently used workdir.
+func (s *workDirStore) Remember(dir string) error {
+	if s.recentPath == "" {
+		return nil
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return config.AddRecentWorkDir(s.recentPath, dir)
+}
+
+// SaveDefault sets dir as the workdir in the config file.
+func (s *workDirStore) SaveDefault(dir string) (string, error) {
+	if s.configPath == "" {
+		return "", errors.New("no config file location; use --config")
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return s.configPath, config.SaveWorkDir(s.configPath, dir)
+}
+
+// EnvOverridesDefault reports whether MOMENTUM_WORKDIR is set; it is used
+// instead of the workdir in the config file.
+func (s *workDirStore) EnvOverridesDefault() bool {
+	return os.Getenv(workDirEnv) != ""
+}
+
+// ShellExport returns the user's shell startup file and the line setting
+// MOMENTUM_WORKDIR to dir in it.
+func (s *workDirStore) ShellExport(dir string) (string, string) {
+	rc := shell.RCFile()
+	if rc == "" {
+		return "", ""
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return rc, shell.ExportLine(rc, workDirEnv, dir)
+}
+
+// SaveShellExport sets line in Momentum's block of the shell startup file,
+// replacing the export saved there before.
+func (s *workDirStore) SaveShellExport(file, line string) error {
+	return shell.SetBlock(file, line)
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/workspace"
+)
+
+// workspaceFor returns where task's agent runs: its project's or epic's
+// mapped directory or repository checkout, or the current workdir setting.
+func (env *workerEnv) workspaceFor(task *client.Task) (workspace.Workspace, error) {
+	return env.workspaces.Resolve(task, GetWorkDir())
+}
+
+// prepareWorkspace clones or updates the checkout task runs in, if its
+// project names a repository. A checkout that could not be updated is
+// still used, and the reason is shown.
+func (env *workerEnv) prepareWorkspace(ctx context.Context, ws workspace.Workspace) error {
+	err := env.workspaces.Prepare(ctx, ws)
+	if errors.Is(err, workspace.ErrNotUpdated) {
+		env.reportError(err)
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+// Package config loads Momentum's optional JSON configuration file.
+//
+// The file lives at <user config dir>/momentum/config.json (for example
+// ~/.config/momentum/config.json on Linux) unless --config points elsewhere.
+// A missing default file is not an error; every setting has a default.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/approval"
+	"github.com/sirsjg/momentum/hooks"
+	"github.com/sirsjg/momentum/lease"
+	"github.com/sirsjg/momentum/notify"
+	"github.com/sirsjg/momentum/sandbox"
+	"github.com/sirsjg/momentum/vcs"
+	"github.com/sirsjg/momentum/verify"
+	"github.com/sirsjg/momentum/workflow"
+	"github.com/sirsjg/momentum/workspace"
+)
+
+// Config is the top-level configuration file.
+type Config struct {
+	// WorkDir is where agents run unless --workdir or MOMENTUM_WORKDIR
+	// says otherwise
+	WorkDir string `json:"workdir,omitempty"`
+	// Workdirs maps Flux projects and epics to the directories their
+	// tasks run in
+	Workdirs workspace.Config `json:"workdirs"`
+	// Hooks are commands run at points in each task's lifecycle
+	Hooks hooks.Config `json:"hooks"`
+	// Verify configures checks that must pass before a task is marked done
+	Verify verify.Config `json:"verify"`
+	// Approval holds risky tasks for a human review before they are done
+	Approval approval.Config `json:"approval"`
+	// Git commits each task's changes to a branch of its own
+	Git vcs.Config `json:"git"`
+	// Workflow names the statuses tasks move through on the board
+	Workflow workflow.States `json:"workflow"`
+	// Notify sends lifecycle events to webhooks and the desktop
+	Notify notify.Config `json:"notify"`
+	// Leases coordinate task claims between several Momentum instances
+	Leases lease.Config `json:"leases"`
+	// Sandbox defines execution profiles restricting agent processes
+	Sandbox sandbox.Config `json:"sandbox"`
+	// Agent names the agent backend used unless --agent overrides it
+	Agent string `json:"agent,omitempty"`
+	// Container configures the "container" agent backend
+	Container agent.ContainerConfig `json:"container"`
+	// Fake scripts the "fake" agent backend used for testing
+	Fake agent.FakeConfig `json:"fake"`
+}
+
+// DefaultPath returns the default config file location, or "" if the user
+// config directory cannot be determined.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "momentum", "config.json")
+}
+
+// Load reads and validates the config file at path.
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config: %w", err)
+	}
+	defer f.Close()
+
+	var cfg Config
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// LoadDefault reads the config file at DefaultPath, returning an empty
+// config if it does not exist.
+func LoadDefault() (*Config, error) {
+	path := DefaultPath()
+	if path == "" {
+		return &Config{}, nil
+	}
+	cfg, err := Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Config{}, nil
+	}
+	return cfg, err
+}
@@ -0,0 +1,252 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `{
+		"hooks": {
+			"pre-run": ["make setup"],
+			"post-run": ["make test", "make lint"],
+			"timeout": "5m"
+		}
+	}`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Hooks.PreRun) != 1 || cfg.Hooks.PreRun[0] != "make setup" {
+		t.Errorf("unexpected pre-run hooks: %v", cfg.Hooks.PreRun)
+	}
+	if len(cfg.Hooks.PostRun) != 2 {
+		t.Errorf("unexpected post-run hooks: %v", cfg.Hooks.PostRun)
+	}
+	if cfg.Hooks.Timeout != "5m" {
+		t.Errorf("unexpected timeout: %q", cfg.Hooks.Timeout)
+	}
+}
+
+func TestLoad_Verify(t *testing.T) {
+	path := writeConfig(t, `{
+		"verify": {
+			"commands": ["go test ./..."],
+			"require-diff": true,
+			"acceptance-check": true,
+			"failure-status": "review"
+		}
+	}`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v := cfg.Verify
+	if len(v.Commands) != 1 || !v.RequireDiff || !v.AcceptanceCheck || v.FailureStatus != "review" {
+		t.Errorf("unexpected verify config: %+v", v)
+	}
+}
+
+func TestLoad_Sandbox(t *testing.T) {
+	path := writeConfig(t, `{
+		"sandbox": {
+			"profile": "ci",
+			"profiles": {
+				"ci": {
+					"env": ["PATH", "HOME", "ANTHROPIC_API_KEY"],
+					"limits": {"cpu-time": "30m", "memory-mb": 8192, "processes": 512},
+					"filesystem": {"read-only": true, "writable": ["~/.cache/go-build"]},
+					"permissions": {"allowed-tools": ["Read", "Edit", "mcp__flux"]}
+				}
+			}
+		}
+	}`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p, err := cfg.Sandbox.Select("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(p.Env) != 3 || p.Limits.MemoryMB != 8192 || !p.Filesystem.ReadOnly || len(p.Permissions.AllowedTools) != 3 {
+		t.Errorf("unexpected sandbox profile: %+v", p)
+	}
+}
+
+func TestLoad_Approval(t *testing.T) {
+	path := writeConfig(t, `{
+		"approval": {
+			"epics": ["epic-risky"],
+			"protected-paths": ["migrations/**", "*.tf"],
+			"status": "needs-review"
+		}
+	}`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a := cfg.Approval
+	if len(a.Epics) != 1 || len(a.ProtectedPaths) != 2 || a.Status != "needs-review" {
+		t.Errorf("unexpected approval config: %+v", a)
+	}
+}
+
+func TestLoad_Workflow(t *testing.T) {
+	path := writeConfig(t, `{
+		"workflow": {
+			"pickup": ["ready", "todo"],
+			"running": "building",
+			"success": "qa",
+			"failure": "blocked",
+			"transitions": {
+				"ready": ["building"],
+				"building": ["qa", "blocked", "ready"]
+			}
+		}
+	}`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w := cfg.Workflow
+	if len(w.Pickup) != 2 || w.Running != "building" || w.Success != "qa" || w.Failure != "blocked" || len(w.Transitions["building"]) != 3 {
+		t.Errorf("unexpected workflow config: %+v", w)
+	}
+}
+
+func TestLoad_Workdirs(t *testing.T) {
+	path := writeConfig(t, `{"workdirs": {
+		"projects": {"proj-1": "~/src/api", "Web": "web"},
+		"epics": {"epic-1": "/srv/docs"},
+		"repositories": {"proj-2": "git@github.com:acme/app.git"},
+		"cache-dir": "~/repos"
+	}}`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w := cfg.Workdirs
+	if w.Projects["proj-1"] != "~/src/api" || w.Projects["Web"] != "web" || w.Epics["epic-1"] != "/srv/docs" ||
+		w.Repositories["proj-2"] != "git@github.com:acme/app.git" || w.CacheDir != "~/repos" {
+		t.Errorf("unexpected workdirs config: %+v", w)
+	}
+}
+
+func TestLoad_Git(t *testing.T) {
+	path := writeConfig(t, `{"git": {"enabled": true, "remote": "origin", "branch-prefix": "agent/"}}`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if g := cfg.Git; !g.Enabled || g.Remote != "origin" || g.BranchPrefix != "agent/" {
+		t.Errorf("unexpected git config: %+v", g)
+	}
+}
+
+func TestLoad_Notify(t *testing.T) {
+	path := writeConfig(t, `{
+		"notify": {
+			"webhooks": [{
+				"name": "team",
+				"url": "https://hooks.slack.com/services/T/B/X",
+				"format": "slack",
+				"events": ["agent-failed", "flux-disconnected"],
+				"projects": ["proj-1"],
+				"secret-env": "MOMENTUM_WEBHOOK_SECRET",
+				"retries": 0
+			}],
+			"desktop": {"enabled": true, "events": ["*"]}
+		}
+	}`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	n := cfg.Notify
+	if len(n.Webhooks) != 1 {
+		t.Fatalf("expected 1 webhook, got %+v", n.Webhooks)
+	}
+	w := n.Webhooks[0]
+	if w.Name != "team" || w.Format != "slack" || len(w.Events) != 2 || len(w.Projects) != 1 || w.SecretEnv != "MOMENTUM_WEBHOOK_SECRET" || w.Retries == nil || *w.Retries != 0 {
+		t.Errorf("unexpected webhook config: %+v", w)
+	}
+	if !n.Desktop.Enabled || len(n.Desktop.Events) != 1 {
+		t.Errorf("unexpected desktop config: %+v", n.Desktop)
+	}
+}
+
+func TestLoad_Container(t *testing.T) {
+	path := writeConfig(t, `{
+		"agent": "container",
+		"container": {
+			"image": "ghcr.io/example/agent:1",
+			"network": "none",
+			"cpus": "2",
+			"memory": "4g",
+			"pids-limit": 256,
+			"env": ["ANTHROPIC_API_KEY"],
+			"mounts": ["~/.claude:/home/node/.claude"]
+		}
+	}`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Agent != "container" || cfg.Container.Image != "ghcr.io/example/agent:1" || cfg.Container.PidsLimit != 256 || len(cfg.Container.Mounts) != 1 {
+		t.Errorf("unexpected container config: %s %+v", cfg.Agent, cfg.Container)
+	}
+	if err := cfg.Container.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLoad_UnknownField(t *testing.T) {
+	path := writeConfig(t, `{"hooks": {"post_run": ["make test"]}}`)
+	if _, err := Load(path); err == nil {
+		t.Error("expected error for misspelled field")
+	}
+}
+
+func TestLoad_Missing(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected not-exist error, got %v", err)
+	}
+}
+
+func TestLoadDefault_Missing(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("AppData", dir)
+
+	cfg, err := LoadDefault()
+	if err != nil {
+		t.Fatalf("expected missing default config to be ignored, got %v", err)
+	}
+	if cfg == nil {
+		t.Fatal("expected empty config")
+	}
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// maxRecentWorkDirs bounds the recent workdirs list.
+const maxRecentWorkDirs = 10
+
+// SaveWorkDir sets the workdir setting in the config file at path, creating
+// the file if needed. The file's other settings are kept, though its keys
+// are sorted and reindented.
+func SaveWorkDir(path, dir string) error {
+	settings := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("failed to read config: %w", err)
+	default:
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return fmt.Errorf("failed to parse config %s: %w", path, err)
+		}
+	}
+	value, err := json.Marshal(dir)
+	if err != nil {
+		return err
+	}
+	settings["workdir"] = value
+	out, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(path, append(out, '\n'))
+}
+
+// DefaultRecentWorkDirsPath returns the default location of the recent
+// workdirs list, or "" if the user config directory cannot be determined.
+func DefaultRecentWorkDirsPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "momentum", "recent-workdirs.json")
+}
+
+// LoadRecentWorkDirs reads the recent workdirs list at path, most recent
+// first. A missing list is empty.
+func LoadRecentWorkDirs(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recent workdirs: %w", err)
+	}
+	var dirs []string
+	if err := json.Unmarshal(data, &dirs); err != nil {
+		return nil, fmt.Errorf("failed to parse recent workdirs %s: %w", path, err)
+	}
+	return dirs, nil
+}
+
+// AddRecentWorkDir moves dir to the front of the recent workdirs list at
+// path, dropping the oldest entries beyond the list's limit.
+func AddRecentWorkDir(path, dir string) error {
+	dirs, err := LoadRecentWorkDirs(path)
+	if err != nil {
+		// A corrupt list is replaced rather than blocking new entries
+		dirs = nil
+	}
+	dirs = slices.DeleteFunc(dirs, func(d string) bool { return d == dir })
+	dirs = append([]string{dir}, dirs...)
+	if len(dirs) > maxRecentWorkDirs {
+		dirs = dirs[:maxRecentWorkDirs]
+	}
+	data, err := json.MarshalIndent(dirs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(path, append(data, '\n'))
+}
+
+// writeFile replaces the file at path with data, so a crash never leaves
+// it half written. An existing file keeps its permissions; a new one is
+// created with 0644.
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	mode := fs.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// CreateTemp makes files readable by their owner only
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestSaveWorkDir(t *testing.T) {
+	path := writeConfig(t, `{"agent": "fake", "git": {"enabled": true}}`)
+	if err := SaveWorkDir(path, "/src/app"); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("expected a valid config, got %v", err)
+	}
+	if cfg.WorkDir != "/src/app" || cfg.Agent != "fake" || !cfg.Git.Enabled {
+		t.Errorf("expected the workdir added and other settings kept, got %+v", cfg)
+	}
+}
+
+func TestSaveWorkDir_NewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "momentum", "config.json")
+	if err := SaveWorkDir(path, "~/src/app"); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil || cfg.WorkDir != "~/src/app" {
+		t.Errorf("expected a new config with the workdir, got %+v, %v", cfg, err)
+	}
+}
+
+func TestSaveWorkDir_KeepsMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not kept on Windows")
+	}
+	path := writeConfig(t, `{"agent": "fake"}`)
+	if err := os.Chmod(path, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveWorkDir(path, "/src/app"); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o640 {
+		t.Errorf("expected the config to keep mode 0640, got %v, %v", info.Mode().Perm(), err)
+	}
+
+	path = filepath.Join(t.TempDir(), "config.json")
+	if err := SaveWorkDir(path, "/src/app"); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o644 {
+		t.Errorf("expected a new config with mode 0644, got %v, %v", info.Mode().Perm(), err)
+	}
+}
+
+func TestSaveWorkDir_InvalidFile(t *testing.T) {
+	path := writeConfig(t, `{"agent": `)
+	if err := SaveWorkDir(path, "/src/app"); err == nil {
+		t.Error("expected an error for a config that cannot be parsed")
+	}
+	if data, _ := os.ReadFile(path); string(data) != `{"agent": ` {
+		t.Error("expected the broken config to be left alone")
+	}
+}
+
+func TestRecentWorkDirs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recent-workdirs.json")
+	if dirs, err := LoadRecentWorkDirs(path); err != nil || dirs != nil {
+		t.Fatalf("expected an empty list, got %v, %v", dirs, err)
+	}
+	for i := range maxRecentWorkDirs + 2 {
+		if err := AddRecentWorkDir(path, fmt.Sprintf("/src/%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Using a directory again moves it to the front
+	if err := AddRecentWorkDir(path, "/src/5"); err != nil {
+		t.Fatal(err)
+	}
+	dirs, err := LoadRecentWorkDirs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != maxRecentWorkDirs || dirs[0] != "/src/5" || dirs[1] != "/src/11" || slices.Contains(dirs, "/src/0") {
+		t.Errorf("unexpected recent workdirs %v", dirs)
+	}
+	if slices.Index(dirs[1:], "/src/5") >= 0 {
+		t.Errorf("expected no duplicates, got %v", dirs)
+	}
+}
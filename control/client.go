@@ -0,0 +1,227 @@
+package control
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+
+	"github.com/sirsjg/momentum/agent"
+)
+
+// Client talks to a running instance over its control socket, or to a
+// coordinator over HTTP.
+type Client struct {
+	baseURL string
+	token   string
+	// where describes the instance's address in errors
+	where      string
+	httpClient *http.Client
+}
+
+// NewClient creates a control client for the given socket path.
+func NewClient(path string) *Client {
+	return &Client{
+		baseURL: "http://momentum",
+		where:   "socket " + path,
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", path)
+				},
+			},
+		},
+	}
+}
+
+// NewRemoteClient creates a control client for a coordinator started with
+// `momentum serve`, e.g. http://build-box:7420, authenticating with token.
+func NewRemoteClient(baseURL, token string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		where:      "coordinator " + baseURL,
+		httpClient: &http.Client{},
+	}
+}
+
+// Status returns the instance status.
+func (c *Client) Status() (*Status, error) {
+	var status Status
+	if err := c.do(context.Background(), http.MethodGet, "/status", &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Stop stops the agent running the given task.
+func (c *Client) Stop(taskID string) error {
+	return c.do(context.Background(), http.MethodPost, "/tasks/"+url.PathEscape(taskID)+"/stop", nil)
+}
+
+// Continue resumes the agent session of a task that is not running, passing
+// the given extra instructions to the agent.
+func (c *Client) Continue(taskID, instructions string) error {
+	body, err := json.Marshal(ContinueRequest{Instructions: instructions})
+	if err != nil {
+		return err
+	}
+	resp, err := c.sendBody(context.Background(), http.MethodPost, "/tasks/"+url.PathEscape(taskID)+"/continue", body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Approve marks a task awaiting review as done, recording the reviewer's
+// optional comment.
+func (c *Client) Approve(taskID, comment string) error {
+	return c.review(taskID, "approve", comment)
+}
+
+// Reject sends a task awaiting review back to the queue with the reviewer's
+// comment for its next run.
+func (c *Client) Reject(taskID, comment string) error {
+	return c.review(taskID, "reject", comment)
+}
+
+func (c *Client) review(taskID, decision, comment string) error {
+	body, err := json.Marshal(ReviewRequest{Comment: comment})
+	if err != nil {
+		return err
+	}
+	resp, err := c.sendBody(context.Background(), http.MethodPost, "/tasks/"+url.PathEscape(taskID)+"/"+decision, body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Pause stops the instance from starting new tasks.
+func (c *Client) Pause() (*Status, error) {
+	var status Status
+	if err := c.do(context.Background(), http.MethodPost, "/pause", &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Resume lets a paused instance start new tasks again.
+func (c *Client) Resume() (*Status, error) {
+	var status Status
+	if err := c.do(context.Background(), http.MethodPost, "/resume", &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Tail streams output lines for the given task to fn until the task
+// finishes or ctx is cancelled.
+func (c *Client) Tail(ctx context.Context, taskID string, fn func(agent.OutputLine)) error {
+	resp, err := c.send(ctx, http.MethodGet, "/tasks/"+url.PathEscape(taskID)+"/tail")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var line agent.OutputLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return fmt.Errorf("failed to decode output line: %w", err)
+		}
+		fn(line)
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, result interface{}) error {
+	resp, err := c.send(ctx, method, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// send performs a request and converts error responses into errors.
+func (c *Client) send(ctx context.Context, method, path string) (*http.Response, error) {
+	return c.sendBody(ctx, method, path, nil)
+}
+
+// sendBody is send with a JSON request body.
+func (c *Client) sendBody(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.ECONNREFUSED) {
+			return nil, fmt.Errorf("%w (%s)", ErrNotRunning, c.where)
+		}
+		return nil, fmt.Errorf("failed to reach %s: %w", c.where, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		var e errorResponse
+		if json.Unmarshal(bytes.TrimSpace(body), &e) != nil || e.Error == "" {
+			e.Error = fmt.Sprintf("control request failed with status %d", resp.StatusCode)
+		}
+		return nil, &remoteError{status: resp.StatusCode, message: e.Error}
+	}
+
+	return resp, nil
+}
+
+// remoteError carries an error reported by the control server.
+type remoteError struct {
+	status  int
+	message string
+}
+
+func (e *remoteError) Error() string {
+	return e.message
+}
+
+func (e *remoteError) Unwrap() error {
+	if e.status == http.StatusNotFound {
+		return ErrTaskNotFound
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// Package control provides a local control socket for a running Momentum
+// instance. The server side exposes the instance state over HTTP on a unix
+// domain socket, and the client side is used by CLI subcommands such as
+// `momentum status` to inspect and steer the instance from another terminal.
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/pool"
+)
+
+// ErrTaskNotFound is returned when a control request references a task that
+// has no running agent.
+var ErrTaskNotFound = errors.New("task not found")
+
+// ContinueRequest is the body of a request to continue a task.
+type ContinueRequest struct {
+	Instructions string `json:"instructions,omitempty"`
+}
+
+// ReviewRequest is the body of a request to approve or reject a task
+// awaiting review.
+type ReviewRequest struct {
+	Comment string `json:"comment,omitempty"`
+}
+
+// ErrNotRunning is returned by the client when no instance is listening on
+// the control socket.
+var ErrNotRunning = errors.New("no momentum instance is running")
+
+// Status describes the current state of a Momentum instance.
+type Status struct {
+	PID       int    `json:"pid"`
+	Criteria  string `json:"criteria"`
+	Mode      string `json:"mode"`
+	Paused    bool   `json:"paused"`
+	Connected bool   `json:"connected"`
+	LastError string `json:"last_error,omitempty"`
+	WorkDir   string `json:"workdir"`
+	// Sandbox describes the restrictions agents run under
+	Sandbox        string       `json:"sandbox,omitempty"`
+	TasksCompleted int          `json:"tasks_completed"`
+	Running        []TaskStatus `json:"running"`
+	Queued         []TaskStatus `json:"queued"`
+	// Finished lists the most recently finished tasks, newest last
+	Finished []TaskStatus `json:"finished,omitempty"`
+	// Runners lists the remote runners connected to a coordinator
+	Runners []pool.RunnerStatus `json:"runners,omitempty"`
+	// Reviews lists successful runs waiting for a reviewer
+	Reviews []ReviewStatus `json:"reviews,omitempty"`
+}
+
+// ReviewStatus describes a task waiting for a reviewer to approve or reject
+// its agent's work.
+type ReviewStatus struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	// Reason says why the task needs approval
+	Reason string `json:"reason"`
+	// Changes summarises the files the agent changed
+	Changes     []string  `json:"changes,omitempty"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// TaskStatus describes a running or queued task.
+type TaskStatus struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	PID       int       `json:"pid,omitempty"`
+	StartedAt time.Time `json:"started_at,omitempty"`
+	// ExitCode is set once the task's agent has exited
+	ExitCode *int `json:"exit_code,omitempty"`
+}
+
+// Backend is implemented by the running instance to answer control requests.
+type Backend interface {
+	// Status returns a snapshot of the instance state
+	Status() Status
+	// StopTask stops the agent running the given task
+	StopTask(taskID string) error
+	// SetPaused pauses or resumes selection of new tasks
+	SetPaused(paused bool)
+	// ContinueTask resumes the agent session of a task that is not running,
+	// with optional extra instructions for the agent
+	ContinueTask(taskID, instructions string) error
+	// ApproveTask marks a task awaiting review as done
+	ApproveTask(taskID, comment string) error
+	// RejectTask sends a task awaiting review back to the queue, passing the
+	// reviewer's comment to its next run
+	RejectTask(taskID, comment string) error
+	// Tail streams output lines for the given task by calling send until the
+	// task finishes, ctx is cancelled or send returns an error
+	Tail(ctx context.Context, taskID string, send func(agent.OutputLine) error) error
+}
+
+// DefaultSocketPath returns the control socket path used when none is
+// configured. It prefers $XDG_RUNTIME_DIR and falls back to the temp dir.
+func DefaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "momentum.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("momentum-%d.sock", os.Getuid()))
+}
@@ -0,0 +1,332 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/pool"
+)
+
+// fakeBackend is an in-memory Backend for testing.
+type fakeBackend struct {
+	mu      sync.Mutex
+	paused  bool
+	stopped []string
+	// continued maps continued tasks to their instructions
+	continued map[string]string
+	// reviewed maps reviewed tasks to "approve: comment" or "reject: comment"
+	reviewed map[string]string
+	lines    []agent.OutputLine
+}
+
+func (f *fakeBackend) Status() Status {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return Status{
+		PID:       42,
+		Criteria:  "Project: demo",
+		Mode:      "async",
+		Paused:    f.paused,
+		Connected: true,
+		Running:   []TaskStatus{{ID: "task-1", Title: "Fix bug", PID: 100}},
+		Queued:    []TaskStatus{{ID: "task-2", Title: "Next"}},
+	}
+}
+
+func (f *fakeBackend) StopTask(taskID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if taskID != "task-1" {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+	f.stopped = append(f.stopped, taskID)
+	return nil
+}
+
+func (f *fakeBackend) ContinueTask(taskID, instructions string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if taskID != "task-3" {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+	if f.continued == nil {
+		f.continued = make(map[string]string)
+	}
+	f.continued[taskID] = instructions
+	return nil
+}
+
+func (f *fakeBackend) ApproveTask(taskID, comment string) error {
+	return f.review(taskID, "approve: "+comment)
+}
+
+func (f *fakeBackend) RejectTask(taskID, comment string) error {
+	return f.review(taskID, "reject: "+comment)
+}
+
+func (f *fakeBackend) review(taskID, decision string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if taskID != "task-4" {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+	if f.reviewed == nil {
+		f.reviewed = make(map[string]string)
+	}
+	f.reviewed[taskID] = decision
+	return nil
+}
+
+func (f *fakeBackend) SetPaused(paused bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paused = paused
+}
+
+func (f *fakeBackend) Tail(ctx context.Context, taskID string, send func(agent.OutputLine) error) error {
+	if taskID != "task-1" {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+	for _, line := range f.lines {
+		if err := send(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startTestServer starts a control server on a temporary socket.
+func startTestServer(t *testing.T, backend Backend) *Client {
+	t.Helper()
+	// Keep the path short; unix socket paths are limited to ~100 bytes
+	dir, err := os.MkdirTemp("", "mctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "m.sock")
+	server := NewServer(path, backend)
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	return NewClient(path)
+}
+
+func TestClient_Status(t *testing.T) {
+	c := startTestServer(t, &fakeBackend{})
+
+	status, err := c.Status()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.PID != 42 {
+		t.Errorf("expected pid 42, got %d", status.PID)
+	}
+	if len(status.Running) != 1 || status.Running[0].ID != "task-1" {
+		t.Errorf("unexpected running tasks: %+v", status.Running)
+	}
+	if len(status.Queued) != 1 || status.Queued[0].ID != "task-2" {
+		t.Errorf("unexpected queued tasks: %+v", status.Queued)
+	}
+}
+
+func TestClient_PauseResume(t *testing.T) {
+	backend := &fakeBackend{}
+	c := startTestServer(t, backend)
+
+	status, err := c.Pause()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !status.Paused {
+		t.Error("expected paused status after Pause")
+	}
+
+	status, err = c.Resume()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.Paused {
+		t.Error("expected resumed status after Resume")
+	}
+}
+
+func TestClient_Stop(t *testing.T) {
+	backend := &fakeBackend{}
+	c := startTestServer(t, backend)
+
+	if err := c.Stop("task-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(backend.stopped) != 1 || backend.stopped[0] != "task-1" {
+		t.Errorf("expected task-1 to be stopped, got %v", backend.stopped)
+	}
+}
+
+func TestClient_StopUnknownTask(t *testing.T) {
+	c := startTestServer(t, &fakeBackend{})
+
+	err := c.Stop("task-9")
+	if !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestClient_Continue(t *testing.T) {
+	backend := &fakeBackend{}
+	c := startTestServer(t, backend)
+
+	if err := c.Continue("task-3", "also update the docs"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := backend.continued["task-3"]; got != "also update the docs" {
+		t.Errorf("expected the instructions to reach the backend, got %q", got)
+	}
+	if err := c.Continue("task-9", ""); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestClient_Review(t *testing.T) {
+	backend := &fakeBackend{}
+	c := startTestServer(t, backend)
+
+	if err := c.Approve("task-4", "looks good"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := backend.reviewed["task-4"]; got != "approve: looks good" {
+		t.Errorf("expected the approval to reach the backend, got %q", got)
+	}
+	if err := c.Reject("task-4", "add tests"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := backend.reviewed["task-4"]; got != "reject: add tests" {
+		t.Errorf("expected the rejection to reach the backend, got %q", got)
+	}
+	if err := c.Approve("task-9", ""); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestClient_Tail(t *testing.T) {
+	backend := &fakeBackend{lines: []agent.OutputLine{
+		{Text: "line one", Timestamp: time.Now()},
+		{Text: "line two", IsStderr: true, Timestamp: time.Now()},
+	}}
+	c := startTestServer(t, backend)
+
+	var got []agent.OutputLine
+	err := c.Tail(context.Background(), "task-1", func(line agent.OutputLine) {
+		got = append(got, line)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(got))
+	}
+	if got[0].Text != "line one" || !got[1].IsStderr {
+		t.Errorf("unexpected lines: %+v", got)
+	}
+}
+
+func TestClient_TailUnknownTask(t *testing.T) {
+	c := startTestServer(t, &fakeBackend{})
+
+	err := c.Tail(context.Background(), "task-9", func(agent.OutputLine) {})
+	if !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestClient_NotRunning(t *testing.T) {
+	dir := t.TempDir()
+	c := NewClient(filepath.Join(dir, "missing.sock"))
+
+	_, err := c.Status()
+	if !errors.Is(err, ErrNotRunning) {
+		t.Errorf("expected ErrNotRunning, got %v", err)
+	}
+}
+
+func TestServer_StartRejectsLiveSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "m.sock")
+
+	first := NewServer(path, &fakeBackend{})
+	if err := first.Start(); err != nil {
+		t.Fatalf("failed to start first server: %v", err)
+	}
+	defer first.Close()
+
+	second := NewServer(path, &fakeBackend{})
+	if err := second.Start(); err == nil {
+		second.Close()
+		t.Error("expected error when socket is already served")
+	}
+}
+
+func TestServer_StartReplacesStaleSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "m.sock")
+
+	// Leave a stale file behind as a crashed instance would
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(path, &fakeBackend{})
+	if err := server.Start(); err != nil {
+		t.Fatalf("expected stale socket to be replaced, got %v", err)
+	}
+	server.Close()
+}
+
+func TestRemoteClient_Token(t *testing.T) {
+	srv := httptest.NewServer(pool.Authorize("secret", NewServer("", &fakeBackend{}).Handler()))
+	defer srv.Close()
+
+	status, err := NewRemoteClient(srv.URL+"/", "secret").Status()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.PID != 42 {
+		t.Errorf("expected pid 42, got %d", status.PID)
+	}
+
+	if _, err := NewRemoteClient(srv.URL, "wrong").Status(); err == nil || !strings.Contains(err.Error(), "token") {
+		t.Errorf("expected a token error, got %v", err)
+	}
+}
+
+func TestRemoteClient_NotRunning(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	_, err := NewRemoteClient(url, "").Status()
+	if !errors.Is(err, ErrNotRunning) {
+		t.Errorf("expected ErrNotRunning, got %v", err)
+	}
+}
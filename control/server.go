@@ -0,0 +1,173 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/sirsjg/momentum/agent"
+)
+
+// Server serves control requests on a unix domain socket.
+type Server struct {
+	path    string
+	backend Backend
+	server  *http.Server
+	ln      net.Listener
+}
+
+// NewServer creates a control server for the given socket path and backend.
+func NewServer(path string, backend Backend) *Server {
+	s := &Server{
+		path:    path,
+		backend: backend,
+	}
+	s.server = &http.Server{Handler: s.Handler()}
+	return s
+}
+
+// Handler returns the HTTP handler serving the control API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("POST /pause", s.handlePause(true))
+	mux.HandleFunc("POST /resume", s.handlePause(false))
+	mux.HandleFunc("POST /tasks/{id}/stop", s.handleStop)
+	mux.HandleFunc("POST /tasks/{id}/continue", s.handleContinue)
+	mux.HandleFunc("POST /tasks/{id}/approve", s.handleReview(true))
+	mux.HandleFunc("POST /tasks/{id}/reject", s.handleReview(false))
+	mux.HandleFunc("GET /tasks/{id}/tail", s.handleTail)
+	return mux
+}
+
+// Start begins listening on the socket. A stale socket left behind by a
+// crashed instance is removed; a live one results in an error.
+func (s *Server) Start() error {
+	if conn, err := net.DialTimeout("unix", s.path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("control socket %s is in use by another instance", s.path)
+	}
+	os.Remove(s.path)
+
+	ln, err := net.Listen("unix", s.path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on control socket: %w", err)
+	}
+	s.ln = ln
+
+	go s.server.Serve(ln)
+	return nil
+}
+
+// Close stops the server and removes the socket file.
+func (s *Server) Close() error {
+	err := s.server.Close()
+	if s.ln != nil {
+		os.Remove(s.path)
+	}
+	return err
+}
+
+// Path returns the socket path.
+func (s *Server) Path() string {
+	return s.path
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.backend.Status(), http.StatusOK)
+}
+
+func (s *Server) handlePause(paused bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.backend.SetPaused(paused)
+		writeJSON(w, s.backend.Status(), http.StatusOK)
+	}
+}
+
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	if err := s.backend.StopTask(r.PathValue("id")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleContinue(w http.ResponseWriter, r *http.Request) {
+	var req ContinueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeJSON(w, errorResponse{Error: "invalid request body: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if err := s.backend.ContinueTask(r.PathValue("id"), req.Instructions); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) handleReview(approve bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ReviewRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeJSON(w, errorResponse{Error: "invalid request body: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		review := s.backend.RejectTask
+		if approve {
+			review = s.backend.ApproveTask
+		}
+		if err := review(r.PathValue("id"), req.Comment); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleTail streams output lines as newline-delimited JSON.
+func (s *Server) handleTail(w http.ResponseWriter, r *http.Request) {
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	started := false
+
+	err := s.backend.Tail(r.Context(), r.PathValue("id"), func(line agent.OutputLine) error {
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil && !started {
+		writeError(w, err)
+	}
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, ErrTaskNotFound) {
+		status = http.StatusNotFound
+	}
+	writeJSON(w, errorResponse{Error: err.Error()}, status)
+}
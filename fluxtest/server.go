@@ -0,0 +1,597 @@
+// Package fluxtest provides an in-process Flux server for tests.
+//
+// The server keeps projects, epics, tasks and comments in memory and
+// implements the REST endpoints used by client.Client and the event stream
+// used by sse.Subscriber, so Momentum's task loop can run end to end without
+// a real Flux instance. Every change is broadcast to subscribers as a
+// "<kind>.<change>" event whose payload carries the changed object and, for
+// tasks, their epic.
+package fluxtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sirsjg/momentum/client"
+)
+
+// Default statuses for new epics and tasks
+const (
+	EpicStatus = "planning"
+	TaskStatus = "todo"
+)
+
+// Server is a fake Flux server listening on a local port.
+type Server struct {
+	// URL is the base URL, e.g. http://127.0.0.1:53412
+	URL string
+
+	srv    *httptest.Server
+	closed chan struct{}
+
+	mu          sync.Mutex
+	nextID      int
+	projects    []client.Project
+	epics       []client.Epic
+	tasks       []client.Task
+	comments    []client.Comment
+	history     map[string][]string
+	failures    map[string]int
+	subscribers map[*subscriber]struct{}
+}
+
+// subscriber is one open event stream.
+type subscriber struct {
+	events chan event
+	drop   chan struct{}
+}
+
+type event struct {
+	typ  string
+	data []byte
+}
+
+// NewServer starts a server with no data. Call Close when done.
+func NewServer() *Server {
+	s := &Server{
+		closed:      make(chan struct{}),
+		history:     make(map[string][]string),
+		failures:    make(map[string]int),
+		subscribers: make(map[*subscriber]struct{}),
+	}
+	s.srv = httptest.NewServer(s.Handler())
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close ends open event streams and shuts the server down.
+func (s *Server) Close() {
+	close(s.closed)
+	s.srv.Close()
+}
+
+// Handler returns the server's HTTP handler.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/projects", s.listProjects)
+	mux.HandleFunc("POST /api/projects", s.createProject)
+	mux.HandleFunc("PATCH /api/projects/{id}", s.updateProject)
+	mux.HandleFunc("DELETE /api/projects/{id}", s.deleteProject)
+	mux.HandleFunc("GET /api/projects/{id}/epics", s.listEpics)
+	mux.HandleFunc("POST /api/projects/{id}/epics", s.createEpic)
+	mux.HandleFunc("PATCH /api/epics/{id}", s.updateEpic)
+	mux.HandleFunc("DELETE /api/epics/{id}", s.deleteEpic)
+	mux.HandleFunc("GET /api/projects/{id}/tasks", s.listTasks)
+	mux.HandleFunc("POST /api/projects/{id}/tasks", s.createTask)
+	mux.HandleFunc("PATCH /api/tasks/{id}", s.updateTask)
+	mux.HandleFunc("POST /api/tasks/status", s.moveTasks)
+	mux.HandleFunc("DELETE /api/tasks/{id}", s.deleteTask)
+	mux.HandleFunc("POST /api/tasks/{id}/comments", s.addComment)
+	mux.HandleFunc("GET /api/events", s.events)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		status := s.failures[r.Method+" "+r.URL.Path]
+		s.mu.Unlock()
+		if status != 0 {
+			http.Error(w, "injected failure", status)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// Fail makes requests for method and path (without query) fail with status
+// until called again with status 0.
+func (s *Server) Fail(method, path string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if status == 0 {
+		delete(s.failures, method+" "+path)
+	} else {
+		s.failures[method+" "+path] = status
+	}
+}
+
+// AddProject stores a project, assigning an ID if it has none.
+func (s *Server) AddProject(p client.Project) client.Project {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p.ID == "" {
+		p.ID = s.newID("project")
+	}
+	s.projects = append(s.projects, p)
+	s.publish("project.created", map[string]any{"project": p})
+	return p
+}
+
+// AddEpic stores an epic, assigning an ID and status if it has none.
+func (s *Server) AddEpic(e client.Epic) client.Epic {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e.ID == "" {
+		e.ID = s.newID("epic")
+	}
+	if e.Status == "" {
+		e.Status = EpicStatus
+	}
+	s.epics = append(s.epics, e)
+	s.publish("epic.created", map[string]any{"epic": e})
+	return e
+}
+
+// AddTask stores a task, assigning an ID and status if it has none.
+func (s *Server) AddTask(t client.Task) client.Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.ID == "" {
+		t.ID = s.newID("task")
+	}
+	if t.Status == "" {
+		t.Status = TaskStatus
+	}
+	s.tasks = append(s.tasks, t)
+	s.history[t.ID] = []string{t.Status}
+	s.publishTask("task.created", t)
+	return s.withBlocked(t)
+}
+
+// Task returns the current state of a task.
+func (s *Server) Task(id string) (client.Task, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.taskIndex(id); i >= 0 {
+		return s.withBlocked(s.tasks[i]), true
+	}
+	return client.Task{}, false
+}
+
+// StatusHistory returns every status a task has had, oldest first.
+func (s *Server) StatusHistory(id string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.history[id])
+}
+
+// Comments returns the comments added to a task, oldest first.
+func (s *Server) Comments(taskID string) []client.Comment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var comments []client.Comment
+	for _, c := range s.comments {
+		if c.TaskID == taskID {
+			comments = append(comments, c)
+		}
+	}
+	return comments
+}
+
+// Subscribers returns the number of open event streams.
+func (s *Server) Subscribers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subscribers)
+}
+
+// DropSubscribers closes every open event stream, as a server restart would.
+func (s *Server) DropSubscribers() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subscribers {
+		close(sub.drop)
+		delete(s.subscribers, sub)
+	}
+}
+
+// --- Projects ---
+
+func (s *Server) listProjects(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, nonNil(s.projects))
+}
+
+func (s *Server) createProject(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	writeJSON(w, http.StatusCreated, s.AddProject(client.Project{Name: body.Name, Description: body.Description}))
+}
+
+func (s *Server) updateProject(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.projects, func(p client.Project) bool { return p.ID == r.PathValue("id") })
+	if i < 0 {
+		http.Error(w, "project not found", http.StatusNotFound)
+		return
+	}
+	if body.Name != nil {
+		s.projects[i].Name = *body.Name
+	}
+	if body.Description != nil {
+		s.projects[i].Description = *body.Description
+	}
+	s.publish("project.updated", map[string]any{"project": s.projects[i]})
+	writeJSON(w, http.StatusOK, s.projects[i])
+}
+
+func (s *Server) deleteProject(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := r.PathValue("id")
+	i := slices.IndexFunc(s.projects, func(p client.Project) bool { return p.ID == id })
+	if i < 0 {
+		http.Error(w, "project not found", http.StatusNotFound)
+		return
+	}
+	project := s.projects[i]
+	s.projects = slices.Delete(s.projects, i, i+1)
+	s.epics = slices.DeleteFunc(s.epics, func(e client.Epic) bool { return e.ProjectID == id })
+	s.tasks = slices.DeleteFunc(s.tasks, func(t client.Task) bool { return t.ProjectID == id })
+	s.publish("project.deleted", map[string]any{"project": project})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Epics ---
+
+func (s *Server) listEpics(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	epics := []client.Epic{}
+	for _, e := range s.epics {
+		if e.ProjectID == r.PathValue("id") {
+			epics = append(epics, e)
+		}
+	}
+	writeJSON(w, http.StatusOK, epics)
+}
+
+func (s *Server) createEpic(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Title string `json:"title"`
+		Notes string `json:"notes"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	writeJSON(w, http.StatusCreated, s.AddEpic(client.Epic{
+		Title:     body.Title,
+		Notes:     body.Notes,
+		ProjectID: r.PathValue("id"),
+	}))
+}
+
+func (s *Server) updateEpic(w http.ResponseWriter, r *http.Request) {
+	var body client.EpicUpdate
+	if !readJSON(w, r, &body) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.epicIndex(r.PathValue("id"))
+	if i < 0 {
+		http.Error(w, "epic not found", http.StatusNotFound)
+		return
+	}
+	e := &s.epics[i]
+	if body.Title != nil {
+		e.Title = *body.Title
+	}
+	if body.Notes != nil {
+		e.Notes = *body.Notes
+	}
+	if body.Status != nil {
+		e.Status = *body.Status
+	}
+	if body.DependsOn != nil {
+		e.DependsOn = *body.DependsOn
+	}
+	s.publish("epic.updated", map[string]any{"epic": *e})
+	writeJSON(w, http.StatusOK, *e)
+}
+
+func (s *Server) deleteEpic(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.epicIndex(r.PathValue("id"))
+	if i < 0 {
+		http.Error(w, "epic not found", http.StatusNotFound)
+		return
+	}
+	epic := s.epics[i]
+	s.epics = slices.Delete(s.epics, i, i+1)
+	s.publish("epic.deleted", map[string]any{"epic": epic})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Tasks ---
+
+func (s *Server) listTasks(w http.ResponseWriter, r *http.Request) {
+	epicID, status := r.URL.Query().Get("epic_id"), r.URL.Query().Get("status")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tasks := []client.Task{}
+	for _, t := range s.tasks {
+		if t.ProjectID != r.PathValue("id") ||
+			(epicID != "" && t.EpicID != epicID) ||
+			(status != "" && t.Status != status) {
+			continue
+		}
+		tasks = append(tasks, s.withBlocked(t))
+	}
+	writeJSON(w, http.StatusOK, tasks)
+}
+
+func (s *Server) createTask(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Title  string `json:"title"`
+		Notes  string `json:"notes"`
+		EpicID string `json:"epic_id"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	writeJSON(w, http.StatusCreated, s.AddTask(client.Task{
+		Title:     body.Title,
+		Notes:     body.Notes,
+		EpicID:    body.EpicID,
+		ProjectID: r.PathValue("id"),
+	}))
+}
+
+func (s *Server) updateTask(w http.ResponseWriter, r *http.Request) {
+	var body client.TaskUpdate
+	if !readJSON(w, r, &body) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.taskIndex(r.PathValue("id"))
+	if i < 0 {
+		http.Error(w, "task not found", http.StatusNotFound)
+		return
+	}
+	t := &s.tasks[i]
+	if body.Title != nil {
+		t.Title = *body.Title
+	}
+	if body.Notes != nil {
+		t.Notes = *body.Notes
+	}
+	if body.EpicID != nil {
+		t.EpicID = *body.EpicID
+	}
+	if body.DependsOn != nil {
+		t.DependsOn = *body.DependsOn
+	}
+	if body.Status == nil || !s.setStatus(t, *body.Status) {
+		s.publishTask("task.updated", *t)
+	}
+	writeJSON(w, http.StatusOK, s.withBlocked(*t))
+}
+
+// moveTasks is the batch status endpoint: every task is moved that exists,
+// with one result per requested ID.
+func (s *Server) moveTasks(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		IDs    []string `json:"ids"`
+		Status string   `json:"status"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := make([]client.BatchMoveResult, 0, len(body.IDs))
+	for _, id := range body.IDs {
+		i := s.taskIndex(id)
+		if i < 0 {
+			results = append(results, client.BatchMoveResult{ID: id, Code: http.StatusNotFound, Error: "task not found"})
+			continue
+		}
+		t := &s.tasks[i]
+		s.setStatus(t, body.Status)
+		moved := s.withBlocked(*t)
+		results = append(results, client.BatchMoveResult{ID: id, Task: &moved})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"results": results})
+}
+
+// setStatus changes a task's status, recording and broadcasting the change.
+// It reports whether the status changed; callers hold s.mu.
+func (s *Server) setStatus(t *client.Task, status string) bool {
+	if status == t.Status {
+		return false
+	}
+	t.Status = status
+	s.history[t.ID] = append(s.history[t.ID], t.Status)
+	s.publishTask("task.status_changed", *t)
+	return true
+}
+
+func (s *Server) deleteTask(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.taskIndex(r.PathValue("id"))
+	if i < 0 {
+		http.Error(w, "task not found", http.StatusNotFound)
+		return
+	}
+	task := s.tasks[i]
+	s.tasks = slices.Delete(s.tasks, i, i+1)
+	s.publishTask("task.deleted", task)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) addComment(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Body string `json:"body"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.taskIndex(r.PathValue("id")) < 0 {
+		http.Error(w, "task not found", http.StatusNotFound)
+		return
+	}
+	comment := client.Comment{
+		ID:        s.newID("comment"),
+		TaskID:    r.PathValue("id"),
+		Body:      body.Body,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	s.comments = append(s.comments, comment)
+	writeJSON(w, http.StatusCreated, comment)
+}
+
+// --- Events ---
+
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	sub := &subscriber{events: make(chan event, 100), drop: make(chan struct{})}
+	s.mu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, sub)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.closed:
+			return
+		case <-sub.drop:
+			return
+		case e := <-sub.events:
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.typ, e.data)
+			flusher.Flush()
+		}
+	}
+}
+
+// publishTask broadcasts a task change along with the task's epic, which
+// Momentum checks for auto mode.
+func (s *Server) publishTask(typ string, t client.Task) {
+	payload := map[string]any{"task": s.withBlocked(t)}
+	if i := s.epicIndex(t.EpicID); i >= 0 {
+		payload["epic"] = s.epics[i]
+	}
+	s.publish(typ, payload)
+}
+
+// publish sends an event to every subscriber; callers hold s.mu. Slow
+// subscribers miss events rather than block the server.
+func (s *Server) publish(typ string, payload map[string]any) {
+	payload["type"] = typ
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	for sub := range s.subscribers {
+		select {
+		case sub.events <- event{typ: typ, data: data}:
+		default:
+		}
+	}
+}
+
+// --- Helpers ---
+
+func (s *Server) newID(kind string) string {
+	s.nextID++
+	return fmt.Sprintf("%s-%d", kind, s.nextID)
+}
+
+func (s *Server) epicIndex(id string) int {
+	return slices.IndexFunc(s.epics, func(e client.Epic) bool { return e.ID == id })
+}
+
+func (s *Server) taskIndex(id string) int {
+	return slices.IndexFunc(s.tasks, func(t client.Task) bool { return t.ID == id })
+}
+
+// withBlocked sets Blocked like Flux does: a task is blocked while any task
+// it depends on is not done.
+func (s *Server) withBlocked(t client.Task) client.Task {
+	t.Blocked = false
+	for _, dep := range t.DependsOn {
+		if i := s.taskIndex(dep); i >= 0 && s.tasks[i].Status != "done" {
+			t.Blocked = true
+		}
+	}
+	return t
+}
+
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,181 @@
+package fluxtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/sse"
+)
+
+func TestServer_REST(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	c := client.NewClient(s.URL)
+
+	project, err := c.CreateProject("demo", "a project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	epic, err := c.CreateEpic(project.ID, "auth", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if epic.Status != EpicStatus || epic.ProjectID != project.ID {
+		t.Errorf("unexpected epic %+v", epic)
+	}
+	first, err := c.CreateTask(project.ID, "schema", "", epic.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.CreateTask(project.ID, "login", "notes", epic.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UpdateTask(second.ID, client.TaskUpdate{DependsOn: client.StringSlicePtr([]string{first.ID})}); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := c.ListTasks(project.ID, client.TaskFilters{EpicID: &epic.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 || tasks[0].Blocked || !tasks[1].Blocked {
+		t.Fatalf("expected the dependent task to be blocked, got %+v", tasks)
+	}
+
+	if _, err := c.MoveTaskStatus(first.ID, "in_progress"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.MoveTaskStatus(first.ID, "done"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.Task(second.ID); got.Blocked {
+		t.Error("expected the dependent task to be unblocked")
+	}
+	if got := s.StatusHistory(first.ID); !slices.Equal(got, []string{"todo", "in_progress", "done"}) {
+		t.Errorf("unexpected status history %v", got)
+	}
+
+	todo := "todo"
+	if tasks, _ := c.ListTasks(project.ID, client.TaskFilters{Status: &todo}); len(tasks) != 1 || tasks[0].ID != second.ID {
+		t.Errorf("expected the status filter to apply, got %+v", tasks)
+	}
+
+	if _, err := c.AddComment(second.ID, "looks good"); err != nil {
+		t.Fatal(err)
+	}
+	if comments := s.Comments(second.ID); len(comments) != 1 || comments[0].Body != "looks good" {
+		t.Errorf("unexpected comments %+v", comments)
+	}
+
+	if err := c.DeleteTask(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Task(first.ID); ok {
+		t.Error("expected the task to be deleted")
+	}
+	var apiErr *client.APIError
+	if _, err := c.UpdateTask(first.ID, client.TaskUpdate{}); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected a 404 for a deleted task, got %v", err)
+	}
+}
+
+func TestServer_MoveTasks(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	c := client.NewClient(s.URL)
+
+	project := s.AddProject(client.Project{Name: "demo"})
+	first := s.AddTask(client.Task{Title: "one", ProjectID: project.ID})
+	second := s.AddTask(client.Task{Title: "two", ProjectID: project.ID})
+
+	results, err := c.MoveTasksStatus([]string{first.ID, "task-missing", second.ID}, "in_progress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Err() != nil || results[2].Err() != nil || results[1].Err() == nil {
+		t.Errorf("expected only the missing task to fail, got %+v", results)
+	}
+	if got := s.StatusHistory(second.ID); !slices.Equal(got, []string{"todo", "in_progress"}) {
+		t.Errorf("unexpected status history %v", got)
+	}
+}
+
+func TestServer_Fail(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	c := client.NewClient(s.URL)
+
+	s.Fail(http.MethodGet, "/api/projects", http.StatusServiceUnavailable)
+	var apiErr *client.APIError
+	if _, err := c.ListProjects(); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected the injected failure, got %v", err)
+	}
+
+	s.Fail(http.MethodGet, "/api/projects", 0)
+	if projects, err := c.ListProjects(); err != nil || len(projects) != 0 {
+		t.Errorf("expected no projects, got %v (%v)", projects, err)
+	}
+}
+
+func TestServer_Events(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub := sse.NewSubscriber(s.URL)
+	events := sub.Start(ctx)
+	defer sub.Stop()
+	waitFor(t, "subscriber", func() bool { return s.Subscribers() == 1 })
+
+	project := s.AddProject(client.Project{Name: "demo"})
+	epic := s.AddEpic(client.Epic{Title: "auto", ProjectID: project.ID, Auto: true})
+	task := s.AddTask(client.Task{Title: "work", ProjectID: project.ID, EpicID: epic.ID})
+
+	for _, want := range []string{"project.created", "epic.created", "task.created"} {
+		select {
+		case e := <-events:
+			if e.Type != want {
+				t.Fatalf("expected %s, got %s", want, e.Type)
+			}
+			if want != "task.created" {
+				continue
+			}
+			var data struct {
+				Task client.Task `json:"task"`
+				Epic client.Epic `json:"epic"`
+			}
+			if err := json.Unmarshal([]byte(e.Data), &data); err != nil {
+				t.Fatal(err)
+			}
+			if data.Task.ID != task.ID || !data.Epic.Auto {
+				t.Errorf("expected the task and its auto epic, got %s", e.Data)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected a %s event", want)
+		}
+	}
+
+	s.DropSubscribers()
+	if s.Subscribers() != 0 {
+		t.Error("expected streams to be dropped")
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
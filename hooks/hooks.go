@@ -0,0 +1,209 @@
+// Package hooks runs user-configured shell commands at points in a task's
+// lifecycle, such as installing dependencies before an agent starts or
+// running the test suite after it finishes.
+//
+// Commands run in the task's working directory with task metadata exposed as
+// MOMENTUM_* environment variables. Commands for an event run in order and
+// stop at the first failure.
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sirsjg/momentum/shell"
+	"github.com/sirsjg/momentum/tracing"
+)
+
+// Event identifies a point in the task lifecycle.
+type Event string
+
+const (
+	// PreSelect runs before the worker looks for a new task
+	PreSelect Event = "pre-select"
+	// PreRun runs after a task is claimed, before its agent starts
+	PreRun Event = "pre-run"
+	// PostRun runs after the agent exits, unless it was stopped by the user.
+	// A failing post-run hook vetoes marking the task complete.
+	PostRun Event = "post-run"
+	// OnSuccess runs after a task has been marked complete
+	OnSuccess Event = "on-success"
+	// OnFailure runs when the agent fails or a post-run hook vetoes completion
+	OnFailure Event = "on-failure"
+	// OnStop runs when the user stops an agent
+	OnStop Event = "on-stop"
+)
+
+// DefaultTimeout bounds each hook command when no timeout is configured.
+const DefaultTimeout = 10 * time.Minute
+
+// Config lists the commands to run for each event.
+type Config struct {
+	PreSelect []string `json:"pre-select,omitempty"`
+	PreRun    []string `json:"pre-run,omitempty"`
+	PostRun   []string `json:"post-run,omitempty"`
+	OnSuccess []string `json:"on-success,omitempty"`
+	OnFailure []string `json:"on-failure,omitempty"`
+	OnStop    []string `json:"on-stop,omitempty"`
+	// Timeout bounds each command, as a Go duration string (default 10m)
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// commands returns the configured commands for event.
+func (c Config) commands(event Event) []string {
+	switch event {
+	case PreSelect:
+		return c.PreSelect
+	case PreRun:
+		return c.PreRun
+	case PostRun:
+		return c.PostRun
+	case OnSuccess:
+		return c.OnSuccess
+	case OnFailure:
+		return c.OnFailure
+	case OnStop:
+		return c.OnStop
+	}
+	return nil
+}
+
+// TaskInfo describes the task a hook runs for. All fields are optional;
+// pre-select hooks have no task.
+type TaskInfo struct {
+	ID        string
+	Title     string
+	ProjectID string
+	EpicID    string
+	WorkDir   string
+	// ExitCode is the agent's exit code, set for hooks that run after the agent
+	ExitCode *int
+}
+
+// env returns the MOMENTUM_* variables for the hook.
+func (t TaskInfo) env(event Event) []string {
+	vars := []string{
+		"MOMENTUM_HOOK=" + string(event),
+		"MOMENTUM_TASK_ID=" + t.ID,
+		"MOMENTUM_TASK_TITLE=" + t.Title,
+		"MOMENTUM_PROJECT_ID=" + t.ProjectID,
+		"MOMENTUM_EPIC_ID=" + t.EpicID,
+		"MOMENTUM_WORKDIR=" + t.WorkDir,
+	}
+	if t.ExitCode != nil {
+		vars = append(vars, "MOMENTUM_EXIT_CODE="+strconv.Itoa(*t.ExitCode))
+	}
+	return vars
+}
+
+// Error reports a failed hook command.
+type Error struct {
+	Event   Event
+	Command string
+	// ExitCode is the command's exit code, or -1 if it could not be run
+	ExitCode int
+	// Output is the tail of the command's combined stdout and stderr
+	Output string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.ExitCode >= 0 {
+		return fmt.Sprintf("%s hook %q failed with exit code %d", e.Event, e.Command, e.ExitCode)
+	}
+	return fmt.Sprintf("%s hook %q failed: %v", e.Event, e.Command, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Runner executes configured hooks. A nil *Runner runs nothing.
+type Runner struct {
+	cfg     Config
+	timeout time.Duration
+}
+
+// NewRunner validates cfg and returns a runner for it.
+func NewRunner(cfg Config) (*Runner, error) {
+	timeout := DefaultTimeout
+	if cfg.Timeout != "" {
+		d, err := time.ParseDuration(cfg.Timeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid hook timeout %q", cfg.Timeout)
+		}
+		timeout = d
+	}
+	return &Runner{cfg: cfg, timeout: timeout}, nil
+}
+
+// Enabled reports whether commands are configured for any event.
+func (r *Runner) Enabled() bool {
+	for _, event := range []Event{PreSelect, PreRun, PostRun, OnSuccess, OnFailure, OnStop} {
+		if r.Has(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// Has reports whether any commands are configured for event.
+func (r *Runner) Has(event Event) bool {
+	return r != nil && len(r.cfg.commands(event)) > 0
+}
+
+// Run executes the commands for event in order, stopping at the first
+// failure, which is returned as an *Error. Each line of output is passed to
+// output if it is non-nil.
+func (r *Runner) Run(ctx context.Context, event Event, task TaskInfo, output func(line string)) error {
+	if !r.Has(event) {
+		return nil
+	}
+
+	for _, command := range r.cfg.commands(event) {
+		if err := r.runCommand(ctx, event, command, task, output); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Runner) runCommand(ctx context.Context, event Event, command string, task TaskInfo, output func(line string)) (err error) {
+	ctx, span := tracing.Start(ctx, "hook."+string(event),
+		tracing.String("hook.command", command),
+		tracing.String("task.id", task.ID),
+	)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
+	runErr := shell.Run(ctx, shell.Command{
+		Command: command,
+		Dir:     task.WorkDir,
+		Env:     task.env(event),
+		Timeout: r.timeout,
+		Output:  output,
+	})
+	if runErr == nil {
+		return nil
+	}
+
+	hookErr := &Error{
+		Event:    event,
+		Command:  command,
+		ExitCode: -1,
+		Err:      runErr,
+	}
+	var shellErr *shell.Error
+	if errors.As(runErr, &shellErr) {
+		hookErr.ExitCode = shellErr.ExitCode
+		hookErr.Output = shellErr.Output
+		hookErr.Err = shellErr.Err
+	}
+	span.SetAttributes(tracing.Int("hook.exit_code", hookErr.ExitCode))
+	return hookErr
+}
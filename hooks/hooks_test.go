@@ -0,0 +1,147 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func skipOnWindows(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("hook tests use POSIX shell commands")
+	}
+}
+
+func TestNewRunner_Timeout(t *testing.T) {
+	r, err := NewRunner(Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.timeout != DefaultTimeout {
+		t.Errorf("expected default timeout, got %s", r.timeout)
+	}
+
+	if _, err := NewRunner(Config{Timeout: "soon"}); err == nil {
+		t.Error("expected error for invalid timeout")
+	}
+	if _, err := NewRunner(Config{Timeout: "-1s"}); err == nil {
+		t.Error("expected error for negative timeout")
+	}
+}
+
+func TestRunner_Has(t *testing.T) {
+	r, _ := NewRunner(Config{PostRun: []string{"make test"}})
+	if !r.Has(PostRun) {
+		t.Error("expected post-run hooks")
+	}
+	if r.Has(PreRun) {
+		t.Error("expected no pre-run hooks")
+	}
+	if !r.Enabled() {
+		t.Error("expected the runner to be enabled")
+	}
+	if empty, _ := NewRunner(Config{}); empty.Enabled() {
+		t.Error("expected a runner without commands to be disabled")
+	}
+
+	var nilRunner *Runner
+	if nilRunner.Has(PostRun) {
+		t.Error("nil runner should have no hooks")
+	}
+	if err := nilRunner.Run(context.Background(), PostRun, TaskInfo{}, nil); err != nil {
+		t.Errorf("nil runner should run nothing, got %v", err)
+	}
+}
+
+func TestRunner_EnvAndWorkDir(t *testing.T) {
+	skipOnWindows(t)
+	dir := t.TempDir()
+	r, _ := NewRunner(Config{
+		PostRun: []string{`echo "$MOMENTUM_HOOK $MOMENTUM_TASK_ID $MOMENTUM_EXIT_CODE $MOMENTUM_TASK_TITLE" > out.txt`},
+	})
+
+	code := 3
+	err := r.Run(context.Background(), PostRun, TaskInfo{
+		ID:       "task-1",
+		Title:    "Fix bug",
+		WorkDir:  dir,
+		ExitCode: &code,
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "out.txt"))
+	if err != nil {
+		t.Fatalf("expected hook to run in workdir: %v", err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "post-run task-1 3 Fix bug" {
+		t.Errorf("unexpected hook environment: %q", got)
+	}
+}
+
+func TestRunner_StreamsOutput(t *testing.T) {
+	skipOnWindows(t)
+	r, _ := NewRunner(Config{PreRun: []string{"echo one; echo two >&2; printf three"}})
+
+	var lines []string
+	err := r.Run(context.Background(), PreRun, TaskInfo{WorkDir: t.TempDir()}, func(line string) {
+		lines = append(lines, line)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(lines, ",") != "one,two,three" {
+		t.Errorf("unexpected output lines: %q", lines)
+	}
+}
+
+func TestRunner_StopsAtFirstFailure(t *testing.T) {
+	skipOnWindows(t)
+	dir := t.TempDir()
+	r, _ := NewRunner(Config{
+		PostRun: []string{"echo checking", "echo tests failed; exit 2", "touch ran-after-failure"},
+	})
+
+	err := r.Run(context.Background(), PostRun, TaskInfo{WorkDir: dir}, nil)
+	var hookErr *Error
+	if !errors.As(err, &hookErr) {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if hookErr.Event != PostRun || hookErr.ExitCode != 2 {
+		t.Errorf("unexpected error: %+v", hookErr)
+	}
+	if hookErr.Command != "echo tests failed; exit 2" {
+		t.Errorf("expected failing command, got %q", hookErr.Command)
+	}
+	if hookErr.Output != "tests failed" {
+		t.Errorf("expected output of failing command, got %q", hookErr.Output)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ran-after-failure")); err == nil {
+		t.Error("expected later hooks to be skipped after a failure")
+	}
+}
+
+func TestRunner_Timeout(t *testing.T) {
+	skipOnWindows(t)
+	r, _ := NewRunner(Config{PreRun: []string{"sleep 10"}, Timeout: "100ms"})
+
+	start := time.Now()
+	err := r.Run(context.Background(), PreRun, TaskInfo{WorkDir: t.TempDir()}, nil)
+	var hookErr *Error
+	if !errors.As(err, &hookErr) {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if hookErr.ExitCode != -1 || !strings.Contains(hookErr.Error(), "timed out") {
+		t.Errorf("expected timeout error, got %v", hookErr)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("expected hook to be killed at the timeout")
+	}
+}
@@ -0,0 +1,184 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// emptyTree is git's well-known empty tree object, the base for a
+// repository without commits.
+const emptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// Baseline records the commit the working directory was at before a run.
+type Baseline struct {
+	isRepo bool
+	head   string
+}
+
+// TakeBaseline records the git HEAD of workDir before the agent runs.
+func TakeBaseline(ctx context.Context, workDir string) Baseline {
+	if _, err := Run(ctx, workDir, "rev-parse", "--is-inside-work-tree"); err != nil {
+		return Baseline{}
+	}
+	// A repository without commits has no HEAD
+	head, _ := Run(ctx, workDir, "rev-parse", "HEAD")
+	return Baseline{isRepo: true, head: strings.TrimSpace(head)}
+}
+
+// FileChange is one file in a Diff.
+type FileChange struct {
+	Path    string
+	Added   int
+	Deleted int
+	// Binary is set for files git cannot count lines in
+	Binary bool
+}
+
+// Diff lists the files that changed since a Baseline, committed or not.
+type Diff struct {
+	Files []FileChange
+}
+
+// Changes returns the changes made to workDir since base, including new
+// untracked files.
+func Changes(ctx context.Context, workDir string, base Baseline) (*Diff, error) {
+	if !base.isRepo {
+		return nil, fmt.Errorf("%s is not a git repository", workDir)
+	}
+	from := base.head
+	if from == "" {
+		from = emptyTree
+	}
+	numstat, err := Run(ctx, workDir, "diff", "--numstat", "--no-renames", from)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &Diff{}
+	for _, line := range strings.Split(numstat, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		change := FileChange{Path: fields[2], Binary: fields[0] == "-"}
+		change.Added, _ = strconv.Atoi(fields[0])
+		change.Deleted, _ = strconv.Atoi(fields[1])
+		diff.Files = append(diff.Files, change)
+	}
+
+	untracked, err := Run(ctx, workDir, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Split(untracked, "\x00") {
+		if name == "" {
+			continue
+		}
+		data, _ := os.ReadFile(filepath.Join(workDir, name))
+		diff.Files = append(diff.Files, FileChange{Path: name, Added: countLines(data)})
+	}
+
+	slices.SortFunc(diff.Files, func(a, b FileChange) int { return strings.Compare(a.Path, b.Path) })
+	return diff, nil
+}
+
+// Paths returns the changed file paths.
+func (d *Diff) Paths() []string {
+	if d == nil {
+		return nil
+	}
+	paths := make([]string, len(d.Files))
+	for i, f := range d.Files {
+		paths[i] = f.Path
+	}
+	return paths
+}
+
+// Summary describes the diff in a few lines for display: a total followed by
+// one line per file.
+func (d *Diff) Summary() []string {
+	if d == nil || len(d.Files) == 0 {
+		return []string{"no changes"}
+	}
+	added, deleted := 0, 0
+	lines := []string{""}
+	for _, f := range d.Files {
+		added += f.Added
+		deleted += f.Deleted
+		if f.Binary {
+			lines = append(lines, fmt.Sprintf("  %s (binary)", f.Path))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("  %s +%d -%d", f.Path, f.Added, f.Deleted))
+	}
+	files := "files"
+	if len(d.Files) == 1 {
+		files = "file"
+	}
+	lines[0] = fmt.Sprintf("%d %s changed, +%d -%d", len(d.Files), files, added, deleted)
+	return lines
+}
+
+// Patch returns the unified diff of the changes made to workDir since base,
+// including new untracked files. A patch longer than limit bytes is cut at a
+// line boundary and truncated is set.
+func Patch(ctx context.Context, workDir string, base Baseline, limit int) (patch string, truncated bool, err error) {
+	if !base.isRepo {
+		return "", false, fmt.Errorf("%s is not a git repository", workDir)
+	}
+	from := base.head
+	if from == "" {
+		from = emptyTree
+	}
+	diff, err := Run(ctx, workDir, "diff", "--no-color", "--no-ext-diff", "--no-renames", from)
+	if err != nil {
+		return "", false, err
+	}
+	var b strings.Builder
+	b.WriteString(diff)
+
+	untracked, err := Run(ctx, workDir, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return "", false, err
+	}
+	for _, name := range strings.Split(untracked, "\x00") {
+		if name == "" || b.Len() > limit {
+			continue
+		}
+		// --no-index exits 1 when the files differ, which a new file always does
+		out, err := command(ctx, workDir, "diff", "--no-color", "--no-ext-diff", "--no-index", "--", os.DevNull, name).Output()
+		var exitErr *exec.ExitError
+		if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+			return "", false, fmt.Errorf("git diff --no-index %s: %w", name, err)
+		}
+		b.Write(out)
+	}
+
+	patch = b.String()
+	if len(patch) > limit {
+		patch = patch[:limit]
+		if i := strings.LastIndexByte(patch, '\n'); i >= 0 {
+			patch = patch[:i+1]
+		}
+		truncated = true
+	}
+	return patch, truncated, nil
+}
+
+func countLines(data []byte) int {
+	if len(data) == 0 {
+		return 0
+	}
+	n := strings.Count(string(data), "\n")
+	if data[len(data)-1] != '\n' {
+		n++
+	}
+	return n
+}
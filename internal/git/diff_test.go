@@ -0,0 +1,114 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestChanges(t *testing.T) {
+	dir := initRepo(t)
+	base := TakeBaseline(context.Background(), dir)
+
+	os.WriteFile(filepath.Join(dir, "README"), []byte("hello\nworld\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "new.txt"), []byte("a\nb\nc"), 0o644)
+	// Committed changes count too, not just the working tree
+	os.WriteFile(filepath.Join(dir, "committed.go"), []byte("package x\n"), 0o644)
+	run(t, dir, "add", "committed.go")
+	run(t, dir, "commit", "-q", "-m", "agent commit")
+
+	diff, err := Changes(context.Background(), dir, base)
+	if err != nil {
+		t.Fatalf("Changes: %v", err)
+	}
+	if got := diff.Paths(); !slices.Equal(got, []string{"README", "committed.go", "new.txt"}) {
+		t.Fatalf("unexpected paths %v", got)
+	}
+	if f := diff.Files[2]; f.Added != 3 || f.Deleted != 0 {
+		t.Errorf("expected untracked file counted as 3 added lines, got %+v", f)
+	}
+
+	summary := diff.Summary()
+	if summary[0] != "3 files changed, +5 -0" {
+		t.Errorf("unexpected summary total %q", summary[0])
+	}
+	if len(summary) != 4 || !strings.Contains(summary[1], "README +1 -0") {
+		t.Errorf("unexpected summary %q", summary)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	dir := initRepo(t)
+	base := TakeBaseline(context.Background(), dir)
+
+	os.WriteFile(filepath.Join(dir, "README"), []byte("hello\nworld\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "new.txt"), []byte("fresh\n"), 0o644)
+
+	patch, truncated, err := Patch(context.Background(), dir, base, 1<<20)
+	if err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+	if truncated || !strings.Contains(patch, "diff --git a/README b/README") || !strings.Contains(patch, "+world") ||
+		!strings.Contains(patch, "b/new.txt") || !strings.Contains(patch, "+fresh") {
+		t.Errorf("unexpected patch:\n%s", patch)
+	}
+
+	short, truncated, err := Patch(context.Background(), dir, base, 40)
+	if err != nil || !truncated || len(short) > 40 || !strings.HasSuffix(short, "\n") {
+		t.Errorf("expected a patch cut at a line boundary, got %q, %v, %v", short, truncated, err)
+	}
+}
+
+func TestChanges_NoCommits(t *testing.T) {
+	dir := initRepo(t)
+	os.RemoveAll(filepath.Join(dir, ".git"))
+	run(t, dir, "init", "-q")
+	base := TakeBaseline(context.Background(), dir)
+
+	diff, err := Changes(context.Background(), dir, base)
+	if err != nil {
+		t.Fatalf("Changes: %v", err)
+	}
+	if got := diff.Paths(); !slices.Equal(got, []string{"README"}) {
+		t.Errorf("unexpected paths %v", got)
+	}
+}
+
+func TestChanges_NotARepo(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Changes(context.Background(), dir, TakeBaseline(context.Background(), dir)); err == nil {
+		t.Error("expected an error outside a git repository")
+	}
+}
+
+func TestDiff_SummaryEmpty(t *testing.T) {
+	if got := (&Diff{}).Summary(); !slices.Equal(got, []string{"no changes"}) {
+		t.Errorf("unexpected summary %q", got)
+	}
+}
+
+func initRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	run(t, dir, "init", "-q")
+	run(t, dir, "config", "user.email", "test@example.com")
+	run(t, dir, "config", "user.name", "Test")
+	os.WriteFile(filepath.Join(dir, "README"), []byte("hello\n"), 0o644)
+	run(t, dir, "add", ".")
+	run(t, dir, "commit", "-q", "-m", "init")
+	return dir
+}
+
+func run(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if _, err := Run(context.Background(), dir, args...); err != nil {
+		t.Fatalf("git %v: %v", args, err)
+	}
+}
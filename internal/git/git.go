@@ -0,0 +1,49 @@
+// Package git runs git commands for the packages that work with the agent's
+// working directory, and lists the changes a run made to it.
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Run runs git with args in dir and returns its output. Errors name the git
+// subcommand and include its stderr; the other arguments are left out, as
+// they can hold remote URLs with credentials.
+func Run(ctx context.Context, dir string, args ...string) (string, error) {
+	out, err := command(ctx, dir, args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %w: %s", subcommand(args), err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", subcommand(args), err)
+	}
+	return string(out), nil
+}
+
+// subcommand returns the git command args run, skipping global options such
+// as "-c user.name=Momentum".
+func subcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-c" || args[i] == "-C":
+			i++
+		case !strings.HasPrefix(args[i], "-"):
+			return args[i]
+		}
+	}
+	return strings.Join(args, " ")
+}
+
+func command(ctx context.Context, dir string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	// A missing credential must fail a clone or push, not wait for a password
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	return cmd
+}
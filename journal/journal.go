@@ -0,0 +1,158 @@
+// Package journal persists the tasks a Momentum instance has claimed so that
+// a later run can reconcile them after a crash, sleep or forced exit.
+//
+// Each claimed task is stored as its own small JSON file written atomically,
+// so a crash mid-write never corrupts other entries and several instances
+// can share one journal directory.
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry records a task claimed by a Momentum instance.
+type Entry struct {
+	TaskID    string `json:"task_id"`
+	Title     string `json:"title,omitempty"`
+	ProjectID string `json:"project_id,omitempty"`
+	// BaseURL is the Flux server the task belongs to
+	BaseURL string `json:"base_url,omitempty"`
+	// OwnerPID is the PID of the Momentum process that claimed the task
+	OwnerPID int `json:"owner_pid"`
+	// PID is the agent process ID, or 0 if the agent had not started
+	PID int `json:"pid,omitempty"`
+	// Command is the agent executable, used to guard against PID reuse
+	Command   string    `json:"command,omitempty"`
+	WorkDir   string    `json:"workdir,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// Journal stores entries in a directory. A nil *Journal records nothing.
+type Journal struct {
+	dir string
+}
+
+// DefaultDir returns the default journal directory, or "" if the user
+// config directory cannot be determined.
+func DefaultDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "momentum", "journal")
+}
+
+// Open creates the journal directory if needed and returns a journal for it.
+func Open(dir string) (*Journal, error) {
+	if dir == "" {
+		return nil, errors.New("no journal directory")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	return &Journal{dir: dir}, nil
+}
+
+// Dir returns the journal directory.
+func (j *Journal) Dir() string {
+	if j == nil {
+		return ""
+	}
+	return j.dir
+}
+
+// Record writes or replaces the entry for e.TaskID.
+func (j *Journal) Record(e Entry) error {
+	if j == nil {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %w", err)
+	}
+
+	// Write to a temp file and rename so readers never see a partial entry
+	tmp, err := os.CreateTemp(j.dir, ".entry-*")
+	if err != nil {
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	tmp.Close()
+
+	if err := os.Rename(tmp.Name(), j.path(e.TaskID)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the entry for taskID. Removing a missing entry is not an error.
+func (j *Journal) Remove(taskID string) error {
+	if j == nil {
+		return nil
+	}
+	if err := os.Remove(j.path(taskID)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove journal entry: %w", err)
+	}
+	return nil
+}
+
+// Entries returns all recorded entries, oldest first. Unreadable entries are
+// skipped and reported in the returned error alongside the valid entries.
+func (j *Journal) Entries() ([]Entry, error) {
+	if j == nil {
+		return nil, nil
+	}
+
+	files, err := os.ReadDir(j.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	var entries []Entry
+	var errs []error
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(j.dir, f.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(data, &e); err != nil || e.TaskID == "" {
+			errs = append(errs, fmt.Errorf("invalid journal entry %s", f.Name()))
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].StartedAt.Before(entries[b].StartedAt)
+	})
+	return entries, errors.Join(errs...)
+}
+
+func (j *Journal) path(taskID string) string {
+	return filepath.Join(j.dir, url.PathEscape(taskID)+".json")
+}
@@ -0,0 +1,120 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordAndEntries(t *testing.T) {
+	j, err := Open(filepath.Join(t.TempDir(), "journal"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	j.Record(Entry{TaskID: "task-2", OwnerPID: 10, StartedAt: now})
+	j.Record(Entry{TaskID: "task-1", OwnerPID: 10, StartedAt: now.Add(-time.Minute)})
+
+	entries, err := j.Entries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].TaskID != "task-1" || entries[1].TaskID != "task-2" {
+		t.Errorf("expected entries oldest first, got %s, %s", entries[0].TaskID, entries[1].TaskID)
+	}
+	if !entries[1].StartedAt.Equal(now) {
+		t.Errorf("expected start time %v, got %v", now, entries[1].StartedAt)
+	}
+}
+
+func TestRecord_ReplacesEntry(t *testing.T) {
+	j, _ := Open(t.TempDir())
+
+	j.Record(Entry{TaskID: "task-1", OwnerPID: 10})
+	j.Record(Entry{TaskID: "task-1", OwnerPID: 10, PID: 42, Command: "claude"})
+
+	entries, _ := j.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	if entries[0].PID != 42 || entries[0].Command != "claude" {
+		t.Errorf("expected updated entry, got %+v", entries[0])
+	}
+}
+
+func TestRecord_EscapesTaskID(t *testing.T) {
+	dir := t.TempDir()
+	j, _ := Open(dir)
+
+	if err := j.Record(Entry{TaskID: "../task/1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("expected entry inside the journal directory, got %d files", len(files))
+	}
+
+	entries, _ := j.Entries()
+	if len(entries) != 1 || entries[0].TaskID != "../task/1" {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	j, _ := Open(t.TempDir())
+
+	j.Record(Entry{TaskID: "task-1"})
+	if err := j.Remove("task-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := j.Remove("task-1"); err != nil {
+		t.Errorf("removing a missing entry should not fail: %v", err)
+	}
+
+	entries, _ := j.Entries()
+	if len(entries) != 0 {
+		t.Errorf("expected no entries, got %d", len(entries))
+	}
+}
+
+func TestEntries_SkipsInvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	j, _ := Open(dir)
+
+	j.Record(Entry{TaskID: "task-1"})
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o600)
+	os.WriteFile(filepath.Join(dir, ".entry-123"), []byte("partial"), 0o600)
+
+	entries, err := j.Entries()
+	if err == nil {
+		t.Error("expected error for the invalid entry")
+	}
+	if len(entries) != 1 || entries[0].TaskID != "task-1" {
+		t.Errorf("expected valid entries to be returned, got %+v", entries)
+	}
+}
+
+func TestNilJournal(t *testing.T) {
+	var j *Journal
+
+	if err := j.Record(Entry{TaskID: "task-1"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := j.Remove("task-1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if entries, err := j.Entries(); entries != nil || err != nil {
+		t.Errorf("expected no entries, got %v, %v", entries, err)
+	}
+}
+
+func TestOpen_NoDirectory(t *testing.T) {
+	if _, err := Open(""); err == nil {
+		t.Error("expected error for empty directory")
+	}
+}
@@ -0,0 +1,381 @@
+// Package lease lets several Momentum instances share one Flux board without
+// claiming the same task twice.
+//
+// Flux has no compare-and-swap, so a claim is an optimistic write followed
+// by a read-back: the claimant moves the task to in_progress with a lease
+// marker in its notes, waits briefly for competing writes to land, and keeps
+// the task only if its own marker survived. While the agent runs the lease
+// is renewed; a lease that is not renewed before it expires may be taken
+// over by another instance.
+//
+// The marker is an HTML comment on the last line of the task notes, so it
+// stays out of sight in rendered Markdown:
+//
+//	<!-- momentum-lease owner="build-1:4242" expires="2026-01-02T15:04:05Z" -->
+package lease
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/workflow"
+)
+
+// DefaultTTL is how long a lease lasts without renewal when no TTL is configured.
+const DefaultTTL = 2 * time.Minute
+
+// maxSettle bounds the wait between writing a claim and reading it back.
+const maxSettle = time.Second
+
+// ErrHeld is returned when a task is leased by another live instance or is
+// no longer available to claim.
+var ErrHeld = errors.New("task is claimed by another instance")
+
+// ErrLost is returned when another instance has taken over a lease.
+var ErrLost = errors.New("task lease was taken over by another instance")
+
+// Config enables task leases.
+type Config struct {
+	// Enabled turns on lease-based claiming
+	Enabled bool `json:"enabled,omitempty"`
+	// TTL is how long a lease lasts without renewal (default 2m)
+	TTL string `json:"ttl,omitempty"`
+	// Owner identifies this instance in leases (default "<hostname>:<pid>")
+	Owner string `json:"owner,omitempty"`
+}
+
+// Lease is a claim on a task by one instance.
+type Lease struct {
+	Owner   string
+	Expires time.Time
+}
+
+// Expired reports whether the lease has run out at now.
+func (l Lease) Expired(now time.Time) bool {
+	return !now.Before(l.Expires)
+}
+
+var markerPattern = regexp.MustCompile(`(?m)^[ \t]*<!-- momentum-lease owner=("(?:[^"\\]|\\.)*") expires="([^"]*)" -->[ \t]*\r?\n?`)
+
+// Parse returns the lease recorded in task notes, or nil if there is none.
+func Parse(notes string) *Lease {
+	m := markerPattern.FindAllStringSubmatch(notes, -1)
+	if len(m) == 0 {
+		return nil
+	}
+	last := m[len(m)-1]
+	owner, err := strconv.Unquote(last[1])
+	if err != nil {
+		return nil
+	}
+	expires, err := time.Parse(time.RFC3339, last[2])
+	if err != nil {
+		return nil
+	}
+	return &Lease{Owner: owner, Expires: expires}
+}
+
+// Strip removes any lease marker from task notes.
+func Strip(notes string) string {
+	if !strings.Contains(notes, "momentum-lease") {
+		return notes
+	}
+	return strings.TrimRight(markerPattern.ReplaceAllString(notes, ""), " \t\r\n")
+}
+
+// Set returns notes with l recorded as the task's lease, replacing any
+// existing marker.
+func Set(notes string, l Lease) string {
+	marker := fmt.Sprintf(`<!-- momentum-lease owner=%s expires="%s" -->`,
+		strconv.Quote(l.Owner), l.Expires.UTC().Format(time.RFC3339))
+	notes = Strip(notes)
+	if notes == "" {
+		return marker
+	}
+	return notes + "\n\n" + marker
+}
+
+// Stealable reports whether task is in the running status under a lease
+// that has expired, so another instance may take it over.
+func Stealable(task client.Task, running string, now time.Time) bool {
+	if task.Status != running {
+		return false
+	}
+	l := Parse(task.Notes)
+	return l != nil && l.Expired(now)
+}
+
+// DefaultOwner identifies this process as "<hostname>:<pid>".
+func DefaultOwner() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "localhost"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+// Manager claims, renews and releases leases for one instance through the
+// Flux client passed to each call. A nil *Manager is disabled.
+type Manager struct {
+	owner  string
+	ttl    time.Duration
+	settle time.Duration
+	now    func() time.Time
+	// states names the claimable and running statuses; nil uses "todo" and
+	// "in_progress"
+	states *workflow.StateMachine
+}
+
+// New validates cfg and returns a manager, or nil if leases are not enabled.
+func New(cfg Config) (*Manager, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	ttl := DefaultTTL
+	if cfg.TTL != "" {
+		d, err := time.ParseDuration(cfg.TTL)
+		if err != nil || d < 10*time.Second {
+			return nil, fmt.Errorf("invalid lease ttl %q (minimum 10s)", cfg.TTL)
+		}
+		ttl = d
+	}
+	owner := strings.TrimSpace(cfg.Owner)
+	if owner == "" {
+		owner = DefaultOwner()
+	}
+	return &Manager{
+		owner:  owner,
+		ttl:    ttl,
+		settle: min(maxSettle, ttl/10),
+		now:    time.Now,
+	}, nil
+}
+
+// SetStates configures the statuses tasks are claimed from and held in
+// while leased.
+func (m *Manager) SetStates(states *workflow.StateMachine) {
+	if m != nil {
+		m.states = states
+	}
+}
+
+// Enabled reports whether leases are in use.
+func (m *Manager) Enabled() bool {
+	return m != nil
+}
+
+// Owner returns this instance's lease owner identity.
+func (m *Manager) Owner() string {
+	if m == nil {
+		return ""
+	}
+	return m.owner
+}
+
+// TTL returns the lease duration.
+func (m *Manager) TTL() time.Duration {
+	if m == nil {
+		return 0
+	}
+	return m.ttl
+}
+
+// HeldElsewhere reports whether task carries a live lease owned by another
+// instance.
+func (m *Manager) HeldElsewhere(task client.Task) bool {
+	now := time.Now()
+	if m != nil {
+		now = m.now()
+	}
+	l := Parse(task.Notes)
+	if l == nil || l.Expired(now) {
+		return false
+	}
+	return l.Owner != m.Owner()
+}
+
+// Claim moves task to the running status under a lease owned by this instance. It
+// returns the claimed task, or an error wrapping ErrHeld if the task is
+// leased elsewhere, no longer claimable or another instance won the race.
+func (m *Manager) Claim(ctx context.Context, c *client.Client, task *client.Task) (*client.Task, error) {
+	c = c.WithContext(ctx)
+
+	current, err := c.FindTask(task.ProjectID, task.ID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, fmt.Errorf("task %s not found: %w", task.ID, ErrHeld)
+	}
+	now := m.now()
+	if l := Parse(current.Notes); l != nil && l.Owner != m.owner && !l.Expired(now) {
+		return nil, fmt.Errorf("task %s is leased by %s until %s: %w", task.ID, l.Owner, l.Expires.Format(time.RFC3339), ErrHeld)
+	}
+	if !m.states.IsPickup(current.Status) && !Stealable(*current, m.states.Running(), now) {
+		return nil, fmt.Errorf("task %s is %s: %w", task.ID, current.Status, ErrHeld)
+	}
+
+	notes := Set(current.Notes, Lease{Owner: m.owner, Expires: now.Add(m.ttl)})
+	if _, err := c.UpdateTask(task.ID, client.TaskUpdate{
+		Status: client.StringPtr(m.states.Running()),
+		Notes:  client.StringPtr(notes),
+	}); err != nil {
+		return nil, err
+	}
+
+	// Let competing claims land, then check whose marker survived
+	if err := sleep(ctx, m.settle); err != nil {
+		return nil, err
+	}
+	claimed, err := c.FindTask(task.ProjectID, task.ID)
+	if err != nil {
+		return nil, err
+	}
+	if claimed == nil {
+		return nil, fmt.Errorf("task %s not found: %w", task.ID, ErrHeld)
+	}
+	if l := Parse(claimed.Notes); l == nil || l.Owner != m.owner {
+		return nil, fmt.Errorf("task %s was claimed concurrently by another instance: %w", task.ID, ErrHeld)
+	}
+	return claimed, nil
+}
+
+// Renew extends this instance's lease on task. It returns an error wrapping
+// ErrLost if another instance holds the lease, and done=true if the task
+// has left the running status so there is nothing left to renew.
+func (m *Manager) Renew(ctx context.Context, c *client.Client, task *client.Task) (done bool, err error) {
+	c = c.WithContext(ctx)
+
+	current, err := c.FindTask(task.ProjectID, task.ID)
+	if err != nil {
+		return false, err
+	}
+	if current == nil || current.Status != m.states.Running() {
+		return true, nil
+	}
+	if l := Parse(current.Notes); l != nil && l.Owner != m.owner {
+		return false, fmt.Errorf("task %s is now leased by %s: %w", task.ID, l.Owner, ErrLost)
+	}
+
+	notes := Set(current.Notes, Lease{Owner: m.owner, Expires: m.now().Add(m.ttl)})
+	_, err = c.UpdateTask(task.ID, client.TaskUpdate{Notes: client.StringPtr(notes)})
+	return false, err
+}
+
+// Release removes this instance's lease marker from task, leaving leases
+// held by other instances alone.
+func (m *Manager) Release(ctx context.Context, c *client.Client, task *client.Task) error {
+	if m == nil {
+		return nil
+	}
+	c = c.WithContext(ctx)
+
+	current, err := c.FindTask(task.ProjectID, task.ID)
+	if err != nil || current == nil {
+		return err
+	}
+	if l := Parse(current.Notes); l == nil || l.Owner != m.owner {
+		return nil
+	}
+	_, err = c.UpdateTask(task.ID, client.TaskUpdate{Notes: client.StringPtr(Strip(current.Notes))})
+	return err
+}
+
+// Heartbeat renews the lease on task until stopped.
+type Heartbeat struct {
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+
+	mu   sync.Mutex
+	lost error
+}
+
+// Heartbeat renews the lease on task every third of the TTL until Stop is
+// called or the task leaves in_progress. If another instance takes over the
+// lease, onLost is called once with an error wrapping ErrLost and renewal
+// stops. Other renewal errors are passed to onError and retried.
+func (m *Manager) Heartbeat(ctx context.Context, c *client.Client, task *client.Task, onLost, onError func(error)) *Heartbeat {
+	if m == nil {
+		return nil
+	}
+	h := &Heartbeat{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(h.done)
+		ticker := time.NewTicker(m.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-h.stop:
+				return
+			case <-ticker.C:
+			}
+
+			done, err := m.Renew(ctx, c, task)
+			switch {
+			case errors.Is(err, ErrLost):
+				h.mu.Lock()
+				h.lost = err
+				h.mu.Unlock()
+				if onLost != nil {
+					onLost(err)
+				}
+				return
+			case err != nil:
+				if onError != nil && ctx.Err() == nil {
+					onError(err)
+				}
+			case done:
+				return
+			}
+		}
+	}()
+	return h
+}
+
+// Stop ends renewal and waits for any renewal in flight to finish.
+func (h *Heartbeat) Stop() {
+	if h == nil {
+		return
+	}
+	h.once.Do(func() { close(h.stop) })
+	<-h.done
+}
+
+// Lost returns the ErrLost error if another instance took over the lease.
+func (h *Heartbeat) Lost() error {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.lost
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,403 @@
+package lease
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/workflow"
+)
+
+// fakeFlux serves a single project's tasks and applies status and notes updates.
+type fakeFlux struct {
+	mu    sync.Mutex
+	tasks map[string]*client.Task
+	// afterPatch, if set, runs after each update while the lock is held
+	afterPatch func(task *client.Task)
+}
+
+func newFakeFlux(t *testing.T, tasks ...client.Task) (*fakeFlux, *client.Client) {
+	t.Helper()
+	f := &fakeFlux{tasks: make(map[string]*client.Task)}
+	for i := range tasks {
+		task := tasks[i]
+		task.ProjectID = "proj-1"
+		f.tasks[task.ID] = &task
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/projects/proj-1/tasks":
+			list := []client.Task{}
+			for _, task := range f.tasks {
+				list = append(list, *task)
+			}
+			json.NewEncoder(w).Encode(list)
+		case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, "/api/tasks/"):
+			task := f.tasks[strings.TrimPrefix(r.URL.Path, "/api/tasks/")]
+			if task == nil {
+				http.NotFound(w, r)
+				return
+			}
+			var update client.TaskUpdate
+			json.NewDecoder(r.Body).Decode(&update)
+			if update.Status != nil {
+				task.Status = *update.Status
+			}
+			if update.Notes != nil {
+				task.Notes = *update.Notes
+			}
+			if f.afterPatch != nil {
+				f.afterPatch(task)
+			}
+			json.NewEncoder(w).Encode(task)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return f, client.NewClient(server.URL)
+}
+
+func (f *fakeFlux) task(id string) client.Task {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return *f.tasks[id]
+}
+
+func (f *fakeFlux) setNotes(id, notes string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tasks[id].Notes = notes
+}
+
+func testManager(owner string, ttl time.Duration) *Manager {
+	return &Manager{owner: owner, ttl: ttl, now: time.Now}
+}
+
+func TestSetParseStrip(t *testing.T) {
+	expires := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	notes := Set("Fix the bug.\n\nSee logs.", Lease{Owner: `host "a":1`, Expires: expires})
+
+	if !strings.HasPrefix(notes, "Fix the bug.\n\nSee logs.\n\n<!-- momentum-lease") {
+		t.Errorf("marker should follow the notes:\n%s", notes)
+	}
+	l := Parse(notes)
+	if l == nil || l.Owner != `host "a":1` || !l.Expires.Equal(expires) {
+		t.Fatalf("unexpected lease: %+v", l)
+	}
+	if got := Strip(notes); got != "Fix the bug.\n\nSee logs." {
+		t.Errorf("strip should restore the notes, got %q", got)
+	}
+
+	// Setting again replaces the marker rather than adding another
+	renewed := Set(notes, Lease{Owner: "host-b:2", Expires: expires.Add(time.Hour)})
+	if strings.Count(renewed, "momentum-lease") != 1 {
+		t.Errorf("expected a single marker:\n%s", renewed)
+	}
+	if l := Parse(renewed); l.Owner != "host-b:2" {
+		t.Errorf("expected new owner, got %s", l.Owner)
+	}
+
+	if Set("", Lease{Owner: "x", Expires: expires}) != `<!-- momentum-lease owner="x" expires="2026-01-02T15:04:05Z" -->` {
+		t.Error("empty notes should hold only the marker")
+	}
+	if Parse("no lease here") != nil {
+		t.Error("expected no lease")
+	}
+}
+
+func TestStealable(t *testing.T) {
+	now := time.Now()
+	expired := Set("", Lease{Owner: "a", Expires: now.Add(-time.Second)})
+	live := Set("", Lease{Owner: "a", Expires: now.Add(time.Minute)})
+
+	tests := []struct {
+		task client.Task
+		want bool
+	}{
+		{client.Task{Status: "in_progress", Notes: expired}, true},
+		{client.Task{Status: "in_progress", Notes: live}, false},
+		{client.Task{Status: "in_progress"}, false},
+		{client.Task{Status: "done", Notes: expired}, false},
+	}
+	for _, tt := range tests {
+		if got := Stealable(tt.task, workflow.StatusInProgress, now); got != tt.want {
+			t.Errorf("Stealable(%s, %q) = %v, want %v", tt.task.Status, tt.task.Notes, got, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	m, err := New(Config{})
+	if m != nil || err != nil {
+		t.Errorf("disabled config should give a nil manager, got %v, %v", m, err)
+	}
+	if m.Enabled() {
+		t.Error("nil manager should be disabled")
+	}
+
+	if _, err := New(Config{Enabled: true, TTL: "1s"}); err == nil {
+		t.Error("expected error for a TTL under 10s")
+	}
+
+	m, err = New(Config{Enabled: true, TTL: "30s", Owner: "runner-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Owner() != "runner-1" || m.TTL() != 30*time.Second {
+		t.Errorf("unexpected manager: owner=%s ttl=%s", m.Owner(), m.TTL())
+	}
+
+	m, _ = New(Config{Enabled: true})
+	if !strings.Contains(m.Owner(), ":") || m.TTL() != DefaultTTL {
+		t.Errorf("expected default owner and TTL, got %s, %s", m.Owner(), m.TTL())
+	}
+}
+
+func TestClaim(t *testing.T) {
+	flux, c := newFakeFlux(t, client.Task{ID: "task-1", Status: "todo", Notes: "Details"})
+	m := testManager("host-a:1", time.Minute)
+
+	claimed, err := m.Claim(context.Background(), c, &client.Task{ID: "task-1", ProjectID: "proj-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claimed.Status != "in_progress" {
+		t.Errorf("expected in_progress, got %s", claimed.Status)
+	}
+
+	stored := flux.task("task-1")
+	l := Parse(stored.Notes)
+	if l == nil || l.Owner != "host-a:1" {
+		t.Fatalf("expected lease for host-a:1, got %q", stored.Notes)
+	}
+	if Strip(stored.Notes) != "Details" {
+		t.Errorf("claim should keep the task notes, got %q", stored.Notes)
+	}
+}
+
+func TestClaim_HeldByLiveLease(t *testing.T) {
+	notes := Set("", Lease{Owner: "host-b:2", Expires: time.Now().Add(time.Minute)})
+	flux, c := newFakeFlux(t, client.Task{ID: "task-1", Status: "in_progress", Notes: notes})
+	m := testManager("host-a:1", time.Minute)
+
+	_, err := m.Claim(context.Background(), c, &client.Task{ID: "task-1", ProjectID: "proj-1"})
+	if !errors.Is(err, ErrHeld) {
+		t.Fatalf("expected ErrHeld, got %v", err)
+	}
+	if Parse(flux.task("task-1").Notes).Owner != "host-b:2" {
+		t.Error("a live lease must not be overwritten")
+	}
+}
+
+func TestClaim_NotClaimable(t *testing.T) {
+	_, c := newFakeFlux(t,
+		client.Task{ID: "task-done", Status: "done"},
+		client.Task{ID: "task-running", Status: "in_progress"},
+	)
+	m := testManager("host-a:1", time.Minute)
+
+	for _, id := range []string{"task-done", "task-running", "task-missing"} {
+		if _, err := m.Claim(context.Background(), c, &client.Task{ID: id, ProjectID: "proj-1"}); !errors.Is(err, ErrHeld) {
+			t.Errorf("%s: expected ErrHeld, got %v", id, err)
+		}
+	}
+}
+
+func TestClaim_StealsExpiredLease(t *testing.T) {
+	notes := Set("Details", Lease{Owner: "host-b:2", Expires: time.Now().Add(-time.Second)})
+	flux, c := newFakeFlux(t, client.Task{ID: "task-1", Status: "in_progress", Notes: notes})
+	m := testManager("host-a:1", time.Minute)
+
+	if _, err := m.Claim(context.Background(), c, &client.Task{ID: "task-1", ProjectID: "proj-1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if Parse(flux.task("task-1").Notes).Owner != "host-a:1" {
+		t.Error("expected the expired lease to be taken over")
+	}
+}
+
+func TestClaim_LosesRace(t *testing.T) {
+	flux, c := newFakeFlux(t, client.Task{ID: "task-1", Status: "todo"})
+	// Another instance's claim lands right after ours
+	flux.afterPatch = func(task *client.Task) {
+		task.Notes = Set(task.Notes, Lease{Owner: "host-b:2", Expires: time.Now().Add(time.Minute)})
+	}
+	m := testManager("host-a:1", time.Minute)
+
+	if _, err := m.Claim(context.Background(), c, &client.Task{ID: "task-1", ProjectID: "proj-1"}); !errors.Is(err, ErrHeld) {
+		t.Errorf("expected ErrHeld after losing the race, got %v", err)
+	}
+}
+
+func TestClaim_ConcurrentInstances(t *testing.T) {
+	_, c := newFakeFlux(t, client.Task{ID: "task-1", Status: "todo"})
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	winners := 0
+	for _, owner := range []string{"host-a:1", "host-b:2", "host-c:3"} {
+		m := testManager(owner, time.Minute)
+		m.settle = 100 * time.Millisecond
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := m.Claim(context.Background(), c, &client.Task{ID: "task-1", ProjectID: "proj-1"}); err == nil {
+				mu.Lock()
+				winners++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if winners != 1 {
+		t.Errorf("expected exactly one instance to win the claim, got %d", winners)
+	}
+}
+
+func TestRenew(t *testing.T) {
+	expires := time.Now().Add(time.Second)
+	notes := Set("Details", Lease{Owner: "host-a:1", Expires: expires})
+	flux, c := newFakeFlux(t, client.Task{ID: "task-1", Status: "in_progress", Notes: notes})
+	m := testManager("host-a:1", time.Hour)
+	task := &client.Task{ID: "task-1", ProjectID: "proj-1"}
+
+	done, err := m.Renew(context.Background(), c, task)
+	if done || err != nil {
+		t.Fatalf("unexpected result: %v, %v", done, err)
+	}
+	if l := Parse(flux.task("task-1").Notes); !l.Expires.After(expires.Add(time.Minute)) {
+		t.Errorf("expected the lease to be extended, expires %v", l.Expires)
+	}
+
+	// The agent overwrote the notes; renewal restores the marker
+	flux.setNotes("task-1", "Rewritten by agent")
+	if _, err := m.Renew(context.Background(), c, task); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stored := flux.task("task-1").Notes; Parse(stored) == nil || Strip(stored) != "Rewritten by agent" {
+		t.Errorf("expected marker restored after agent notes, got %q", stored)
+	}
+
+	flux.setNotes("task-1", Set("", Lease{Owner: "host-b:2", Expires: time.Now().Add(time.Hour)}))
+	if _, err := m.Renew(context.Background(), c, task); !errors.Is(err, ErrLost) {
+		t.Errorf("expected ErrLost, got %v", err)
+	}
+}
+
+func TestRenew_TaskFinished(t *testing.T) {
+	_, c := newFakeFlux(t, client.Task{ID: "task-1", Status: "done"})
+	m := testManager("host-a:1", time.Hour)
+
+	done, err := m.Renew(context.Background(), c, &client.Task{ID: "task-1", ProjectID: "proj-1"})
+	if !done || err != nil {
+		t.Errorf("expected done for a finished task, got %v, %v", done, err)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	mine := Set("Details", Lease{Owner: "host-a:1", Expires: time.Now().Add(time.Hour)})
+	theirs := Set("Other", Lease{Owner: "host-b:2", Expires: time.Now().Add(time.Hour)})
+	flux, c := newFakeFlux(t,
+		client.Task{ID: "task-1", Status: "done", Notes: mine},
+		client.Task{ID: "task-2", Status: "in_progress", Notes: theirs},
+	)
+	m := testManager("host-a:1", time.Hour)
+
+	for _, id := range []string{"task-1", "task-2"} {
+		if err := m.Release(context.Background(), c, &client.Task{ID: id, ProjectID: "proj-1"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if got := flux.task("task-1").Notes; got != "Details" {
+		t.Errorf("expected own marker removed, got %q", got)
+	}
+	if got := flux.task("task-2").Notes; got != theirs {
+		t.Errorf("another instance's lease must be left alone, got %q", got)
+	}
+}
+
+func TestHeartbeat_DetectsTakeover(t *testing.T) {
+	notes := Set("", Lease{Owner: "host-a:1", Expires: time.Now().Add(time.Minute)})
+	flux, c := newFakeFlux(t, client.Task{ID: "task-1", Status: "in_progress", Notes: notes})
+	m := testManager("host-a:1", 60*time.Millisecond)
+
+	lost := make(chan error, 1)
+	hb := m.Heartbeat(context.Background(), c, &client.Task{ID: "task-1", ProjectID: "proj-1"}, func(err error) {
+		lost <- err
+	}, nil)
+
+	// Renewals keep the lease ours
+	time.Sleep(50 * time.Millisecond)
+	if Parse(flux.task("task-1").Notes).Owner != "host-a:1" {
+		t.Fatal("expected the lease to be renewed")
+	}
+
+	flux.setNotes("task-1", Set("", Lease{Owner: "host-b:2", Expires: time.Now().Add(time.Minute)}))
+	select {
+	case err := <-lost:
+		if !errors.Is(err, ErrLost) {
+			t.Errorf("expected ErrLost, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for lease loss")
+	}
+	hb.Stop()
+	if !errors.Is(hb.Lost(), ErrLost) {
+		t.Errorf("expected Lost to report the takeover, got %v", hb.Lost())
+	}
+}
+
+func TestNilManager(t *testing.T) {
+	var m *Manager
+	if err := m.Release(context.Background(), nil, &client.Task{ID: "task-1"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	hb := m.Heartbeat(context.Background(), nil, &client.Task{ID: "task-1"}, nil, nil)
+	hb.Stop()
+	if hb.Lost() != nil {
+		t.Error("nil heartbeat should never report a lost lease")
+	}
+
+	live := client.Task{Notes: Set("", Lease{Owner: "host-b:2", Expires: time.Now().Add(time.Minute)})}
+	if !m.HeldElsewhere(live) {
+		t.Error("a live lease is held elsewhere when leases are disabled")
+	}
+}
+
+func TestClaim_CustomStates(t *testing.T) {
+	flux, c := newFakeFlux(t, client.Task{ID: "task-1", Status: "ready"})
+	m := testManager("host-a:1", time.Minute)
+	states, err := workflow.NewStateMachine(workflow.States{Pickup: []string{"ready"}, Running: "doing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetStates(states)
+
+	claimed, err := m.Claim(context.Background(), c, &client.Task{ID: "task-1", ProjectID: "proj-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claimed.Status != "doing" {
+		t.Errorf("expected the running status, got %s", claimed.Status)
+	}
+	if done, err := m.Renew(context.Background(), c, claimed); done || err != nil {
+		t.Errorf("expected the lease to be renewed, got done=%v err=%v", done, err)
+	}
+	if l := Parse(flux.task("task-1").Notes); l == nil || l.Owner != "host-a:1" {
+		t.Errorf("expected the lease to be kept, got %+v", l)
+	}
+}
@@ -0,0 +1,203 @@
+// Package memory finds the files Claude Code reads when it starts in a
+// directory: memory files (CLAUDE.md and the files they @import) and
+// settings, so Momentum can show what an agent is given.
+package memory
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxImportDepth is how many hops of @imports Claude Code follows.
+const maxImportDepth = 5
+
+// Scopes of memory and settings files, in the order Claude Code reads them.
+const (
+	ScopeManaged = "managed"
+	ScopeUser    = "user"
+	ScopeProject = "project"
+	ScopeLocal   = "local"
+	ScopeImport  = "import"
+)
+
+// File is a memory or settings file.
+type File struct {
+	// Path is where the file lives
+	Path string
+	// Scope says why the file applies
+	Scope string
+	// ImportedBy is the file whose @import pulled this one in, if any
+	ImportedBy string
+	// Content is the file's text
+	Content string
+}
+
+// locations are the directories Claude Code reads outside the project.
+type locations struct {
+	home    string
+	managed string
+}
+
+func defaultLocations() locations {
+	home, _ := os.UserHomeDir()
+	return locations{home: home, managed: managedDir(runtime.GOOS)}
+}
+
+// managedDir is where administrators put organisation-wide Claude Code
+// instructions and settings.
+func managedDir(goos string) string {
+	switch goos {
+	case "darwin":
+		return "/Library/Application Support/ClaudeCode"
+	case "windows":
+		return `C:\ProgramData\ClaudeCode`
+	}
+	return "/etc/claude-code"
+}
+
+// Load returns the memory files Claude Code reads when started in workDir,
+// in the order it reads them: the managed policy, the user's
+// ~/.claude/CLAUDE.md, then CLAUDE.md, .claude/CLAUDE.md and CLAUDE.local.md
+// in each directory from the root down to workDir. Each file is followed by
+// the files it imports.
+func Load(workDir string) []File {
+	return defaultLocations().load(workDir)
+}
+
+func (l locations) load(workDir string) []File {
+	seen := make(map[string]bool)
+	var files []File
+	add := func(path, scope string) {
+		files = l.appendFile(files, seen, path, scope, "", 0)
+	}
+
+	if l.managed != "" {
+		add(filepath.Join(l.managed, "CLAUDE.md"), ScopeManaged)
+	}
+	if l.home != "" {
+		add(filepath.Join(l.home, ".claude", "CLAUDE.md"), ScopeUser)
+	}
+	for _, dir := range ancestors(workDir) {
+		add(filepath.Join(dir, "CLAUDE.md"), ScopeProject)
+		add(filepath.Join(dir, ".claude", "CLAUDE.md"), ScopeProject)
+		add(filepath.Join(dir, "CLAUDE.local.md"), ScopeLocal)
+	}
+	return files
+}
+
+// appendFile appends the file at path, if it exists and was not read
+// already, followed by its imports.
+func (l locations) appendFile(files []File, seen map[string]bool, path, scope, importedBy string, depth int) []File {
+	if seen[path] {
+		return files
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return files
+	}
+	seen[path] = true
+	files = append(files, File{Path: path, Scope: scope, ImportedBy: importedBy, Content: string(content)})
+	if depth >= maxImportDepth {
+		return files
+	}
+	for _, ref := range Imports(string(content)) {
+		files = l.appendFile(files, seen, l.resolveImport(path, ref), ScopeImport, path, depth+1)
+	}
+	return files
+}
+
+// resolveImport returns the path an @import in the file at from refers to:
+// ~/ is the home directory and relative paths start at the importing file.
+func (l locations) resolveImport(from, ref string) string {
+	if rest, ok := strings.CutPrefix(ref, "~/"); ok && l.home != "" {
+		return filepath.Join(l.home, rest)
+	}
+	if filepath.IsAbs(ref) {
+		return filepath.Clean(ref)
+	}
+	return filepath.Join(filepath.Dir(from), ref)
+}
+
+// ancestors returns dir and its parents, from the root down.
+func ancestors(dir string) []string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for {
+		dirs = append([]string{abs}, dirs...)
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return dirs
+		}
+		abs = parent
+	}
+}
+
+var (
+	importPattern = regexp.MustCompile(`(?:^|\s)@((?:[^\s\\]|\\ )+)`)
+	codeSpan      = regexp.MustCompile("`[^`\n]*`")
+)
+
+// Imports returns the paths a memory file imports with @path references,
+// in order. References in code blocks and code spans are not imports.
+func Imports(content string) []string {
+	var refs []string
+	fence := ""
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+		line = codeSpan.ReplaceAllString(line, " ")
+		for _, m := range importPattern.FindAllStringSubmatch(line, -1) {
+			refs = append(refs, strings.ReplaceAll(m[1], `\ `, " "))
+		}
+	}
+	return refs
+}
+
+// Settings returns the Claude Code settings files that apply in workDir,
+// lowest precedence first: the user's, the project's shared and local
+// settings, then the managed settings that override them all.
+func Settings(workDir string) []File {
+	return defaultLocations().settings(workDir)
+}
+
+func (l locations) settings(workDir string) []File {
+	var files []File
+	add := func(path, scope string) {
+		if content, err := os.ReadFile(path); err == nil {
+			files = append(files, File{Path: path, Scope: scope, Content: string(content)})
+		}
+	}
+	if l.home != "" {
+		add(filepath.Join(l.home, ".claude", "settings.json"), ScopeUser)
+	}
+	if abs, err := filepath.Abs(workDir); err == nil {
+		add(filepath.Join(abs, ".claude", "settings.json"), ScopeProject)
+		add(filepath.Join(abs, ".claude", "settings.local.json"), ScopeLocal)
+	}
+	if l.managed != "" {
+		add(filepath.Join(l.managed, "managed-settings.json"), ScopeManaged)
+	}
+	return files
+}
+
+// EstimateTokens roughly estimates how many tokens text takes up in the
+// model's context, at the usual four characters per token.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
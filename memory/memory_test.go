@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	root := t.TempDir()
+	l := locations{home: filepath.Join(root, "home"), managed: filepath.Join(root, "etc")}
+	project := filepath.Join(root, "src", "app")
+	sub := filepath.Join(project, "api")
+
+	writeFile(t, filepath.Join(l.managed, "CLAUDE.md"), "Company policy")
+	writeFile(t, filepath.Join(l.home, ".claude", "CLAUDE.md"), "Personal notes\n@~/.claude/style.md")
+	writeFile(t, filepath.Join(l.home, ".claude", "style.md"), "Use tabs")
+	writeFile(t, filepath.Join(project, "CLAUDE.md"), "See @docs/build.md and @missing.md")
+	writeFile(t, filepath.Join(project, "docs", "build.md"), "Run make\n@../CLAUDE.md")
+	writeFile(t, filepath.Join(project, "CLAUDE.local.md"), "My sandbox URL")
+	writeFile(t, filepath.Join(sub, ".claude", "CLAUDE.md"), "API rules")
+
+	var got []string
+	for _, f := range l.load(sub) {
+		rel, _ := filepath.Rel(root, f.Path)
+		got = append(got, f.Scope+" "+filepath.ToSlash(rel))
+	}
+	want := []string{
+		"managed etc/CLAUDE.md",
+		"user home/.claude/CLAUDE.md",
+		"import home/.claude/style.md",
+		"project src/app/CLAUDE.md",
+		"import src/app/docs/build.md",
+		"local src/app/CLAUDE.local.md",
+		"project src/app/api/.claude/CLAUDE.md",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("unexpected files:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestLoad_ImportDepth(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "CLAUDE.md"), "@1.md")
+	for i := 1; i <= 7; i++ {
+		writeFile(t, filepath.Join(dir, string(rune('0'+i))+".md"), "@"+string(rune('0'+i+1))+".md")
+	}
+	files := locations{}.load(dir)
+	if len(files) != 1+maxImportDepth {
+		t.Errorf("expected imports to stop after %d hops, got %d files", maxImportDepth, len(files))
+	}
+	if files[1].ImportedBy != filepath.Join(dir, "CLAUDE.md") {
+		t.Errorf("unexpected importer %q", files[1].ImportedBy)
+	}
+}
+
+func TestImports(t *testing.T) {
+	content := "Read @README.md and @docs/my\\ notes.md first.\n" +
+		"Mail me at dev@example.com, not `@code.md`.\n" +
+		"```\n@fenced.md\n```\n" +
+		"@~/shared.md"
+	want := []string{"README.md", "docs/my notes.md", "~/shared.md"}
+	if got := Imports(content); !slices.Equal(got, want) {
+		t.Errorf("Imports = %q, want %q", got, want)
+	}
+}
+
+func TestSettings(t *testing.T) {
+	root := t.TempDir()
+	l := locations{home: filepath.Join(root, "home"), managed: filepath.Join(root, "etc")}
+	project := filepath.Join(root, "app")
+	writeFile(t, filepath.Join(l.home, ".claude", "settings.json"), `{"model": "opus"}`)
+	writeFile(t, filepath.Join(project, ".claude", "settings.local.json"), `{}`)
+	writeFile(t, filepath.Join(l.managed, "managed-settings.json"), `{}`)
+
+	var scopes []string
+	for _, f := range l.settings(project) {
+		scopes = append(scopes, f.Scope)
+	}
+	if want := []string{ScopeUser, ScopeLocal, ScopeManaged}; !slices.Equal(scopes, want) {
+		t.Errorf("expected %v, got %v", want, scopes)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	if got := EstimateTokens(""); got != 0 {
+		t.Errorf("expected 0 tokens, got %d", got)
+	}
+	if got := EstimateTokens("abcdefgh!"); got != 3 {
+		t.Errorf("expected 3 tokens, got %d", got)
+	}
+}
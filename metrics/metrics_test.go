@@ -0,0 +1,188 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func render(t *testing.T, r *Registry) string {
+	t.Helper()
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	return b.String()
+}
+
+func TestCounter_Unlabelled(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("jobs_total", "Jobs processed.")
+
+	out := render(t, r)
+	if !strings.Contains(out, "jobs_total 0\n") {
+		t.Errorf("expected unlabelled counter to start at zero, got:\n%s", out)
+	}
+
+	c.Inc()
+	c.Add(2)
+	c.Add(-5) // ignored
+
+	out = render(t, r)
+	expected := "# HELP jobs_total Jobs processed.\n# TYPE jobs_total counter\njobs_total 3\n"
+	if out != expected {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", out, expected)
+	}
+	if c.Value() != 3 {
+		t.Errorf("expected value 3, got %v", c.Value())
+	}
+}
+
+func TestCounter_Labelled(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("exits_total", "Exit codes.", "code")
+	c.Inc("1")
+	c.Inc("0")
+	c.Inc("0")
+
+	out := render(t, r)
+	if !strings.Contains(out, `exits_total{code="0"} 2`) {
+		t.Errorf("missing code=0 series:\n%s", out)
+	}
+	if !strings.Contains(out, `exits_total{code="1"} 1`) {
+		t.Errorf("missing code=1 series:\n%s", out)
+	}
+	if strings.Index(out, `code="0"`) > strings.Index(out, `code="1"`) {
+		t.Error("expected series to be sorted by label values")
+	}
+}
+
+func TestCounter_WrongLabelCountPanics(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("x_total", "x", "a")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for wrong label count")
+		}
+	}()
+	c.Inc()
+}
+
+func TestGauge(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGauge("temperature", "Current temperature.")
+	g.Set(10)
+	g.Add(-2.5)
+
+	out := render(t, r)
+	if !strings.Contains(out, "# TYPE temperature gauge\ntemperature 7.5\n") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
+
+func TestGaugeFuncAndCounterFunc(t *testing.T) {
+	r := NewRegistry()
+	depth := 4.0
+	r.NewGaugeFunc("queue_depth", "Queued items.", func() float64 { return depth })
+	r.NewCounterFunc("reconnects_total", "Reconnects.", func() float64 { return 2 })
+
+	out := render(t, r)
+	if !strings.Contains(out, "queue_depth 4\n") {
+		t.Errorf("missing gauge func value:\n%s", out)
+	}
+	if !strings.Contains(out, "# TYPE reconnects_total counter\nreconnects_total 2\n") {
+		t.Errorf("missing counter func value:\n%s", out)
+	}
+
+	depth = 1
+	if !strings.Contains(render(t, r), "queue_depth 1\n") {
+		t.Error("expected gauge func to be sampled at scrape time")
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("latency_seconds", "Latency.", []float64{1, 0.1}, "method")
+	h.Observe(0.05, "GET")
+	h.Observe(0.5, "GET")
+	h.Observe(3, "GET")
+
+	out := render(t, r)
+	for _, want := range []string{
+		"# TYPE latency_seconds histogram\n",
+		`latency_seconds_bucket{method="GET",le="0.1"} 1`,
+		`latency_seconds_bucket{method="GET",le="1"} 2`,
+		`latency_seconds_bucket{method="GET",le="+Inf"} 3`,
+		`latency_seconds_sum{method="GET"} 3.55`,
+		`latency_seconds_count{method="GET"} 3`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if h.Count("GET") != 3 {
+		t.Errorf("expected count 3, got %d", h.Count("GET"))
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("errors_total", "Errors with \\ and\nnewline.", "msg")
+	c.Inc("say \"hi\"\n")
+
+	out := render(t, r)
+	if !strings.Contains(out, `# HELP errors_total Errors with \\ and\nnewline.`) {
+		t.Errorf("help not escaped:\n%s", out)
+	}
+	if !strings.Contains(out, `errors_total{msg="say \"hi\"\n"} 1`) {
+		t.Errorf("label value not escaped:\n%s", out)
+	}
+}
+
+func TestRegistry_DuplicatePanics(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("dup_total", "x")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for duplicate metric name")
+		}
+	}()
+	r.NewGauge("dup_total", "x")
+}
+
+func TestRegistry_SortedOutput(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("b_total", "b")
+	r.NewCounter("a_total", "a")
+
+	out := render(t, r)
+	if strings.Index(out, "a_total") > strings.Index(out, "b_total") {
+		t.Errorf("expected families sorted by name:\n%s", out)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("hits_total", "Hits.").Inc()
+
+	server := httptest.NewServer(r.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != ContentType {
+		t.Errorf("expected content type %q, got %q", ContentType, ct)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "hits_total 1") {
+		t.Errorf("unexpected body:\n%s", body)
+	}
+}
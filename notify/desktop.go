@@ -0,0 +1,62 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// desktopCommand shows desktop notifications on Linux and BSD desktops.
+const desktopCommand = "notify-send"
+
+// desktopTimeout bounds each notify-send invocation.
+const desktopTimeout = 5 * time.Second
+
+// DesktopConfig enables desktop notifications.
+type DesktopConfig struct {
+	// Enabled shows notifications with notify-send when it is installed
+	Enabled bool `json:"enabled,omitempty"`
+	Route
+}
+
+// desktop shows notifications with notify-send.
+type desktop struct {
+	path string
+}
+
+// newDesktop returns the desktop sink, or nil if it is disabled or
+// notify-send is not installed.
+func newDesktop(cfg DesktopConfig) *desktop {
+	if !cfg.Enabled {
+		return nil
+	}
+	path, err := exec.LookPath(desktopCommand)
+	if err != nil {
+		return nil
+	}
+	return &desktop{path: path}
+}
+
+func (d *desktop) name() string {
+	return "desktop"
+}
+
+func (d *desktop) send(ctx context.Context, n Notification) error {
+	ctx, cancel := context.WithTimeout(ctx, desktopTimeout)
+	defer cancel()
+
+	urgency := "normal"
+	if n.Event == AgentFailed || n.Event == FluxDisconnected {
+		urgency = "critical"
+	}
+	out, err := exec.CommandContext(ctx, d.path, "--app-name=Momentum", "--urgency="+urgency, "--", n.Summary, n.Message).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s: %w: %s", desktopCommand, err, msg)
+		}
+		return fmt.Errorf("%s: %w", desktopCommand, err)
+	}
+	return nil
+}
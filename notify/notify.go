@@ -0,0 +1,249 @@
+// Package notify sends notifications about the worker's lifecycle, such as a
+// failed agent, a completed task or a lost Flux connection, to webhooks and
+// the desktop.
+//
+// Each webhook and the desktop sink has its own routing rule: the events it
+// wants and, optionally, the projects whose tasks it cares about. Webhook
+// payloads are JSON, either the Notification itself or rendered from a
+// template, so the same sender can post to Slack, Teams or any JSON endpoint.
+// Delivery is asynchronous and failed requests are retried, so notifying
+// never blocks the worker.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Event identifies what a notification is about.
+type Event string
+
+const (
+	// AgentStarted is sent when an agent starts working on a task
+	AgentStarted Event = "agent-started"
+	// AgentFailed is sent when an agent fails or times out, or its work is
+	// rejected by a post-run hook or verification
+	AgentFailed Event = "agent-failed"
+	// AgentStopped is sent when the user stops an agent
+	AgentStopped Event = "agent-stopped"
+	// TaskCompleted is sent when a task is marked done
+	TaskCompleted Event = "task-completed"
+	// ReviewRequested is sent when a task is held for a human review
+	ReviewRequested Event = "review-requested"
+	// FluxDisconnected is sent when Momentum has lost its Flux connection
+	FluxDisconnected Event = "flux-disconnected"
+	// FluxReconnected is sent when a lost Flux connection is back
+	FluxReconnected Event = "flux-reconnected"
+)
+
+// Events lists every event, in lifecycle order.
+var Events = []Event{AgentStarted, AgentFailed, AgentStopped, TaskCompleted, ReviewRequested, FluxDisconnected, FluxReconnected}
+
+// DefaultEvents are the events a sink receives when its config lists none.
+var DefaultEvents = []Event{AgentFailed, TaskCompleted, FluxDisconnected}
+
+// allEvents in a sink's event list selects every event.
+const allEvents = "*"
+
+// queueSize bounds the notifications waiting for each sink.
+const queueSize = 64
+
+// closeTimeout bounds how long Close waits for queued notifications.
+const closeTimeout = 5 * time.Second
+
+// Notification is one lifecycle event. It is the default webhook payload and
+// the data webhook templates are rendered with.
+type Notification struct {
+	Event Event     `json:"event"`
+	Time  time.Time `json:"time"`
+	// Summary is a one-line description, e.g. "Agent failed: Fix login"
+	Summary string `json:"summary"`
+	// Message gives the details, e.g. "agent exited with code 1"
+	Message   string `json:"message,omitempty"`
+	TaskID    string `json:"task_id,omitempty"`
+	TaskTitle string `json:"task_title,omitempty"`
+	ProjectID string `json:"project_id,omitempty"`
+	EpicID    string `json:"epic_id,omitempty"`
+	// ExitCode is the agent's exit code, for events after an agent ran
+	ExitCode *int `json:"exit_code,omitempty"`
+	// Instance names the Momentum instance that sent the notification
+	Instance string `json:"instance,omitempty"`
+}
+
+// Config lists where notifications go.
+type Config struct {
+	Webhooks []WebhookConfig `json:"webhooks,omitempty"`
+	Desktop  DesktopConfig   `json:"desktop"`
+}
+
+// Route selects the notifications a sink receives.
+type Route struct {
+	// Events are the events to send, or ["*"] for all of them (default
+	// agent-failed, task-completed and flux-disconnected)
+	Events []Event `json:"events,omitempty"`
+	// Projects limits task events to these project IDs; events without a
+	// task, such as flux-disconnected, are always sent
+	Projects []string `json:"projects,omitempty"`
+}
+
+// validate checks that every event is known.
+func (r Route) validate() error {
+	for _, e := range r.Events {
+		if e != allEvents && !slices.Contains(Events, e) {
+			return fmt.Errorf("unknown notification event %q", e)
+		}
+	}
+	return nil
+}
+
+// matches reports whether n should be sent along the route.
+func (r Route) matches(n Notification) bool {
+	events := r.Events
+	if len(events) == 0 {
+		events = DefaultEvents
+	}
+	if !slices.Contains(events, n.Event) && !slices.Contains(events, allEvents) {
+		return false
+	}
+	return n.TaskID == "" || len(r.Projects) == 0 || slices.Contains(r.Projects, n.ProjectID)
+}
+
+// sink delivers notifications to one destination.
+type sink interface {
+	name() string
+	send(ctx context.Context, n Notification) error
+}
+
+// queue feeds one sink from its own goroutine.
+type queue struct {
+	sink  sink
+	route Route
+	ch    chan Notification
+}
+
+// Notifier routes notifications to the configured sinks. A nil *Notifier
+// sends nothing.
+type Notifier struct {
+	queues  []*queue
+	onError func(error)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// New validates cfg and starts delivering to its sinks. It returns nil when
+// nothing is configured. Delivery failures are passed to onError, which may
+// be nil.
+func New(cfg Config, onError func(error)) (*Notifier, error) {
+	var queues []*queue
+	for i, wc := range cfg.Webhooks {
+		w, err := newWebhook(wc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook %d: %w", i+1, err)
+		}
+		if err := wc.Route.validate(); err != nil {
+			return nil, fmt.Errorf("invalid webhook %s: %w", w.name(), err)
+		}
+		queues = append(queues, &queue{sink: w, route: wc.Route})
+	}
+	if err := cfg.Desktop.Route.validate(); err != nil {
+		return nil, fmt.Errorf("invalid desktop notifications: %w", err)
+	}
+	// Desktop notifications are skipped where notify-send is not installed
+	if d := newDesktop(cfg.Desktop); d != nil {
+		queues = append(queues, &queue{sink: d, route: cfg.Desktop.Route})
+	}
+	if len(queues) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &Notifier{queues: queues, onError: onError, ctx: ctx, cancel: cancel}
+	for _, q := range queues {
+		q.ch = make(chan Notification, queueSize)
+		n.wg.Add(1)
+		go n.deliver(q)
+	}
+	return n, nil
+}
+
+// Notify queues n for every sink whose route matches it. It does not block;
+// when a sink's queue is full the notification is dropped for that sink.
+func (n *Notifier) Notify(note Notification) {
+	if n == nil {
+		return
+	}
+	if note.Time.IsZero() {
+		note.Time = time.Now()
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+	for _, q := range n.queues {
+		if !q.route.matches(note) {
+			continue
+		}
+		select {
+		case q.ch <- note:
+		default:
+			n.report(fmt.Errorf("notification queue for %s is full, dropping %s", q.sink.name(), note.Event))
+		}
+	}
+}
+
+// Close stops accepting notifications and waits briefly for queued ones to
+// be delivered; anything still pending after that is abandoned.
+func (n *Notifier) Close() {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		return
+	}
+	n.closed = true
+	for _, q := range n.queues {
+		close(q.ch)
+	}
+	n.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(closeTimeout):
+		n.cancel()
+		<-done
+	}
+	n.cancel()
+}
+
+// deliver sends q's notifications in order until the queue is closed.
+func (n *Notifier) deliver(q *queue) {
+	defer n.wg.Done()
+	for note := range q.ch {
+		if err := q.sink.send(n.ctx, note); err != nil {
+			n.report(fmt.Errorf("failed to send %s notification to %s: %w", note.Event, q.sink.name(), err))
+		}
+	}
+}
+
+func (n *Notifier) report(err error) {
+	if n.onError != nil {
+		n.onError(err)
+	}
+}
@@ -0,0 +1,313 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// request is one webhook request received by the test server.
+type request struct {
+	header http.Header
+	body   []byte
+}
+
+// webhookServer records requests, answering with the given status codes in
+// turn and 200 once they run out.
+type webhookServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []request
+	statuses []int
+}
+
+func newWebhookServer(t *testing.T, statuses ...int) *webhookServer {
+	t.Helper()
+	s := &webhookServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.requests = append(s.requests, request{header: r.Header.Clone(), body: body})
+		status := http.StatusOK
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		s.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *webhookServer) received() []request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]request(nil), s.requests...)
+}
+
+// newNotifier returns a notifier for cfg that records delivery errors.
+func newNotifier(t *testing.T, cfg Config) (*Notifier, func() []error) {
+	t.Helper()
+	var mu sync.Mutex
+	var errs []error
+	n, err := New(cfg, func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return n, func() []error {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]error(nil), errs...)
+	}
+}
+
+func fastRetries(t *testing.T) {
+	t.Helper()
+	old := retryDelay
+	retryDelay = time.Millisecond
+	t.Cleanup(func() { retryDelay = old })
+}
+
+func TestNew_Nothing(t *testing.T) {
+	n, err := New(Config{Desktop: DesktopConfig{Enabled: false}}, nil)
+	if err != nil || n != nil {
+		t.Fatalf("expected no notifier, got %v, %v", n, err)
+	}
+	// A nil notifier is usable
+	n.Notify(Notification{Event: AgentFailed})
+	n.Close()
+}
+
+func TestNew_Invalid(t *testing.T) {
+	negative := -1
+	tests := []struct {
+		name string
+		cfg  WebhookConfig
+		want string
+	}{
+		{"url", WebhookConfig{URL: "ftp://example.com"}, "invalid url"},
+		{"event", WebhookConfig{URL: "http://example.com", Route: Route{Events: []Event{"agent-exploded"}}}, "unknown notification event"},
+		{"format", WebhookConfig{URL: "http://example.com", Format: "irc"}, "unknown format"},
+		{"template syntax", WebhookConfig{URL: "http://example.com", Template: "{{.Summary"}, "invalid template"},
+		{"template field", WebhookConfig{URL: "http://example.com", Template: `{"x": {{json .Nope}}}`}, "failed to render"},
+		{"template output", WebhookConfig{URL: "http://example.com", Template: `text: {{.Summary}}`}, "did not produce JSON"},
+		{"retries", WebhookConfig{URL: "http://example.com", Retries: &negative}, "invalid retries"},
+		{"timeout", WebhookConfig{URL: "http://example.com", Timeout: "soon"}, "invalid timeout"},
+		{"secret env", WebhookConfig{URL: "http://example.com", SecretEnv: "MOMENTUM_TEST_UNSET_SECRET"}, "is not set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(Config{Webhooks: []WebhookConfig{tt.cfg}}, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestWebhook_DefaultPayload(t *testing.T) {
+	server := newWebhookServer(t)
+	n, errs := newNotifier(t, Config{Webhooks: []WebhookConfig{{URL: server.URL, Secret: "s3cret"}}})
+
+	exitCode := 2
+	n.Notify(Notification{Event: AgentFailed, Summary: "Agent failed: Fix login", Message: "agent exited with code 2",
+		TaskID: "task-1", TaskTitle: "Fix login", ProjectID: "proj-1", ExitCode: &exitCode})
+	n.Close()
+
+	got := server.received()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 request, got %d (errors %v)", len(got), errs())
+	}
+	var payload Notification
+	if err := json.Unmarshal(got[0].body, &payload); err != nil {
+		t.Fatalf("payload is not a notification: %v", err)
+	}
+	if payload.Event != AgentFailed || payload.TaskID != "task-1" || payload.ExitCode == nil || *payload.ExitCode != 2 || payload.Time.IsZero() {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+	if got[0].header.Get("Content-Type") != "application/json" || got[0].header.Get(EventHeader) != string(AgentFailed) {
+		t.Errorf("unexpected headers %v", got[0].header)
+	}
+	if sig := got[0].header.Get(SignatureHeader); sig != Sign([]byte("s3cret"), got[0].body) {
+		t.Errorf("signature %q does not match the body", sig)
+	}
+}
+
+func TestWebhook_Formats(t *testing.T) {
+	server := newWebhookServer(t)
+	t.Setenv("MOMENTUM_TEST_SECRET", "from-env")
+	n, errs := newNotifier(t, Config{Webhooks: []WebhookConfig{
+		{URL: server.URL, Format: "slack", Route: Route{Events: []Event{TaskCompleted}}},
+		{URL: server.URL, Template: `{"title": {{json .Summary}}, "task": {{json .TaskID}}}`, SecretEnv: "MOMENTUM_TEST_SECRET",
+			Headers: map[string]string{"Authorization": "Bearer token"}, Route: Route{Events: []Event{TaskCompleted}}},
+	}})
+
+	n.Notify(Notification{Event: TaskCompleted, Summary: `Task completed: "quoted"`, Message: "agent finished", TaskID: "task-1"})
+	n.Close()
+
+	got := server.received()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 requests, got %d (errors %v)", len(got), errs())
+	}
+	bodies := map[string]request{}
+	for _, r := range got {
+		var m map[string]string
+		if err := json.Unmarshal(r.body, &m); err != nil {
+			t.Fatalf("payload is not JSON: %s", r.body)
+		}
+		for k := range m {
+			bodies[k] = r
+		}
+	}
+	slack, ok := bodies["text"]
+	if !ok || !strings.Contains(string(slack.body), `*Task completed: \"quoted\"*\nagent finished`) {
+		t.Errorf("unexpected slack payload: %s", slack.body)
+	}
+	custom, ok := bodies["task"]
+	if !ok || custom.header.Get("Authorization") != "Bearer token" || custom.header.Get(SignatureHeader) != Sign([]byte("from-env"), custom.body) {
+		t.Errorf("unexpected templated request: %s %v", custom.body, custom.header)
+	}
+}
+
+func TestWebhook_Retries(t *testing.T) {
+	fastRetries(t)
+	server := newWebhookServer(t, http.StatusBadGateway, http.StatusTooManyRequests)
+	n, errs := newNotifier(t, Config{Webhooks: []WebhookConfig{{URL: server.URL}}})
+
+	n.Notify(Notification{Event: FluxDisconnected, Summary: "Lost connection to Flux"})
+	n.Close()
+
+	if got := len(server.received()); got != 3 {
+		t.Errorf("expected 2 retries, got %d requests", got)
+	}
+	if len(errs()) != 0 {
+		t.Errorf("expected the retry to succeed, got %v", errs())
+	}
+}
+
+func TestWebhook_GivesUp(t *testing.T) {
+	fastRetries(t)
+	one := 1
+	server := newWebhookServer(t, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	badRequest := newWebhookServer(t, http.StatusBadRequest)
+	n, errs := newNotifier(t, Config{Webhooks: []WebhookConfig{
+		{Name: "flaky", URL: server.URL, Retries: &one},
+		{Name: "strict", URL: badRequest.URL},
+	}})
+
+	n.Notify(Notification{Event: AgentFailed, Summary: "Agent failed"})
+	n.Close()
+
+	if got := len(server.received()); got != 2 {
+		t.Errorf("expected 1 retry, got %d requests", got)
+	}
+	// Client errors are not retried
+	if got := len(badRequest.received()); got != 1 {
+		t.Errorf("expected no retry after a 400, got %d requests", got)
+	}
+	failures := errs()
+	if len(failures) != 2 {
+		t.Fatalf("expected both webhooks to report a failure, got %v", failures)
+	}
+	joined := failures[0].Error() + "\n" + failures[1].Error()
+	if !strings.Contains(joined, "webhook flaky") || !strings.Contains(joined, "after 2 attempts") || !strings.Contains(joined, "webhook strict") {
+		t.Errorf("unexpected errors: %s", joined)
+	}
+}
+
+func TestRoute_Matches(t *testing.T) {
+	tests := []struct {
+		name  string
+		route Route
+		n     Notification
+		want  bool
+	}{
+		{"default event", Route{}, Notification{Event: AgentFailed}, true},
+		{"default skips", Route{}, Notification{Event: AgentStarted}, false},
+		{"listed", Route{Events: []Event{AgentStarted}}, Notification{Event: AgentStarted}, true},
+		{"not listed", Route{Events: []Event{AgentStarted}}, Notification{Event: AgentFailed}, false},
+		{"all", Route{Events: []Event{"*"}}, Notification{Event: ReviewRequested}, true},
+		{"project", Route{Projects: []string{"p1"}}, Notification{Event: AgentFailed, TaskID: "t", ProjectID: "p1"}, true},
+		{"other project", Route{Projects: []string{"p1"}}, Notification{Event: AgentFailed, TaskID: "t", ProjectID: "p2"}, false},
+		{"no task", Route{Projects: []string{"p1"}}, Notification{Event: FluxDisconnected}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.route.matches(tt.n); got != tt.want {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotifier_Routing(t *testing.T) {
+	failures := newWebhookServer(t)
+	everything := newWebhookServer(t)
+	n, _ := newNotifier(t, Config{Webhooks: []WebhookConfig{
+		{URL: failures.URL, Route: Route{Events: []Event{AgentFailed}, Projects: []string{"proj-1"}}},
+		{URL: everything.URL, Route: Route{Events: []Event{"*"}}},
+	}})
+
+	n.Notify(Notification{Event: AgentStarted, TaskID: "task-1", ProjectID: "proj-1"})
+	n.Notify(Notification{Event: AgentFailed, TaskID: "task-1", ProjectID: "proj-1"})
+	n.Notify(Notification{Event: AgentFailed, TaskID: "task-2", ProjectID: "proj-2"})
+	n.Close()
+	// Nothing is sent once closed
+	n.Notify(Notification{Event: AgentFailed, TaskID: "task-1", ProjectID: "proj-1"})
+
+	if got := len(failures.received()); got != 1 {
+		t.Errorf("expected 1 routed notification, got %d", got)
+	}
+	if got := len(everything.received()); got != 3 {
+		t.Errorf("expected every notification, got %d", got)
+	}
+}
+
+func TestDesktop(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("notify-send is a shell script here")
+	}
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	script := "#!/bin/sh\nprintf '%s\\n' \"$@\" > " + argsFile + "\n"
+	if err := os.WriteFile(filepath.Join(dir, desktopCommand), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+
+	n, errs := newNotifier(t, Config{Desktop: DesktopConfig{Enabled: true}})
+	if n == nil {
+		t.Fatal("expected desktop notifications with notify-send on the PATH")
+	}
+	n.Notify(Notification{Event: FluxDisconnected, Summary: "Lost connection to Flux", Message: "no events for 30s"})
+	n.Close()
+
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatalf("notify-send was not run: %v (errors %v)", err, errs())
+	}
+	want := "--app-name=Momentum\n--urgency=critical\n--\nLost connection to Flux\nno events for 30s\n"
+	if string(args) != want {
+		t.Errorf("unexpected notify-send arguments:\n%s", args)
+	}
+}
+
+func TestDesktop_NotInstalled(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	n, err := New(Config{Desktop: DesktopConfig{Enabled: true}}, nil)
+	if err != nil || n != nil {
+		t.Errorf("expected desktop notifications to be skipped, got %v, %v", n, err)
+	}
+}
@@ -0,0 +1,245 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// DefaultRetries is how often a failed webhook request is retried
+	DefaultRetries = 3
+	// DefaultTimeout bounds each webhook request
+	DefaultTimeout = 10 * time.Second
+	// SignatureHeader carries the payload's HMAC-SHA256 when a secret is set
+	SignatureHeader = "X-Momentum-Signature"
+	// EventHeader names the notification's event
+	EventHeader = "X-Momentum-Event"
+)
+
+// retryDelay is the wait before the first retry; it doubles for each one.
+var retryDelay = time.Second
+
+// Payload formats with a built-in template.
+var formats = map[string]string{
+	"json": "",
+	// Slack incoming webhooks
+	"slack": `{"text": {{json (printf "*%s*\n%s" .Summary .Message)}}}`,
+	// Microsoft Teams incoming webhooks
+	"teams": `{"@type": "MessageCard", "@context": "https://schema.org/extensions", "summary": {{json .Summary}}, "title": {{json .Summary}}, "text": {{json .Message}}}`,
+}
+
+// WebhookConfig describes one webhook.
+type WebhookConfig struct {
+	// Name identifies the webhook in errors (default the URL's host)
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url"`
+	Route
+	// Format picks a built-in payload: "json" (the Notification, the
+	// default), "slack" or "teams"
+	Format string `json:"format,omitempty"`
+	// Template renders the payload with text/template from the
+	// Notification, overriding Format. The result must be JSON; the "json"
+	// function quotes a value, e.g. {"text": {{json .Summary}}}.
+	Template string `json:"template,omitempty"`
+	// Headers are added to every request
+	Headers map[string]string `json:"headers,omitempty"`
+	// Secret signs each payload with HMAC-SHA256, sent as
+	// "X-Momentum-Signature: sha256=<hex>"
+	Secret string `json:"secret,omitempty"`
+	// SecretEnv names an environment variable holding the secret, to keep it
+	// out of the config file
+	SecretEnv string `json:"secret-env,omitempty"`
+	// Retries is how often a failed request is retried (default 3); requests
+	// are retried on network errors, 429 and 5xx responses
+	Retries *int `json:"retries,omitempty"`
+	// Timeout bounds each request, as a Go duration string (default 10s)
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// webhook posts notifications as JSON.
+type webhook struct {
+	label    string
+	url      string
+	template *template.Template
+	headers  map[string]string
+	secret   []byte
+	retries  int
+	client   *http.Client
+}
+
+// newWebhook validates cfg and returns its sink.
+func newWebhook(cfg WebhookConfig) (*webhook, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid url %q", cfg.URL)
+	}
+	w := &webhook{
+		label:   cfg.Name,
+		url:     cfg.URL,
+		headers: cfg.Headers,
+		retries: DefaultRetries,
+		client:  &http.Client{Timeout: DefaultTimeout},
+	}
+	if w.label == "" {
+		w.label = u.Host
+	}
+
+	text := cfg.Template
+	if text == "" {
+		format := cfg.Format
+		if format == "" {
+			format = "json"
+		}
+		builtin, ok := formats[format]
+		if !ok {
+			return nil, fmt.Errorf("unknown format %q (use json, slack or teams)", cfg.Format)
+		}
+		text = builtin
+	}
+	if text != "" {
+		w.template, err = template.New(w.label).Funcs(template.FuncMap{"json": toJSON}).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+		// Render a sample so a template that cannot produce JSON fails now
+		// rather than on the first failed agent
+		exitCode := 1
+		if _, err := w.payload(Notification{Event: AgentFailed, Time: time.Now(), Summary: "Agent failed", ExitCode: &exitCode}); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case cfg.SecretEnv != "":
+		secret := os.Getenv(cfg.SecretEnv)
+		if secret == "" {
+			return nil, fmt.Errorf("secret environment variable %s is not set", cfg.SecretEnv)
+		}
+		w.secret = []byte(secret)
+	case cfg.Secret != "":
+		w.secret = []byte(cfg.Secret)
+	}
+	if cfg.Retries != nil {
+		if *cfg.Retries < 0 {
+			return nil, fmt.Errorf("invalid retries %d", *cfg.Retries)
+		}
+		w.retries = *cfg.Retries
+	}
+	if cfg.Timeout != "" {
+		d, err := time.ParseDuration(cfg.Timeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid timeout %q", cfg.Timeout)
+		}
+		w.client.Timeout = d
+	}
+	return w, nil
+}
+
+func (w *webhook) name() string {
+	return "webhook " + w.label
+}
+
+// payload renders the request body for n.
+func (w *webhook) payload(n Notification) ([]byte, error) {
+	if w.template == nil {
+		return json.Marshal(n)
+	}
+	var buf bytes.Buffer
+	if err := w.template.Execute(&buf, n); err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("template did not produce JSON: %s", strings.TrimSpace(buf.String()))
+	}
+	return buf.Bytes(), nil
+}
+
+// send posts n, retrying with exponential backoff while the failure may be
+// temporary.
+func (w *webhook) send(ctx context.Context, n Notification) error {
+	body, err := w.payload(n)
+	if err != nil {
+		return err
+	}
+
+	delay := retryDelay
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(ctx, n.Event, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= w.retries {
+			if attempt > 0 {
+				return fmt.Errorf("%w (after %d attempts)", err, attempt+1)
+			}
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// post makes one request, reporting whether a failure is worth retrying.
+func (w *webhook) post(ctx context.Context, event Event, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "momentum")
+	req.Header.Set(EventHeader, string(event))
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+	if w.secret != nil {
+		req.Header.Set(SignatureHeader, Sign(w.secret, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return !errors.Is(err, context.Canceled), err
+	}
+	defer resp.Body.Close()
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	err = fmt.Errorf("unexpected status %d", resp.StatusCode)
+	if msg := strings.TrimSpace(string(detail)); msg != "" {
+		err = fmt.Errorf("unexpected status %d: %s", resp.StatusCode, msg)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// Sign returns the signature header value for body: "sha256=" followed by
+// the hex HMAC-SHA256 of body keyed with secret. Receivers recompute it to
+// check that a payload came from Momentum.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// toJSON quotes v for use inside a JSON template.
+func toJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
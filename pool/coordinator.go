@@ -0,0 +1,514 @@
+package pool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirsjg/momentum/agent"
+)
+
+// pollWait is how long a runner's request for work is held open.
+const pollWait = 20 * time.Second
+
+// Coordinator hands agent runs to remote runners. Agents created with
+// NewAgent are queued until a runner with a free slot picks them up.
+type Coordinator struct {
+	mu       sync.Mutex
+	runners  map[string]*runnerState
+	pending  []*remoteAgent
+	assigned map[string]*remoteAgent
+	// notify is closed and replaced whenever work is queued
+	notify chan struct{}
+
+	timeout  time.Duration
+	pollWait time.Duration
+	now      func() time.Time
+}
+
+// runnerState is the coordinator's view of one runner.
+type runnerState struct {
+	status RunnerStatus
+	active map[string]*remoteAgent
+}
+
+// NewCoordinator creates a coordinator with no runners.
+func NewCoordinator() *Coordinator {
+	return &Coordinator{
+		runners:  make(map[string]*runnerState),
+		assigned: make(map[string]*remoteAgent),
+		notify:   make(chan struct{}),
+		timeout:  DefaultRunnerTimeout,
+		pollWait: pollWait,
+		now:      time.Now,
+	}
+}
+
+// Handler returns the HTTP handler for the runner protocol. Wrap it with
+// Authorize before exposing it.
+func (c *Coordinator) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /pool/runners", c.handleRegister)
+	mux.HandleFunc("GET /pool/runners/{id}/next", c.handleNext)
+	mux.HandleFunc("POST /pool/assignments/{id}/output", c.handleOutput)
+	mux.HandleFunc("POST /pool/assignments/{id}/result", c.handleResult)
+	return mux
+}
+
+// Run fails the assignments of runners that stop responding, until ctx is
+// cancelled. Queued and assigned agents are cancelled when it returns.
+func (c *Coordinator) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.timeout / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			c.shutdown()
+			return
+		case <-ticker.C:
+			c.reap()
+		}
+	}
+}
+
+// NewAgent returns an agent that runs on a remote runner. It satisfies
+// agent.AgentFactory.
+func (c *Coordinator) NewAgent(cfg agent.Config) agent.Agent {
+	return &remoteAgent{
+		coord:  c,
+		id:     newID(),
+		config: cfg,
+	}
+}
+
+// HasCapacity reports whether a connected runner has a slot free for
+// another agent beyond those already queued. A nil coordinator always has
+// capacity, so callers can gate on it unconditionally.
+func (c *Coordinator) HasCapacity() bool {
+	if c == nil {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	free := 0
+	for _, rs := range c.runners {
+		free += rs.status.Capacity - len(rs.active)
+	}
+	return free > len(c.pending)
+}
+
+// Runners returns the connected runners ordered by name.
+func (c *Coordinator) Runners() []RunnerStatus {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	runners := make([]RunnerStatus, 0, len(c.runners))
+	for _, rs := range c.runners {
+		status := rs.status
+		status.Active = len(rs.active)
+		runners = append(runners, status)
+	}
+	sort.Slice(runners, func(i, j int) bool {
+		if runners[i].Name != runners[j].Name {
+			return runners[i].Name < runners[j].Name
+		}
+		return runners[i].ID < runners[j].ID
+	})
+	return runners
+}
+
+func (c *Coordinator) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var reg Registration
+	if err := json.NewDecoder(r.Body).Decode(&reg); err != nil {
+		writeError(w, fmt.Errorf("invalid registration: %w", err), http.StatusBadRequest)
+		return
+	}
+	if reg.Capacity < 1 {
+		reg.Capacity = 1
+	}
+
+	id := newID()
+	c.mu.Lock()
+	c.runners[id] = &runnerState{
+		status: RunnerStatus{
+			ID:       id,
+			Name:     reg.Name,
+			Hostname: reg.Hostname,
+			Agent:    reg.Agent,
+			Capacity: reg.Capacity,
+			LastSeen: c.now(),
+		},
+		active: make(map[string]*remoteAgent),
+	}
+	c.mu.Unlock()
+
+	writeJSON(w, registered{ID: id}, http.StatusCreated)
+}
+
+// handleNext long-polls for an assignment for the runner, answering 204
+// if none arrives in time.
+func (c *Coordinator) handleNext(w http.ResponseWriter, r *http.Request) {
+	runnerID := r.PathValue("id")
+	timer := time.NewTimer(c.pollWait)
+	defer timer.Stop()
+
+	for {
+		c.mu.Lock()
+		rs := c.runners[runnerID]
+		if rs == nil {
+			c.mu.Unlock()
+			writeError(w, ErrUnknownRunner, http.StatusNotFound)
+			return
+		}
+		rs.status.LastSeen = c.now()
+		a := c.takePending(rs)
+		notify := c.notify
+		c.mu.Unlock()
+
+		if a != nil {
+			writeJSON(w, a.assignment(), http.StatusOK)
+			return
+		}
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (c *Coordinator) handleOutput(w http.ResponseWriter, r *http.Request) {
+	var batch outputBatch
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		writeError(w, fmt.Errorf("invalid output: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	a := c.assigned[r.PathValue("id")]
+	if a != nil {
+		if rs := c.runners[a.runnerID]; rs != nil {
+			rs.status.LastSeen = c.now()
+		}
+	}
+	c.mu.Unlock()
+	if a == nil {
+		writeError(w, ErrUnknownAssignment, http.StatusNotFound)
+		return
+	}
+
+	a.write(batch.Lines)
+	writeJSON(w, outputReply{Cancel: a.cancelRequested()}, http.StatusOK)
+}
+
+func (c *Coordinator) handleResult(w http.ResponseWriter, r *http.Request) {
+	var result resultReport
+	if err := json.NewDecoder(r.Body).Decode(&result); err != nil {
+		writeError(w, fmt.Errorf("invalid result: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	a := c.assigned[r.PathValue("id")]
+	if a != nil {
+		delete(c.assigned, a.id)
+		if rs := c.runners[a.runnerID]; rs != nil {
+			delete(rs.active, a.id)
+			rs.status.LastSeen = c.now()
+		}
+	}
+	c.mu.Unlock()
+	if a == nil {
+		writeError(w, ErrUnknownAssignment, http.StatusNotFound)
+		return
+	}
+
+	var err error
+	if result.Error != "" {
+		err = errors.New(result.Error)
+	}
+	a.finish(result.ExitCode, err)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// enqueue queues a for the next runner with a free slot.
+func (c *Coordinator) enqueue(a *remoteAgent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = append(c.pending, a)
+	close(c.notify)
+	c.notify = make(chan struct{})
+}
+
+// dequeue removes a from the queue, reporting whether it was still queued.
+func (c *Coordinator) dequeue(a *remoteAgent) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, p := range c.pending {
+		if p == a {
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// takePending assigns the oldest queued agent to rs if it has a free slot.
+// The caller holds c.mu.
+func (c *Coordinator) takePending(rs *runnerState) *remoteAgent {
+	if len(c.pending) == 0 || len(rs.active) >= rs.status.Capacity {
+		return nil
+	}
+	a := c.pending[0]
+	c.pending = c.pending[1:]
+	a.setRunner(rs.status.ID, rs.status.Name, rs.status.Agent)
+	rs.active[a.id] = a
+	c.assigned[a.id] = a
+	return a
+}
+
+// reap drops runners that have not been seen within the timeout and fails
+// their assignments.
+func (c *Coordinator) reap() {
+	c.mu.Lock()
+	var lost []*remoteAgent
+	cutoff := c.now().Add(-c.timeout)
+	for id, rs := range c.runners {
+		if rs.status.LastSeen.After(cutoff) {
+			continue
+		}
+		for _, a := range rs.active {
+			delete(c.assigned, a.id)
+			lost = append(lost, a)
+		}
+		delete(c.runners, id)
+	}
+	c.mu.Unlock()
+
+	for _, a := range lost {
+		a.finish(-1, ErrRunnerLost)
+	}
+}
+
+// shutdown fails everything still queued or assigned.
+func (c *Coordinator) shutdown() {
+	c.mu.Lock()
+	agents := append([]*remoteAgent(nil), c.pending...)
+	for _, a := range c.assigned {
+		agents = append(agents, a)
+	}
+	c.pending = nil
+	c.assigned = make(map[string]*remoteAgent)
+	for _, rs := range c.runners {
+		rs.active = make(map[string]*remoteAgent)
+	}
+	c.mu.Unlock()
+
+	for _, a := range agents {
+		a.finish(-1, agent.ErrAgentCancelled)
+	}
+}
+
+// remoteAgent implements agent.Agent for a run executed by a remote runner.
+// Output posted by the runner is replayed through in-process pipes so the
+// agent works with agent.Runner like a local one.
+type remoteAgent struct {
+	coord  *Coordinator
+	id     string
+	config agent.Config
+
+	mu          sync.Mutex
+	prompt      string
+	started     bool
+	cancelled   bool
+	runnerID    string
+	runnerName  string
+	runnerAgent string
+	stdoutR     *io.PipeReader
+	stdoutW     *io.PipeWriter
+	stderrR     *io.PipeReader
+	stderrW     *io.PipeWriter
+	done        chan struct{}
+	finishOnce  sync.Once
+	exitCode    int
+	err         error
+}
+
+// Name returns the agent's display name: the runner's agent backend and
+// the runner once assigned
+func (a *remoteAgent) Name() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.runnerName == "" {
+		return "Remote agent"
+	}
+	backend := a.runnerAgent
+	if backend == "" {
+		backend = "Remote agent"
+	}
+	return backend + " @ " + a.runnerName
+}
+
+// Start queues the run for the next free runner
+func (a *remoteAgent) Start(ctx context.Context, prompt string) error {
+	a.mu.Lock()
+	if a.started {
+		a.mu.Unlock()
+		return agent.ErrAgentAlreadyRunning
+	}
+	a.started = true
+	a.prompt = prompt
+	a.stdoutR, a.stdoutW = io.Pipe()
+	a.stderrR, a.stderrW = io.Pipe()
+	a.done = make(chan struct{})
+	done := a.done
+	a.mu.Unlock()
+
+	a.coord.enqueue(a)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			a.Cancel()
+		case <-done:
+		}
+	}()
+	return nil
+}
+
+// Stdout returns the runner's relayed stdout
+func (a *remoteAgent) Stdout() io.Reader {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stdoutR == nil {
+		return nil
+	}
+	return a.stdoutR
+}
+
+// Stderr returns the runner's relayed stderr
+func (a *remoteAgent) Stderr() io.Reader {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stderrR == nil {
+		return nil
+	}
+	return a.stderrR
+}
+
+// Wait blocks until the runner reports the result
+func (a *remoteAgent) Wait() (int, error) {
+	a.mu.Lock()
+	done := a.done
+	a.mu.Unlock()
+	if done == nil {
+		return -1, agent.ErrAgentNotStarted
+	}
+	<-done
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.exitCode, a.err
+}
+
+// Cancel drops a queued run, or asks the runner to stop an assigned one
+func (a *remoteAgent) Cancel() error {
+	a.mu.Lock()
+	if !a.started {
+		a.mu.Unlock()
+		return nil
+	}
+	a.cancelled = true
+	a.mu.Unlock()
+
+	if a.coord.dequeue(a) {
+		a.finish(-1, agent.ErrAgentCancelled)
+	}
+	return nil
+}
+
+// IsRunning returns whether the run is queued or executing
+func (a *remoteAgent) IsRunning() bool {
+	a.mu.Lock()
+	done := a.done
+	a.mu.Unlock()
+	if done == nil {
+		return false
+	}
+	select {
+	case <-done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (a *remoteAgent) assignment() Assignment {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return Assignment{
+		ID:         a.id,
+		Prompt:     a.prompt,
+		TimeoutMS:  a.config.Timeout.Milliseconds(),
+		Repository: a.config.Repository,
+	}
+}
+
+func (a *remoteAgent) setRunner(id, name, agentName string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.runnerID = id
+	a.runnerName = name
+	a.runnerAgent = agentName
+}
+
+func (a *remoteAgent) cancelRequested() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.cancelled
+}
+
+// write relays output lines to the agent's pipes.
+func (a *remoteAgent) write(lines []agent.OutputLine) {
+	a.mu.Lock()
+	stdout, stderr := a.stdoutW, a.stderrW
+	a.mu.Unlock()
+
+	for _, line := range lines {
+		w := stdout
+		if line.IsStderr {
+			w = stderr
+		}
+		if _, err := io.WriteString(w, line.Text+"\n"); err != nil {
+			return
+		}
+	}
+}
+
+// finish records the result and ends the output streams.
+func (a *remoteAgent) finish(exitCode int, err error) {
+	a.finishOnce.Do(func() {
+		a.mu.Lock()
+		a.exitCode = exitCode
+		a.err = err
+		a.stdoutW.Close()
+		a.stderrW.Close()
+		done := a.done
+		a.mu.Unlock()
+		close(done)
+	})
+}
@@ -0,0 +1,363 @@
+package pool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirsjg/momentum/agent"
+)
+
+// fakeAgent prints its prompt's lines and exits with exitCode, or blocks
+// until cancelled when block is set.
+type fakeAgent struct {
+	exitCode int
+	block    bool
+
+	mu       sync.Mutex
+	prompt   string
+	config   agent.Config
+	stdout   *io.PipeReader
+	stderr   *io.PipeReader
+	done     chan struct{}
+	cancel   chan struct{}
+	cancelMu sync.Once
+}
+
+func (f *fakeAgent) Name() string { return "fake" }
+
+func (f *fakeAgent) Start(ctx context.Context, prompt string) error {
+	stdoutR, stdoutW := io.Pipe()
+	stderrR, stderrW := io.Pipe()
+	f.mu.Lock()
+	f.prompt = prompt
+	f.stdout, f.stderr = stdoutR, stderrR
+	f.done = make(chan struct{})
+	f.cancel = make(chan struct{})
+	f.mu.Unlock()
+
+	go func() {
+		defer close(f.done)
+		defer stdoutW.Close()
+		defer stderrW.Close()
+		for _, line := range strings.Split(prompt, "\n") {
+			io.WriteString(stdoutW, line+"\n")
+		}
+		io.WriteString(stderrW, "warning\n")
+		if f.block {
+			select {
+			case <-f.cancel:
+				f.exitCode = -1
+			case <-ctx.Done():
+				f.exitCode = -1
+			}
+		}
+	}()
+	return nil
+}
+
+func (f *fakeAgent) Stdout() io.Reader { return f.stdout }
+func (f *fakeAgent) Stderr() io.Reader { return f.stderr }
+
+func (f *fakeAgent) Wait() (int, error) {
+	<-f.done
+	return f.exitCode, nil
+}
+
+func (f *fakeAgent) Cancel() error {
+	f.cancelMu.Do(func() { close(f.cancel) })
+	return nil
+}
+
+func (f *fakeAgent) IsRunning() bool { return false }
+
+// startPool serves a coordinator and runs a worker against it until the
+// test ends.
+func startPool(t *testing.T, newAgent agent.AgentFactory) (*Coordinator, *httptest.Server) {
+	t.Helper()
+	coord := NewCoordinator()
+	srv := httptest.NewServer(Authorize("secret", coord.Handler()))
+	t.Cleanup(srv.Close)
+
+	worker, err := NewWorker(WorkerConfig{
+		Coordinator: srv.URL,
+		Token:       "secret",
+		Name:        "test-runner",
+		WorkDir:     "/remote/work",
+		Agent:       "fake",
+		NewAgent:    newAgent,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		worker.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return coord, srv
+}
+
+// waitFor polls cond until it holds or the test times out.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCoordinator_RunsAgentOnRemoteRunner(t *testing.T) {
+	var mu sync.Mutex
+	var created []*fakeAgent
+	coord, _ := startPool(t, func(cfg agent.Config) agent.Agent {
+		a := &fakeAgent{exitCode: 3, config: cfg}
+		mu.Lock()
+		created = append(created, a)
+		mu.Unlock()
+		return a
+	})
+	waitFor(t, "runner registration", func() bool { return len(coord.Runners()) == 1 })
+	if agentName := coord.Runners()[0].Agent; agentName != "fake" {
+		t.Errorf("expected the runner to report its agent backend, got %q", agentName)
+	}
+
+	remote := coord.NewAgent(agent.Config{WorkDir: "/local", Timeout: time.Minute})
+	if remote.Name() != "Remote agent" {
+		t.Errorf("unexpected agent name before assignment %q", remote.Name())
+	}
+	runner := agent.NewRunner(remote)
+	if err := runner.Run(context.Background(), "first\nsecond"); err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []agent.OutputLine
+	for line := range runner.Output() {
+		lines = append(lines, line)
+	}
+	result := <-runner.Done()
+
+	if result.ExitCode != 3 || result.Error != nil {
+		t.Errorf("expected exit 3 without error, got %d (%v)", result.ExitCode, result.Error)
+	}
+	var stdout []string
+	stderr := 0
+	for _, l := range lines {
+		if l.IsStderr {
+			stderr++
+		} else {
+			stdout = append(stdout, l.Text)
+		}
+	}
+	if strings.Join(stdout, ",") != "first,second" || stderr != 1 {
+		t.Errorf("unexpected relayed output: %+v", lines)
+	}
+	if remote.Name() != "fake @ test-runner" {
+		t.Errorf("unexpected agent name %q", remote.Name())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(created) != 1 {
+		t.Fatalf("expected one agent on the runner, got %d", len(created))
+	}
+	if created[0].config.WorkDir != "/remote/work" || created[0].config.Timeout != time.Minute {
+		t.Errorf("runner agent got config %+v", created[0].config)
+	}
+}
+
+func TestCoordinator_CancelStopsRemoteAgent(t *testing.T) {
+	coord, _ := startPool(t, func(agent.Config) agent.Agent {
+		return &fakeAgent{block: true}
+	})
+	waitFor(t, "runner registration", func() bool { return len(coord.Runners()) == 1 })
+
+	runner := agent.NewRunner(coord.NewAgent(agent.Config{}))
+	if err := runner.Run(context.Background(), "working"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "assignment", func() bool { return coord.Runners()[0].Active == 1 })
+
+	runner.Cancel()
+	select {
+	case result := <-runner.Done():
+		if result.ExitCode != -1 {
+			t.Errorf("expected exit -1 for cancelled agent, got %d", result.ExitCode)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("remote agent was not stopped")
+	}
+}
+
+func TestCoordinator_CancelQueuedAgent(t *testing.T) {
+	coord := NewCoordinator()
+	runner := agent.NewRunner(coord.NewAgent(agent.Config{}))
+	if err := runner.Run(context.Background(), "never assigned"); err != nil {
+		t.Fatal(err)
+	}
+
+	runner.Cancel()
+	result := <-runner.Done()
+	if !errors.Is(result.Error, agent.ErrAgentCancelled) {
+		t.Errorf("expected ErrAgentCancelled, got %v", result.Error)
+	}
+	if len(coord.pending) != 0 {
+		t.Errorf("expected the queue to be empty, got %d", len(coord.pending))
+	}
+}
+
+func TestCoordinator_HasCapacity(t *testing.T) {
+	var nilCoord *Coordinator
+	if !nilCoord.HasCapacity() {
+		t.Error("expected a nil coordinator to always have capacity")
+	}
+
+	coord := NewCoordinator()
+	if coord.HasCapacity() {
+		t.Error("expected no capacity without runners")
+	}
+
+	register(t, coord, Registration{Name: "a", Capacity: 2})
+	if !coord.HasCapacity() {
+		t.Error("expected capacity with an idle runner")
+	}
+
+	coord.NewAgent(agent.Config{}).Start(context.Background(), "one")
+	if !coord.HasCapacity() {
+		t.Error("expected a second slot to be free")
+	}
+	coord.NewAgent(agent.Config{}).Start(context.Background(), "two")
+	if coord.HasCapacity() {
+		t.Error("expected both slots to be spoken for")
+	}
+}
+
+func TestCoordinator_ReapsLostRunners(t *testing.T) {
+	coord := NewCoordinator()
+	now := time.Now()
+	coord.now = func() time.Time { return now }
+	coord.pollWait = 10 * time.Millisecond
+	srv := httptest.NewServer(coord.Handler())
+	defer srv.Close()
+
+	id := register(t, coord, Registration{Name: "flaky"})
+	runner := agent.NewRunner(coord.NewAgent(agent.Config{}))
+	if err := runner.Run(context.Background(), "task"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get(srv.URL + "/pool/runners/" + id + "/next")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected an assignment, got %s", resp.Status)
+	}
+
+	now = now.Add(DefaultRunnerTimeout + time.Second)
+	coord.reap()
+
+	result := <-runner.Done()
+	if !errors.Is(result.Error, ErrRunnerLost) || result.ExitCode != -1 {
+		t.Errorf("expected ErrRunnerLost, got %d (%v)", result.ExitCode, result.Error)
+	}
+	if len(coord.Runners()) != 0 {
+		t.Errorf("expected the lost runner to be dropped, got %+v", coord.Runners())
+	}
+}
+
+func TestCoordinator_NextWithoutWork(t *testing.T) {
+	coord := NewCoordinator()
+	coord.pollWait = 10 * time.Millisecond
+	srv := httptest.NewServer(coord.Handler())
+	defer srv.Close()
+
+	id := register(t, coord, Registration{Name: "idle"})
+	resp, err := http.Get(srv.URL + "/pool/runners/" + id + "/next")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected 204 without work, got %s", resp.Status)
+	}
+
+	resp, err = http.Get(srv.URL + "/pool/runners/unknown/next")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown runner, got %s", resp.Status)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	h := Authorize("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for _, tc := range []struct {
+		header string
+		want   int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"Bearer secret", http.StatusNoContent},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/pool/runners", nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("Authorization %q: expected %d, got %d", tc.header, tc.want, rec.Code)
+		}
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1:7420": true,
+		"localhost:7420": true,
+		"[::1]:7420":     true,
+		":7420":          false,
+		"0.0.0.0:7420":   false,
+		"10.0.0.5:7420":  false,
+	} {
+		if got := IsLoopback(addr); got != want {
+			t.Errorf("IsLoopback(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+// register registers a runner directly through the handler.
+func register(t *testing.T, coord *Coordinator, reg Registration) string {
+	t.Helper()
+	body, _ := json.Marshal(reg)
+	rec := httptest.NewRecorder()
+	coord.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/pool/runners", bytes.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register failed: %d %s", rec.Code, rec.Body)
+	}
+	var resp registered
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	return resp.ID
+}
@@ -0,0 +1,147 @@
+// Package pool distributes agent runs from one Momentum coordinator to
+// remote runner processes, typically on other machines.
+//
+// The coordinator keeps the Flux queue, SSE subscription and task
+// lifecycle. Each agent run it starts is handed to a runner, which executes
+// the agent locally and streams its output lines and result back. Runners
+// pull work over plain HTTP: they long-poll for an assignment, post output
+// in small batches (which doubles as a heartbeat and carries cancellation
+// requests back), and post the result when the agent exits.
+//
+// All endpoints live under /pool/ and are protected by a shared bearer token.
+package pool
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirsjg/momentum/agent"
+)
+
+// DefaultListenAddr is the coordinator's default listen address.
+const DefaultListenAddr = "127.0.0.1:7420"
+
+// DefaultRunnerTimeout is how long a runner may go without contacting the
+// coordinator before its assignments are failed.
+const DefaultRunnerTimeout = time.Minute
+
+// TokenEnv is the environment variable holding the shared pool token.
+const TokenEnv = "MOMENTUM_POOL_TOKEN"
+
+// ErrRunnerLost is the result error for assignments whose runner stopped
+// contacting the coordinator.
+var ErrRunnerLost = errors.New("remote runner stopped responding")
+
+// ErrUnknownRunner is returned to runners the coordinator does not know,
+// for example after a coordinator restart; they should register again.
+var ErrUnknownRunner = errors.New("unknown runner")
+
+// ErrUnknownAssignment is returned for assignments the coordinator no
+// longer tracks; the runner should stop the agent.
+var ErrUnknownAssignment = errors.New("unknown assignment")
+
+// ErrUnauthorized is returned when the pool token is missing or wrong.
+var ErrUnauthorized = errors.New("invalid or missing pool token")
+
+// Registration is sent by a runner when it connects.
+type Registration struct {
+	Name     string `json:"name"`
+	Hostname string `json:"hostname,omitempty"`
+	// Agent is the display name of the agent backend the runner uses
+	Agent string `json:"agent,omitempty"`
+	// Capacity is the number of agents the runner runs at once
+	Capacity int `json:"capacity"`
+}
+
+// Assignment is one agent run handed to a runner.
+type Assignment struct {
+	ID     string `json:"id"`
+	Prompt string `json:"prompt"`
+	// TimeoutMS bounds the agent run (0 = no timeout)
+	TimeoutMS int64 `json:"timeout_ms,omitempty"`
+	// Repository is the git URL of the task's repository, which the runner
+	// checks out to run the agent in; empty runs it in the runner's workdir
+	Repository string `json:"repository,omitempty"`
+}
+
+// RunnerStatus describes a connected runner.
+type RunnerStatus struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Hostname string    `json:"hostname,omitempty"`
+	Agent    string    `json:"agent,omitempty"`
+	Capacity int       `json:"capacity"`
+	Active   int       `json:"active"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+type registered struct {
+	ID string `json:"id"`
+}
+
+type outputBatch struct {
+	Lines []agent.OutputLine `json:"lines"`
+}
+
+type outputReply struct {
+	// Cancel asks the runner to stop the agent
+	Cancel bool `json:"cancel"`
+}
+
+type resultReport struct {
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Authorize wraps next so that requests must carry "Authorization: Bearer
+// <token>". An empty token disables the check.
+func Authorize(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			writeError(w, ErrUnauthorized, http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IsLoopback reports whether a listen address only accepts local connections.
+func IsLoopback(addr string) bool {
+	host := addr
+	if i := strings.LastIndex(addr, ":"); i >= 0 {
+		host = addr[:i]
+	}
+	host = strings.Trim(host, "[]")
+	return host == "localhost" || host == "::1" || strings.HasPrefix(host, "127.")
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error, status int) {
+	writeJSON(w, errorResponse{Error: err.Error()}, status)
+}
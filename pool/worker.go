@@ -0,0 +1,414 @@
+package pool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirsjg/momentum/agent"
+)
+
+const (
+	// flushInterval is how often a runner posts buffered output; an empty
+	// post still serves as a heartbeat
+	flushInterval = time.Second
+	// maxBatch flushes output early once this many lines are buffered
+	maxBatch = 200
+	// requestTimeout bounds every runner request except the long poll
+	requestTimeout = 15 * time.Second
+	// retryDelay is the pause after a failed request for work
+	retryDelay = 2 * time.Second
+)
+
+// WorkerConfig configures a runner process.
+type WorkerConfig struct {
+	// Coordinator is the coordinator's base URL, e.g. http://build-box:7420
+	Coordinator string
+	// Token is the shared pool token
+	Token string
+	// Name identifies the runner in the coordinator's status
+	Name string
+	// Capacity is the number of agents run at once (default 1)
+	Capacity int
+	// WorkDir is the working directory for agents
+	WorkDir string
+	// Checkout returns an up-to-date checkout of a repository, for
+	// assignments that name one; nil runs them in WorkDir too
+	Checkout func(ctx context.Context, repository string) (string, error)
+	// NewAgent creates the local agent for each assignment
+	NewAgent agent.AgentFactory
+	// Agent is the display name of the agents NewAgent creates, reported
+	// to the coordinator
+	Agent string
+	// Timeout is how long the coordinator may be unreachable during a run
+	// before the agent is stopped (default DefaultRunnerTimeout)
+	Timeout time.Duration
+	// Logf receives progress messages, if non-nil
+	Logf func(format string, args ...interface{})
+}
+
+// Worker pulls assignments from a coordinator and runs them locally.
+type Worker struct {
+	cfg     WorkerConfig
+	baseURL string
+	http    *http.Client
+
+	mu sync.Mutex
+	id string
+}
+
+// NewWorker validates cfg and creates a runner.
+func NewWorker(cfg WorkerConfig) (*Worker, error) {
+	u, err := url.Parse(cfg.Coordinator)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid coordinator URL %q", cfg.Coordinator)
+	}
+	if cfg.NewAgent == nil {
+		return nil, errors.New("no agent factory configured")
+	}
+	if cfg.Capacity < 1 {
+		cfg.Capacity = 1
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultRunnerTimeout
+	}
+	if cfg.Name == "" {
+		cfg.Name, _ = os.Hostname()
+	}
+	return &Worker{
+		cfg:     cfg,
+		baseURL: strings.TrimRight(cfg.Coordinator, "/"),
+		http:    &http.Client{},
+	}, nil
+}
+
+// Run registers with the coordinator and executes assignments until ctx is
+// cancelled. Agents still running at that point are stopped and their
+// results reported. Registration is retried until the coordinator is
+// reachable, except when it rejects the token.
+func (w *Worker) Run(ctx context.Context) error {
+	for {
+		_, err := w.register(ctx, "")
+		if err == nil {
+			break
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		w.logf("%v; retrying", err)
+		sleep(ctx, retryDelay)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.cfg.Capacity; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.slot(ctx)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// slot runs one assignment at a time until ctx is cancelled.
+func (w *Worker) slot(ctx context.Context) {
+	for ctx.Err() == nil {
+		id := w.runnerID()
+		a, err := w.next(ctx, id)
+		switch {
+		case errors.Is(err, ErrUnknownRunner):
+			w.logf("coordinator forgot this runner, registering again")
+			if _, err := w.register(ctx, id); err != nil && ctx.Err() == nil {
+				w.logf("register failed: %v", err)
+				sleep(ctx, retryDelay)
+			}
+		case err != nil:
+			if ctx.Err() == nil {
+				w.logf("waiting for work failed: %v", err)
+				sleep(ctx, retryDelay)
+			}
+		case a != nil:
+			w.execute(ctx, a)
+		}
+	}
+}
+
+// register registers the runner unless another slot already replaced the
+// stale registration.
+func (w *Worker) register(ctx context.Context, stale string) (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.id != stale {
+		return w.id, nil
+	}
+
+	hostname, _ := os.Hostname()
+	reg := Registration{Name: w.cfg.Name, Hostname: hostname, Agent: w.cfg.Agent, Capacity: w.cfg.Capacity}
+	var resp registered
+	reqCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	if _, err := w.do(reqCtx, http.MethodPost, "/pool/runners", reg, &resp); err != nil {
+		return "", fmt.Errorf("failed to register with coordinator: %w", err)
+	}
+	w.id = resp.ID
+	w.logf("registered with %s as %s (capacity %d)", w.baseURL, w.cfg.Name, w.cfg.Capacity)
+	return w.id, nil
+}
+
+func (w *Worker) runnerID() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.id
+}
+
+// next long-polls for an assignment, returning nil if none arrived.
+func (w *Worker) next(ctx context.Context, id string) (*Assignment, error) {
+	var a Assignment
+	status, err := w.do(ctx, http.MethodGet, "/pool/runners/"+url.PathEscape(id)+"/next", nil, &a)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNoContent {
+		return nil, nil
+	}
+	return &a, nil
+}
+
+// execute runs one assignment and reports its output and result.
+func (w *Worker) execute(ctx context.Context, a *Assignment) {
+	w.logf("starting assignment %s", a.ID)
+	workDir := w.cfg.WorkDir
+	if a.Repository != "" && w.cfg.Checkout != nil {
+		dir, err := w.checkout(ctx, a)
+		if err != nil {
+			w.report(a.ID, agent.Result{ExitCode: -1, Error: fmt.Errorf("failed to check out %s: %w", a.Repository, err)})
+			return
+		}
+		workDir = dir
+	}
+	runner := agent.NewRunner(w.cfg.NewAgent(agent.Config{
+		WorkDir: workDir,
+		Timeout: time.Duration(a.TimeoutMS) * time.Millisecond,
+	}))
+	if err := runner.Run(ctx, a.Prompt); err != nil {
+		w.report(a.ID, agent.Result{ExitCode: -1, Error: err})
+		return
+	}
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var (
+		batch     []agent.OutputLine
+		cancelled bool
+		lastOK    = time.Now()
+	)
+	stop := func(reason string) {
+		if !cancelled {
+			cancelled = true
+			w.logf("stopping assignment %s: %s", a.ID, reason)
+			runner.Cancel()
+		}
+	}
+	flush := func() {
+		reply, err := w.postOutput(a.ID, batch)
+		batch = batch[:0]
+		switch {
+		case errors.Is(err, ErrUnknownAssignment):
+			stop("the coordinator no longer tracks it")
+		case err != nil:
+			if time.Since(lastOK) > w.cfg.Timeout {
+				stop("the coordinator is unreachable")
+			}
+		default:
+			lastOK = time.Now()
+			if reply.Cancel {
+				stop("cancelled by the coordinator")
+			}
+		}
+	}
+
+	output := runner.Output()
+	for output != nil {
+		select {
+		case line, ok := <-output:
+			if !ok {
+				output = nil
+				break
+			}
+			batch = append(batch, line)
+			if len(batch) >= maxBatch {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+	if len(batch) > 0 {
+		flush()
+	}
+
+	result := <-runner.Done()
+	w.report(a.ID, result)
+	w.logf("finished assignment %s (exit %d)", a.ID, result.ExitCode)
+}
+
+// checkout runs the Checkout hook for a, which may clone the whole
+// repository, while posting heartbeats so the coordinator does not take the
+// runner for lost. The checkout is stopped if the assignment is cancelled or
+// the coordinator stays unreachable.
+func (w *Worker) checkout(ctx context.Context, a *Assignment) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type checkedOut struct {
+		dir string
+		err error
+	}
+	done := make(chan checkedOut, 1)
+	go func() {
+		dir, err := w.cfg.Checkout(ctx, a.Repository)
+		done <- checkedOut{dir, err}
+	}()
+	// Wait for the checkout to stop before the next assignment can use it
+	stop := func(reason error) error {
+		cancel()
+		<-done
+		return reason
+	}
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	lastOK := time.Now()
+	for {
+		select {
+		case c := <-done:
+			return c.dir, c.err
+		case <-ticker.C:
+			reply, err := w.postOutput(a.ID, nil)
+			switch {
+			case errors.Is(err, ErrUnknownAssignment):
+				return "", stop(err)
+			case err != nil:
+				if time.Since(lastOK) > w.cfg.Timeout {
+					return "", stop(errors.New("the coordinator is unreachable"))
+				}
+			default:
+				lastOK = time.Now()
+				if reply.Cancel {
+					return "", stop(errors.New("cancelled by the coordinator"))
+				}
+			}
+		}
+	}
+}
+
+func (w *Worker) postOutput(id string, lines []agent.OutputLine) (outputReply, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	if lines == nil {
+		lines = []agent.OutputLine{}
+	}
+	var reply outputReply
+	_, err := w.do(ctx, http.MethodPost, "/pool/assignments/"+url.PathEscape(id)+"/output", outputBatch{Lines: lines}, &reply)
+	return reply, err
+}
+
+// report posts the result, retrying briefly so a transient failure does not
+// leave the coordinator waiting for the runner timeout.
+func (w *Worker) report(id string, result agent.Result) {
+	body := resultReport{ExitCode: result.ExitCode}
+	if result.Error != nil {
+		body.Error = result.Error.Error()
+	}
+	for attempt := 0; attempt < 3; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		_, err := w.do(ctx, http.MethodPost, "/pool/assignments/"+url.PathEscape(id)+"/result", body, nil)
+		cancel()
+		if err == nil || errors.Is(err, ErrUnknownAssignment) {
+			return
+		}
+		w.logf("reporting result for %s failed: %v", id, err)
+		time.Sleep(retryDelay)
+	}
+}
+
+// do sends a JSON request and decodes a JSON response into out.
+func (w *Worker) do(ctx context.Context, method, path string, in, out interface{}) (int, error) {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return 0, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, w.baseURL+path, body)
+	if err != nil {
+		return 0, err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if w.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.cfg.Token)
+	}
+
+	resp, err := w.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNoContent:
+		return resp.StatusCode, nil
+	case resp.StatusCode >= 300:
+		var e errorResponse
+		json.NewDecoder(resp.Body).Decode(&e)
+		switch e.Error {
+		case ErrUnknownRunner.Error():
+			return resp.StatusCode, ErrUnknownRunner
+		case ErrUnknownAssignment.Error():
+			return resp.StatusCode, ErrUnknownAssignment
+		case ErrUnauthorized.Error():
+			return resp.StatusCode, ErrUnauthorized
+		}
+		return resp.StatusCode, fmt.Errorf("coordinator returned %s: %s", resp.Status, e.Error)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("invalid coordinator response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+func (w *Worker) logf(format string, args ...interface{}) {
+	if w.cfg.Logf != nil {
+		w.cfg.Logf(format, args...)
+	}
+}
+
+// sleep waits for d or until ctx is cancelled.
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
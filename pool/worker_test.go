@@ -0,0 +1,372 @@
+package pool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirsjg/momentum/agent"
+)
+
+func TestNewWorker_Validates(t *testing.T) {
+	factory := func(agent.Config) agent.Agent { return &fakeAgent{} }
+	if _, err := NewWorker(WorkerConfig{Coordinator: "build-box:7420", NewAgent: factory}); err == nil {
+		t.Error("expected an error for a URL without scheme")
+	}
+	if _, err := NewWorker(WorkerConfig{Coordinator: "http://build-box:7420"}); err == nil {
+		t.Error("expected an error without an agent factory")
+	}
+
+	w, err := NewWorker(WorkerConfig{Coordinator: "http://build-box:7420/", NewAgent: factory})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.cfg.Capacity != 1 || w.cfg.Timeout != DefaultRunnerTimeout || w.baseURL != "http://build-box:7420" {
+		t.Errorf("unexpected defaults: %+v (base %s)", w.cfg, w.baseURL)
+	}
+}
+
+func TestWorker_RejectedToken(t *testing.T) {
+	srv := httptest.NewServer(Authorize("secret", NewCoordinator().Handler()))
+	defer srv.Close()
+
+	w, err := NewWorker(WorkerConfig{
+		Coordinator: srv.URL,
+		Token:       "wrong",
+		NewAgent:    func(agent.Config) agent.Agent { return &fakeAgent{} },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Run(context.Background()); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestWorker_ReregistersAfterCoordinatorRestart(t *testing.T) {
+	var mu sync.Mutex
+	coord := NewCoordinator()
+	coord.pollWait = 10 * time.Millisecond
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		h := coord.Handler()
+		mu.Unlock()
+		h.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	w, err := NewWorker(WorkerConfig{
+		Coordinator: srv.URL,
+		Name:        "survivor",
+		NewAgent:    func(agent.Config) agent.Agent { return &fakeAgent{} },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	waitFor(t, "registration", func() bool { return len(coord.Runners()) == 1 })
+
+	// A restarted coordinator knows none of its old runners
+	restarted := NewCoordinator()
+	restarted.pollWait = 10 * time.Millisecond
+	mu.Lock()
+	coord = restarted
+	mu.Unlock()
+
+	waitFor(t, "re-registration", func() bool {
+		runners := restarted.Runners()
+		return len(runners) == 1 && runners[0].Name == "survivor"
+	})
+}
+
+func TestWorker_StopsAgentForUnknownAssignment(t *testing.T) {
+	assigned := make(chan struct{})
+	var once sync.Once
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/pool/runners":
+			writeJSON(w, registered{ID: "r1"}, http.StatusCreated)
+		case r.URL.Path == "/pool/runners/r1/next":
+			served := false
+			once.Do(func() {
+				writeJSON(w, Assignment{ID: "a1", Prompt: "hello"}, http.StatusOK)
+				close(assigned)
+				served = true
+			})
+			if !served {
+				time.Sleep(10 * time.Millisecond)
+				w.WriteHeader(http.StatusNoContent)
+			}
+		default:
+			// The coordinator has forgotten the assignment
+			writeError(w, ErrUnknownAssignment, http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	stopped := make(chan struct{})
+	w, err := NewWorker(WorkerConfig{
+		Coordinator: srv.URL,
+		NewAgent: func(agent.Config) agent.Agent {
+			return &cancelRecorder{fakeAgent: &fakeAgent{block: true}, cancelled: stopped}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	<-assigned
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the agent to be stopped")
+	}
+}
+
+// cancelRecorder signals when its agent is cancelled.
+type cancelRecorder struct {
+	*fakeAgent
+	cancelled chan struct{}
+	once      sync.Once
+}
+
+func (c *cancelRecorder) Cancel() error {
+	c.once.Do(func() { close(c.cancelled) })
+	return c.fakeAgent.Cancel()
+}
+
+func TestWorker_ReportsStartFailure(t *testing.T) {
+	results := make(chan resultReport, 1)
+	var once sync.Once
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pool/runners":
+			writeJSON(w, registered{ID: "r1"}, http.StatusCreated)
+		case "/pool/runners/r1/next":
+			served := false
+			once.Do(func() {
+				writeJSON(w, Assignment{ID: "a1"}, http.StatusOK)
+				served = true
+			})
+			if !served {
+				time.Sleep(10 * time.Millisecond)
+				w.WriteHeader(http.StatusNoContent)
+			}
+		case "/pool/assignments/a1/result":
+			var res resultReport
+			json.NewDecoder(r.Body).Decode(&res)
+			results <- res
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	w, err := NewWorker(WorkerConfig{
+		Coordinator: srv.URL,
+		NewAgent:    func(agent.Config) agent.Agent { return &failingAgent{} },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	select {
+	case res := <-results:
+		if res.ExitCode != -1 || res.Error != "claude not found" {
+			t.Errorf("unexpected result %+v", res)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a result report")
+	}
+}
+
+// serveAssignment serves a coordinator that hands out one assignment and
+// records its result.
+func serveAssignment(t *testing.T, a Assignment) (string, <-chan resultReport) {
+	t.Helper()
+	results := make(chan resultReport, 1)
+	var once sync.Once
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pool/runners":
+			writeJSON(w, registered{ID: "r1"}, http.StatusCreated)
+		case "/pool/runners/r1/next":
+			served := false
+			once.Do(func() {
+				writeJSON(w, a, http.StatusOK)
+				served = true
+			})
+			if !served {
+				time.Sleep(10 * time.Millisecond)
+				w.WriteHeader(http.StatusNoContent)
+			}
+		case "/pool/assignments/" + a.ID + "/result":
+			var res resultReport
+			json.NewDecoder(r.Body).Decode(&res)
+			results <- res
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, results
+}
+
+func TestWorker_ChecksOutRepository(t *testing.T) {
+	url, results := serveAssignment(t, Assignment{ID: "a1", Repository: "https://example.com/acme/api.git"})
+
+	var mu sync.Mutex
+	var checkedOut, workDir string
+	w, err := NewWorker(WorkerConfig{
+		Coordinator: url,
+		WorkDir:     "/remote/work",
+		Checkout: func(_ context.Context, repository string) (string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			checkedOut = repository
+			return "/cache/api", nil
+		},
+		NewAgent: func(cfg agent.Config) agent.Agent {
+			mu.Lock()
+			defer mu.Unlock()
+			workDir = cfg.WorkDir
+			return &failingAgent{}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	select {
+	case <-results:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a result report")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if checkedOut != "https://example.com/acme/api.git" || workDir != "/cache/api" {
+		t.Errorf("expected the agent to run in the checkout, got %q in %q", checkedOut, workDir)
+	}
+}
+
+func TestWorker_ReportsCheckoutFailure(t *testing.T) {
+	url, results := serveAssignment(t, Assignment{ID: "a1", Repository: "https://example.com/acme/api.git"})
+
+	w, err := NewWorker(WorkerConfig{
+		Coordinator: url,
+		Checkout: func(context.Context, string) (string, error) {
+			return "", errors.New("repository not found")
+		},
+		NewAgent: func(agent.Config) agent.Agent {
+			t.Error("expected no agent without a checkout")
+			return &failingAgent{}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	select {
+	case res := <-results:
+		if res.ExitCode != -1 || res.Error != "failed to check out https://example.com/acme/api.git: repository not found" {
+			t.Errorf("unexpected result %+v", res)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a result report")
+	}
+}
+
+func TestWorker_HeartbeatsDuringCheckout(t *testing.T) {
+	results := make(chan resultReport, 1)
+	var once sync.Once
+	var heartbeats atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pool/runners":
+			writeJSON(w, registered{ID: "r1"}, http.StatusCreated)
+		case "/pool/runners/r1/next":
+			served := false
+			once.Do(func() {
+				writeJSON(w, Assignment{ID: "a1", Repository: "https://example.com/acme/api.git"}, http.StatusOK)
+				served = true
+			})
+			if !served {
+				time.Sleep(10 * time.Millisecond)
+				w.WriteHeader(http.StatusNoContent)
+			}
+		case "/pool/assignments/a1/output":
+			// Cancel the assignment on the second heartbeat
+			writeJSON(w, outputReply{Cancel: heartbeats.Add(1) > 1}, http.StatusOK)
+		case "/pool/assignments/a1/result":
+			var res resultReport
+			json.NewDecoder(r.Body).Decode(&res)
+			results <- res
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	w, err := NewWorker(WorkerConfig{
+		Coordinator: srv.URL,
+		// A clone that only ends when it is stopped
+		Checkout: func(ctx context.Context, _ string) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		},
+		NewAgent: func(agent.Config) agent.Agent {
+			t.Error("expected no agent without a checkout")
+			return &failingAgent{}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	select {
+	case res := <-results:
+		if res.ExitCode != -1 || !strings.Contains(res.Error, "cancelled by the coordinator") {
+			t.Errorf("unexpected result %+v", res)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected a result report")
+	}
+	if n := heartbeats.Load(); n < 2 {
+		t.Errorf("expected heartbeats while checking out, got %d", n)
+	}
+}
+
+// failingAgent cannot be started.
+type failingAgent struct{ fakeAgent }
+
+func (*failingAgent) Start(context.Context, string) error {
+	return errors.New("claude not found")
+}
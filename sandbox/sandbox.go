@@ -0,0 +1,293 @@
+// Package sandbox restricts agent processes according to a configured
+// execution profile.
+//
+// A profile can limit the environment passed to the agent to an allowlist,
+// apply resource limits (CPU time, address space and process count, via the
+// prlimit tool from util-linux), mount everything outside the working
+// directory read-only (via bubblewrap) and replace Claude Code's
+// --dangerously-skip-permissions with explicit tool allowlists.
+//
+// Restrictions fail closed: if a profile asks for a limit or a read-only
+// filesystem and the required tool is not installed, the agent is not
+// started.
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnavailable is returned when a profile needs a tool that is not installed.
+var ErrUnavailable = errors.New("sandbox tool not available")
+
+// Tools used to apply restrictions; variables so tests can substitute them.
+var (
+	prlimitCommand = "prlimit"
+	bwrapCommand   = "bwrap"
+)
+
+// Config selects one of several named execution profiles.
+type Config struct {
+	// Profile names the profile used unless --profile overrides it
+	Profile string `json:"profile,omitempty"`
+	// Profiles maps profile names to their restrictions
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+}
+
+// Profile describes how an agent process is restricted.
+type Profile struct {
+	// Env lists the environment variables passed through to the agent, as
+	// exact names or prefixes ending in "*". Empty passes the full
+	// environment. Variables set explicitly for the agent always pass.
+	Env []string `json:"env,omitempty"`
+	// Limits are resource limits applied to the agent and its children
+	Limits Limits `json:"limits,omitempty"`
+	// Filesystem restricts where the agent can write
+	Filesystem Filesystem `json:"filesystem,omitempty"`
+	// Permissions replace skipping Claude Code's permission checks
+	Permissions Permissions `json:"permissions,omitempty"`
+}
+
+// Limits are per-process resource limits (rlimits), inherited by children.
+type Limits struct {
+	// CPUTime bounds CPU time, as a Go duration string (e.g. "30m")
+	CPUTime string `json:"cpu-time,omitempty"`
+	// MemoryMB bounds each process's address space. Node.js reserves a lot
+	// of virtual memory, so leave generous headroom.
+	MemoryMB int `json:"memory-mb,omitempty"`
+	// Processes bounds the number of processes for the user (RLIMIT_NPROC),
+	// which includes processes outside the agent
+	Processes int `json:"processes,omitempty"`
+}
+
+// Filesystem restricts the agent's view of the filesystem.
+type Filesystem struct {
+	// ReadOnly mounts everything except the working directory, /tmp and
+	// Writable read-only
+	ReadOnly bool `json:"read-only,omitempty"`
+	// Writable lists extra paths that stay writable; "~/" is expanded
+	Writable []string `json:"writable,omitempty"`
+}
+
+// Permissions configure Claude Code's tool permission checks.
+type Permissions struct {
+	// AllowedTools are permitted without prompting, e.g. "Edit" or
+	// "Bash(go test:*)". Setting any rules stops Momentum from passing
+	// --dangerously-skip-permissions; tools not allowed are denied.
+	AllowedTools []string `json:"allowed-tools,omitempty"`
+	// DisallowedTools are always denied
+	DisallowedTools []string `json:"disallowed-tools,omitempty"`
+}
+
+// Select returns the profile named by name, or by the config's default when
+// name is empty. A nil profile means agents run unrestricted.
+func (c Config) Select(name string) (*Profile, error) {
+	if name == "" {
+		name = c.Profile
+	}
+	if name == "" {
+		return nil, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown sandbox profile %q (configured: %s)", name, strings.Join(c.names(), ", "))
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("sandbox profile %q: %w", name, err)
+	}
+	return &p, nil
+}
+
+func (c Config) names() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return []string{"none"}
+	}
+	return names
+}
+
+// Validate checks the profile's settings.
+func (p *Profile) Validate() error {
+	if p.Limits.CPUTime != "" {
+		d, err := time.ParseDuration(p.Limits.CPUTime)
+		if err != nil || d < time.Second {
+			return fmt.Errorf("invalid cpu-time %q", p.Limits.CPUTime)
+		}
+	}
+	if p.Limits.MemoryMB < 0 || p.Limits.Processes < 0 {
+		return errors.New("limits must not be negative")
+	}
+	for _, pattern := range p.Env {
+		// "*" is only allowed as a trailing wildcard
+		if pattern == "" || strings.Contains(pattern, "=") || strings.Contains(strings.TrimSuffix(pattern, "*"), "*") {
+			return fmt.Errorf("invalid env pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// Environ builds the agent's environment from base (usually os.Environ())
+// filtered by the allowlist, plus extra. A nil profile keeps base whole.
+func (p *Profile) Environ(base []string, extra map[string]string) []string {
+	env := make([]string, 0, len(base)+len(extra))
+	for _, kv := range base {
+		name, _, _ := strings.Cut(kv, "=")
+		if _, overridden := extra[name]; overridden {
+			continue
+		}
+		if p == nil || len(p.Env) == 0 || p.allows(name) {
+			env = append(env, kv)
+		}
+	}
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+extra[k])
+	}
+	return env
+}
+
+func (p *Profile) allows(name string) bool {
+	for _, pattern := range p.Env {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// RestrictsPermissions reports whether Claude Code's permission checks
+// should stay on.
+func (p *Profile) RestrictsPermissions() bool {
+	return p != nil && (len(p.Permissions.AllowedTools) > 0 || len(p.Permissions.DisallowedTools) > 0)
+}
+
+// ClaudeArgs returns the Claude Code permission flags for the profile.
+func (p *Profile) ClaudeArgs() []string {
+	if !p.RestrictsPermissions() {
+		return []string{"--dangerously-skip-permissions"}
+	}
+	var args []string
+	if len(p.Permissions.AllowedTools) > 0 {
+		args = append(args, "--allowedTools", strings.Join(p.Permissions.AllowedTools, ","))
+	}
+	if len(p.Permissions.DisallowedTools) > 0 {
+		args = append(args, "--disallowedTools", strings.Join(p.Permissions.DisallowedTools, ","))
+	}
+	return args
+}
+
+// Wrap rewrites cmd so it runs under the profile's resource limits and
+// filesystem restrictions. workDir stays writable, as do writable, which
+// lists state the agent itself needs (e.g. its config directory). A nil
+// profile leaves cmd unchanged.
+func (p *Profile) Wrap(cmd *exec.Cmd, workDir string, writable []string) error {
+	if p == nil {
+		return nil
+	}
+
+	argv := append([]string{cmd.Path}, cmd.Args[1:]...)
+
+	if p.Filesystem.ReadOnly {
+		bwrap, err := exec.LookPath(bwrapCommand)
+		if err != nil {
+			return fmt.Errorf("%w: a read-only filesystem needs bubblewrap (%s)", ErrUnavailable, bwrapCommand)
+		}
+		dir, err := filepath.Abs(workDir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve workdir: %w", err)
+		}
+		args := []string{bwrap,
+			"--ro-bind", "/", "/",
+			"--dev", "/dev",
+			"--proc", "/proc",
+			"--tmpfs", "/tmp",
+			"--bind", dir, dir,
+		}
+		for _, path := range append(append([]string(nil), writable...), p.Filesystem.Writable...) {
+			path = expandHome(path)
+			args = append(args, "--bind-try", path, path)
+		}
+		args = append(args, "--unshare-pid", "--unshare-ipc", "--die-with-parent", "--chdir", dir, "--")
+		argv = append(args, argv...)
+	}
+
+	if limits := p.Limits.prlimitArgs(); len(limits) > 0 {
+		prlimit, err := exec.LookPath(prlimitCommand)
+		if err != nil {
+			return fmt.Errorf("%w: resource limits need prlimit from util-linux (%s)", ErrUnavailable, prlimitCommand)
+		}
+		args := append([]string{prlimit}, limits...)
+		argv = append(append(args, "--"), argv...)
+	}
+
+	cmd.Path = argv[0]
+	cmd.Args = argv
+	return nil
+}
+
+// prlimitArgs returns prlimit flags for the configured limits.
+func (l Limits) prlimitArgs() []string {
+	var args []string
+	if l.CPUTime != "" {
+		d, _ := time.ParseDuration(l.CPUTime)
+		args = append(args, "--cpu="+strconv.Itoa(int(d.Seconds())))
+	}
+	if l.MemoryMB > 0 {
+		args = append(args, "--as="+strconv.FormatInt(int64(l.MemoryMB)*1024*1024, 10))
+	}
+	if l.Processes > 0 {
+		args = append(args, "--nproc="+strconv.Itoa(l.Processes))
+	}
+	return args
+}
+
+// Describe summarises the profile for logs and status output.
+func (p *Profile) Describe() string {
+	if p == nil {
+		return "unrestricted"
+	}
+	var parts []string
+	if len(p.Env) > 0 {
+		parts = append(parts, fmt.Sprintf("env allowlist (%d)", len(p.Env)))
+	}
+	if args := p.Limits.prlimitArgs(); len(args) > 0 {
+		parts = append(parts, "limits "+strings.Join(args, " "))
+	}
+	if p.Filesystem.ReadOnly {
+		parts = append(parts, "read-only filesystem")
+	}
+	if p.RestrictsPermissions() {
+		parts = append(parts, "tool allowlist")
+	} else {
+		parts = append(parts, "permissions skipped")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
@@ -0,0 +1,196 @@
+package sandbox
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	cfg := Config{
+		Profile: "strict",
+		Profiles: map[string]Profile{
+			"strict":  {Env: []string{"PATH"}},
+			"relaxed": {},
+			"broken":  {Limits: Limits{CPUTime: "soon"}},
+		},
+	}
+
+	p, err := cfg.Select("")
+	if err != nil || p == nil || len(p.Env) != 1 {
+		t.Errorf("expected the default profile, got %+v (%v)", p, err)
+	}
+	if p, err := cfg.Select("relaxed"); err != nil || p == nil || len(p.Env) != 0 {
+		t.Errorf("expected --profile to override the default, got %+v (%v)", p, err)
+	}
+	if _, err := cfg.Select("missing"); err == nil || !strings.Contains(err.Error(), "broken, relaxed, strict") {
+		t.Errorf("expected an unknown profile error listing profiles, got %v", err)
+	}
+	if _, err := cfg.Select("broken"); err == nil {
+		t.Error("expected an invalid profile to be rejected")
+	}
+	if p, err := (Config{}).Select(""); p != nil || err != nil {
+		t.Errorf("expected no profile without config, got %+v (%v)", p, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		profile Profile
+		valid   bool
+	}{
+		{"empty", Profile{}, true},
+		{"limits", Profile{Limits: Limits{CPUTime: "30m", MemoryMB: 4096, Processes: 256}}, true},
+		{"prefix", Profile{Env: []string{"LC_*", "PATH"}}, true},
+		{"short cpu", Profile{Limits: Limits{CPUTime: "10ms"}}, false},
+		{"negative", Profile{Limits: Limits{Processes: -1}}, false},
+		{"inner wildcard", Profile{Env: []string{"A*B"}}, false},
+		{"assignment", Profile{Env: []string{"PATH=/bin"}}, false},
+	} {
+		err := tc.profile.Validate()
+		if (err == nil) != tc.valid {
+			t.Errorf("%s: valid=%v, got %v", tc.name, tc.valid, err)
+		}
+	}
+}
+
+func TestEnviron(t *testing.T) {
+	base := []string{"PATH=/bin", "HOME=/home/me", "LC_ALL=C", "AWS_SECRET_ACCESS_KEY=shh", "TOKEN=old"}
+	extra := map[string]string{"TOKEN": "new", "MOMENTUM_TASK": "t1"}
+
+	p := &Profile{Env: []string{"PATH", "LC_*"}}
+	got := strings.Join(p.Environ(base, extra), " ")
+	if got != "PATH=/bin LC_ALL=C MOMENTUM_TASK=t1 TOKEN=new" {
+		t.Errorf("unexpected filtered env: %s", got)
+	}
+
+	var unrestricted *Profile
+	got = strings.Join(unrestricted.Environ(base, extra), " ")
+	if got != "PATH=/bin HOME=/home/me LC_ALL=C AWS_SECRET_ACCESS_KEY=shh MOMENTUM_TASK=t1 TOKEN=new" {
+		t.Errorf("expected the full env plus overrides, got %s", got)
+	}
+}
+
+func TestClaudeArgs(t *testing.T) {
+	var unrestricted *Profile
+	if got := unrestricted.ClaudeArgs(); len(got) != 1 || got[0] != "--dangerously-skip-permissions" {
+		t.Errorf("expected permissions to be skipped without a profile, got %v", got)
+	}
+
+	p := &Profile{Permissions: Permissions{
+		AllowedTools:    []string{"Read", "Edit", "Bash(go test:*)", "mcp__flux"},
+		DisallowedTools: []string{"WebFetch"},
+	}}
+	got := strings.Join(p.ClaudeArgs(), " ")
+	if got != "--allowedTools Read,Edit,Bash(go test:*),mcp__flux --disallowedTools WebFetch" {
+		t.Errorf("unexpected permission args: %s", got)
+	}
+}
+
+// fakeTool creates an executable standing in for a sandbox tool.
+func fakeTool(t *testing.T, name string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools are shell scripts")
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestWrap(t *testing.T) {
+	oldPrlimit, oldBwrap := prlimitCommand, bwrapCommand
+	defer func() { prlimitCommand, bwrapCommand = oldPrlimit, oldBwrap }()
+	prlimitCommand = fakeTool(t, "prlimit")
+	bwrapCommand = fakeTool(t, "bwrap")
+
+	work := t.TempDir()
+	p := &Profile{
+		Limits:     Limits{CPUTime: "30m", MemoryMB: 2048, Processes: 100},
+		Filesystem: Filesystem{ReadOnly: true, Writable: []string{"/var/cache/app"}},
+	}
+	cmd := exec.Command("/usr/bin/claude", "-p", "prompt")
+	if err := p.Wrap(cmd, work, []string{"/state"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := strings.Join(cmd.Args, " ")
+	want := prlimitCommand + " --cpu=1800 --as=2147483648 --nproc=100 -- " +
+		bwrapCommand + " --ro-bind / / --dev /dev --proc /proc --tmpfs /tmp --bind " + work + " " + work +
+		" --bind-try /state /state --bind-try /var/cache/app /var/cache/app" +
+		" --unshare-pid --unshare-ipc --die-with-parent --chdir " + work + " -- /usr/bin/claude -p prompt"
+	if got != want {
+		t.Errorf("unexpected wrapped command:\n got %s\nwant %s", got, want)
+	}
+	if cmd.Path != prlimitCommand {
+		t.Errorf("expected prlimit to be executed, got %s", cmd.Path)
+	}
+}
+
+func TestWrap_MissingToolFailsClosed(t *testing.T) {
+	oldPrlimit, oldBwrap := prlimitCommand, bwrapCommand
+	defer func() { prlimitCommand, bwrapCommand = oldPrlimit, oldBwrap }()
+	prlimitCommand = "momentum-no-such-prlimit"
+	bwrapCommand = "momentum-no-such-bwrap"
+
+	for _, p := range []*Profile{
+		{Limits: Limits{Processes: 10}},
+		{Filesystem: Filesystem{ReadOnly: true}},
+	} {
+		err := p.Wrap(exec.Command("claude"), ".", nil)
+		if !errors.Is(err, ErrUnavailable) {
+			t.Errorf("expected ErrUnavailable for %+v, got %v", p, err)
+		}
+	}
+
+	// Profiles without limits or filesystem rules need no tools
+	cmd := exec.Command("claude")
+	if err := (&Profile{Env: []string{"PATH"}}).Wrap(cmd, ".", nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(cmd.Args) != 1 {
+		t.Errorf("expected the command to stay unwrapped, got %v", cmd.Args)
+	}
+}
+
+func TestWrap_AppliesLimits(t *testing.T) {
+	if _, err := exec.LookPath(prlimitCommand); err != nil {
+		t.Skip("prlimit not installed")
+	}
+
+	cmd := exec.Command("/bin/sh", "-c", "ulimit -t")
+	p := &Profile{Limits: Limits{CPUTime: "42s"}}
+	if err := p.Wrap(cmd, ".", nil); err != nil {
+		t.Fatal(err)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("wrapped command failed: %v", err)
+	}
+	if strings.TrimSpace(string(out)) != "42" {
+		t.Errorf("expected a 42s CPU limit, got %q", out)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	var unrestricted *Profile
+	if unrestricted.Describe() != "unrestricted" {
+		t.Errorf("unexpected description %q", unrestricted.Describe())
+	}
+	p := &Profile{
+		Env:         []string{"PATH", "HOME"},
+		Limits:      Limits{Processes: 50},
+		Filesystem:  Filesystem{ReadOnly: true},
+		Permissions: Permissions{AllowedTools: []string{"Read"}},
+	}
+	if got := p.Describe(); got != "env allowlist (2), limits --nproc=50, read-only filesystem, tool allowlist" {
+		t.Errorf("unexpected description %q", got)
+	}
+}
@@ -2,11 +2,16 @@
 package selection
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/lease"
+	"github.com/sirsjg/momentum/tracing"
+	"github.com/sirsjg/momentum/workflow"
 )
 
 // ErrNoTaskAvailable is returned when no suitable task can be found.
@@ -19,6 +24,11 @@ type Selector struct {
 	projectID string
 	epicID    string
 	taskID    string
+	ctx       context.Context
+	// stealExpired also offers in_progress tasks whose lease has expired
+	stealExpired bool
+	// states names the pickup statuses; nil picks up "todo" tasks
+	states *workflow.StateMachine
 }
 
 // NewSelector creates a new Selector with the given filters.
@@ -41,7 +51,7 @@ func NewSelector(c *client.Client, projectID, epicID, taskID string) *Selector {
 //
 // Only tasks meeting ALL of these criteria are considered:
 //   - Task belongs to an epic with auto=true
-//   - Task has status "todo"
+//   - Task has a pickup status ("todo" unless configured with WithStates)
 //   - Task is unblocked (blocked=false)
 //
 // Within the qualifying tasks, newer tasks (by ID) come first.
@@ -49,8 +59,58 @@ func (s *Selector) SelectTask() (*client.Task, error) {
 	return s.SelectTaskExcluding(nil)
 }
 
+// WithContext returns a shallow copy of the selector whose Flux requests use
+// ctx for cancellation and trace propagation.
+func (s *Selector) WithContext(ctx context.Context) *Selector {
+	sc := *s
+	sc.ctx = ctx
+	sc.client = s.client.WithContext(ctx)
+	return &sc
+}
+
+// WithExpiredLeases returns a copy of the selector that also considers
+// in_progress tasks whose lease has expired, so a task abandoned by a
+// crashed instance can be taken over.
+func (s *Selector) WithExpiredLeases() *Selector {
+	sc := *s
+	sc.stealExpired = true
+	return &sc
+}
+
+// WithStates returns a copy of the selector that picks up tasks in the
+// pickup statuses of states instead of "todo".
+func (s *Selector) WithStates(states *workflow.StateMachine) *Selector {
+	sc := *s
+	sc.states = states
+	return &sc
+}
+
 // SelectTaskExcluding selects a task while skipping any task IDs in excluded.
 func (s *Selector) SelectTaskExcluding(excluded map[string]bool) (*client.Task, error) {
+	if s.ctx == nil {
+		return s.selectTaskExcluding(excluded)
+	}
+
+	ctx, span := tracing.Start(s.ctx, "selection.select",
+		tracing.String("selection.project_id", s.projectID),
+		tracing.String("selection.epic_id", s.epicID),
+		tracing.String("selection.task_id", s.taskID),
+		tracing.Int("selection.excluded", len(excluded)),
+	)
+	defer span.End()
+
+	task, err := s.WithContext(ctx).selectTaskExcluding(excluded)
+	if err != nil {
+		if !errors.Is(err, ErrNoTaskAvailable) {
+			span.SetError(err)
+		}
+		return nil, err
+	}
+	span.SetAttributes(tracing.String("task.id", task.ID))
+	return task, nil
+}
+
+func (s *Selector) selectTaskExcluding(excluded map[string]bool) (*client.Task, error) {
 	// Case 1: Specific task ID provided
 	if s.taskID != "" {
 		return s.fetchSpecificTask(excluded)
@@ -222,7 +282,7 @@ func (s *Selector) getAutoEpicIDs(projectID string) (map[string]bool, error) {
 }
 
 // selectBestTask selects the best task from a list.
-// Only tasks belonging to auto-enabled epics with status "todo" and unblocked are considered.
+// Only tasks belonging to auto-enabled epics with a pickup status and unblocked are considered.
 // Tasks are sorted by ID descending (newer first).
 func (s *Selector) selectBestTask(tasks []client.Task, autoEpicIDs map[string]bool, excluded map[string]bool) (*client.Task, error) {
 	if len(tasks) == 0 {
@@ -238,7 +298,7 @@ func (s *Selector) selectBestTask(tasks []client.Task, autoEpicIDs map[string]bo
 	}
 
 	// Filter and sort tasks
-	candidates := filterAndSortTasks(autoTasks, excluded)
+	candidates := filterAndSortCandidates(autoTasks, excluded, s.states, s.stealExpired)
 
 	if len(candidates) == 0 {
 		return nil, ErrNoTaskAvailable
@@ -250,13 +310,24 @@ func (s *Selector) selectBestTask(tasks []client.Task, autoEpicIDs map[string]bo
 // filterAndSortTasks filters tasks to only include unblocked tasks with status "todo",
 // sorted by ID descending (newer first).
 func filterAndSortTasks(tasks []client.Task, excluded map[string]bool) []client.Task {
+	return filterAndSortCandidates(tasks, excluded, nil, false)
+}
+
+// filterAndSortCandidates is filterAndSortTasks for the pickup statuses of
+// states that, when stealExpired is set, also keeps unblocked running tasks
+// whose lease has expired.
+func filterAndSortCandidates(tasks []client.Task, excluded map[string]bool, states *workflow.StateMachine, stealExpired bool) []client.Task {
 	var unblockedTodos []client.Task
+	now := time.Now()
 
 	for _, task := range tasks {
 		if excluded != nil && excluded[task.ID] {
 			continue
 		}
-		if !task.Blocked && task.Status == "todo" {
+		if task.Blocked {
+			continue
+		}
+		if states.IsPickup(task.Status) || (stealExpired && lease.Stealable(task, states.Running(), now)) {
 			unblockedTodos = append(unblockedTodos, task)
 		}
 	}
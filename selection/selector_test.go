@@ -1,13 +1,18 @@
 package selection
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/lease"
+	"github.com/sirsjg/momentum/tracing"
+	"github.com/sirsjg/momentum/workflow"
 )
 
 // mockServer creates a test server that responds with the given data.
@@ -619,6 +624,44 @@ func TestPrioritizeTodoStatusOverOtherStatuses(t *testing.T) {
 	}
 }
 
+func TestSelectWithExpiredLeases(t *testing.T) {
+	expired := lease.Set("Details", lease.Lease{Owner: "host-a:1", Expires: time.Now().Add(-time.Minute)})
+	live := lease.Set("Details", lease.Lease{Owner: "host-b:2", Expires: time.Now().Add(time.Hour)})
+
+	m := newMockServer()
+	m.projects = []client.Project{{ID: "proj-1", Name: "Project 1"}}
+	m.epics = map[string][]client.Epic{
+		"proj-1": {{ID: "epic-1", Title: "Epic 1", ProjectID: "proj-1", Auto: true}},
+	}
+	m.tasks = map[string][]client.Task{"proj-1": {
+		{ID: "task-a", Title: "Todo", Status: "todo", EpicID: "epic-1"},
+		{ID: "task-b", Title: "Abandoned", Status: "in_progress", EpicID: "epic-1", Notes: expired},
+		{ID: "task-c", Title: "Running elsewhere", Status: "in_progress", EpicID: "epic-1", Notes: live},
+		{ID: "task-d", Title: "No lease", Status: "in_progress", EpicID: "epic-1"},
+	}}
+
+	server, c := setupTest(m)
+	defer server.Close()
+
+	task, err := NewSelector(c, "proj-1", "", "").SelectTask()
+	if err != nil || task.ID != "task-a" {
+		t.Fatalf("without leases expected task-a, got %v, %v", task, err)
+	}
+
+	task, err = NewSelector(c, "proj-1", "", "").WithExpiredLeases().SelectTask()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if task.ID != "task-b" {
+		t.Errorf("expected abandoned task-b to be offered, got %s", task.ID)
+	}
+
+	task, err = NewSelector(c, "proj-1", "", "").WithExpiredLeases().SelectTaskExcluding(map[string]bool{"task-a": true, "task-b": true})
+	if !errors.Is(err, ErrNoTaskAvailable) {
+		t.Errorf("live leases and lease-less in_progress tasks should not be offered, got %v", task)
+	}
+}
+
 // --- Error Cases ---
 
 func TestNoTasksAvailable(t *testing.T) {
@@ -644,6 +687,62 @@ func TestNoTasksAvailable(t *testing.T) {
 	}
 }
 
+func TestSelectWithContext_TracesRequests(t *testing.T) {
+	m := newMockServer()
+	m.projects = []client.Project{{ID: "proj-1", Name: "Project 1"}}
+	m.epics = map[string][]client.Epic{
+		"proj-1": {{ID: "epic-1", Title: "Epic 1", ProjectID: "proj-1", Auto: true}},
+	}
+	m.tasks = map[string][]client.Task{
+		"proj-1": {{ID: "task-1", Title: "Task 1", Status: "todo", ProjectID: "proj-1", EpicID: "epic-1"}},
+	}
+
+	server, c := setupTest(m)
+	defer server.Close()
+
+	exp := tracing.NewMemoryExporter()
+	ctx, root := tracing.NewTracer(exp).Start(context.Background(), "iteration")
+
+	task, err := NewSelector(c, "proj-1", "", "").WithContext(ctx).SelectTask()
+	root.End()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var selectSpan tracing.SpanData
+	for _, span := range exp.Spans() {
+		if span.Name == "selection.select" {
+			selectSpan = span
+		}
+	}
+	if selectSpan.ParentSpanID != root.SpanID() {
+		t.Fatal("expected selection span to be a child of the iteration span")
+	}
+
+	requests := 0
+	for _, span := range exp.Spans() {
+		if span.Name == "flux GET" {
+			requests++
+			if span.ParentSpanID != selectSpan.SpanID {
+				t.Error("expected request spans to be children of the selection span")
+			}
+		}
+	}
+	if requests == 0 {
+		t.Error("expected at least one request span")
+	}
+
+	found := false
+	for _, attr := range selectSpan.Attributes {
+		if attr.Key == "task.id" && attr.Value == task.ID {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected selected task ID on span, got %+v", selectSpan.Attributes)
+	}
+}
+
 func TestTaskNotFound(t *testing.T) {
 	m := newMockServer()
 	m.projects = []client.Project{{ID: "proj-1", Name: "Project 1"}}
@@ -904,3 +1003,32 @@ func TestSelectionPriorityOrder(t *testing.T) {
 		})
 	}
 }
+
+func TestSelectWithStates(t *testing.T) {
+	m := newMockServer()
+	m.projects = []client.Project{{ID: "proj-1", Name: "Project 1"}}
+	m.epics = map[string][]client.Epic{
+		"proj-1": {{ID: "epic-1", Title: "Epic 1", ProjectID: "proj-1", Auto: true}},
+	}
+	m.tasks = map[string][]client.Task{"proj-1": {
+		{ID: "task-a", Title: "Todo", Status: "todo", EpicID: "epic-1"},
+		{ID: "task-b", Title: "Ready", Status: "ready", EpicID: "epic-1"},
+		{ID: "task-c", Title: "Blocked", Status: "blocked", EpicID: "epic-1"},
+	}}
+
+	server, c := setupTest(m)
+	defer server.Close()
+
+	states, err := workflow.NewStateMachine(workflow.States{Pickup: []string{"ready"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	task, err := NewSelector(c, "proj-1", "", "").WithStates(states).SelectTask()
+	if err != nil || task.ID != "task-b" {
+		t.Fatalf("expected the ready task, got %v, %v", task, err)
+	}
+	_, err = NewSelector(c, "proj-1", "", "").WithStates(states).SelectTaskExcluding(map[string]bool{"task-b": true})
+	if !errors.Is(err, ErrNoTaskAvailable) {
+		t.Errorf("expected todo tasks to be ignored with custom pickup statuses, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+// Package session remembers the Claude Code session each task last ran in, so
+// a retry, a restarted Momentum or a user asking for more work can resume the
+// conversation instead of starting from scratch.
+//
+// Like the journal, each task is stored as its own small JSON file written
+// atomically, keyed by task ID.
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Record is the last agent session of a task.
+type Record struct {
+	TaskID    string `json:"task_id"`
+	ProjectID string `json:"project_id,omitempty"`
+	SessionID string `json:"session_id"`
+	// BaseURL is the Flux server the task belongs to
+	BaseURL string `json:"base_url,omitempty"`
+	// WorkDir is where the session ran; Claude Code keys sessions by directory
+	WorkDir string `json:"workdir,omitempty"`
+	// Outcome describes how the session's last run ended, for the prompt
+	// that resumes it
+	Outcome   string    `json:"outcome,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Matches reports whether the record belongs to the given Flux server and
+// working directory, the only place the session can be resumed from.
+func (r *Record) Matches(baseURL, workDir string) bool {
+	return r != nil && r.BaseURL == baseURL && r.WorkDir == workDir
+}
+
+// Store keeps records in a directory. A nil *Store remembers nothing.
+type Store struct {
+	dir string
+}
+
+// DefaultDir returns the default session directory, or "" if the user
+// config directory cannot be determined.
+func DefaultDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "momentum", "sessions")
+}
+
+// Open creates the session directory if needed and returns a store for it.
+func Open(dir string) (*Store, error) {
+	if dir == "" {
+		return nil, errors.New("no session directory")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Save writes or replaces the record for r.TaskID.
+func (s *Store) Save(r Record) error {
+	if s == nil {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session record: %w", err)
+	}
+
+	// Write to a temp file and rename so readers never see a partial record
+	tmp, err := os.CreateTemp(s.dir, ".session-*")
+	if err != nil {
+		return fmt.Errorf("failed to write session record: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write session record: %w", err)
+	}
+	tmp.Close()
+
+	if err := os.Rename(tmp.Name(), s.path(r.TaskID)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write session record: %w", err)
+	}
+	return nil
+}
+
+// Load returns the record for taskID, or nil if there is none.
+func (s *Store) Load(taskID string) (*Record, error) {
+	if s == nil {
+		return nil, nil
+	}
+	data, err := os.ReadFile(s.path(taskID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session record: %w", err)
+	}
+	var r Record
+	if err := json.Unmarshal(data, &r); err != nil || r.SessionID == "" {
+		return nil, fmt.Errorf("invalid session record for task %s", taskID)
+	}
+	return &r, nil
+}
+
+// Delete forgets the record for taskID. Deleting a missing record is not an
+// error.
+func (s *Store) Delete(taskID string) error {
+	if s == nil {
+		return nil
+	}
+	if err := os.Remove(s.path(taskID)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove session record: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) path(taskID string) string {
+	return filepath.Join(s.dir, url.PathEscape(taskID)+".json")
+}
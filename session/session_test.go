@@ -0,0 +1,77 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveLoadDelete(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "sessions"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if r, err := s.Load("task/1"); r != nil || err != nil {
+		t.Fatalf("expected no record, got %+v (%v)", r, err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	s.Save(Record{TaskID: "task/1", SessionID: "first", UpdatedAt: now})
+	s.Save(Record{TaskID: "task/1", SessionID: "second", WorkDir: "/work", Outcome: "stopped", UpdatedAt: now})
+
+	r, err := s.Load("task/1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.SessionID != "second" || r.Outcome != "stopped" || !r.UpdatedAt.Equal(now) {
+		t.Errorf("expected the latest record, got %+v", r)
+	}
+
+	if err := s.Delete("task/1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Delete("task/1"); err != nil {
+		t.Errorf("expected deleting a missing record to succeed, got %v", err)
+	}
+	if r, _ := s.Load("task/1"); r != nil {
+		t.Errorf("expected the record to be gone, got %+v", r)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := Open(dir)
+	os.WriteFile(filepath.Join(dir, "task-1.json"), []byte(`{"task_id":"task-1"}`), 0o600)
+
+	if r, err := s.Load("task-1"); r != nil || err == nil {
+		t.Errorf("expected a record without a session to be rejected, got %+v", r)
+	}
+}
+
+func TestRecordMatches(t *testing.T) {
+	r := &Record{SessionID: "s", BaseURL: "http://flux", WorkDir: "/work"}
+	if !r.Matches("http://flux", "/work") {
+		t.Error("expected a match")
+	}
+	if r.Matches("http://other", "/work") || r.Matches("http://flux", "/elsewhere") {
+		t.Error("expected another server or directory not to match")
+	}
+	if (*Record)(nil).Matches("http://flux", "/work") {
+		t.Error("expected a nil record not to match")
+	}
+}
+
+func TestNilStore(t *testing.T) {
+	var s *Store
+	if err := s.Save(Record{TaskID: "task-1", SessionID: "s"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if r, err := s.Load("task-1"); r != nil || err != nil {
+		t.Errorf("expected nothing from a nil store, got %+v (%v)", r, err)
+	}
+	if err := s.Delete("task-1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
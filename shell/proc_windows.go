@@ -0,0 +1,22 @@
+//go:build windows
+
+package shell
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// setProcAttr is a no-op on Windows (no process groups)
+func setProcAttr(cmd *exec.Cmd) {
+	// Windows doesn't support Setpgid
+}
+
+// killProcessTree kills the command and its children using taskkill
+func killProcessTree(cmd *exec.Cmd) error {
+	kill := exec.Command("taskkill", "/F", "/T", "/PID", strconv.Itoa(cmd.Process.Pid))
+	if err := kill.Run(); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
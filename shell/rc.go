@@ -0,0 +1,102 @@
+package shell
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// RCFile returns the startup file of the user's login shell, going by
+// $SHELL: ~/.zshrc for zsh, ~/.bashrc for bash (~/.bash_profile on macOS,
+// where terminals start login shells) and ~/.config/fish/config.fish for
+// fish. It returns "" for other shells and on Windows.
+func RCFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return rcFile(os.Getenv("SHELL"), home, runtime.GOOS)
+}
+
+func rcFile(shellPath, home, goos string) string {
+	if goos == "windows" || shellPath == "" {
+		return ""
+	}
+	switch filepath.Base(shellPath) {
+	case "zsh":
+		return filepath.Join(home, ".zshrc")
+	case "bash":
+		if goos == "darwin" {
+			return filepath.Join(home, ".bash_profile")
+		}
+		return filepath.Join(home, ".bashrc")
+	case "fish":
+		return filepath.Join(home, ".config", "fish", "config.fish")
+	}
+	return ""
+}
+
+// ExportLine returns the line setting the environment variable name to
+// value in the language of the shell that reads rc.
+func ExportLine(rc, name, value string) string {
+	quoted := "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+	if filepath.Ext(rc) == ".fish" {
+		return fmt.Sprintf("set -gx %s %s", name, quoted)
+	}
+	return fmt.Sprintf("export %s=%s", name, quoted)
+}
+
+// Markers around the lines Momentum manages in a shell startup file.
+const (
+	blockStart = "# >>> momentum >>>"
+	blockEnd   = "# <<< momentum <<<"
+)
+
+// SetBlock makes lines the contents of Momentum's block in the file at
+// path, replacing what the block held before. The block is added to the end
+// of the file, which is created if needed, when it has none. The rest of the
+// file and its permissions are left alone.
+func SetBlock(path string, lines ...string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var block bytes.Buffer
+	block.WriteString(blockStart + "\n")
+	for _, line := range lines {
+		block.WriteString(line + "\n")
+	}
+	block.WriteString(blockEnd + "\n")
+
+	var out []byte
+	start := bytes.Index(data, []byte(blockStart+"\n"))
+	end := -1
+	if start >= 0 {
+		end = bytes.Index(data[start:], []byte(blockEnd))
+	}
+	if end >= 0 {
+		end += start + len(blockEnd)
+		if end < len(data) && data[end] == '\n' {
+			end++
+		}
+		out = append(out, data[:start]...)
+		out = append(out, block.Bytes()...)
+		out = append(out, data[end:]...)
+	} else {
+		out = append(out, data...)
+		if len(out) > 0 && !bytes.HasSuffix(out, []byte("\n")) {
+			out = append(out, '\n')
+		}
+		out = append(out, block.Bytes()...)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// Writing in place keeps the file's mode and any symlink to it
+	return os.WriteFile(path, out, 0o644)
+}
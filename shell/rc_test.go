@@ -0,0 +1,68 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestRCFile(t *testing.T) {
+	tests := []struct {
+		shell, goos, want string
+	}{
+		{"/bin/zsh", "linux", "/home/u/.zshrc"},
+		{"/usr/bin/bash", "linux", "/home/u/.bashrc"},
+		{"/bin/bash", "darwin", "/home/u/.bash_profile"},
+		{"/usr/local/bin/fish", "linux", "/home/u/.config/fish/config.fish"},
+		{"/bin/tcsh", "linux", ""},
+		{"", "linux", ""},
+		{"/bin/zsh", "windows", ""},
+	}
+	for _, tt := range tests {
+		want := tt.want
+		if want != "" {
+			want = filepath.FromSlash(want)
+		}
+		if got := rcFile(tt.shell, filepath.FromSlash("/home/u"), tt.goos); got != want {
+			t.Errorf("rcFile(%q, %q) = %q, want %q", tt.shell, tt.goos, got, want)
+		}
+	}
+}
+
+func TestExportLine(t *testing.T) {
+	if got := ExportLine("/home/u/.zshrc", "MOMENTUM_WORKDIR", "/src/bob's app"); got != `export MOMENTUM_WORKDIR='/src/bob'\''s app'` {
+		t.Errorf("unexpected line %q", got)
+	}
+	if got := ExportLine("/home/u/.config/fish/config.fish", "MOMENTUM_WORKDIR", "/src/app"); got != `set -gx MOMENTUM_WORKDIR '/src/app'` {
+		t.Errorf("unexpected fish line %q", got)
+	}
+}
+
+func TestSetBlock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fish", "config.fish")
+	if err := SetBlock(path, "set -gx A '1'"); err != nil {
+		t.Fatal(err)
+	}
+	// A file without a trailing newline gets one before the block
+	os.WriteFile(path, []byte("alias ll 'ls -l'"), 0o644)
+	os.Chmod(path, 0o600)
+	if err := SetBlock(path, "set -gx A '1'"); err != nil {
+		t.Fatal(err)
+	}
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString("alias la 'ls -a'\n")
+	f.Close()
+	// Setting the block again replaces it rather than adding another
+	if err := SetBlock(path, "set -gx A '2'"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	want := "alias ll 'ls -l'\n# >>> momentum >>>\nset -gx A '2'\n# <<< momentum <<<\nalias la 'ls -a'\n"
+	if got := string(data); got != want {
+		t.Errorf("unexpected file %q", got)
+	}
+	if info, err := os.Stat(path); err == nil && runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Errorf("expected the file to keep mode 0600, got %v", info.Mode().Perm())
+	}
+}
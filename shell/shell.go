@@ -0,0 +1,158 @@
+// Package shell runs user-supplied commands through the platform shell
+// (sh -c, or cmd /C on Windows) with a timeout, process tree cleanup and
+// captured output. It also finds and edits the user's shell startup file.
+package shell
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxOutput is the number of trailing bytes of output kept in an Error.
+const MaxOutput = 16 * 1024
+
+// Command describes a shell command to run.
+type Command struct {
+	// Command is the shell command line
+	Command string
+	// Dir is the working directory
+	Dir string
+	// Env is appended to the current process environment
+	Env []string
+	// Timeout bounds the command (0 = no timeout)
+	Timeout time.Duration
+	// Output receives each line of combined stdout and stderr, if non-nil
+	Output func(line string)
+}
+
+// Error reports a failed command.
+type Error struct {
+	Command string
+	// ExitCode is the command's exit code, or -1 if it could not be run or timed out
+	ExitCode int
+	// Output is the tail of the command's combined stdout and stderr
+	Output string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.ExitCode >= 0 {
+		return fmt.Sprintf("%q failed with exit code %d", e.Command, e.ExitCode)
+	}
+	return fmt.Sprintf("%q failed: %v", e.Command, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Run executes the command and waits for it to finish. A non-zero exit,
+// timeout or start failure is returned as an *Error.
+func Run(ctx context.Context, c Command) error {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	cmd := platformCommand(ctx, c.Command)
+	cmd.Dir = c.Dir
+	cmd.Env = append(os.Environ(), c.Env...)
+	setProcAttr(cmd)
+	cmd.Cancel = func() error {
+		return killProcessTree(cmd)
+	}
+	// Don't wait forever on grandchildren that keep the output pipes open
+	cmd.WaitDelay = 5 * time.Second
+
+	w := &lineWriter{fn: c.Output}
+	cmd.Stdout = w
+	cmd.Stderr = w
+
+	runErr := cmd.Run()
+	w.flush()
+	if runErr == nil {
+		return nil
+	}
+
+	err := &Error{
+		Command:  c.Command,
+		ExitCode: -1,
+		Output:   w.tail(),
+		Err:      runErr,
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		err.Err = fmt.Errorf("timed out after %s", c.Timeout)
+	} else if exitErr, ok := runErr.(*exec.ExitError); ok {
+		err.ExitCode = exitErr.ExitCode()
+	}
+	return err
+}
+
+// platformCommand runs command through the platform shell.
+func platformCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// lineWriter collects combined command output, keeping the trailing
+// MaxOutput bytes and passing complete lines to fn.
+type lineWriter struct {
+	mu      sync.Mutex
+	fn      func(string)
+	partial []byte
+	buf     bytes.Buffer
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(p)
+	if w.buf.Len() > 2*MaxOutput {
+		tail := append([]byte(nil), w.buf.Bytes()[w.buf.Len()-MaxOutput:]...)
+		w.buf.Reset()
+		w.buf.Write(tail)
+	}
+
+	if w.fn != nil {
+		w.partial = append(w.partial, p...)
+		for {
+			i := bytes.IndexByte(w.partial, '\n')
+			if i < 0 {
+				break
+			}
+			w.fn(strings.TrimRight(string(w.partial[:i]), "\r"))
+			w.partial = w.partial[i+1:]
+		}
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.fn != nil && len(w.partial) > 0 {
+		w.fn(string(w.partial))
+	}
+	w.partial = nil
+}
+
+func (w *lineWriter) tail() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	b := w.buf.Bytes()
+	if len(b) > MaxOutput {
+		b = b[len(b)-MaxOutput:]
+	}
+	return strings.TrimRight(string(b), "\n")
+}
@@ -0,0 +1,90 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func skipOnWindows(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell tests use POSIX shell commands")
+	}
+}
+
+func TestRun_Success(t *testing.T) {
+	skipOnWindows(t)
+	dir := t.TempDir()
+
+	var lines []string
+	err := Run(context.Background(), Command{
+		Command: `pwd; echo "$GREETING"`,
+		Dir:     dir,
+		Env:     []string{"GREETING=hello"},
+		Output:  func(line string) { lines = append(lines, line) },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], dir[strings.LastIndex(dir, "/"):]) || lines[1] != "hello" {
+		t.Errorf("unexpected output: %q", lines)
+	}
+}
+
+func TestRun_ExitCode(t *testing.T) {
+	skipOnWindows(t)
+	err := Run(context.Background(), Command{Command: "echo broken >&2; exit 4"})
+
+	var shellErr *Error
+	if !errors.As(err, &shellErr) {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if shellErr.ExitCode != 4 || shellErr.Output != "broken" {
+		t.Errorf("unexpected error: %+v", shellErr)
+	}
+	if shellErr.Error() != `"echo broken >&2; exit 4" failed with exit code 4` {
+		t.Errorf("unexpected message: %s", shellErr.Error())
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	skipOnWindows(t)
+	start := time.Now()
+	err := Run(context.Background(), Command{Command: "sleep 10 & sleep 10", Timeout: 100 * time.Millisecond})
+
+	var shellErr *Error
+	if !errors.As(err, &shellErr) {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if shellErr.ExitCode != -1 || !strings.Contains(shellErr.Error(), "timed out") {
+		t.Errorf("expected timeout error, got %v", shellErr)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("expected the process tree to be killed at the timeout")
+	}
+}
+
+func TestLineWriter_KeepsTail(t *testing.T) {
+	w := &lineWriter{}
+	w.Write([]byte(strings.Repeat("a", 3*MaxOutput)))
+	w.Write([]byte("end"))
+	tail := w.tail()
+	if len(tail) != MaxOutput || !strings.HasSuffix(tail, "end") {
+		t.Errorf("expected last %d bytes, got %d", MaxOutput, len(tail))
+	}
+}
+
+func TestLineWriter_SplitsLines(t *testing.T) {
+	var lines []string
+	w := &lineWriter{fn: func(line string) { lines = append(lines, line) }}
+	w.Write([]byte("one\r\ntw"))
+	w.Write([]byte("o\nthree"))
+	w.flush()
+	if strings.Join(lines, ",") != "one,two,three" {
+		t.Errorf("unexpected lines: %q", lines)
+	}
+}
@@ -48,6 +48,18 @@ type Subscriber struct {
 	pollingInterval time.Duration
 	// client is the HTTP client used for connections
 	client *http.Client
+	// stats holds connection statistics, protected by mu
+	stats Stats
+}
+
+// Stats describes the connection health of a Subscriber.
+type Stats struct {
+	// Connected is true while an SSE stream is open
+	Connected bool
+	// Polling is true while the subscriber has fallen back to polling
+	Polling bool
+	// Reconnects counts failed or closed SSE connections
+	Reconnects int
 }
 
 // NewSubscriber creates a new SSE Subscriber for the Flux API.
@@ -126,9 +138,15 @@ func (s *Subscriber) run(ctx context.Context) {
 				s.consecutiveFailures++
 				log.Printf("SSE subscriber: connection error (attempt %d): %v", s.consecutiveFailures, err)
 
-				if s.consecutiveFailures >= s.maxFailuresBeforePolling {
+				polling := s.consecutiveFailures >= s.maxFailuresBeforePolling
+				if polling {
 					log.Printf("SSE subscriber: falling back to polling (every %v)", s.pollingInterval)
 				}
+				s.updateStats(func(st *Stats) {
+					st.Connected = false
+					st.Polling = polling
+					st.Reconnects++
+				})
 
 				s.handleReconnect(ctx)
 			}
@@ -161,6 +179,7 @@ func (s *Subscriber) connect(ctx context.Context) error {
 
 	// Reset backoff on successful connection
 	s.resetBackoff()
+	s.updateStats(func(st *Stats) { st.Connected = true })
 	log.Printf("SSE subscriber: connected to %s", s.url)
 
 	// Read and parse SSE events
@@ -235,6 +254,21 @@ func (s *Subscriber) handleReconnect(ctx context.Context) {
 func (s *Subscriber) resetBackoff() {
 	s.reconnectDelay = 1 * time.Second
 	s.consecutiveFailures = 0
+	s.updateStats(func(st *Stats) { st.Polling = false })
+}
+
+// updateStats applies fn to the connection statistics under the lock.
+func (s *Subscriber) updateStats(fn func(*Stats)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.stats)
+}
+
+// Stats returns a snapshot of the connection statistics.
+func (s *Subscriber) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
 }
 
 // waitWithContext waits for the specified duration or until context is cancelled.
@@ -710,3 +710,75 @@ func TestSSEHeadersSet(t *testing.T) {
 		t.Errorf("expected Connection header to contain 'keep-alive', got %q", connection)
 	}
 }
+
+// TestStatsTrackReconnectsAndPolling verifies that failed connections are
+// counted and the polling fallback is reported in Stats.
+func TestStatsTrackReconnectsAndPolling(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	sub := NewSubscriber(server.URL)
+	sub.url = server.URL
+	sub.maxFailuresBeforePolling = 2
+	sub.pollingInterval = time.Hour
+	sub.reconnectDelay = 5 * time.Millisecond
+	sub.maxReconnectDelay = 5 * time.Millisecond
+
+	if stats := sub.Stats(); stats != (Stats{}) {
+		t.Errorf("expected zero stats before start, got %+v", stats)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	sub.Start(ctx)
+	defer sub.Stop()
+
+	deadline := time.After(time.Second)
+	for {
+		stats := sub.Stats()
+		if stats.Polling {
+			if stats.Reconnects < 2 {
+				t.Errorf("expected at least 2 reconnects before polling, got %d", stats.Reconnects)
+			}
+			if stats.Connected {
+				t.Error("expected Connected=false while polling")
+			}
+			return
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("timed out waiting for polling fallback, stats=%+v", stats)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+// TestStatsConnected verifies that an open stream is reported as connected.
+func TestStatsConnected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	sub := NewSubscriber(server.URL)
+	sub.url = server.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub.Start(ctx)
+	defer sub.Stop()
+
+	deadline := time.After(time.Second)
+	for !sub.Stats().Connected {
+		select {
+		case <-deadline:
+			t.Fatal("timed out waiting for connection")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTracer_ParentChild(t *testing.T) {
+	exp := NewMemoryExporter()
+	tracer := NewTracer(exp)
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := Start(ctx, "child", String("k", "v"))
+	child.End()
+	root.End()
+
+	spans := exp.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	c, r := spans[0], spans[1]
+	if c.Name != "child" || r.Name != "root" {
+		t.Fatalf("unexpected span order: %q, %q", c.Name, r.Name)
+	}
+	if c.TraceID != r.TraceID {
+		t.Error("expected child to share the root trace ID")
+	}
+	if c.ParentSpanID != r.SpanID {
+		t.Error("expected child parent to be the root span")
+	}
+	if r.ParentSpanID != (SpanID{}) {
+		t.Error("expected root span to have no parent")
+	}
+	if len(c.Attributes) != 1 || c.Attributes[0].Value != "v" {
+		t.Errorf("unexpected attributes: %+v", c.Attributes)
+	}
+	if r.End.Before(r.Start) {
+		t.Error("expected end after start")
+	}
+}
+
+func TestStart_WithoutSpanIsNoop(t *testing.T) {
+	ctx := context.Background()
+	got, span := Start(ctx, "orphan")
+	if span != nil {
+		t.Error("expected nil span when context carries no span")
+	}
+	if got != ctx {
+		t.Error("expected context to be returned unchanged")
+	}
+
+	// Nil spans and tracers must be safe to use
+	span.SetAttributes(Int("n", 1))
+	span.SetError(errors.New("boom"))
+	span.End()
+	var tracer *Tracer
+	if _, s := tracer.Start(ctx, "x"); s != nil {
+		t.Error("expected nil tracer to return nil span")
+	}
+	if err := tracer.Shutdown(); err != nil {
+		t.Errorf("unexpected shutdown error: %v", err)
+	}
+}
+
+func TestSpan_EndIsIdempotent(t *testing.T) {
+	exp := NewMemoryExporter()
+	_, span := NewTracer(exp).Start(context.Background(), "once")
+	span.End()
+	span.End()
+	if len(exp.Spans()) != 1 {
+		t.Errorf("expected span to be exported once, got %d", len(exp.Spans()))
+	}
+}
+
+func TestSpan_SetError(t *testing.T) {
+	exp := NewMemoryExporter()
+	_, span := NewTracer(exp).Start(context.Background(), "failing")
+	span.SetError(nil)
+	span.SetError(errors.New("boom"))
+	span.End()
+
+	got := exp.Spans()[0]
+	if got.Status != StatusError || got.StatusMessage != "boom" {
+		t.Errorf("unexpected status: %v %q", got.Status, got.StatusMessage)
+	}
+}
+
+func TestWriterExporter_OTLPJSON(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer(NewWriterExporter(&buf))
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := Start(ctx, "child",
+		String("s", "text"),
+		Int("i", 42),
+		Float("f", 1.5),
+		Bool("b", true),
+	)
+	child.SetError(errors.New("failed"))
+	child.End()
+	root.End()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d:\n%s", len(lines), buf.String())
+	}
+
+	var req struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []struct {
+					Key   string
+					Value map[string]interface{}
+				}
+			}
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID           string `json:"traceId"`
+					SpanID            string `json:"spanId"`
+					ParentSpanID      string `json:"parentSpanId"`
+					Name              string `json:"name"`
+					StartTimeUnixNano string `json:"startTimeUnixNano"`
+					Attributes        []struct {
+						Key   string
+						Value map[string]interface{}
+					}
+					Status struct {
+						Code    int
+						Message string
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &req); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	rs := req.ResourceSpans[0]
+	if rs.Resource.Attributes[0].Key != "service.name" || rs.Resource.Attributes[0].Value["stringValue"] != ServiceName {
+		t.Errorf("unexpected resource: %+v", rs.Resource)
+	}
+	span := rs.ScopeSpans[0].Spans[0]
+	if span.Name != "child" {
+		t.Errorf("expected child span, got %q", span.Name)
+	}
+	if len(span.TraceID) != 32 || len(span.SpanID) != 16 || len(span.ParentSpanID) != 16 {
+		t.Errorf("unexpected ID lengths: %q %q %q", span.TraceID, span.SpanID, span.ParentSpanID)
+	}
+	if span.StartTimeUnixNano == "" || span.StartTimeUnixNano == "0" {
+		t.Error("expected start time to be set")
+	}
+	if span.Status.Code != int(StatusError) || span.Status.Message != "failed" {
+		t.Errorf("unexpected status: %+v", span.Status)
+	}
+
+	want := map[string]string{"s": "stringValue", "i": "intValue", "f": "doubleValue", "b": "boolValue"}
+	for _, attr := range span.Attributes {
+		if _, ok := attr.Value[want[attr.Key]]; !ok {
+			t.Errorf("attribute %s: expected %s, got %v", attr.Key, want[attr.Key], attr.Value)
+		}
+	}
+	for _, attr := range span.Attributes {
+		if attr.Key == "i" && attr.Value["intValue"] != "42" {
+			t.Errorf("expected intValue to be string-encoded, got %v", attr.Value["intValue"])
+		}
+	}
+
+	if !strings.Contains(lines[1], `"name":"root"`) || strings.Contains(lines[1], "parentSpanId") {
+		t.Errorf("unexpected root line: %s", lines[1])
+	}
+}
+
+func TestFileExporter_Appends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+
+	for i := 0; i < 2; i++ {
+		exp, err := NewFileExporter(path)
+		if err != nil {
+			t.Fatalf("NewFileExporter failed: %v", err)
+		}
+		tracer := NewTracer(exp)
+		_, span := tracer.Start(context.Background(), "run")
+		span.End()
+		if err := tracer.Shutdown(); err != nil {
+			t.Fatalf("Shutdown failed: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read trace file: %v", err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 2 {
+		t.Errorf("expected 2 lines after two runs, got %d", n)
+	}
+}
@@ -0,0 +1,375 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sirsjg/momentum/agent"
+)
+
+// outputFilter limits the console to some kinds of output lines.
+type outputFilter int
+
+const (
+	filterAll outputFilter = iota
+	filterStderr
+	filterTools
+	filterErrors
+	numOutputFilters
+)
+
+func (f outputFilter) String() string {
+	switch f {
+	case filterStderr:
+		return "stderr"
+	case filterTools:
+		return "tool calls"
+	case filterErrors:
+		return "errors"
+	}
+	return "all"
+}
+
+// keeps reports whether line passes the filter.
+func (f outputFilter) keeps(line agent.OutputLine) bool {
+	switch f {
+	case filterStderr:
+		return line.IsStderr
+	case filterTools:
+		return strings.Contains(line.Text, "[Tool: ")
+	case filterErrors:
+		return isErrorLine(line.Text)
+	}
+	return true
+}
+
+// isErrorLine reports whether text is an error Claude reported, or an
+// error message written by the agent process.
+func isErrorLine(text string) bool {
+	if strings.HasPrefix(text, "[Error") {
+		return true
+	}
+	lower := strings.ToLower(strings.TrimSpace(text))
+	return strings.HasPrefix(lower, "error") || strings.HasPrefix(lower, "fatal") || strings.HasPrefix(lower, "panic:")
+}
+
+var (
+	matchStyle        = lipgloss.NewStyle().Foreground(Charcoal).Background(Amber)
+	currentMatchStyle = lipgloss.NewStyle().Foreground(Charcoal).Background(GlowGreen).Bold(true)
+)
+
+// consoleKey handles console keys for searching, filtering, following and
+// exporting the focused panel's output. It reports whether it used the key.
+func (m *Model) consoleKey(key string) (tea.Cmd, bool) {
+	switch key {
+	case "/":
+		m.consoleSearchMode = true
+		m.consoleSearch.SetValue(m.consoleQuery)
+		m.consoleSearch.CursorEnd()
+		return m.consoleSearch.Focus(), true
+	case "n", "N":
+		if len(m.consoleMatches) > 0 {
+			step := 1
+			if key == "N" {
+				step = len(m.consoleMatches) - 1
+			}
+			m.gotoConsoleMatch((m.consoleMatch + step) % len(m.consoleMatches))
+		}
+		return nil, true
+	case "f":
+		m.consoleFilter = (m.consoleFilter + 1) % numOutputFilters
+		m.consoleMatch = 0
+		m.updateConsoleContent()
+		return nil, true
+	case "t":
+		m.consoleFollow = !m.consoleFollow
+		m.updateConsoleContent()
+		return nil, true
+	case "e", "E":
+		m.exportTranscript(key == "E")
+		return nil, true
+	}
+	return nil, false
+}
+
+// consoleSearchKey handles a key while typing a search. The matches and
+// the view follow each keystroke.
+func (m *Model) consoleSearchKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		m.consoleSearchMode = false
+		m.consoleSearch.Blur()
+		m.consoleQuery = ""
+		m.updateConsoleContent()
+		return nil
+	case "enter":
+		m.consoleSearchMode = false
+		m.consoleSearch.Blur()
+		return nil
+	}
+	var cmd tea.Cmd
+	m.consoleSearch, cmd = m.consoleSearch.Update(msg)
+	if query := m.consoleSearch.Value(); query != m.consoleQuery {
+		m.consoleQuery = query
+		m.consoleMatch = 0
+		m.updateConsoleContent()
+		// Start from the first match below the top of the view
+		first := 0
+		for i, row := range m.consoleMatches {
+			if row >= m.viewport.YOffset {
+				first = i
+				break
+			}
+		}
+		m.gotoConsoleMatch(first)
+	}
+	return cmd
+}
+
+// searchPattern matches the search query, ignoring case; nil without one.
+func (m *Model) searchPattern() *regexp.Regexp {
+	if m.consoleQuery == "" {
+		return nil
+	}
+	return regexp.MustCompile("(?i)" + regexp.QuoteMeta(m.consoleQuery))
+}
+
+// updateConsoleContent shows the focused panel's output lines that pass
+// the filter, highlighting search matches, and keeps the view at the end
+// of a running agent's output while following it.
+func (m *Model) updateConsoleContent() {
+	if m.focusedPanel < 0 || m.focusedPanel >= len(m.panels) {
+		m.viewport.SetContent("")
+		m.consoleMatches = nil
+		return
+	}
+
+	panel := m.panels[m.focusedPanel]
+	pattern := m.searchPattern()
+	var b strings.Builder
+	var matches []int
+	row := 0
+	for _, line := range panel.Output {
+		if !m.consoleFilter.keeps(line) {
+			continue
+		}
+		style := OutputStyle
+		if line.IsStderr {
+			style = StderrStyle
+		}
+		var spans [][]int
+		if pattern != nil {
+			spans = pattern.FindAllStringIndex(line.Text, -1)
+		}
+		if len(spans) > 0 {
+			matches = append(matches, row+strings.Count(line.Text[:spans[0][0]], "\n"))
+		}
+		current := len(spans) > 0 && m.consoleMatch == len(matches)-1
+		b.WriteString(highlight(line.Text, spans, style, current))
+		b.WriteString("\n")
+		row += strings.Count(line.Text, "\n") + 1
+	}
+
+	m.viewport.SetContent(b.String())
+	m.consoleMatches = matches
+	if m.consoleMatch >= len(matches) {
+		m.consoleMatch = 0
+	}
+
+	if m.consoleFollow && panel.IsRunning() {
+		m.viewport.GotoBottom()
+	}
+}
+
+// gotoConsoleMatch highlights match i and scrolls it into the middle of
+// the view. Following stops, so new output does not scroll it away again.
+func (m *Model) gotoConsoleMatch(i int) {
+	if i < 0 || i >= len(m.consoleMatches) {
+		return
+	}
+	m.consoleMatch = i
+	m.consoleFollow = false
+	m.updateConsoleContent()
+	m.viewport.SetYOffset(m.consoleMatches[i] - m.viewport.Height/2)
+}
+
+// highlight renders text in style with the spans marked as search matches.
+func highlight(text string, spans [][]int, style lipgloss.Style, current bool) string {
+	if len(spans) == 0 {
+		return style.Render(text)
+	}
+	mark := matchStyle
+	if current {
+		mark = currentMatchStyle
+	}
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		if span[0] > last {
+			b.WriteString(style.Render(text[last:span[0]]))
+		}
+		b.WriteString(mark.Render(text[span[0]:span[1]]))
+		last = span[1]
+	}
+	if last < len(text) {
+		b.WriteString(style.Render(text[last:]))
+	}
+	return b.String()
+}
+
+// consoleFooter shows the search input while typing, then the outcome of
+// the last export, otherwise the console keys.
+func (m *Model) consoleFooter() string {
+	if m.consoleSearchMode {
+		return "/" + m.consoleSearch.View()
+	}
+	width := max(m.consoleWidth-4, 20)
+	if m.consoleNotice != "" {
+		if m.consoleNoticeErr {
+			return StatusError.Render(truncate(m.consoleNotice, width))
+		}
+		return StatusConnected.Render(truncate(m.consoleNotice, width))
+	}
+	var parts []string
+	if m.consoleQuery != "" {
+		if len(m.consoleMatches) == 0 {
+			parts = append(parts, fmt.Sprintf("no matches for %q", m.consoleQuery))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d/%d %q · n/N next/prev", m.consoleMatch+1, len(m.consoleMatches), m.consoleQuery))
+		}
+	} else {
+		parts = append(parts, "/ search")
+	}
+	follow := "off"
+	if m.consoleFollow {
+		follow = "on"
+	}
+	parts = append(parts,
+		"f filter: "+m.consoleFilter.String(),
+		"t follow: "+follow,
+		"e/E export md/json",
+		"pgup/pgdn scroll",
+		"esc close",
+	)
+	// Leave out the last hints rather than wrap in a narrow console
+	help := parts[0]
+	for _, part := range parts[1:] {
+		if lipgloss.Width(help+" · "+part) > width {
+			break
+		}
+		help += " · " + part
+	}
+	return HelpStyle.Render(help)
+}
+
+// transcript is the JSON export of an agent panel's output.
+type transcript struct {
+	TaskID    string           `json:"task_id"`
+	TaskTitle string           `json:"task_title"`
+	Agent     string           `json:"agent"`
+	WorkDir   string           `json:"workdir,omitempty"`
+	Started   time.Time        `json:"started"`
+	Finished  *time.Time       `json:"finished,omitempty"`
+	ExitCode  *int             `json:"exit_code,omitempty"`
+	Lines     []transcriptLine `json:"lines"`
+}
+
+type transcriptLine struct {
+	Time   time.Time `json:"time"`
+	Text   string    `json:"text"`
+	Stderr bool      `json:"stderr,omitempty"`
+}
+
+// exportTranscript writes the focused panel's full output, regardless of
+// the filter, to a Markdown or JSON file and shows where.
+func (m *Model) exportTranscript(asJSON bool) {
+	if m.focusedPanel < 0 || m.focusedPanel >= len(m.panels) {
+		return
+	}
+	panel := m.panels[m.focusedPanel]
+	var data []byte
+	ext := ".md"
+	if asJSON {
+		var err error
+		if data, err = json.MarshalIndent(panelTranscript(panel), "", "  "); err != nil {
+			m.consoleNotice, m.consoleNoticeErr = "Export failed: "+err.Error(), true
+			return
+		}
+		data = append(data, '\n')
+		ext = ".json"
+	} else {
+		data = []byte(markdownTranscript(panel))
+	}
+
+	dir := m.exportDir
+	if dir == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			m.consoleNotice, m.consoleNoticeErr = "Export failed: "+err.Error(), true
+			return
+		}
+		dir = filepath.Join(configDir, "momentum", "transcripts")
+	}
+	name := strings.NewReplacer("/", "-", `\`, "-").Replace(panel.TaskID) + "-" + time.Now().Format("20060102-150405") + ext
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		m.consoleNotice, m.consoleNoticeErr = "Export failed: "+err.Error(), true
+		return
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		m.consoleNotice, m.consoleNoticeErr = "Export failed: "+err.Error(), true
+		return
+	}
+	m.consoleNotice, m.consoleNoticeErr = "Exported to "+shortenPath(path), false
+}
+
+func panelTranscript(panel *AgentPanel) transcript {
+	t := transcript{
+		TaskID:    panel.TaskID,
+		TaskTitle: panel.TaskTitle,
+		Agent:     panel.AgentName,
+		WorkDir:   panel.WorkDir,
+		Started:   panel.StartTime,
+		Lines:     make([]transcriptLine, len(panel.Output)),
+	}
+	if panel.Result != nil {
+		finished := panel.EndTime
+		t.Finished = &finished
+		t.ExitCode = &panel.Result.ExitCode
+	}
+	for i, line := range panel.Output {
+		t.Lines[i] = transcriptLine{Time: line.Timestamp, Text: line.Text, Stderr: line.IsStderr}
+	}
+	return t
+}
+
+func markdownTranscript(panel *AgentPanel) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", panel.TaskTitle)
+	fmt.Fprintf(&b, "- Task: %s\n", panel.TaskID)
+	fmt.Fprintf(&b, "- Agent: %s\n", panel.AgentName)
+	if panel.WorkDir != "" {
+		fmt.Fprintf(&b, "- Workdir: %s\n", panel.WorkDir)
+	}
+	fmt.Fprintf(&b, "- Started: %s\n", panel.StartTime.Format(time.RFC3339))
+	if panel.Result != nil {
+		fmt.Fprintf(&b, "- Finished: %s (exit code %d)\n", panel.EndTime.Format(time.RFC3339), panel.Result.ExitCode)
+	}
+	b.WriteString("\n## Output\n\n")
+	for _, line := range panel.Output {
+		fmt.Fprintf(&b, "`%s` ", line.Timestamp.Format("15:04:05"))
+		if line.IsStderr {
+			b.WriteString("**stderr:** ")
+		}
+		b.WriteString(line.Text)
+		b.WriteString("\n\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,183 @@
+package ui
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sirsjg/momentum/agent"
+)
+
+// consoleModel returns a model whose console shows a finished panel
+// holding lines.
+func consoleModel(t *testing.T, lines ...agent.OutputLine) *Model {
+	t.Helper()
+	model := NewModel("test", ExecutionModeAsync, ".", nil, nil, nil)
+	model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	model.Update(AddAgentMsg{TaskID: "task-1", TaskTitle: "Task 1", AgentName: "Claude"})
+	model.panels[0].Output = lines
+	model.Update(AgentCompletedMsg{TaskID: "task-1", Result: agent.Result{ExitCode: 1}})
+	if !model.consoleOpen {
+		t.Fatal("expected the console to open on the first panel")
+	}
+	return &model
+}
+
+func out(text string) agent.OutputLine {
+	return agent.OutputLine{Text: text, Timestamp: time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)}
+}
+
+func stderr(text string) agent.OutputLine {
+	line := out(text)
+	line.IsStderr = true
+	return line
+}
+
+func TestModel_ConsoleSearch(t *testing.T) {
+	model := consoleModel(t,
+		out("Reading the handler"),
+		out("[Tool: Read]"),
+		out("first line\nsecond Handler line"),
+		out("done with HANDLER"),
+	)
+
+	keys(model, "/", "handler")
+	if !model.consoleSearchMode || model.consoleQuery != "handler" {
+		t.Fatalf("expected an incremental search, got %q", model.consoleQuery)
+	}
+	// Matches are case-insensitive and counted in viewport rows
+	if got := model.consoleMatches; len(got) != 3 || got[0] != 0 || got[1] != 3 || got[2] != 4 {
+		t.Errorf("unexpected match rows %v", got)
+	}
+	keys(model, "enter")
+	if model.consoleSearchMode || model.consoleQuery != "handler" {
+		t.Error("expected enter to keep the query")
+	}
+
+	keys(model, "n")
+	if model.consoleMatch != 1 {
+		t.Errorf("expected the second match, got %d", model.consoleMatch)
+	}
+	keys(model, "N", "N")
+	if model.consoleMatch != 2 {
+		t.Errorf("expected N to wrap to the last match, got %d", model.consoleMatch)
+	}
+	if model.consoleFollow {
+		t.Error("expected jumping to a match to stop following")
+	}
+
+	keys(model, "/", "esc")
+	if model.consoleQuery != "" || len(model.consoleMatches) != 0 {
+		t.Error("expected esc to clear the search")
+	}
+}
+
+func TestModel_ConsoleFilter(t *testing.T) {
+	model := consoleModel(t,
+		out("Looking around"),
+		out("[Tool: Bash]"),
+		stderr("warning: deprecated flag"),
+		out("[Error: overloaded]"),
+		stderr("Error: exit status 1"),
+	)
+	visible := func() []string {
+		model.viewport.Height = 20
+		var lines []string
+		for _, line := range strings.Split(strings.TrimSpace(model.viewport.View()), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		return lines
+	}
+
+	tests := []struct {
+		filter outputFilter
+		want   []string
+	}{
+		{filterStderr, []string{"warning: deprecated flag", "Error: exit status 1"}},
+		{filterTools, []string{"[Tool: Bash]"}},
+		{filterErrors, []string{"[Error: overloaded]", "Error: exit status 1"}},
+		{filterAll, []string{"Looking around", "[Tool: Bash]", "warning: deprecated flag", "[Error: overloaded]", "Error: exit status 1"}},
+	}
+	for _, tt := range tests {
+		keys(model, "f")
+		if model.consoleFilter != tt.filter {
+			t.Fatalf("expected the %s filter, got %s", tt.filter, model.consoleFilter)
+		}
+		if got := visible(); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s filter shows %q, want %q", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestModel_ConsoleFollow(t *testing.T) {
+	model := NewModel("test", ExecutionModeAsync, ".", nil, nil, nil)
+	model.Update(tea.WindowSizeMsg{Width: 120, Height: 30})
+	model.Update(AddAgentMsg{TaskID: "task-1", TaskTitle: "Task 1", AgentName: "Claude", Runner: runningRunner(t)})
+	for i := 0; i < 100; i++ {
+		model.Update(AgentOutputMsg{TaskID: "task-1", Line: out("line")})
+	}
+	if !model.viewport.AtBottom() {
+		t.Fatal("expected the console to follow a running agent")
+	}
+
+	keys(&model, "t")
+	model.viewport.GotoTop()
+	model.Update(AgentOutputMsg{TaskID: "task-1", Line: out("more")})
+	if model.viewport.YOffset != 0 {
+		t.Error("expected the view to stay put without following")
+	}
+
+	keys(&model, "t")
+	model.Update(AgentOutputMsg{TaskID: "task-1", Line: out("more")})
+	if !model.viewport.AtBottom() {
+		t.Error("expected following to resume")
+	}
+}
+
+func TestModel_ConsoleExport(t *testing.T) {
+	model := consoleModel(t, out("Working on it"), stderr("warning: slow disk"))
+	model.exportDir = t.TempDir()
+	model.consoleFilter = filterStderr
+
+	keys(model, "e")
+	if model.consoleNoticeErr || !strings.HasPrefix(model.consoleNotice, "Exported to ") {
+		t.Fatalf("unexpected notice %q", model.consoleNotice)
+	}
+	md, _ := filepath.Glob(filepath.Join(model.exportDir, "task-1-*.md"))
+	if len(md) != 1 {
+		t.Fatalf("expected a Markdown transcript, got %v", md)
+	}
+	data, _ := os.ReadFile(md[0])
+	for _, want := range []string{"# Task 1", "- Task: task-1", "exit code 1", "`15:04:05` Working on it", "**stderr:** warning: slow disk"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected %q in the Markdown transcript:\n%s", want, data)
+		}
+	}
+
+	keys(model, "E")
+	js, _ := filepath.Glob(filepath.Join(model.exportDir, "task-1-*.json"))
+	if len(js) != 1 {
+		t.Fatalf("expected a JSON transcript, got %v", js)
+	}
+	data, _ = os.ReadFile(js[0])
+	var got transcript
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	// Exports hold the full output, whatever the filter
+	if got.TaskID != "task-1" || got.ExitCode == nil || *got.ExitCode != 1 || len(got.Lines) != 2 || !got.Lines[1].Stderr {
+		t.Errorf("unexpected JSON transcript %+v", got)
+	}
+
+	model.exportDir = filepath.Join(md[0], "not-a-dir")
+	keys(model, "e")
+	if !model.consoleNoticeErr {
+		t.Errorf("expected an export error, got %q", model.consoleNotice)
+	}
+}
@@ -0,0 +1,210 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// FileChange is one file an agent run changed.
+type FileChange struct {
+	Path    string
+	Added   int
+	Deleted int
+	// Binary is set for files git cannot count lines in
+	Binary bool
+}
+
+// Changes describes what an agent run changed in its working directory,
+// relative to the commit the run started from.
+type Changes struct {
+	Files []FileChange
+	// Patch is the unified diff of every file
+	Patch string
+	// Truncated is set when Patch was cut short
+	Truncated bool
+	// Err says why the changes could not be captured, e.g. the working
+	// directory is not a git repository
+	Err string
+}
+
+// Summary describes the changes in a few words, e.g. "3 files +12 -4".
+func (c *Changes) Summary() string {
+	if c == nil {
+		return ""
+	}
+	if c.Err != "" {
+		return "changes unavailable"
+	}
+	if len(c.Files) == 0 {
+		return "no changes"
+	}
+	added, deleted := 0, 0
+	for _, f := range c.Files {
+		added += f.Added
+		deleted += f.Deleted
+	}
+	files := "files"
+	if len(c.Files) == 1 {
+		files = "file"
+	}
+	return fmt.Sprintf("%d %s +%d -%d", len(c.Files), files, added, deleted)
+}
+
+// filePatch returns the part of the patch for path, or "" if it has none.
+// Renames are not detected, so each file's header names it twice.
+func (c *Changes) filePatch(path string) string {
+	header := "diff --git a/" + path + " b/" + path + "\n"
+	var b strings.Builder
+	in := false
+	for _, line := range strings.SplitAfter(c.Patch, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			in = line == header
+		}
+		if in {
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
+
+// Diff line styles
+var (
+	diffAddStyle    = lipgloss.NewStyle().Foreground(Green)
+	diffDeleteStyle = lipgloss.NewStyle().Foreground(Red)
+	diffHunkStyle   = lipgloss.NewStyle().Foreground(Cyan)
+	diffHeaderStyle = lipgloss.NewStyle().Foreground(Gray).Bold(true)
+)
+
+// colorDiff styles each line of a unified diff.
+func colorDiff(patch string) string {
+	lines := strings.Split(strings.TrimRight(patch, "\n"), "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"),
+			strings.HasPrefix(line, "diff --git"), strings.HasPrefix(line, "index "),
+			strings.HasPrefix(line, "new file"), strings.HasPrefix(line, "deleted file"):
+			lines[i] = diffHeaderStyle.Render(line)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = diffHunkStyle.Render(line)
+		case strings.HasPrefix(line, "+"):
+			lines[i] = diffAddStyle.Render(line)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = diffDeleteStyle.Render(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// maxDiffFiles is how many changed files the diff view lists at once.
+const maxDiffFiles = 8
+
+// diffPanel returns the focused panel if it has changes to show, or nil.
+func (m *Model) diffPanel() *AgentPanel {
+	if m.focusedPanel < 0 || m.focusedPanel >= len(m.panels) {
+		return nil
+	}
+	if panel := m.panels[m.focusedPanel]; panel.Changes != nil {
+		return panel
+	}
+	return nil
+}
+
+// renderDiff shows the focused panel's changed files and the selected
+// file's diff.
+func (m *Model) renderDiff() string {
+	panel := m.diffPanel()
+	if panel == nil {
+		return ""
+	}
+	changes := panel.Changes
+
+	width := m.width - 10
+	if width > 140 {
+		width = 140
+	}
+	if width < 20 {
+		width = 20
+	}
+	height := m.height - 6
+
+	var b strings.Builder
+	title := lipgloss.NewStyle().Bold(true).Foreground(GlowGreen).Render("Changes: " + truncate(panel.TaskTitle, width-20))
+	b.WriteString(title)
+	b.WriteString("  ")
+	b.WriteString(HelpStyle.Render(changes.Summary()))
+	b.WriteString("\n\n")
+
+	listed := 0
+	switch {
+	case changes.Err != "":
+		b.WriteString(StatusError.Render(changes.Err))
+		b.WriteString("\n")
+		listed = 1
+	case len(changes.Files) == 0:
+		b.WriteString("The agent did not change any files.\n")
+		listed = 1
+	default:
+		// Keep the selected file in the visible part of the list
+		start := 0
+		if m.diffFile >= maxDiffFiles {
+			start = m.diffFile - maxDiffFiles + 1
+		}
+		end := min(start+maxDiffFiles, len(changes.Files))
+		for i := start; i < end; i++ {
+			f := changes.Files[i]
+			stats := diffAddStyle.Render(fmt.Sprintf("+%d", f.Added)) + " " + diffDeleteStyle.Render(fmt.Sprintf("-%d", f.Deleted))
+			if f.Binary {
+				stats = HelpStyle.Render("binary")
+			}
+			line := "  " + truncate(f.Path, width-20) + "  " + stats
+			if i == m.diffFile {
+				line = SelectedRowStyle.Render("> "+truncate(f.Path, width-20)) + "  " + stats
+			}
+			b.WriteString(line)
+			b.WriteString("\n")
+			listed++
+		}
+		if len(changes.Files) > maxDiffFiles {
+			b.WriteString(HelpStyle.Render(fmt.Sprintf("  %d of %d files", m.diffFile+1, len(changes.Files))))
+			b.WriteString("\n")
+			listed++
+		}
+	}
+	b.WriteString(HelpStyle.Render(strings.Repeat("─", width-4)))
+	b.WriteString("\n")
+
+	m.diffViewport.Width = width - 4
+	m.diffViewport.Height = max(height-listed-9, 3)
+	b.WriteString(m.diffViewport.View())
+	b.WriteString("\n\n")
+	b.WriteString(HelpStyle.Render("esc to close  j/k file  pgup/pgdn scroll"))
+
+	content := PanelStyle.Width(width).Render(b.String())
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+}
+
+// updateDiffContent shows the selected file's diff in the viewport.
+func (m *Model) updateDiffContent() {
+	panel := m.diffPanel()
+	if panel == nil {
+		return
+	}
+	changes := panel.Changes
+	content := ""
+	if m.diffFile >= 0 && m.diffFile < len(changes.Files) {
+		f := changes.Files[m.diffFile]
+		patch := changes.filePatch(f.Path)
+		switch {
+		case patch != "":
+			content = colorDiff(patch)
+		case changes.Truncated:
+			content = HelpStyle.Render("The diff is too large to show this file.")
+		default:
+			content = HelpStyle.Render("No diff for this file.")
+		}
+	}
+	m.diffViewport.SetContent(content)
+	m.diffViewport.GotoTop()
+}
@@ -0,0 +1,91 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sirsjg/momentum/agent"
+)
+
+const testPatch = `diff --git a/README.md b/README.md
+index e69de29..8b13789 100644
+--- a/README.md
++++ b/README.md
+@@ -1 +1,2 @@
+ # Demo
++Usage notes
+diff --git a/old.go b/old.go
+deleted file mode 100644
+--- a/old.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package old
+`
+
+func TestChanges_Summary(t *testing.T) {
+	var none *Changes
+	if none.Summary() != "" {
+		t.Error("expected no summary without changes")
+	}
+	changes := &Changes{Files: []FileChange{{Path: "a", Added: 3, Deleted: 1}, {Path: "b", Added: 2}}}
+	if got := changes.Summary(); got != "2 files +5 -1" {
+		t.Errorf("unexpected summary %q", got)
+	}
+	if got := (&Changes{Err: "not a git repository"}).Summary(); got != "changes unavailable" {
+		t.Errorf("unexpected summary %q", got)
+	}
+}
+
+func TestChanges_FilePatch(t *testing.T) {
+	changes := &Changes{Patch: testPatch}
+	readme := changes.filePatch("README.md")
+	if !strings.HasPrefix(readme, "diff --git a/README.md") || !strings.Contains(readme, "+Usage notes") || strings.Contains(readme, "old.go") {
+		t.Errorf("unexpected README patch:\n%s", readme)
+	}
+	if old := changes.filePatch("old.go"); !strings.Contains(old, "-package old") {
+		t.Errorf("unexpected old.go patch:\n%s", old)
+	}
+	if changes.filePatch("README") != "" {
+		t.Error("expected no patch for a file the diff does not touch")
+	}
+}
+
+func TestModel_DiffView(t *testing.T) {
+	model := NewModel("test", ExecutionModeAsync, ".", nil, nil, nil)
+	model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	model.Update(AddAgentMsg{TaskID: "task-1", TaskTitle: "Write docs", AgentName: "Claude"})
+
+	// Nothing to show while the agent runs
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	if model.diffOpen {
+		t.Fatal("expected no diff view before the run finished")
+	}
+
+	model.Update(AgentCompletedMsg{TaskID: "task-1", Result: agent.Result{}, Changes: &Changes{
+		Files: []FileChange{{Path: "README.md", Added: 1}, {Path: "old.go", Deleted: 1}},
+		Patch: testPatch,
+	}})
+	if !strings.Contains(model.renderHelp(), "diff") || !strings.Contains(model.renderConsolePanel(), "2 files +1 -1") {
+		t.Error("expected the finished panel to offer its diff")
+	}
+
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	view := model.View()
+	if !model.diffOpen || !strings.Contains(view, "Changes: Write docs") || !strings.Contains(view, "+Usage notes") {
+		t.Fatalf("expected d to show the first file's diff, got:\n%s", view)
+	}
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	if view := model.View(); !strings.Contains(view, "-package old") || strings.Contains(view, "+Usage notes") {
+		t.Errorf("expected j to show the next file's diff, got:\n%s", view)
+	}
+	// The selection stops at the last file
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	if model.diffFile != 1 {
+		t.Errorf("expected the selection to stay on the last file, got %d", model.diffFile)
+	}
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyEsc})
+	if model.diffOpen {
+		t.Error("expected esc to close the diff view")
+	}
+}
@@ -6,6 +6,12 @@ import (
 	"strings"
 )
 
+// ParseClaudeOutput extracts displayable text from a line of Claude's
+// stream-json output. It returns an empty string for uninteresting messages.
+func ParseClaudeOutput(text string) string {
+	return parseClaudeOutput(text)
+}
+
 // parseClaudeOutput extracts meaningful text from Claude's stream-json output
 func parseClaudeOutput(text string) string {
 	text = strings.TrimSpace(text)
@@ -41,6 +41,15 @@ type AgentPanel struct {
 	Closed    bool
 	Stopping  bool // Set when stop is requested but process hasn't exited yet
 	PID       int
+	// WorkDir is the directory the agent works in
+	WorkDir string
+	// AwaitingReview is set while a successful run waits for a reviewer
+	AwaitingReview bool
+	// Rejected is set once a reviewer rejects the run
+	Rejected bool
+	// Changes is what the finished run changed in the working directory;
+	// nil until it finishes
+	Changes *Changes
 }
 
 // IsRunning returns whether the agent is still running
@@ -72,6 +81,7 @@ type Model struct {
 	taskCount    int
 	lastTaskTime time.Time
 	mode         ExecutionMode
+	paused       bool
 
 	// Agent panels
 	panels       []*AgentPanel
@@ -94,6 +104,18 @@ type Model struct {
 	modeUpdates chan<- ExecutionMode
 	stopUpdates chan<- string // sends taskID when user stops an agent
 
+	// Messages to running agents; nil when the agents cannot be messaged
+	messageUpdates   chan<- AgentMessage
+	messageInputMode bool
+	messageInput     textinput.Model
+	messageTaskID    string
+
+	// Review decisions for tasks awaiting approval; nil when reviews are off
+	reviewUpdates   chan<- ReviewDecision
+	rejectInputMode bool
+	rejectInput     textinput.Model
+	rejectTaskID    string
+
 	// WorkDir settings
 	workDir           string
 	workDirUpdates    chan<- string
@@ -101,16 +123,56 @@ type Model struct {
 	workDirInputMode  bool
 	workDirInput      textinput.Model
 	promptPreviewOpen bool
-	claudeMdFiles     []claudeMdFile
+	promptPreview     *PromptPreview
 	promptViewport    viewport.Model
-}
 
-// claudeMdFile represents a CLAUDE.md file and its content
-type claudeMdFile struct {
-	Path    string
-	Content string
+	// workDirStore saves workdir choices; nil hides the options using it
+	workDirStore     WorkDirStore
+	workDirNotice    string
+	workDirNoticeErr bool
+	// Recent workdirs picker
+	workDirPickerOpen bool
+	workDirFilter     textinput.Model
+	workDirRecent     []string
+	workDirPick       int
+	// Shell startup file change awaiting confirmation, if any
+	workDirShellFile string
+	workDirShellLine string
+
+	// Task status changes, oldest first, for the activity feed
+	activity         []TransitionMsg
+	activityOpen     bool
+	activityViewport viewport.Model
+
+	// Diff view of the focused panel's changes
+	diffOpen     bool
+	diffFile     int
+	diffViewport viewport.Model
+
+	// Prompt previews come from the worker; nil composes them here
+	promptPreviewUpdates chan<- string
+	promptPreviewLoading bool
+	promptPreviewErr     error
+
+	// Search, filter and follow settings for the console, and the outcome
+	// of the last transcript export
+	consoleSearchMode bool
+	consoleSearch     textinput.Model
+	consoleQuery      string
+	consoleMatches    []int // viewport rows of matching lines
+	consoleMatch      int
+	consoleFilter     outputFilter
+	consoleFollow     bool
+	consoleNotice     string
+	consoleNoticeErr  bool
+	// exportDir is where transcripts are written; "" means the user's
+	// config directory
+	exportDir string
 }
 
+// maxActivity is how many status changes the activity feed keeps.
+const maxActivity = 200
+
 // NewModel creates a new TUI model
 func NewModel(criteria string, mode ExecutionMode, workDir string, modeUpdates chan<- ExecutionMode, stopUpdates chan<- string, workDirUpdates chan<- string) Model {
 	s := spinner.New()
@@ -123,24 +185,58 @@ func NewModel(criteria string, mode ExecutionMode, workDir string, modeUpdates c
 	// Initialize viewport for prompt preview
 	promptVp := viewport.New(0, 0)
 
+	// Initialize viewport for the activity feed
+	activityVp := viewport.New(0, 0)
+
+	// Initialize viewport for the diff view
+	diffVp := viewport.New(0, 0)
+
 	// Initialize text input for workdir
 	ti := textinput.New()
 	ti.Placeholder = "Enter path..."
 	ti.CharLimit = 256
 
+	// Initialize text input for filtering recent workdirs
+	fi := textinput.New()
+	fi.Placeholder = "Type part of a path..."
+	fi.CharLimit = 256
+
+	// Initialize text input for console searches
+	si := textinput.New()
+	si.Placeholder = "Search output..."
+	si.Prompt = ""
+	si.CharLimit = 256
+
+	// Initialize text input for agent messages
+	mi := textinput.New()
+	mi.Placeholder = "Tell the agent what to do differently..."
+	mi.CharLimit = 2000
+
+	// Initialize text input for review rejections
+	ri := textinput.New()
+	ri.Placeholder = "Tell the agent what to change..."
+	ri.CharLimit = 2000
+
 	return Model{
-		criteria:       criteria,
-		mode:           mode,
-		workDir:        workDir,
-		spinner:        s,
-		panels:         make([]*AgentPanel, 0),
-		viewport:       vp,
-		promptViewport: promptVp,
-		workDirInput:   ti,
-		agentUpdates:   make(chan AgentUpdate, 100),
-		modeUpdates:    modeUpdates,
-		stopUpdates:    stopUpdates,
-		workDirUpdates: workDirUpdates,
+		criteria:         criteria,
+		mode:             mode,
+		workDir:          workDir,
+		spinner:          s,
+		panels:           make([]*AgentPanel, 0),
+		viewport:         vp,
+		promptViewport:   promptVp,
+		activityViewport: activityVp,
+		diffViewport:     diffVp,
+		workDirInput:     ti,
+		workDirFilter:    fi,
+		messageInput:     mi,
+		rejectInput:      ri,
+		consoleSearch:    si,
+		consoleFollow:    true,
+		agentUpdates:     make(chan AgentUpdate, 100),
+		modeUpdates:      modeUpdates,
+		stopUpdates:      stopUpdates,
+		workDirUpdates:   workDirUpdates,
 	}
 }
 
@@ -164,6 +260,50 @@ type AddAgentMsg struct {
 	TaskTitle string
 	AgentName string
 	Runner    *agent.Runner
+	// Resumed continues the task's existing panel, if it has one, because
+	// the agent picks up an earlier session
+	Resumed bool
+	// WorkDir is the directory the agent works in
+	WorkDir string
+}
+
+// AgentMessage is a message typed by the user for the agent running a task
+type AgentMessage struct {
+	TaskID string
+	Text   string
+}
+
+// ReviewDecision is a reviewer's verdict on a task awaiting approval
+type ReviewDecision struct {
+	TaskID  string
+	Approve bool
+	// Comment is passed to the task's next run when it is rejected
+	Comment string
+}
+
+// ReviewRequestedMsg signals that a task's successful run awaits approval
+type ReviewRequestedMsg struct {
+	TaskID string
+	Reason string
+}
+
+// ReviewResolvedMsg signals that a reviewer approved or rejected a task
+type ReviewResolvedMsg struct {
+	TaskID   string
+	Approved bool
+}
+
+// TransitionMsg reports a task status change made by the worker, for the
+// activity feed.
+type TransitionMsg struct {
+	Time   time.Time
+	TaskID string
+	Title  string
+	From   string
+	To     string
+	Reason string
+	// Error is set when the change failed
+	Error string
 }
 
 // AgentOutputMsg sends output to an agent panel
@@ -176,6 +316,18 @@ type AgentOutputMsg struct {
 type AgentCompletedMsg struct {
 	TaskID string
 	Result agent.Result
+	// Changes is what the run changed, if it was captured
+	Changes *Changes
+}
+
+// AgentStoppingMsg signals that an agent was asked to stop from outside the TUI
+type AgentStoppingMsg struct {
+	TaskID string
+}
+
+// PausedMsg signals that task selection was paused or resumed
+type PausedMsg struct {
+	Paused bool
 }
 
 // Init initializes the model
@@ -235,7 +387,10 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case AddAgentMsg:
-		m.addAgentPanel(msg.TaskID, msg.TaskTitle, msg.AgentName, msg.Runner)
+		if msg.Resumed && m.resumeAgentPanel(msg.TaskID, msg.AgentName, msg.Runner, msg.WorkDir) {
+			return m, nil
+		}
+		m.addAgentPanel(msg.TaskID, msg.TaskTitle, msg.AgentName, msg.Runner, msg.WorkDir)
 		return m, nil
 
 	case AgentOutputMsg:
@@ -243,7 +398,49 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case AgentCompletedMsg:
-		m.completeAgent(msg.TaskID, msg.Result)
+		m.completeAgent(msg.TaskID, msg.Result, msg.Changes)
+		return m, nil
+
+	case AgentStoppingMsg:
+		for _, panel := range m.panels {
+			if panel.TaskID == msg.TaskID && panel.IsRunning() {
+				panel.Stopping = true
+			}
+		}
+		return m, nil
+
+	case ReviewRequestedMsg:
+		if panel := m.latestPanel(msg.TaskID); panel != nil {
+			panel.AwaitingReview = true
+			panel.Rejected = false
+		}
+		return m, nil
+
+	case ReviewResolvedMsg:
+		if panel := m.latestPanel(msg.TaskID); panel != nil {
+			panel.AwaitingReview = false
+			panel.Rejected = !msg.Approved
+		}
+		return m, nil
+
+	case TransitionMsg:
+		m.activity = append(m.activity, msg)
+		if len(m.activity) > maxActivity {
+			m.activity = m.activity[len(m.activity)-maxActivity:]
+		}
+		if m.activityOpen {
+			m.updateActivityContent()
+		}
+		return m, nil
+
+	case PromptPreviewMsg:
+		m.promptPreview, m.promptPreviewErr = msg.Preview, msg.Err
+		m.promptPreviewLoading = false
+		m.updatePromptPreviewContent()
+		return m, nil
+
+	case PausedMsg:
+		m.paused = msg.Paused
 		return m, nil
 
 	case versionCheckMsg:
@@ -255,7 +452,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func (m *Model) addAgentPanel(taskID, taskTitle, agentName string, runner *agent.Runner) {
+func (m *Model) addAgentPanel(taskID, taskTitle, agentName string, runner *agent.Runner, workDir string) {
 	m.nextPanelID++
 	id := fmt.Sprintf("agent-%d", m.nextPanelID)
 
@@ -273,6 +470,7 @@ func (m *Model) addAgentPanel(taskID, taskTitle, agentName string, runner *agent
 		Output:    make([]agent.OutputLine, 0),
 		StartTime: time.Now(),
 		PID:       pid,
+		WorkDir:   workDir,
 	}
 
 	m.panels = append(m.panels, panel)
@@ -288,6 +486,44 @@ func (m *Model) addAgentPanel(taskID, taskTitle, agentName string, runner *agent
 	m.updateConsoleContent()
 }
 
+// resumeAgentPanel hands the task's newest panel to the runner of a resumed
+// session, reporting false if the task has no panel.
+func (m *Model) resumeAgentPanel(taskID, agentName string, runner *agent.Runner, workDir string) bool {
+	for i := len(m.panels) - 1; i >= 0; i-- {
+		panel := m.panels[i]
+		if panel.TaskID != taskID {
+			continue
+		}
+		panel.AgentName = agentName
+		panel.Runner = runner
+		panel.WorkDir = workDir
+		panel.PID = 0
+		if runner != nil {
+			panel.PID = runner.PID()
+		}
+		panel.Result = nil
+		panel.EndTime = time.Time{}
+		panel.Stopping = false
+		panel.AwaitingReview = false
+		panel.Rejected = false
+		if i == m.focusedPanel {
+			m.updateConsoleContent()
+		}
+		return true
+	}
+	return false
+}
+
+// latestPanel returns the task's newest panel, or nil if it has none.
+func (m *Model) latestPanel(taskID string) *AgentPanel {
+	for i := len(m.panels) - 1; i >= 0; i-- {
+		if m.panels[i].TaskID == taskID {
+			return m.panels[i]
+		}
+	}
+	return nil
+}
+
 func (m *Model) appendAgentOutput(taskID string, line agent.OutputLine) {
 	for i, panel := range m.panels {
 		if panel.TaskID == taskID {
@@ -314,10 +550,11 @@ func (m *Model) appendAgentOutput(taskID string, line agent.OutputLine) {
 	}
 }
 
-func (m *Model) completeAgent(taskID string, result agent.Result) {
+func (m *Model) completeAgent(taskID string, result agent.Result, changes *Changes) {
 	for _, panel := range m.panels {
 		if panel.TaskID == taskID {
 			panel.Result = &result
+			panel.Changes = changes
 			panel.EndTime = time.Now()
 			panel.Runner = nil
 			m.taskCount++
@@ -330,6 +567,51 @@ func (m *Model) completeAgent(taskID string, result agent.Result) {
 }
 
 func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// Handle diff view mode
+	if m.diffOpen {
+		panel := m.diffPanel()
+		if panel == nil {
+			m.diffOpen = false
+			return m, nil
+		}
+		switch msg.String() {
+		case "esc", "d":
+			m.diffOpen = false
+			return m, nil
+		case "up", "k":
+			if m.diffFile > 0 {
+				m.diffFile--
+				m.updateDiffContent()
+			}
+			return m, nil
+		case "down", "j":
+			if m.diffFile < len(panel.Changes.Files)-1 {
+				m.diffFile++
+				m.updateDiffContent()
+			}
+			return m, nil
+		case "pgup", "pgdown", "home", "end", " ":
+			var cmd tea.Cmd
+			m.diffViewport, cmd = m.diffViewport.Update(msg)
+			return m, cmd
+		}
+		return m, nil
+	}
+
+	// Handle activity feed mode
+	if m.activityOpen {
+		switch msg.String() {
+		case "esc", "h":
+			m.activityOpen = false
+			return m, nil
+		case "up", "k", "down", "j", "pgup", "pgdown", "home", "end":
+			var cmd tea.Cmd
+			m.activityViewport, cmd = m.activityViewport.Update(msg)
+			return m, cmd
+		}
+		return m, nil
+	}
+
 	// Handle prompt preview mode
 	if m.promptPreviewOpen {
 		switch msg.String() {
@@ -344,6 +626,52 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	// Handle agent message input mode
+	if m.messageInputMode {
+		switch msg.String() {
+		case "esc":
+			m.messageInputMode = false
+			m.messageInput.Reset()
+			return m, nil
+		case "enter":
+			text := strings.TrimSpace(m.messageInput.Value())
+			if text != "" {
+				m.sendAgentMessage(m.messageTaskID, text)
+			}
+			m.messageInputMode = false
+			m.messageInput.Reset()
+			return m, nil
+		default:
+			var cmd tea.Cmd
+			m.messageInput, cmd = m.messageInput.Update(msg)
+			return m, cmd
+		}
+	}
+
+	// Handle review rejection input mode
+	if m.rejectInputMode {
+		switch msg.String() {
+		case "esc":
+			m.rejectInputMode = false
+			m.rejectInput.Reset()
+			return m, nil
+		case "enter":
+			// The comment is what the agent works from next, so it is required
+			comment := strings.TrimSpace(m.rejectInput.Value())
+			if comment == "" {
+				return m, nil
+			}
+			m.sendReviewDecision(ReviewDecision{TaskID: m.rejectTaskID, Comment: comment})
+			m.rejectInputMode = false
+			m.rejectInput.Reset()
+			return m, nil
+		default:
+			var cmd tea.Cmd
+			m.rejectInput, cmd = m.rejectInput.Update(msg)
+			return m, cmd
+		}
+	}
+
 	// Handle workdir text input mode
 	if m.workDirInputMode {
 		switch msg.String() {
@@ -354,13 +682,7 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		case "enter":
 			newPath := m.workDirInput.Value()
 			if newPath != "" {
-				m.workDir = expandHomePath(newPath)
-				if m.workDirUpdates != nil {
-					select {
-					case m.workDirUpdates <- m.workDir:
-					default:
-					}
-				}
+				m.setWorkDir(expandHomePath(newPath))
 			}
 			m.workDirInputMode = false
 			m.workDirInput.Reset()
@@ -372,34 +694,44 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	}
 
+	// Handle the recent workdirs picker
+	if m.workDirPickerOpen {
+		return m, m.workDirPickerKey(msg)
+	}
+
+	// Handle the shell startup file confirmation
+	if m.workDirShellFile != "" {
+		m.workDirShellKey(msg.String())
+		return m, nil
+	}
+
 	// Handle workdir menu mode
 	if m.workDirMenuOpen {
-		switch msg.String() {
-		case "esc":
-			m.workDirMenuOpen = false
-			return m, nil
-		case "1":
-			m.workDirMenuOpen = false
-			m.workDirInputMode = true
-			m.workDirInput.SetValue(m.workDir)
-			m.workDirInput.Focus()
-			return m, nil
-		case "2":
-			m.workDirMenuOpen = false
-			m.saveWorkDirToEnv()
-			return m, nil
-		}
+		m.workDirMenuKey(msg.String())
 		return m, nil
 	}
 
+	// Handle console search input
+	if m.consoleSearchMode {
+		return m, m.consoleSearchKey(msg)
+	}
+
 	if m.consoleOpen {
+		m.consoleNotice, m.consoleNoticeErr = "", false
+		if cmd, ok := m.consoleKey(msg.String()); ok {
+			return m, cmd
+		}
 		switch msg.String() {
 		case "esc":
 			m.consoleOpen = false
 			m.updateLayoutDimensions()
 			return m, nil
 		case "pgup", "pgdown", "home", "end":
-			// Scroll within the output viewport
+			// Scroll within the output viewport; scrolling back stops
+			// following new output
+			if k := msg.String(); k == "pgup" || k == "home" {
+				m.consoleFollow = false
+			}
 			var cmd tea.Cmd
 			m.viewport, cmd = m.viewport.Update(msg)
 			return m, cmd
@@ -446,6 +778,34 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case "i":
+		// Message selected panel's agent if running
+		if m.messageUpdates != nil && m.focusedPanel >= 0 && m.focusedPanel < len(m.panels) {
+			panel := m.panels[m.focusedPanel]
+			if panel.IsRunning() && !panel.Stopping {
+				m.messageTaskID = panel.TaskID
+				m.messageInputMode = true
+				return m, m.messageInput.Focus()
+			}
+		}
+		return m, nil
+
+	case "a":
+		// Approve selected panel's task if awaiting review
+		if panel := m.reviewablePanel(); panel != nil {
+			m.sendReviewDecision(ReviewDecision{TaskID: panel.TaskID, Approve: true})
+		}
+		return m, nil
+
+	case "r":
+		// Reject selected panel's task with a comment if awaiting review
+		if panel := m.reviewablePanel(); panel != nil {
+			m.rejectTaskID = panel.TaskID
+			m.rejectInputMode = true
+			return m, m.rejectInput.Focus()
+		}
+		return m, nil
+
 	case "up", "k":
 		if m.focusedPanel > 0 {
 			m.focusedPanel--
@@ -474,18 +834,74 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	case "w":
 		m.workDirMenuOpen = true
+		m.workDirNotice, m.workDirNoticeErr = "", false
 		return m, nil
 
 	case "p":
-		m.loadClaudeMdFiles()
-		m.promptPreviewOpen = true
-		m.updatePromptPreviewContent()
+		m.openPromptPreview()
+		return m, nil
+
+	case "h":
+		m.activityOpen = true
+		m.updateActivityContent()
+		return m, nil
+
+	case "d":
+		// Show what the selected panel's finished run changed
+		if m.diffPanel() != nil {
+			m.diffOpen = true
+			m.diffFile = 0
+			m.updateDiffContent()
+		}
 		return m, nil
 	}
 
 	return m, nil
 }
 
+// sendAgentMessage passes a message to the worker and echoes it in the
+// task's panel.
+func (m *Model) sendAgentMessage(taskID, text string) {
+	select {
+	case m.messageUpdates <- AgentMessage{TaskID: taskID, Text: text}:
+	default:
+		return
+	}
+	for i := len(m.panels) - 1; i >= 0; i-- {
+		if m.panels[i].TaskID == taskID {
+			m.panels[i].Output = append(m.panels[i].Output, agent.OutputLine{
+				Text:      "You: " + text,
+				Timestamp: time.Now(),
+			})
+			if i == m.focusedPanel {
+				m.updateConsoleContent()
+			}
+			return
+		}
+	}
+}
+
+// reviewablePanel returns the focused panel if its task awaits review and
+// decisions can be sent, or nil.
+func (m *Model) reviewablePanel() *AgentPanel {
+	if m.reviewUpdates == nil || m.focusedPanel < 0 || m.focusedPanel >= len(m.panels) {
+		return nil
+	}
+	if panel := m.panels[m.focusedPanel]; panel.AwaitingReview {
+		return panel
+	}
+	return nil
+}
+
+// sendReviewDecision passes a review decision to the worker. The panel is
+// updated once the worker reports the review resolved.
+func (m *Model) sendReviewDecision(decision ReviewDecision) {
+	select {
+	case m.reviewUpdates <- decision:
+	default:
+	}
+}
+
 func (m *Model) updateLayoutDimensions() {
 	headerHeight := lipgloss.Height(m.renderHeader())
 	helpHeight := lipgloss.Height(m.renderHelp())
@@ -557,7 +973,8 @@ func (m *Model) updateLayoutDimensions() {
 
 	if m.consoleHeight > 0 {
 		m.viewport.Width = m.consoleWidth - 4
-		m.viewport.Height = m.consoleHeight - 4
+		// A short terminal leaves no room below the title and borders
+		m.viewport.Height = max(m.consoleHeight-4, 1)
 	} else {
 		m.viewport.Width = listWidth - 4
 		m.viewport.Height = 1
@@ -608,33 +1025,6 @@ func (m *Model) listMaxItems() int {
 	return (m.listBodyHeight + gap) / (rowHeight + gap)
 }
 
-func (m *Model) updateConsoleContent() {
-	if m.focusedPanel < 0 || m.focusedPanel >= len(m.panels) {
-		m.viewport.SetContent("")
-		return
-	}
-
-	panel := m.panels[m.focusedPanel]
-	var b strings.Builder
-
-	for _, line := range panel.Output {
-		text := line.Text
-		if line.IsStderr {
-			b.WriteString(StderrStyle.Render(text))
-		} else {
-			b.WriteString(OutputStyle.Render(text))
-		}
-		b.WriteString("\n")
-	}
-
-	m.viewport.SetContent(b.String())
-
-	// Auto-scroll to bottom if running
-	if panel.IsRunning() {
-		m.viewport.GotoBottom()
-	}
-}
-
 // View renders the UI
 func (m *Model) View() string {
 	if m.width == 0 {
@@ -642,6 +1032,12 @@ func (m *Model) View() string {
 	}
 
 	// Check for overlay modes first
+	if m.diffOpen && m.diffPanel() != nil {
+		return m.renderDiff()
+	}
+	if m.activityOpen {
+		return m.renderActivity()
+	}
 	if m.promptPreviewOpen {
 		return m.renderPromptPreview()
 	}
@@ -651,6 +1047,18 @@ func (m *Model) View() string {
 	if m.workDirInputMode {
 		return m.renderWorkDirInput()
 	}
+	if m.workDirPickerOpen {
+		return m.renderWorkDirPicker()
+	}
+	if m.workDirShellFile != "" {
+		return m.renderWorkDirShellConfirm()
+	}
+	if m.messageInputMode {
+		return m.renderMessageInput()
+	}
+	if m.rejectInputMode {
+		return m.renderRejectInput()
+	}
 
 	var b strings.Builder
 
@@ -686,9 +1094,25 @@ func (m *Model) renderWorkDirMenu() string {
 	b.WriteString(fmt.Sprintf("Current: %s\n\n", shortenPath(m.workDir)))
 
 	b.WriteString(HelpKeyStyle.Render("[1]") + " Change path...\n")
-	b.WriteString(HelpKeyStyle.Render("[2]") + " Save to MOMENTUM_WORKDIR env var\n\n")
+	last := "1"
+	if m.workDirStore != nil {
+		b.WriteString(HelpKeyStyle.Render("[2]") + " Recent workdirs...\n")
+		b.WriteString(HelpKeyStyle.Render("[3]") + " Save as default in config file\n")
+		b.WriteString(HelpKeyStyle.Render("[4]") + " Save to MOMENTUM_WORKDIR in shell startup file...\n")
+		last = "4"
+	}
+	b.WriteString("\n")
+
+	if m.workDirNotice != "" {
+		if m.workDirNoticeErr {
+			b.WriteString(StatusError.Render(m.workDirNotice))
+		} else {
+			b.WriteString(StatusConnected.Render(m.workDirNotice))
+		}
+		b.WriteString("\n\n")
+	}
 
-	b.WriteString(HelpStyle.Render("Press 1-2 or esc to cancel"))
+	b.WriteString(HelpStyle.Render("Press 1-" + last + " or esc to cancel"))
 
 	content := PanelStyle.Width(60).Render(b.String())
 
@@ -715,10 +1139,57 @@ func (m *Model) renderWorkDirInput() string {
 	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
 }
 
+func (m *Model) renderMessageInput() string {
+	var b strings.Builder
+
+	title := lipgloss.NewStyle().Bold(true).Foreground(GlowGreen).Render("Message Agent")
+	b.WriteString(title)
+	b.WriteString("\n\n")
+
+	for _, panel := range m.panels {
+		if panel.TaskID == m.messageTaskID {
+			b.WriteString(fmt.Sprintf("Task: %s\n\n", panel.TaskTitle))
+			break
+		}
+	}
+
+	b.WriteString(m.messageInput.View())
+	b.WriteString("\n\n")
+
+	b.WriteString(HelpStyle.Render("Press enter to send or esc to cancel"))
+
+	content := PanelStyle.Width(70).Render(b.String())
+
+	// Center in screen
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+}
+
+func (m *Model) renderRejectInput() string {
+	var b strings.Builder
+
+	title := lipgloss.NewStyle().Bold(true).Foreground(GlowGreen).Render("Reject Task")
+	b.WriteString(title)
+	b.WriteString("\n\n")
+
+	if panel := m.latestPanel(m.rejectTaskID); panel != nil {
+		b.WriteString(fmt.Sprintf("Task: %s\n\n", panel.TaskTitle))
+	}
+
+	b.WriteString(m.rejectInput.View())
+	b.WriteString("\n\n")
+
+	b.WriteString(HelpStyle.Render("The task is re-queued with your comment. Press enter to reject or esc to cancel"))
+
+	content := PanelStyle.Width(70).Render(b.String())
+
+	// Center in screen
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+}
+
 func (m *Model) renderPromptPreview() string {
 	var b strings.Builder
 
-	title := lipgloss.NewStyle().Bold(true).Foreground(GlowGreen).Render("Inherited System Prompt")
+	title := lipgloss.NewStyle().Bold(true).Foreground(GlowGreen).Render("Prompt Preview")
 	b.WriteString(title)
 	b.WriteString("\n\n")
 
@@ -749,6 +1220,8 @@ func (m *Model) renderListenerPanel() string {
 	var status string
 	if m.lastError != nil {
 		status = StatusError.Render(fmt.Sprintf("Error: %v", m.lastError))
+	} else if m.paused {
+		status = StatusWaiting.Render("Paused - not picking up new tasks (momentum resume)")
 	} else if m.connected {
 		status = StatusConnected.Render("Connected and watching for tasks...") + " " + m.spinner.View()
 	} else {
@@ -765,7 +1238,16 @@ func (m *Model) renderListenerPanel() string {
 		displayWorkDir = "..." + displayWorkDir[len(displayWorkDir)-37:]
 	}
 
-	content := fmt.Sprintf("%s\n%s %s\n%s %s\n%s %s\n%s %d\n\n%s",
+	lastMove := hintStyle.Render("none yet (h for activity)")
+	if len(m.activity) > 0 {
+		maxLen := m.width - labelWidth - 8
+		if maxLen < 20 {
+			maxLen = 20
+		}
+		lastMove = truncate(formatTransition(m.activity[len(m.activity)-1]), maxLen)
+	}
+
+	content := fmt.Sprintf("%s\n%s %s\n%s %s\n%s %s\n%s %d\n%s %s\n\n%s",
 		status,
 		labelStyle.Render("Filter:"),
 		m.criteria,
@@ -775,7 +1257,9 @@ func (m *Model) renderListenerPanel() string {
 		displayWorkDir,
 		labelStyle.Render("Tasks completed:"),
 		m.taskCount,
-		hintStyle.Render("Agents inherit CLAUDE.md from WorkDir. Press p to preview."),
+		labelStyle.Render("Last move:"),
+		lastMove,
+		hintStyle.Render("Agents inherit CLAUDE.md from WorkDir. Press p to preview their input."),
 	)
 
 	return PanelStyle.Width(m.width - 4).Render(content)
@@ -807,8 +1291,17 @@ func (m *Model) renderHelp() string {
 		HelpKeyStyle.Render("m") + HelpStyle.Render(" mode  ") +
 		HelpKeyStyle.Render("w") + HelpStyle.Render(" workdir  ") +
 		HelpKeyStyle.Render("p") + HelpStyle.Render(" prompt  ") +
-		HelpKeyStyle.Render("s") + HelpStyle.Render(" stop  ") +
-		HelpKeyStyle.Render("x") + HelpStyle.Render(" remove  ") +
+		HelpKeyStyle.Render("h") + HelpStyle.Render(" activity  ") +
+		HelpKeyStyle.Render("i") + HelpStyle.Render(" message  ") +
+		HelpKeyStyle.Render("s") + HelpStyle.Render(" stop  ")
+	if m.diffPanel() != nil {
+		help += HelpKeyStyle.Render("d") + HelpStyle.Render(" diff  ")
+	}
+	if m.reviewablePanel() != nil {
+		help += HelpKeyStyle.Render("a") + HelpStyle.Render(" approve  ") +
+			HelpKeyStyle.Render("r") + HelpStyle.Render(" reject  ")
+	}
+	help += HelpKeyStyle.Render("x") + HelpStyle.Render(" remove  ") +
 		HelpKeyStyle.Render("q") + HelpStyle.Render(" quit")
 
 	if m.updateAvailable {
@@ -925,18 +1418,24 @@ func (m *Model) renderConsolePanel() string {
 	panel := m.panels[m.focusedPanel]
 	statusText, statusStyle := statusForPanel(panel)
 	title := fmt.Sprintf("Console: %s · %s · %s", panel.TaskTitle, statusStyle.Render(statusText), formatDuration(panel))
+	if panel.WorkDir != "" {
+		title += " · " + shortenPath(panel.WorkDir)
+	}
+	if panel.Changes != nil {
+		title += " · " + panel.Changes.Summary()
+	}
 
 	content := ConsoleTitleStyle.Width(m.consoleWidth-2).Render(title) + "\n"
 	content += m.viewport.View()
-	content += "\n" + HelpStyle.Render("pgup/pgdn scroll · esc close")
+	content += "\n" + m.consoleFooter()
 
 	if m.consoleHeight <= 0 {
 		return ""
 	}
 
-	// Use red border for stopped or failed tasks
+	// Use red border for stopped, failed or rejected tasks
 	style := ConsoleOverlayStyle
-	if panel.Result != nil && (panel.Stopping || panel.Result.ExitCode != 0) {
+	if panel.Result != nil && (panel.Stopping || panel.Result.ExitCode != 0 || panel.Rejected) {
 		style = ConsoleStoppedStyle
 	}
 
@@ -1047,6 +1546,10 @@ func statusForPanel(panel *AgentPanel) (string, lipgloss.Style) {
 		return "stopping", AgentStopping
 	case panel.IsRunning():
 		return "running", AgentRunning
+	case panel.AwaitingReview:
+		return "awaiting review", StatusWaiting
+	case panel.Rejected:
+		return "rejected", AgentStopped
 	case panel.Result != nil:
 		if panel.Result.ExitCode == 0 {
 			return "complete 100%", AgentCompleted
@@ -1103,6 +1606,18 @@ func clampHeight(s string, height int) string {
 
 // Public methods for external control
 
+// SetMessageUpdates enables the message key, sending the user's messages for
+// running agents to ch
+func (m *Model) SetMessageUpdates(ch chan<- AgentMessage) {
+	m.messageUpdates = ch
+}
+
+// SetReviewUpdates enables the approve and reject keys, sending the
+// reviewer's decisions on tasks awaiting approval to ch
+func (m *Model) SetReviewUpdates(ch chan<- ReviewDecision) {
+	m.reviewUpdates = ch
+}
+
 // SetListening sets the listening state
 func (m *Model) SetListening(listening bool) {
 	m.listening = listening
@@ -1195,84 +1710,68 @@ func expandHomePath(path string) string {
 	return path
 }
 
-// saveWorkDirToEnv prints instructions for saving workdir to env var
-// (actual shell modification not possible from Go, so we inform the user)
-func (m *Model) saveWorkDirToEnv() {
-	// We can't actually modify the user's shell config from here,
-	// but we can show them what to add
-	// For now, this is a no-op - the user sees the current workdir and can set it manually
-}
+// renderActivity shows the activity feed of task status changes.
+func (m *Model) renderActivity() string {
+	var b strings.Builder
 
-// loadClaudeMdFiles finds and loads CLAUDE.md files for preview
-func (m *Model) loadClaudeMdFiles() {
-	m.claudeMdFiles = nil
+	title := lipgloss.NewStyle().Bold(true).Foreground(GlowGreen).Render("Activity")
+	b.WriteString(title)
+	b.WriteString("\n\n")
 
-	// 1. Global ~/.claude/CLAUDE.md
-	home, _ := os.UserHomeDir()
-	globalPath := filepath.Join(home, ".claude", "CLAUDE.md")
-	if content, err := os.ReadFile(globalPath); err == nil {
-		m.claudeMdFiles = append(m.claudeMdFiles, claudeMdFile{
-			Path:    globalPath,
-			Content: string(content),
-		})
-	}
-
-	// 2. Walk from workdir up to root, collecting CLAUDE.md files
-	absWorkDir := m.workDir
-	if !filepath.IsAbs(absWorkDir) {
-		if wd, err := os.Getwd(); err == nil {
-			absWorkDir = filepath.Join(wd, m.workDir)
-		}
+	width := m.width - 10
+	if width > 120 {
+		width = 120
 	}
-	absWorkDir = filepath.Clean(absWorkDir)
-
-	var projectFiles []claudeMdFile
-	dir := absWorkDir
-	for {
-		mdPath := filepath.Join(dir, "CLAUDE.md")
-		if content, err := os.ReadFile(mdPath); err == nil {
-			// Prepend so parent dirs come first
-			projectFiles = append([]claudeMdFile{{
-				Path:    mdPath,
-				Content: string(content),
-			}}, projectFiles...)
-		}
-		parent := filepath.Dir(dir)
-		if parent == dir {
-			break
-		}
-		dir = parent
+	height := m.height - 10
+	if height > 30 {
+		height = 30
 	}
-	m.claudeMdFiles = append(m.claudeMdFiles, projectFiles...)
+	m.activityViewport.Width = width - 4
+	m.activityViewport.Height = height - 6
+
+	b.WriteString(m.activityViewport.View())
+	b.WriteString("\n\n")
+
+	b.WriteString(HelpStyle.Render("esc to close  j/k scroll"))
+
+	content := PanelStyle.Width(width).Render(b.String())
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
 }
 
-// updatePromptPreviewContent updates the prompt preview viewport content
-func (m *Model) updatePromptPreviewContent() {
+// updateActivityContent lists status changes in the feed, newest first.
+func (m *Model) updateActivityContent() {
+	if len(m.activity) == 0 {
+		m.activityViewport.SetContent("No status changes yet.")
+		return
+	}
 	var b strings.Builder
-
-	if len(m.claudeMdFiles) == 0 {
-		b.WriteString("No CLAUDE.md files found for current WorkDir.\n\n")
-		b.WriteString(fmt.Sprintf("WorkDir: %s\n", m.workDir))
-	} else {
-		b.WriteString("Sources:\n")
-		for _, f := range m.claudeMdFiles {
-			b.WriteString(fmt.Sprintf("  %s\n", f.Path))
+	for i := len(m.activity) - 1; i >= 0; i-- {
+		line := formatTransition(m.activity[i])
+		if m.activity[i].Error != "" {
+			line = StatusError.Render(line)
 		}
+		b.WriteString(line)
 		b.WriteString("\n")
-		b.WriteString(strings.Repeat("─", 60))
-		b.WriteString("\n\n")
-
-		for _, f := range m.claudeMdFiles {
-			b.WriteString(fmt.Sprintf("# From %s\n", f.Path))
-			b.WriteString(f.Content)
-			if !strings.HasSuffix(f.Content, "\n") {
-				b.WriteString("\n")
-			}
-			b.WriteString("\n")
-		}
 	}
+	m.activityViewport.SetContent(b.String())
+	m.activityViewport.GotoTop()
+}
 
-	m.promptViewport.SetContent(b.String())
+// formatTransition describes a status change on one line, e.g.
+// "15:04:05 task-1 in_progress → done (agent finished)".
+func formatTransition(t TransitionMsg) string {
+	move := t.To
+	if t.From != "" {
+		move = t.From + " → " + t.To
+	}
+	line := fmt.Sprintf("%s %s %s", t.Time.Local().Format("15:04:05"), t.TaskID, move)
+	if t.Error != "" {
+		return line + " failed: " + t.Error
+	}
+	if t.Reason != "" {
+		line += " (" + t.Reason + ")"
+	}
+	return line
 }
 
 // shortenPath shortens a path for display (replaces home with ~)
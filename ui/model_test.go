@@ -1,6 +1,8 @@
 package ui
 
 import (
+	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -155,6 +157,22 @@ func TestModel_Update_AddAgentMsg(t *testing.T) {
 	}
 }
 
+func TestModel_PanelWorkDir(t *testing.T) {
+	model := NewModel("test", ExecutionModeAsync, ".", nil, nil, nil)
+	model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	model.Update(AddAgentMsg{TaskID: "task-1", TaskTitle: "Task 1", AgentName: "Claude", WorkDir: "/srv/api"})
+
+	if !strings.Contains(model.renderConsolePanel(), "/srv/api") {
+		t.Error("expected the console to show the panel's workdir")
+	}
+
+	// A resumed session may run elsewhere after the mapping changed
+	model.Update(AddAgentMsg{TaskID: "task-1", AgentName: "Claude", Resumed: true, WorkDir: "/srv/web"})
+	if len(model.panels) != 1 || model.panels[0].WorkDir != "/srv/web" {
+		t.Errorf("expected the resumed panel to show the new workdir, got %+v", model.panels)
+	}
+}
+
 func TestModel_Update_AddMultipleAgents(t *testing.T) {
 	model := NewModel("test", ExecutionModeAsync, ".", nil, nil, nil)
 
@@ -421,3 +439,186 @@ type testError struct {
 func (e *testError) Error() string {
 	return e.msg
 }
+
+// runningRunner starts an agent that runs until the test ends.
+func runningRunner(t *testing.T) *agent.Runner {
+	t.Helper()
+	runner := agent.NewRunner(agent.NewFake(agent.Config{}, agent.FakeConfig{Failure: agent.FakeHang}))
+	if err := runner.Run(context.Background(), "prompt"); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for range runner.Output() {
+		}
+	}()
+	t.Cleanup(func() { runner.Cancel() })
+	return runner
+}
+
+func TestModel_HandleKeyPress_MessageAgent(t *testing.T) {
+	messages := make(chan AgentMessage, 1)
+	model := NewModel("test", ExecutionModeAsync, ".", nil, nil, nil)
+	model.SetMessageUpdates(messages)
+	model.Update(AddAgentMsg{TaskID: "task-1", TaskTitle: "Task 1", AgentName: "Claude", Runner: runningRunner(t)})
+
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'i'}})
+	if !model.messageInputMode {
+		t.Fatal("expected 'i' to open the message input")
+	}
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("add tests")})
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if model.messageInputMode {
+		t.Error("expected enter to close the message input")
+	}
+	select {
+	case msg := <-messages:
+		if msg.TaskID != "task-1" || msg.Text != "add tests" {
+			t.Errorf("unexpected message %+v", msg)
+		}
+	default:
+		t.Fatal("expected the message to be sent")
+	}
+	if out := model.panels[0].Output; len(out) != 1 || out[0].Text != "You: add tests" {
+		t.Errorf("expected the message to be echoed in the panel, got %+v", out)
+	}
+}
+
+func TestModel_HandleKeyPress_MessageAgentUnavailable(t *testing.T) {
+	model := NewModel("test", ExecutionModeAsync, ".", nil, nil, nil)
+	model.Update(AddAgentMsg{TaskID: "task-1", TaskTitle: "Task 1", AgentName: "Claude", Runner: runningRunner(t)})
+
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'i'}})
+	if model.messageInputMode {
+		t.Error("expected no message input without a message channel")
+	}
+
+	model.SetMessageUpdates(make(chan AgentMessage, 1))
+	model.Update(AgentCompletedMsg{TaskID: "task-1", Result: agent.Result{ExitCode: 0}})
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'i'}})
+	if model.messageInputMode {
+		t.Error("expected no message input for a finished agent")
+	}
+}
+
+func TestModel_Update_AddAgentMsgResumed(t *testing.T) {
+	model := NewModel("test", ExecutionModeAsync, ".", nil, nil, nil)
+	model.Update(AddAgentMsg{TaskID: "task-1", TaskTitle: "Task 1", AgentName: "Claude"})
+	model.Update(AgentCompletedMsg{TaskID: "task-1", Result: agent.Result{ExitCode: 1}})
+
+	runner := runningRunner(t)
+	model.Update(AddAgentMsg{TaskID: "task-1", TaskTitle: "Task 1", AgentName: "Claude", Runner: runner, Resumed: true})
+	if len(model.panels) != 1 {
+		t.Fatalf("expected the resumed agent to reuse the panel, got %d panels", len(model.panels))
+	}
+	if panel := model.panels[0]; panel.Result != nil || panel.Runner != runner || !panel.IsRunning() {
+		t.Errorf("expected the panel to show the resumed agent running, got %+v", panel)
+	}
+
+	model.Update(AddAgentMsg{TaskID: "task-2", TaskTitle: "Task 2", AgentName: "Claude", Resumed: true})
+	if len(model.panels) != 2 {
+		t.Errorf("expected a resumed task without a panel to get one, got %d panels", len(model.panels))
+	}
+}
+
+func TestModel_Update_ReviewMessages(t *testing.T) {
+	model := NewModel("test", ExecutionModeAsync, ".", nil, nil, nil)
+	model.Update(AddAgentMsg{TaskID: "task-1", TaskTitle: "Task 1", AgentName: "Claude"})
+	model.Update(AgentCompletedMsg{TaskID: "task-1", Result: agent.Result{ExitCode: 0}})
+
+	model.Update(ReviewRequestedMsg{TaskID: "task-1", Reason: "changes protected paths: go.mod"})
+	if status, _ := statusForPanel(model.panels[0]); status != "awaiting review" {
+		t.Errorf("expected awaiting review status, got %q", status)
+	}
+
+	model.Update(ReviewResolvedMsg{TaskID: "task-1", Approved: false})
+	if status, _ := statusForPanel(model.panels[0]); status != "rejected" {
+		t.Errorf("expected rejected status, got %q", status)
+	}
+}
+
+func TestModel_Update_Transitions(t *testing.T) {
+	model := NewModel("test", ExecutionModeAsync, ".", nil, nil, nil)
+	model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	at := time.Date(2026, 3, 4, 3, 0, 0, 0, time.Local)
+
+	model.Update(TransitionMsg{Time: at, TaskID: "task-1", From: "in_progress", To: "done", Reason: "agent finished"})
+	if !strings.Contains(model.renderListenerPanel(), "03:00:00 task-1 in_progress → done (agent finished)") {
+		t.Error("expected the listener panel to show the last move")
+	}
+
+	for i := 0; i < maxActivity+5; i++ {
+		model.Update(TransitionMsg{Time: at, TaskID: "task-2", To: "todo", Error: "flux api error"})
+	}
+	if len(model.activity) != maxActivity {
+		t.Errorf("expected the feed to keep %d entries, got %d", maxActivity, len(model.activity))
+	}
+
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'h'}})
+	if !model.activityOpen || !strings.Contains(model.View(), "task-2 todo failed: flux api error") {
+		t.Error("expected h to open the activity feed")
+	}
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyEsc})
+	if model.activityOpen {
+		t.Error("expected esc to close the activity feed")
+	}
+}
+
+func TestModel_HandleKeyPress_Approve(t *testing.T) {
+	decisions := make(chan ReviewDecision, 1)
+	model := NewModel("test", ExecutionModeAsync, ".", nil, nil, nil)
+	model.SetReviewUpdates(decisions)
+	model.Update(AddAgentMsg{TaskID: "task-1", TaskTitle: "Task 1", AgentName: "Claude"})
+	model.Update(AgentCompletedMsg{TaskID: "task-1", Result: agent.Result{ExitCode: 0}})
+
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}})
+	select {
+	case d := <-decisions:
+		t.Fatalf("expected no decision for a task not awaiting review, got %+v", d)
+	default:
+	}
+
+	model.Update(ReviewRequestedMsg{TaskID: "task-1"})
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}})
+	select {
+	case d := <-decisions:
+		if d.TaskID != "task-1" || !d.Approve {
+			t.Errorf("unexpected decision %+v", d)
+		}
+	default:
+		t.Fatal("expected an approval to be sent")
+	}
+}
+
+func TestModel_HandleKeyPress_Reject(t *testing.T) {
+	decisions := make(chan ReviewDecision, 1)
+	model := NewModel("test", ExecutionModeAsync, ".", nil, nil, nil)
+	model.SetReviewUpdates(decisions)
+	model.Update(AddAgentMsg{TaskID: "task-1", TaskTitle: "Task 1", AgentName: "Claude"})
+	model.Update(AgentCompletedMsg{TaskID: "task-1", Result: agent.Result{ExitCode: 0}})
+	model.Update(ReviewRequestedMsg{TaskID: "task-1"})
+
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	if !model.rejectInputMode {
+		t.Fatal("expected 'r' to open the rejection input")
+	}
+	// A rejection needs a comment for the agent
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyEnter})
+	if !model.rejectInputMode {
+		t.Fatal("expected an empty comment to keep the input open")
+	}
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("keep the old API")})
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if model.rejectInputMode {
+		t.Error("expected enter to close the rejection input")
+	}
+	select {
+	case d := <-decisions:
+		if d.TaskID != "task-1" || d.Approve || d.Comment != "keep the old API" {
+			t.Errorf("unexpected decision %+v", d)
+		}
+	default:
+		t.Fatal("expected a rejection to be sent")
+	}
+}
@@ -0,0 +1,150 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sirsjg/momentum/memory"
+)
+
+// PromptPreview is everything an agent is given for a task: its prompt,
+// the memory files Claude Code loads and the settings it runs with.
+type PromptPreview struct {
+	TaskID    string
+	TaskTitle string
+	WorkDir   string
+	Sections  []PromptSection
+}
+
+// PromptSection is one part of a prompt preview.
+type PromptSection struct {
+	Title   string
+	Content string
+	// Settings is set for sections that configure the agent rather than
+	// take up its context, which get no token estimate
+	Settings bool
+}
+
+// PromptPreviewMsg carries the preview asked for with the prompt preview
+// key, or why it could not be composed.
+type PromptPreviewMsg struct {
+	Preview *PromptPreview
+	Err     error
+}
+
+// SetPromptPreviewUpdates makes the prompt preview show the full input of
+// an agent: the focused panel's task ID, or "" for the next task to be
+// picked up, is sent to ch and the preview comes back as a
+// PromptPreviewMsg. Without it the preview shows the workdir's memory
+// files only.
+func (m *Model) SetPromptPreviewUpdates(ch chan<- string) {
+	m.promptPreviewUpdates = ch
+}
+
+// MemorySections returns a section for each memory file, with its imports,
+// and each settings file Claude Code reads when started in workDir.
+func MemorySections(workDir string) []PromptSection {
+	var sections []PromptSection
+	for _, f := range memory.Load(workDir) {
+		title := fmt.Sprintf("Memory (%s): %s", f.Scope, shortenPath(f.Path))
+		if f.ImportedBy != "" {
+			title = fmt.Sprintf("Memory (imported by %s): %s", filepath.Base(f.ImportedBy), shortenPath(f.Path))
+		}
+		sections = append(sections, PromptSection{Title: title, Content: f.Content})
+	}
+	for _, f := range memory.Settings(workDir) {
+		sections = append(sections, PromptSection{
+			Title:    fmt.Sprintf("Settings (%s): %s", f.Scope, shortenPath(f.Path)),
+			Content:  f.Content,
+			Settings: true,
+		})
+	}
+	return sections
+}
+
+// openPromptPreview shows the input of the focused panel's agent, or of
+// the next task when no panel is open.
+func (m *Model) openPromptPreview() {
+	m.promptPreviewOpen = true
+	m.promptPreview, m.promptPreviewErr = nil, nil
+	if m.promptPreviewUpdates == nil {
+		m.promptPreview = &PromptPreview{WorkDir: m.workDir, Sections: MemorySections(m.workDir)}
+		m.updatePromptPreviewContent()
+		return
+	}
+	taskID := ""
+	if m.focusedPanel >= 0 && m.focusedPanel < len(m.panels) {
+		taskID = m.panels[m.focusedPanel].TaskID
+	}
+	select {
+	case m.promptPreviewUpdates <- taskID:
+		m.promptPreviewLoading = true
+	default:
+		m.promptPreviewErr = fmt.Errorf("the worker is busy; try again")
+	}
+	m.updatePromptPreviewContent()
+}
+
+// updatePromptPreviewContent lists the preview's sections with their token
+// estimates, then their contents.
+func (m *Model) updatePromptPreviewContent() {
+	var b strings.Builder
+	p := m.promptPreview
+
+	switch {
+	case m.promptPreviewLoading:
+		b.WriteString("Composing the agent's input...\n")
+	case m.promptPreviewErr != nil:
+		b.WriteString(StatusError.Render("Error: " + m.promptPreviewErr.Error()))
+		b.WriteString("\n")
+	case p == nil:
+	default:
+		if p.TaskID != "" {
+			b.WriteString(fmt.Sprintf("Task:    %s %s\n", p.TaskID, p.TaskTitle))
+		} else {
+			b.WriteString("No task prompt: memory files for the current workdir only.\n")
+		}
+		b.WriteString(fmt.Sprintf("WorkDir: %s\n\n", shortenPath(p.WorkDir)))
+		if len(p.Sections) == 0 {
+			b.WriteString("No memory or settings files found.\n")
+			break
+		}
+
+		total := 0
+		for _, s := range p.Sections {
+			if s.Settings {
+				b.WriteString(fmt.Sprintf("  %8s  %s\n", "", s.Title))
+				continue
+			}
+			tokens := memory.EstimateTokens(s.Content)
+			total += tokens
+			b.WriteString(fmt.Sprintf("  %8s  %s\n", formatTokens(tokens), s.Title))
+		}
+		b.WriteString(fmt.Sprintf("  %8s  Total context\n\n", formatTokens(total)))
+
+		for _, s := range p.Sections {
+			b.WriteString(strings.Repeat("─", 60))
+			b.WriteString("\n")
+			b.WriteString(lipgloss.NewStyle().Bold(true).Render(s.Title))
+			b.WriteString("\n\n")
+			b.WriteString(s.Content)
+			if !strings.HasSuffix(s.Content, "\n") {
+				b.WriteString("\n")
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	m.promptViewport.SetContent(b.String())
+	m.promptViewport.GotoTop()
+}
+
+// formatTokens shows a token estimate, e.g. "~1.2k".
+func formatTokens(n int) string {
+	if n >= 1000 {
+		return fmt.Sprintf("~%.1fk", float64(n)/1000)
+	}
+	return fmt.Sprintf("~%d", n)
+}
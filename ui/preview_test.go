@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestModel_PromptPreviewRequestsFocusedTask(t *testing.T) {
+	requests := make(chan string, 1)
+	model := NewModel("test", ExecutionModeAsync, ".", nil, nil, nil)
+	model.SetPromptPreviewUpdates(requests)
+
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'p'}})
+	if got := <-requests; got != "" {
+		t.Errorf("expected the next task to be requested without panels, got %q", got)
+	}
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyEsc})
+
+	model.Update(AddAgentMsg{TaskID: "task-1", TaskTitle: "Task 1", AgentName: "Claude"})
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'p'}})
+	if got := <-requests; got != "task-1" {
+		t.Errorf("expected the focused task to be requested, got %q", got)
+	}
+	if !model.promptPreviewOpen || !model.promptPreviewLoading {
+		t.Error("expected the preview to open while it is composed")
+	}
+
+	model.Update(PromptPreviewMsg{Preview: &PromptPreview{
+		TaskID:    "task-1",
+		TaskTitle: "Task 1",
+		WorkDir:   "/src/app",
+		Sections: []PromptSection{
+			{Title: "Task prompt", Content: strings.Repeat("x", 4000)},
+			{Title: "Memory (project): /src/app/CLAUDE.md", Content: "Run make test."},
+			{Title: "Agent settings", Content: "Timeout: none", Settings: true},
+		},
+	}})
+	if model.promptPreviewLoading {
+		t.Error("expected the preview to be shown")
+	}
+	model.promptViewport.Height = 100
+	content := model.promptViewport.View()
+	for _, want := range []string{"task-1 Task 1", "~1.0k  Task prompt", "~4  Memory (project)", "~1.0k  Total context", "Run make test."} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q in the preview:\n%s", want, content)
+		}
+	}
+}
+
+func TestModel_PromptPreviewWithoutWorker(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "CLAUDE.local.md"), []byte("Use the staging API."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	model := NewModel("test", ExecutionModeAsync, dir, nil, nil, nil)
+
+	model.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'p'}})
+	if model.promptPreview == nil || model.promptPreviewLoading {
+		t.Fatal("expected the preview to be composed from the workdir")
+	}
+	found := false
+	for _, s := range model.promptPreview.Sections {
+		found = found || strings.HasPrefix(s.Title, "Memory (local)") && s.Content == "Use the staging API."
+	}
+	if !found {
+		t.Errorf("expected CLAUDE.local.md in %+v", model.promptPreview.Sections)
+	}
+}
+
+func TestFormatTokens(t *testing.T) {
+	if got := formatTokens(950); got != "~950" {
+		t.Errorf("unexpected %q", got)
+	}
+	if got := formatTokens(12345); got != "~12.3k" {
+		t.Errorf("unexpected %q", got)
+	}
+}
@@ -0,0 +1,269 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// WorkDirStore remembers the workdirs chosen in the TUI.
+type WorkDirStore interface {
+	// Recent returns the workdirs used lately, most recent first
+	Recent() []string
+	// Remember records dir as the most recently used workdir
+	Remember(dir string) error
+	// SaveDefault makes dir the workdir of future runs in the config file,
+	// returning the file's path
+	SaveDefault(dir string) (string, error)
+	// EnvOverridesDefault reports whether MOMENTUM_WORKDIR is set, which
+	// takes precedence over the default saved in the config file
+	EnvOverridesDefault() bool
+	// ShellExport returns the user's shell startup file and the line that
+	// sets MOMENTUM_WORKDIR to dir in it; file is "" if no shell is detected
+	ShellExport(dir string) (file, line string)
+	// SaveShellExport sets line in the shell startup file, replacing the
+	// line saved before
+	SaveShellExport(file, line string) error
+}
+
+// maxPickerRows is how many recent workdirs the picker lists at once.
+const maxPickerRows = 8
+
+// SetWorkDirStore enables saving the workdir and picking recent ones from
+// the workdir menu.
+func (m *Model) SetWorkDirStore(store WorkDirStore) {
+	m.workDirStore = store
+}
+
+// setWorkDir switches the workdir for new tasks and remembers it.
+func (m *Model) setWorkDir(dir string) {
+	m.workDir = dir
+	if m.workDirUpdates != nil {
+		select {
+		case m.workDirUpdates <- m.workDir:
+		default:
+		}
+	}
+	if m.workDirStore != nil {
+		if err := m.workDirStore.Remember(dir); err != nil {
+			m.lastError = err
+		}
+	}
+}
+
+// workDirMenuKey handles a key in the workdir menu.
+func (m *Model) workDirMenuKey(key string) {
+	m.workDirNotice, m.workDirNoticeErr = "", false
+	switch key {
+	case "esc":
+		m.workDirMenuOpen = false
+	case "1":
+		m.workDirMenuOpen = false
+		m.workDirInputMode = true
+		m.workDirInput.SetValue(m.workDir)
+		m.workDirInput.Focus()
+	case "2":
+		if m.workDirStore == nil {
+			return
+		}
+		m.workDirMenuOpen = false
+		m.workDirPickerOpen = true
+		m.workDirRecent = m.workDirStore.Recent()
+		m.workDirPick = 0
+		m.workDirFilter.Reset()
+		m.workDirFilter.Focus()
+	case "3":
+		if m.workDirStore == nil {
+			return
+		}
+		path, err := m.workDirStore.SaveDefault(m.workDir)
+		if err != nil {
+			m.workDirNotice, m.workDirNoticeErr = err.Error(), true
+			return
+		}
+		m.workDirNotice = "Saved as the default in " + shortenPath(path)
+		if m.workDirStore.EnvOverridesDefault() {
+			m.workDirNotice += ", but MOMENTUM_WORKDIR overrides it"
+		}
+	case "4":
+		if m.workDirStore == nil {
+			return
+		}
+		file, line := m.workDirStore.ShellExport(m.workDir)
+		if file == "" {
+			m.workDirNotice, m.workDirNoticeErr = "No shell startup file found for $SHELL", true
+			return
+		}
+		m.workDirMenuOpen = false
+		m.workDirShellFile, m.workDirShellLine = file, line
+	}
+}
+
+// workDirShellKey handles a key while asking to change the shell startup
+// file.
+func (m *Model) workDirShellKey(key string) {
+	switch key {
+	case "y", "Y":
+		if err := m.workDirStore.SaveShellExport(m.workDirShellFile, m.workDirShellLine); err != nil {
+			m.workDirNotice, m.workDirNoticeErr = err.Error(), true
+		} else {
+			m.workDirNotice = "Saved to " + shortenPath(m.workDirShellFile) + "; new shells use it"
+		}
+	case "n", "N", "esc":
+	default:
+		return
+	}
+	m.workDirShellFile, m.workDirShellLine = "", ""
+	m.workDirMenuOpen = true
+}
+
+// workDirPickerKey handles a key in the recent workdirs picker.
+func (m *Model) workDirPickerKey(msg tea.KeyMsg) tea.Cmd {
+	matches := m.workDirMatches()
+	switch msg.String() {
+	case "esc":
+		m.workDirPickerOpen = false
+		m.workDirFilter.Blur()
+		m.workDirMenuOpen = true
+		return nil
+	case "enter":
+		if m.workDirPick < len(matches) {
+			m.setWorkDir(matches[m.workDirPick])
+		}
+		m.workDirPickerOpen = false
+		m.workDirFilter.Blur()
+		return nil
+	case "up", "ctrl+p":
+		if m.workDirPick > 0 {
+			m.workDirPick--
+		}
+		return nil
+	case "down", "ctrl+n":
+		if m.workDirPick < len(matches)-1 {
+			m.workDirPick++
+		}
+		return nil
+	}
+	var cmd tea.Cmd
+	m.workDirFilter, cmd = m.workDirFilter.Update(msg)
+	m.workDirPick = 0
+	return cmd
+}
+
+// workDirMatches returns the recent workdirs matching the picker's filter,
+// best match first.
+func (m *Model) workDirMatches() []string {
+	pattern := m.workDirFilter.Value()
+	type match struct {
+		dir   string
+		score int
+	}
+	var matches []match
+	for _, dir := range m.workDirRecent {
+		if score, ok := fuzzyScore(pattern, shortenPath(dir)); ok {
+			matches = append(matches, match{dir, score})
+		}
+	}
+	// Equal scores keep the most recent first
+	slices.SortStableFunc(matches, func(a, b match) int { return b.score - a.score })
+	dirs := make([]string, len(matches))
+	for i, mt := range matches {
+		dirs[i] = mt.dir
+	}
+	return dirs
+}
+
+// fuzzyScore reports whether the characters of pattern appear in s in
+// order, ignoring case, and scores the match: consecutive characters and
+// characters starting a path element score higher.
+func fuzzyScore(pattern, s string) (int, bool) {
+	if pattern == "" {
+		return 0, true
+	}
+	p := []rune(strings.ToLower(pattern))
+	text := []rune(strings.ToLower(s))
+	score, pi := 0, 0
+	prev := -2
+	for i, r := range text {
+		if pi == len(p) {
+			break
+		}
+		if r != p[pi] {
+			continue
+		}
+		score++
+		if prev == i-1 {
+			score += 2
+		}
+		if i == 0 || !unicode.IsLetter(text[i-1]) && !unicode.IsDigit(text[i-1]) {
+			score++
+		}
+		prev = i
+		pi++
+	}
+	if pi < len(p) {
+		return 0, false
+	}
+	return score, true
+}
+
+func (m *Model) renderWorkDirPicker() string {
+	var b strings.Builder
+
+	title := lipgloss.NewStyle().Bold(true).Foreground(GlowGreen).Render("Recent WorkDirs")
+	b.WriteString(title)
+	b.WriteString("\n\n")
+	b.WriteString("Filter: ")
+	b.WriteString(m.workDirFilter.View())
+	b.WriteString("\n\n")
+
+	matches := m.workDirMatches()
+	switch {
+	case len(m.workDirRecent) == 0:
+		b.WriteString(HelpStyle.Render("No recent workdirs yet."))
+		b.WriteString("\n")
+	case len(matches) == 0:
+		b.WriteString(HelpStyle.Render("No workdirs match."))
+		b.WriteString("\n")
+	}
+	// Keep the selected row in the visible part of the list
+	start := 0
+	if m.workDirPick >= maxPickerRows {
+		start = m.workDirPick - maxPickerRows + 1
+	}
+	end := min(start+maxPickerRows, len(matches))
+	for i := start; i < end; i++ {
+		dir := truncate(shortenPath(matches[i]), 52)
+		if i == m.workDirPick {
+			b.WriteString(SelectedRowStyle.Render("> " + dir))
+		} else {
+			b.WriteString("  " + dir)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(HelpStyle.Render("type to filter · ↑/↓ select · enter use · esc back"))
+
+	content := PanelStyle.Width(60).Render(b.String())
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+}
+
+func (m *Model) renderWorkDirShellConfirm() string {
+	var b strings.Builder
+
+	title := lipgloss.NewStyle().Bold(true).Foreground(GlowGreen).Render("WorkDir")
+	b.WriteString(title)
+	b.WriteString("\n\n")
+	b.WriteString(fmt.Sprintf("Save this line to %s?\n\n", shortenPath(m.workDirShellFile)))
+	b.WriteString("  " + m.workDirShellLine + "\n\n")
+	b.WriteString(HelpStyle.Render("It replaces any workdir saved there before. MOMENTUM_WORKDIR overrides the config file default."))
+	b.WriteString("\n\n")
+	b.WriteString(HelpKeyStyle.Render("y") + HelpStyle.Render(" save  ") + HelpKeyStyle.Render("n") + HelpStyle.Render(" cancel"))
+
+	content := PanelStyle.Width(70).Render(b.String())
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+}
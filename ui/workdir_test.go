@@ -0,0 +1,165 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fakeWorkDirStore records what the workdir menu saves.
+type fakeWorkDirStore struct {
+	recent     []string
+	remembered []string
+	saved      string
+	saveErr    error
+	envSet     bool
+	shellFile  string
+	shellLines []string
+}
+
+func (s *fakeWorkDirStore) Recent() []string { return s.recent }
+
+func (s *fakeWorkDirStore) Remember(dir string) error {
+	s.remembered = append(s.remembered, dir)
+	return nil
+}
+
+func (s *fakeWorkDirStore) SaveDefault(dir string) (string, error) {
+	if s.saveErr != nil {
+		return "", s.saveErr
+	}
+	s.saved = dir
+	return "/home/me/.config/momentum/config.json", nil
+}
+
+func (s *fakeWorkDirStore) EnvOverridesDefault() bool { return s.envSet }
+
+func (s *fakeWorkDirStore) ShellExport(dir string) (string, string) {
+	if s.shellFile == "" {
+		return "", ""
+	}
+	return s.shellFile, "export MOMENTUM_WORKDIR='" + dir + "'"
+}
+
+func (s *fakeWorkDirStore) SaveShellExport(file, line string) error {
+	s.shellLines = append(s.shellLines, line)
+	return nil
+}
+
+func keys(model *Model, input ...string) {
+	for _, k := range input {
+		switch k {
+		case "enter":
+			model.handleKeyPress(tea.KeyMsg{Type: tea.KeyEnter})
+		case "esc":
+			model.handleKeyPress(tea.KeyMsg{Type: tea.KeyEsc})
+		case "down":
+			model.handleKeyPress(tea.KeyMsg{Type: tea.KeyDown})
+		default:
+			model.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+		}
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	if _, ok := fuzzyScore("apx", "~/src/api"); ok {
+		t.Error("expected no match when characters are missing")
+	}
+	if _, ok := fuzzyScore("SAPI", "~/src/api"); !ok {
+		t.Error("expected a case-insensitive match")
+	}
+	contiguous, _ := fuzzyScore("api", "~/src/api")
+	scattered, _ := fuzzyScore("api", "~/src/a-project/internal")
+	if contiguous <= scattered {
+		t.Errorf("expected a contiguous match to score higher: %d <= %d", contiguous, scattered)
+	}
+}
+
+func TestModel_WorkDirPicker(t *testing.T) {
+	updates := make(chan string, 1)
+	store := &fakeWorkDirStore{recent: []string{"/src/web", "/src/api", "/srv/apps/payments"}}
+	model := NewModel("test", ExecutionModeAsync, "/src/web", nil, nil, updates)
+	model.SetWorkDirStore(store)
+
+	keys(&model, "w", "2")
+	if !model.workDirPickerOpen {
+		t.Fatal("expected '2' to open the recent workdirs picker")
+	}
+	if got := model.workDirMatches(); len(got) != 3 {
+		t.Errorf("expected every recent workdir without a filter, got %v", got)
+	}
+	keys(&model, "ap")
+	if got := model.workDirMatches(); len(got) != 2 || got[0] != "/src/api" {
+		t.Errorf("expected the closest match first, got %v", got)
+	}
+	keys(&model, "down", "enter")
+
+	if model.workDirPickerOpen || model.workDir != "/srv/apps/payments" {
+		t.Errorf("expected the second match to be picked, got %q", model.workDir)
+	}
+	if got := <-updates; got != "/srv/apps/payments" {
+		t.Errorf("expected the workdir update to be sent, got %q", got)
+	}
+	if len(store.remembered) != 1 || store.remembered[0] != "/srv/apps/payments" {
+		t.Errorf("expected the workdir to be remembered, got %v", store.remembered)
+	}
+}
+
+func TestModel_WorkDirSaveDefault(t *testing.T) {
+	store := &fakeWorkDirStore{}
+	model := NewModel("test", ExecutionModeAsync, "/src/api", nil, nil, nil)
+	model.SetWorkDirStore(store)
+
+	keys(&model, "w", "3")
+	if store.saved != "/src/api" || model.workDirNoticeErr || !strings.Contains(model.workDirNotice, "config.json") {
+		t.Errorf("expected the workdir saved to the config, got %q, notice %q", store.saved, model.workDirNotice)
+	}
+
+	if strings.Contains(model.workDirNotice, "MOMENTUM_WORKDIR") {
+		t.Errorf("expected no mention of MOMENTUM_WORKDIR while it is unset, got %q", model.workDirNotice)
+	}
+
+	// The saved default is not used while the environment variable is set
+	store.envSet = true
+	keys(&model, "3")
+	if !strings.Contains(model.workDirNotice, "MOMENTUM_WORKDIR overrides it") {
+		t.Errorf("expected the notice to say MOMENTUM_WORKDIR overrides the default, got %q", model.workDirNotice)
+	}
+
+	store.saveErr = errors.New("permission denied")
+	keys(&model, "3")
+	if !model.workDirNoticeErr || model.workDirNotice != "permission denied" {
+		t.Errorf("expected the error to be shown, got %q", model.workDirNotice)
+	}
+}
+
+func TestModel_WorkDirShellExport(t *testing.T) {
+	store := &fakeWorkDirStore{}
+	model := NewModel("test", ExecutionModeAsync, "/src/api", nil, nil, nil)
+	model.SetWorkDirStore(store)
+
+	keys(&model, "w", "4")
+	if model.workDirShellFile != "" || !model.workDirNoticeErr {
+		t.Error("expected an error without a detected shell")
+	}
+
+	store.shellFile = "/home/me/.zshrc"
+	keys(&model, "4", "n")
+	if len(store.shellLines) != 0 || !model.workDirMenuOpen {
+		t.Errorf("expected 'n' to go back to the menu without changes, got %v", store.shellLines)
+	}
+	keys(&model, "4", "y")
+	if len(store.shellLines) != 1 || store.shellLines[0] != "export MOMENTUM_WORKDIR='/src/api'" {
+		t.Errorf("expected the export line appended, got %v", store.shellLines)
+	}
+}
+
+func TestModel_WorkDirMenuWithoutStore(t *testing.T) {
+	model := NewModel("test", ExecutionModeAsync, ".", nil, nil, nil)
+	keys(&model, "w", "2")
+	if model.workDirPickerOpen || !model.workDirMenuOpen {
+		t.Error("expected the picker to need a store")
+	}
+}
@@ -0,0 +1,326 @@
+// Package vcs records each task's changes in git, so a Flux task links to
+// the code that completed it.
+//
+// Before an agent starts, Start notes the commit the working directory is
+// at and checks out a branch named after the task. When the task succeeds,
+// Finish commits the agent's changes on that branch with a message naming
+// the task, pushes the branch to the configured remote and switches back to
+// the branch the run started from, ready for the next task. Park does the
+// same for runs that did not succeed, without pushing.
+package vcs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/internal/git"
+)
+
+// DefaultBranchPrefix starts every task branch name.
+const DefaultBranchPrefix = "momentum/"
+
+// maxSlugLength bounds the part of a branch name taken from the task title.
+const maxSlugLength = 40
+
+// Fallback identity for commits in repositories without one configured.
+const (
+	defaultAuthorName  = "Momentum"
+	defaultAuthorEmail = "momentum@localhost"
+)
+
+var (
+	// ErrNotRepository is returned by Start when the working directory is
+	// not in a git repository.
+	ErrNotRepository = errors.New("not a git repository")
+	// ErrDirty is returned by Start when the working directory has
+	// uncommitted changes, which would end up in the task's commit.
+	ErrDirty = errors.New("working directory has uncommitted changes")
+	// ErrBusy is returned by Start when another task's run already owns the
+	// working directory.
+	ErrBusy = errors.New("working directory is in use by another task")
+)
+
+// Config enables the git integration.
+type Config struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// Remote is the remote name or URL task branches are pushed to; empty
+	// keeps them local
+	Remote string `json:"remote,omitempty"`
+	// BranchPrefix starts each task branch name (default "momentum/")
+	BranchPrefix string `json:"branch-prefix,omitempty"`
+}
+
+// Manager starts and finishes the git side of task runs. A nil *Manager
+// does nothing.
+type Manager struct {
+	cfg Config
+
+	mu sync.Mutex
+	// active holds the working directories with a run in progress
+	active map[string]bool
+}
+
+// New validates cfg and returns its manager, or nil if it is disabled.
+func New(cfg Config) (*Manager, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.BranchPrefix == "" {
+		cfg.BranchPrefix = DefaultBranchPrefix
+	}
+	if strings.ContainsAny(cfg.BranchPrefix, " ~^:?*[\\") {
+		return nil, fmt.Errorf("invalid branch prefix %q", cfg.BranchPrefix)
+	}
+	return &Manager{cfg: cfg, active: make(map[string]bool)}, nil
+}
+
+// Enabled reports whether task runs are recorded in git.
+func (m *Manager) Enabled() bool {
+	return m != nil
+}
+
+// Run is the git state of one task's run.
+type Run struct {
+	m       *Manager
+	workDir string
+	task    *client.Task
+	// Base is the commit the working directory was at when the run started
+	Base string
+	// Branch is the task's branch
+	Branch string
+	// previous is the branch to return to, or "" if HEAD was detached
+	previous string
+}
+
+// Result describes the commit a run ended with.
+type Result struct {
+	Branch string
+	// Base is the commit the run started from
+	Base string
+	// Commit is the branch's head after the run
+	Commit string
+	// Committed is set when changes were committed; it is false when the
+	// agent left nothing uncommitted
+	Committed bool
+	// Remote is where the branch was pushed, or "" if it was not
+	Remote string
+}
+
+// Changed reports whether the branch moved during the run.
+func (r *Result) Changed() bool {
+	return r.Commit != r.Base
+}
+
+// Start records the working directory's commit and checks out the task's
+// branch, creating it if needed. It fails with ErrNotRepository or ErrDirty
+// when the run cannot be recorded, and the caller can still run the task
+// without it. ErrBusy means the task must wait: run in the same checkout,
+// its changes would end up in the other run's commit.
+func (m *Manager) Start(ctx context.Context, workDir string, task *client.Task) (*Run, error) {
+	if m == nil {
+		return nil, nil
+	}
+	if _, err := git.Run(ctx, workDir, "rev-parse", "--is-inside-work-tree"); err != nil {
+		return nil, fmt.Errorf("%s: %w", workDir, ErrNotRepository)
+	}
+
+	m.mu.Lock()
+	if m.active[workDir] {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("%s: %w", workDir, ErrBusy)
+	}
+	m.active[workDir] = true
+	m.mu.Unlock()
+
+	run, err := m.start(ctx, workDir, task)
+	if err != nil {
+		m.release(workDir)
+		return nil, err
+	}
+	return run, nil
+}
+
+func (m *Manager) start(ctx context.Context, workDir string, task *client.Task) (*Run, error) {
+	status, err := git.Run(ctx, workDir, "status", "--porcelain", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(status) != "" {
+		return nil, fmt.Errorf("%s: %w", workDir, ErrDirty)
+	}
+	base, err := git.Run(ctx, workDir, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("%s has no commits to branch from: %w", workDir, err)
+	}
+	// A detached HEAD has no branch to return to; the base commit is used
+	previous, _ := git.Run(ctx, workDir, "symbolic-ref", "--quiet", "--short", "HEAD")
+
+	run := &Run{
+		m:        m,
+		workDir:  workDir,
+		task:     task,
+		Base:     strings.TrimSpace(base),
+		Branch:   m.BranchName(task),
+		previous: strings.TrimSpace(previous),
+	}
+	if run.Branch == run.previous {
+		return run, nil
+	}
+	// A task that runs again continues on its existing branch
+	if _, err := git.Run(ctx, workDir, "rev-parse", "--verify", "--quiet", "refs/heads/"+run.Branch); err == nil {
+		if _, err := git.Run(ctx, workDir, "checkout", "--quiet", run.Branch); err != nil {
+			return nil, err
+		}
+		head, err := git.Run(ctx, workDir, "rev-parse", "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		run.Base = strings.TrimSpace(head)
+		return run, nil
+	}
+	if _, err := git.Run(ctx, workDir, "checkout", "--quiet", "-b", run.Branch); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// Busy reports whether a run is in progress in workDir, so that Start would
+// fail with ErrBusy.
+func (m *Manager) Busy(workDir string) bool {
+	if m == nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.active[workDir]
+}
+
+func (m *Manager) release(workDir string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.active, workDir)
+}
+
+// BranchName returns the branch for task: the prefix, the task ID and a
+// slug of its title, e.g. "momentum/task-1-fix-the-login-form".
+func (m *Manager) BranchName(task *client.Task) string {
+	prefix := DefaultBranchPrefix
+	if m != nil {
+		prefix = m.cfg.BranchPrefix
+	}
+	name := prefix + slugify(task.ID)
+	if slug := slugify(task.Title); slug != "" {
+		name += "-" + slug
+	}
+	return name
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slugify lowercases s and joins its words with dashes, keeping it short
+// enough for a branch name.
+func slugify(s string) string {
+	slug := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	if len(slug) > maxSlugLength {
+		slug = strings.TrimRight(slug[:maxSlugLength], "-")
+	}
+	return slug
+}
+
+// Finish commits the agent's changes on the task's branch, pushes the
+// branch and switches back to the branch the run started from. The working
+// directory is released even when Finish fails.
+func (r *Run) Finish(ctx context.Context) (*Result, error) {
+	if r == nil {
+		return nil, nil
+	}
+	defer r.m.release(r.workDir)
+
+	result, err := r.commit(ctx, r.task.Title)
+	if err != nil {
+		return nil, err
+	}
+	// Push before switching back, so a failed push leaves the work where it
+	// was made
+	if r.m.cfg.Remote != "" && result.Changed() {
+		ref := "refs/heads/" + r.Branch
+		if _, err := git.Run(ctx, r.workDir, "push", "--quiet", r.m.cfg.Remote, ref+":"+ref); err != nil {
+			return result, err
+		}
+		result.Remote = r.m.cfg.Remote
+	}
+	return result, r.switchBack(ctx)
+}
+
+// Park commits whatever the agent left as work in progress on the task's
+// branch, without pushing it, and switches back to the branch the run
+// started from. A failed or stopped run leaves the working directory clean
+// for the next task, and the task's next run continues from its work.
+func (r *Run) Park(ctx context.Context) (*Result, error) {
+	if r == nil {
+		return nil, nil
+	}
+	defer r.m.release(r.workDir)
+
+	result, err := r.commit(ctx, "WIP: "+r.task.Title)
+	if err != nil {
+		return nil, err
+	}
+	return result, r.switchBack(ctx)
+}
+
+// commit commits every change in the working directory with subject,
+// referencing the task in the body.
+func (r *Run) commit(ctx context.Context, subject string) (*Result, error) {
+	if _, err := git.Run(ctx, r.workDir, "add", "--all"); err != nil {
+		return nil, err
+	}
+	result := &Result{Branch: r.Branch, Base: r.Base}
+	// diff --quiet exits 1 when something is staged
+	if _, err := git.Run(ctx, r.workDir, "diff", "--cached", "--quiet"); err != nil {
+		args := append(r.m.identity(ctx, r.workDir), "commit", "--quiet", "--no-verify",
+			"-m", subject, "-m", "Flux task: "+r.task.ID)
+		if _, err := git.Run(ctx, r.workDir, args...); err != nil {
+			return nil, err
+		}
+		result.Committed = true
+	}
+	head, err := git.Run(ctx, r.workDir, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	result.Commit = strings.TrimSpace(head)
+	return result, nil
+}
+
+// switchBack checks out the branch the run started from, or its base commit
+// if HEAD was detached.
+func (r *Run) switchBack(ctx context.Context) error {
+	back := r.previous
+	if back == "" {
+		back = r.Base
+	}
+	if back == r.Branch {
+		return nil
+	}
+	_, err := git.Run(ctx, r.workDir, "checkout", "--quiet", back)
+	return err
+}
+
+// identity returns git options setting a fallback author when the
+// repository has none configured, so commits do not fail on fresh machines.
+func (m *Manager) identity(ctx context.Context, workDir string) []string {
+	var args []string
+	if name, _ := git.Run(ctx, workDir, "config", "user.name"); strings.TrimSpace(name) == "" {
+		args = append(args, "-c", "user.name="+defaultAuthorName)
+	}
+	if email, _ := git.Run(ctx, workDir, "config", "user.email"); strings.TrimSpace(email) == "" {
+		args = append(args, "-c", "user.email="+defaultAuthorEmail)
+	}
+	return args
+}
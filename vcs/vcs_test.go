@@ -0,0 +1,236 @@
+package vcs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/internal/git"
+)
+
+// initRepo returns a repository on main with one commit, and a bare
+// repository to push to.
+func initRepo(t *testing.T) (dir, remote string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	remote = filepath.Join(t.TempDir(), "remote.git")
+	run(t, "", "init", "-q", "--bare", remote)
+
+	dir = t.TempDir()
+	run(t, dir, "init", "-q", "-b", "main")
+	run(t, dir, "config", "user.email", "test@example.com")
+	run(t, dir, "config", "user.name", "Test")
+	os.WriteFile(filepath.Join(dir, "README"), []byte("hello\n"), 0o644)
+	run(t, dir, "add", ".")
+	run(t, dir, "commit", "-q", "-m", "init")
+	return dir, remote
+}
+
+func run(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := git.Run(context.Background(), dir, args...)
+	if err != nil {
+		t.Fatalf("git %v: %v", args, err)
+	}
+	return strings.TrimSpace(out)
+}
+
+func newManager(t *testing.T, cfg Config) *Manager {
+	t.Helper()
+	cfg.Enabled = true
+	m, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+var task = &client.Task{ID: "task-1", Title: "Fix the login form!"}
+
+func TestNew_Disabled(t *testing.T) {
+	m, err := New(Config{})
+	if err != nil || m != nil || m.Enabled() {
+		t.Fatalf("expected no manager, got %v, %v", m, err)
+	}
+	// A nil manager and run do nothing
+	r, err := m.Start(context.Background(), t.TempDir(), task)
+	if r != nil || err != nil {
+		t.Errorf("expected nothing from a nil manager, got %v, %v", r, err)
+	}
+	if res, err := r.Finish(context.Background()); res != nil || err != nil {
+		t.Errorf("expected nothing from a nil run, got %v, %v", res, err)
+	}
+}
+
+func TestNew_InvalidPrefix(t *testing.T) {
+	if _, err := New(Config{Enabled: true, BranchPrefix: "bad prefix/"}); err == nil {
+		t.Error("expected an error for a prefix with a space")
+	}
+}
+
+func TestBranchName(t *testing.T) {
+	m := newManager(t, Config{BranchPrefix: "agent/"})
+	if got := m.BranchName(task); got != "agent/task-1-fix-the-login-form" {
+		t.Errorf("unexpected branch %q", got)
+	}
+	long := &client.Task{ID: "T_9", Title: strings.Repeat("word ", 20)}
+	if got := m.BranchName(long); got != "agent/t-9-word-word-word-word-word-word-word-word" {
+		t.Errorf("unexpected branch %q", got)
+	}
+}
+
+func TestFinish(t *testing.T) {
+	dir, remote := initRepo(t)
+	m := newManager(t, Config{Remote: remote})
+	ctx := context.Background()
+	base := run(t, dir, "rev-parse", "HEAD")
+
+	r, err := m.Start(ctx, dir, task)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if r.Base != base || run(t, dir, "branch", "--show-current") != "momentum/task-1-fix-the-login-form" {
+		t.Fatalf("expected the task branch from %s, got %+v", base, r)
+	}
+	os.WriteFile(filepath.Join(dir, "login.go"), []byte("package login\n"), 0o644)
+
+	res, err := r.Finish(ctx)
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if !res.Committed || !res.Changed() || res.Remote != remote {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if got := run(t, dir, "log", "-1", "--format=%s%n%b", res.Commit); !strings.Contains(got, "Fix the login form!") || !strings.Contains(got, "Flux task: task-1") {
+		t.Errorf("unexpected commit message %q", got)
+	}
+	if got := run(t, remote, "rev-parse", "refs/heads/"+res.Branch); got != res.Commit {
+		t.Errorf("expected the remote branch at %s, got %s", res.Commit, got)
+	}
+	// Back on main, clean, for the next task
+	if run(t, dir, "branch", "--show-current") != "main" || run(t, dir, "status", "--porcelain") != "" {
+		t.Error("expected a clean checkout of main")
+	}
+}
+
+func TestFinish_NoChanges(t *testing.T) {
+	dir, remote := initRepo(t)
+	m := newManager(t, Config{Remote: remote})
+	ctx := context.Background()
+
+	r, err := m.Start(ctx, dir, task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := r.Finish(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Committed || res.Changed() || res.Remote != "" {
+		t.Errorf("expected nothing committed or pushed, got %+v", res)
+	}
+}
+
+func TestPark(t *testing.T) {
+	dir, remote := initRepo(t)
+	m := newManager(t, Config{Remote: remote})
+	ctx := context.Background()
+
+	r, err := m.Start(ctx, dir, task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "half.go"), []byte("package half\n"), 0o644)
+	parked, err := r.Park(ctx)
+	if err != nil {
+		t.Fatalf("Park: %v", err)
+	}
+	if !parked.Committed || parked.Remote != "" {
+		t.Errorf("expected a local commit, got %+v", parked)
+	}
+	if run(t, dir, "branch", "--show-current") != "main" {
+		t.Error("expected to be back on main")
+	}
+	if _, err := git.Run(ctx, remote, "rev-parse", "--verify", "--quiet", "refs/heads/"+parked.Branch); err == nil {
+		t.Error("expected parked work not to be pushed")
+	}
+
+	// The next run continues from the parked work
+	r, err = m.Start(ctx, dir, task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Base != parked.Commit {
+		t.Errorf("expected the run to continue from %s, got %s", parked.Commit, r.Base)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "half.go")); err != nil {
+		t.Errorf("expected the parked file back: %v", err)
+	}
+	if _, err := r.Finish(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStart_Refused(t *testing.T) {
+	dir, _ := initRepo(t)
+	m := newManager(t, Config{})
+	ctx := context.Background()
+
+	if _, err := m.Start(ctx, t.TempDir(), task); !errors.Is(err, ErrNotRepository) {
+		t.Errorf("expected ErrNotRepository, got %v", err)
+	}
+
+	r, err := m.Start(ctx, dir, task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Start(ctx, dir, &client.Task{ID: "task-2"}); !errors.Is(err, ErrBusy) {
+		t.Errorf("expected ErrBusy while another run owns the directory, got %v", err)
+	}
+	if !m.Busy(dir) {
+		t.Error("expected the directory to be busy during the run")
+	}
+	if _, err := r.Finish(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if m.Busy(dir) {
+		t.Error("expected the finished run to release the directory")
+	}
+
+	os.WriteFile(filepath.Join(dir, "stray.txt"), []byte("left over\n"), 0o644)
+	if _, err := m.Start(ctx, dir, task); !errors.Is(err, ErrDirty) {
+		t.Errorf("expected ErrDirty, got %v", err)
+	}
+	// A refused start releases the directory
+	os.Remove(filepath.Join(dir, "stray.txt"))
+	if _, err := m.Start(ctx, dir, task); err != nil {
+		t.Errorf("expected the directory to be free, got %v", err)
+	}
+}
+
+func TestFinish_PushFails(t *testing.T) {
+	dir, _ := initRepo(t)
+	m := newManager(t, Config{Remote: filepath.Join(t.TempDir(), "missing.git")})
+	ctx := context.Background()
+
+	r, err := m.Start(ctx, dir, task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "login.go"), []byte("package login\n"), 0o644)
+	res, err := r.Finish(ctx)
+	if err == nil || res == nil || !res.Committed || res.Remote != "" {
+		t.Fatalf("expected the commit with a push error, got %+v, %v", res, err)
+	}
+	// The work stays checked out where it was made
+	if run(t, dir, "branch", "--show-current") != res.Branch {
+		t.Error("expected to stay on the task branch")
+	}
+}
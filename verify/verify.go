@@ -0,0 +1,406 @@
+// Package verify independently checks an agent's work before its task is
+// marked done. Checks run in order and stop at the first failure:
+//
+//  1. require-diff: the working directory changed while the agent ran
+//  2. commands: configured build/test commands succeed
+//  3. acceptance-check: a second, review-only agent pass confirms the task's
+//     acceptance criteria are met
+package verify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/internal/git"
+	"github.com/sirsjg/momentum/shell"
+	"github.com/sirsjg/momentum/tracing"
+)
+
+// DefaultTimeout bounds each check command and the acceptance pass when no
+// timeout is configured.
+const DefaultTimeout = 30 * time.Minute
+
+// DefaultMaxAttempts is how many times in a row a task may fail
+// verification before it is parked instead of being queued again.
+const DefaultMaxAttempts = 3
+
+// Check names used in Failure.
+const (
+	CheckDiff       = "require-diff"
+	CheckCommand    = "command"
+	CheckAcceptance = "acceptance-check"
+)
+
+// Config selects the verification checks to run.
+type Config struct {
+	// Commands are build/test commands that must all succeed
+	Commands []string `json:"commands,omitempty"`
+	// RequireDiff fails verification if the agent left the working directory unchanged
+	RequireDiff bool `json:"require-diff,omitempty"`
+	// AcceptanceCheck asks a second agent pass to review the acceptance criteria
+	AcceptanceCheck bool `json:"acceptance-check,omitempty"`
+	// FailureStatus is the status failed tasks move to, e.g. "review"
+	// (default: the workflow's reset status)
+	FailureStatus string `json:"failure-status,omitempty"`
+	// MaxAttempts is how many verification failures in a row a task may have
+	// before it is parked (default 3)
+	MaxAttempts int `json:"max-attempts,omitempty"`
+	// Timeout bounds each command and the acceptance pass (default 30m)
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// Failure describes why verification failed.
+type Failure struct {
+	Check  string
+	Reason string
+	// Output is the tail of the failing check's output, if any
+	Output string
+}
+
+func (f *Failure) Error() string {
+	return fmt.Sprintf("verification failed (%s): %s", f.Check, f.Reason)
+}
+
+// Verifier runs the configured checks. A nil *Verifier is disabled.
+type Verifier struct {
+	cfg           Config
+	timeout       time.Duration
+	failureStatus string
+	maxAttempts   int
+	newAgent      agent.AgentFactory
+
+	mu sync.Mutex
+	// failures counts each task's verification failures since it last passed
+	failures map[string]int
+}
+
+// New validates cfg and returns a verifier. newAgent creates the agent used
+// for the acceptance check.
+func New(cfg Config, newAgent agent.AgentFactory) (*Verifier, error) {
+	timeout := DefaultTimeout
+	if cfg.Timeout != "" {
+		d, err := time.ParseDuration(cfg.Timeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid verify timeout %q", cfg.Timeout)
+		}
+		timeout = d
+	}
+	maxAttempts := DefaultMaxAttempts
+	if cfg.MaxAttempts < 0 {
+		return nil, fmt.Errorf("invalid verify max-attempts %d", cfg.MaxAttempts)
+	} else if cfg.MaxAttempts > 0 {
+		maxAttempts = cfg.MaxAttempts
+	}
+	if cfg.AcceptanceCheck && newAgent == nil {
+		return nil, errors.New("acceptance check requires an agent")
+	}
+	return &Verifier{
+		cfg:           cfg,
+		timeout:       timeout,
+		failureStatus: strings.TrimSpace(cfg.FailureStatus),
+		maxAttempts:   maxAttempts,
+		newAgent:      newAgent,
+		failures:      make(map[string]int),
+	}, nil
+}
+
+// Enabled reports whether any check is configured.
+func (v *Verifier) Enabled() bool {
+	return v != nil && (len(v.cfg.Commands) > 0 || v.cfg.RequireDiff || v.cfg.AcceptanceCheck)
+}
+
+// FailureStatus returns the status tasks move to when verification fails,
+// or "" for the workflow's reset status.
+func (v *Verifier) FailureStatus() string {
+	if v == nil {
+		return ""
+	}
+	return v.failureStatus
+}
+
+// RecordFailure counts a verification failure for taskID and returns how
+// many it has had in a row. exhausted is true once that reaches the
+// configured maximum; the count then starts over.
+func (v *Verifier) RecordFailure(taskID string) (attempts int, exhausted bool) {
+	if v == nil {
+		return 0, false
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.failures[taskID]++
+	attempts = v.failures[taskID]
+	if attempts >= v.maxAttempts {
+		delete(v.failures, taskID)
+		return attempts, true
+	}
+	return attempts, false
+}
+
+// RecordSuccess clears taskID's failure count.
+func (v *Verifier) RecordSuccess(taskID string) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.failures, taskID)
+}
+
+// Baseline is a fingerprint of the working directory taken before the agent runs.
+type Baseline struct {
+	isRepo      bool
+	fingerprint string
+}
+
+// TakeBaseline fingerprints the git state of workDir. It is cheap enough to
+// call before every agent run.
+func TakeBaseline(ctx context.Context, workDir string) Baseline {
+	fingerprint, err := gitFingerprint(ctx, workDir)
+	if err != nil {
+		return Baseline{}
+	}
+	return Baseline{isRepo: true, fingerprint: fingerprint}
+}
+
+// Verify runs the configured checks against the agent's work on task in
+// workDir, returning a *Failure for the first check that fails. Progress and
+// check output are passed to output as display lines if it is non-nil.
+func (v *Verifier) Verify(ctx context.Context, task *client.Task, workDir string, base Baseline, output func(line string)) (err error) {
+	if !v.Enabled() {
+		return nil
+	}
+	if output == nil {
+		output = func(string) {}
+	}
+
+	ctx, span := tracing.Start(ctx, "verify", tracing.String("task.id", task.ID))
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
+	if v.cfg.RequireDiff {
+		output("[verify] checking for changes")
+		if err := v.checkDiff(ctx, workDir, base); err != nil {
+			return err
+		}
+	}
+
+	for _, command := range v.cfg.Commands {
+		output("[verify] $ " + command)
+		if err := v.runCommand(ctx, task, workDir, command, output); err != nil {
+			return err
+		}
+	}
+
+	if v.cfg.AcceptanceCheck && len(task.AcceptanceCriteria) > 0 {
+		output("[verify] reviewing acceptance criteria")
+		if err := v.checkAcceptance(ctx, task, workDir, output); err != nil {
+			return err
+		}
+	}
+
+	output("[verify] all checks passed")
+	return nil
+}
+
+func (v *Verifier) checkDiff(ctx context.Context, workDir string, base Baseline) error {
+	if !base.isRepo {
+		return &Failure{Check: CheckDiff, Reason: "working directory is not a git repository"}
+	}
+	after, err := gitFingerprint(ctx, workDir)
+	if err != nil {
+		return &Failure{Check: CheckDiff, Reason: fmt.Sprintf("failed to read git state: %v", err)}
+	}
+	if after == base.fingerprint {
+		return &Failure{Check: CheckDiff, Reason: "the agent made no changes to the working directory"}
+	}
+	return nil
+}
+
+func (v *Verifier) runCommand(ctx context.Context, task *client.Task, workDir, command string, output func(string)) error {
+	err := shell.Run(ctx, shell.Command{
+		Command: command,
+		Dir:     workDir,
+		Env: []string{
+			"MOMENTUM_TASK_ID=" + task.ID,
+			"MOMENTUM_TASK_TITLE=" + task.Title,
+			"MOMENTUM_WORKDIR=" + workDir,
+		},
+		Timeout: v.timeout,
+		Output: func(line string) {
+			output("[verify] " + line)
+		},
+	})
+	if err == nil {
+		return nil
+	}
+
+	failure := &Failure{Check: CheckCommand, Reason: err.Error()}
+	var shellErr *shell.Error
+	if errors.As(err, &shellErr) {
+		failure.Output = shellErr.Output
+	}
+	return failure
+}
+
+// --- Acceptance check ---
+
+// verdictPrefix starts the final line of the reviewer's answer.
+const verdictPrefix = "VERDICT:"
+
+func (v *Verifier) checkAcceptance(ctx context.Context, task *client.Task, workDir string, output func(string)) error {
+	ctx, cancel := context.WithTimeout(ctx, v.timeout)
+	defer cancel()
+
+	runner := agent.NewRunner(v.newAgent(agent.Config{WorkDir: workDir}))
+	if err := runner.Run(ctx, buildAcceptancePrompt(task)); err != nil {
+		return &Failure{Check: CheckAcceptance, Reason: fmt.Sprintf("failed to start reviewer: %v", err)}
+	}
+
+	verdict := ""
+	for line := range runner.Output() {
+		output(line.Text)
+		if !line.IsStderr {
+			if found := findVerdict(line.Text); found != "" {
+				verdict = found
+			}
+		}
+	}
+	result := <-runner.Done()
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return &Failure{Check: CheckAcceptance, Reason: fmt.Sprintf("reviewer timed out after %s", v.timeout)}
+	}
+	if result.ExitCode != 0 && verdict == "" {
+		return &Failure{Check: CheckAcceptance, Reason: fmt.Sprintf("reviewer exited with code %d", result.ExitCode)}
+	}
+
+	switch {
+	case verdict == "PASS":
+		return nil
+	case strings.HasPrefix(verdict, "FAIL"):
+		reason := strings.TrimSpace(strings.TrimLeft(strings.TrimPrefix(verdict, "FAIL"), ":-– "))
+		if reason == "" {
+			reason = "acceptance criteria not met"
+		}
+		return &Failure{Check: CheckAcceptance, Reason: reason}
+	default:
+		return &Failure{Check: CheckAcceptance, Reason: "reviewer did not give a verdict"}
+	}
+}
+
+// buildAcceptancePrompt asks the reviewer to check the acceptance criteria
+// without changing anything.
+func buildAcceptancePrompt(task *client.Task) string {
+	var b strings.Builder
+
+	b.WriteString(`Goal: independently review whether another agent's work on a Flux task meets its acceptance criteria.
+
+Rules:
+- Do not modify, create or delete any files.
+- Do not change the task in Flux or add comments.
+- Inspect the current changes (for example with git diff and git log) and the relevant code. You may run read-only checks such as tests.
+
+Finish your answer with exactly one final line, either:
+VERDICT: PASS
+or
+VERDICT: FAIL: <which criteria are not met and why>
+
+Task context:
+`)
+	b.WriteString(fmt.Sprintf("- Task ID: %s\n", task.ID))
+	b.WriteString(fmt.Sprintf("- Task: %s\n", task.Title))
+	if task.Notes != "" {
+		b.WriteString(fmt.Sprintf("- Details:\n%s\n", task.Notes))
+	}
+
+	b.WriteString("\nAcceptance Criteria:\n")
+	for _, ac := range task.AcceptanceCriteria {
+		b.WriteString(fmt.Sprintf("- %s\n", ac))
+	}
+	return b.String()
+}
+
+// findVerdict returns the text after the last "VERDICT:" marker in a line of
+// agent output, which may be plain text or Claude stream-json.
+func findVerdict(line string) string {
+	text := line
+	var msg struct {
+		Type   string `json:"type"`
+		Result string `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(line), &msg); err == nil {
+		if msg.Type != "result" {
+			// Intermediate stream-json messages may quote the instructions
+			return ""
+		}
+		text = msg.Result
+	}
+
+	verdict := ""
+	for _, l := range strings.Split(text, "\n") {
+		l = strings.Trim(strings.TrimSpace(l), "*`")
+		if strings.HasPrefix(strings.ToUpper(l), verdictPrefix) {
+			verdict = strings.TrimSpace(l[len(verdictPrefix):])
+		}
+	}
+	if upper := strings.ToUpper(verdict); strings.HasPrefix(upper, "PASS") {
+		return "PASS"
+	} else if strings.HasPrefix(upper, "FAIL") {
+		return "FAIL" + verdict[len("FAIL"):]
+	}
+	return ""
+}
+
+// --- Git fingerprint ---
+
+// gitFingerprint hashes HEAD, the status, the diff against HEAD and the
+// contents of untracked files, so any change made by the agent - committed
+// or not - alters the result.
+func gitFingerprint(ctx context.Context, workDir string) (string, error) {
+	if _, err := git.Run(ctx, workDir, "rev-parse", "--is-inside-work-tree"); err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	// A repository without commits has no HEAD; status still captures changes
+	head, _ := git.Run(ctx, workDir, "rev-parse", "HEAD")
+	h.Write([]byte(head))
+
+	status, err := git.Run(ctx, workDir, "status", "--porcelain=v1", "--untracked-files=all")
+	if err != nil {
+		return "", err
+	}
+	h.Write([]byte(status))
+
+	diff, _ := git.Run(ctx, workDir, "diff", "HEAD", "--binary")
+	h.Write([]byte(diff))
+
+	// Untracked files only appear by name above, so hash their contents too
+	untracked, err := git.Run(ctx, workDir, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return "", err
+	}
+	for _, name := range strings.Split(untracked, "\x00") {
+		if name == "" {
+			continue
+		}
+		data, _ := os.ReadFile(filepath.Join(workDir, name))
+		h.Write([]byte(name))
+		h.Write(data)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
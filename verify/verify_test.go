@@ -0,0 +1,297 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sirsjg/momentum/agent"
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/internal/git"
+)
+
+// fakeAgent replays fixed stdout and exits with the given code.
+type fakeAgent struct {
+	stdout   string
+	exitCode int
+	prompt   string
+	config   agent.Config
+}
+
+func (f *fakeAgent) Name() string { return "fake" }
+func (f *fakeAgent) Start(ctx context.Context, prompt string) error {
+	f.prompt = prompt
+	return nil
+}
+func (f *fakeAgent) Stdout() io.Reader  { return strings.NewReader(f.stdout) }
+func (f *fakeAgent) Stderr() io.Reader  { return strings.NewReader("") }
+func (f *fakeAgent) Wait() (int, error) { return f.exitCode, nil }
+func (f *fakeAgent) Cancel() error      { return nil }
+func (f *fakeAgent) IsRunning() bool    { return false }
+func (f *fakeAgent) factory() agent.AgentFactory {
+	return func(cfg agent.Config) agent.Agent {
+		f.config = cfg
+		return f
+	}
+}
+
+func skipOnWindows(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("verify tests use POSIX shell commands")
+	}
+}
+
+func initRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test"},
+	} {
+		if _, err := git.Run(context.Background(), dir, args...); err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, "README"), []byte("hello\n"), 0o644)
+	git.Run(context.Background(), dir, "add", ".")
+	if _, err := git.Run(context.Background(), dir, "commit", "-q", "-m", "init"); err != nil {
+		t.Fatalf("git commit: %v", err)
+	}
+	return dir
+}
+
+func expectFailure(t *testing.T, err error, check string) *Failure {
+	t.Helper()
+	var failure *Failure
+	if !errors.As(err, &failure) {
+		t.Fatalf("expected *Failure, got %v", err)
+	}
+	if failure.Check != check {
+		t.Errorf("expected %s failure, got %s: %s", check, failure.Check, failure.Reason)
+	}
+	return failure
+}
+
+var testTask = &client.Task{ID: "task-1", Title: "Add feature"}
+
+func TestNew(t *testing.T) {
+	v, err := New(Config{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.Enabled() {
+		t.Error("expected empty config to be disabled")
+	}
+	if v.FailureStatus() != "" {
+		t.Errorf("expected no failure status by default, got %q", v.FailureStatus())
+	}
+
+	v, _ = New(Config{RequireDiff: true, FailureStatus: "review"}, nil)
+	if !v.Enabled() || v.FailureStatus() != "review" {
+		t.Error("expected enabled verifier with review failure status")
+	}
+
+	if _, err := New(Config{Timeout: "later"}, nil); err == nil {
+		t.Error("expected error for invalid timeout")
+	}
+	if _, err := New(Config{MaxAttempts: -1}, nil); err == nil {
+		t.Error("expected error for negative max attempts")
+	}
+	if _, err := New(Config{AcceptanceCheck: true}, nil); err == nil {
+		t.Error("expected error when acceptance check has no agent")
+	}
+
+	var nilVerifier *Verifier
+	if nilVerifier.Enabled() {
+		t.Error("nil verifier should be disabled")
+	}
+}
+
+func TestVerifier_RecordFailure(t *testing.T) {
+	v, _ := New(Config{RequireDiff: true, MaxAttempts: 2}, nil)
+
+	if attempts, exhausted := v.RecordFailure("task-1"); attempts != 1 || exhausted {
+		t.Errorf("expected first failure not exhausted, got %d %v", attempts, exhausted)
+	}
+	v.RecordSuccess("task-1")
+	if attempts, _ := v.RecordFailure("task-1"); attempts != 1 {
+		t.Errorf("expected success to reset the count, got %d", attempts)
+	}
+	if attempts, exhausted := v.RecordFailure("task-1"); attempts != 2 || !exhausted {
+		t.Errorf("expected second failure exhausted, got %d %v", attempts, exhausted)
+	}
+	if attempts, _ := v.RecordFailure("task-1"); attempts != 1 {
+		t.Errorf("expected the count to start over once exhausted, got %d", attempts)
+	}
+	if attempts, _ := v.RecordFailure("task-2"); attempts != 1 {
+		t.Errorf("expected tasks to be counted separately, got %d", attempts)
+	}
+
+	var nilVerifier *Verifier
+	if _, exhausted := nilVerifier.RecordFailure("task-1"); exhausted {
+		t.Error("nil verifier should never be exhausted")
+	}
+}
+
+func TestVerify_RequireDiff(t *testing.T) {
+	dir := initRepo(t)
+	v, _ := New(Config{RequireDiff: true}, nil)
+	ctx := context.Background()
+
+	base := TakeBaseline(ctx, dir)
+	expectFailure(t, v.Verify(ctx, testTask, dir, base, nil), CheckDiff)
+
+	os.WriteFile(filepath.Join(dir, "README"), []byte("changed\n"), 0o644)
+	if err := v.Verify(ctx, testTask, dir, base, nil); err != nil {
+		t.Errorf("expected modified file to pass, got %v", err)
+	}
+}
+
+func TestVerify_RequireDiff_CommittedAndUntracked(t *testing.T) {
+	dir := initRepo(t)
+	v, _ := New(Config{RequireDiff: true}, nil)
+	ctx := context.Background()
+
+	// An untracked file that already existed before the run
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("v1"), 0o644)
+	base := TakeBaseline(ctx, dir)
+
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("v2"), 0o644)
+	if err := v.Verify(ctx, testTask, dir, base, nil); err != nil {
+		t.Errorf("expected edited untracked file to count as a change, got %v", err)
+	}
+
+	// The agent commits its own work, leaving a clean tree
+	os.Remove(filepath.Join(dir, "notes.txt"))
+	base = TakeBaseline(ctx, dir)
+	os.WriteFile(filepath.Join(dir, "feature.go"), []byte("package x\n"), 0o644)
+	git.Run(ctx, dir, "add", ".")
+	git.Run(ctx, dir, "commit", "-q", "-m", "feature")
+	if err := v.Verify(ctx, testTask, dir, base, nil); err != nil {
+		t.Errorf("expected a new commit to count as a change, got %v", err)
+	}
+}
+
+func TestVerify_RequireDiff_NotARepo(t *testing.T) {
+	dir := t.TempDir()
+	v, _ := New(Config{RequireDiff: true}, nil)
+
+	err := v.Verify(context.Background(), testTask, dir, TakeBaseline(context.Background(), dir), nil)
+	failure := expectFailure(t, err, CheckDiff)
+	if !strings.Contains(failure.Reason, "not a git repository") {
+		t.Errorf("unexpected reason: %s", failure.Reason)
+	}
+}
+
+func TestVerify_Commands(t *testing.T) {
+	skipOnWindows(t)
+	dir := t.TempDir()
+	v, _ := New(Config{Commands: []string{"echo building", `echo "tests failed for $MOMENTUM_TASK_ID"; exit 1`, "touch not-reached"}}, nil)
+
+	var lines []string
+	err := v.Verify(context.Background(), testTask, dir, Baseline{}, func(line string) {
+		lines = append(lines, line)
+	})
+	failure := expectFailure(t, err, CheckCommand)
+	if failure.Output != "tests failed for task-1" {
+		t.Errorf("unexpected output: %q", failure.Output)
+	}
+	if !strings.Contains(failure.Reason, "exit code 1") {
+		t.Errorf("unexpected reason: %s", failure.Reason)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "not-reached")); err == nil {
+		t.Error("expected later commands to be skipped")
+	}
+	if !strings.Contains(strings.Join(lines, "\n"), "[verify] building") {
+		t.Errorf("expected command output to be streamed, got %q", lines)
+	}
+}
+
+func TestVerify_AcceptancePass(t *testing.T) {
+	reviewer := &fakeAgent{stdout: `{"type":"assistant","message":{"content":[{"type":"text","text":"VERDICT: FAIL: quoting the instructions"}]}}
+{"type":"result","result":"All criteria are met.\nVERDICT: PASS"}
+`}
+	v, _ := New(Config{AcceptanceCheck: true}, reviewer.factory())
+	task := &client.Task{ID: "task-1", Title: "Add flag", AcceptanceCriteria: []string{"--verbose flag exists"}}
+
+	if err := v.Verify(context.Background(), task, "/work", Baseline{}, nil); err != nil {
+		t.Fatalf("expected pass, got %v", err)
+	}
+	if reviewer.config.WorkDir != "/work" {
+		t.Errorf("expected reviewer to run in the workdir, got %q", reviewer.config.WorkDir)
+	}
+	if !strings.Contains(reviewer.prompt, "--verbose flag exists") || !strings.Contains(reviewer.prompt, "Do not modify") {
+		t.Errorf("unexpected reviewer prompt:\n%s", reviewer.prompt)
+	}
+}
+
+func TestVerify_AcceptanceFail(t *testing.T) {
+	reviewer := &fakeAgent{stdout: `{"type":"result","result":"VERDICT: FAIL: flag is not documented"}` + "\n"}
+	v, _ := New(Config{AcceptanceCheck: true}, reviewer.factory())
+	task := &client.Task{ID: "task-1", AcceptanceCriteria: []string{"flag is documented"}}
+
+	failure := expectFailure(t, v.Verify(context.Background(), task, "", Baseline{}, nil), CheckAcceptance)
+	if failure.Reason != "flag is not documented" {
+		t.Errorf("unexpected reason: %q", failure.Reason)
+	}
+}
+
+func TestVerify_AcceptanceNoVerdict(t *testing.T) {
+	reviewer := &fakeAgent{stdout: "I looked around.\n"}
+	v, _ := New(Config{AcceptanceCheck: true}, reviewer.factory())
+	task := &client.Task{ID: "task-1", AcceptanceCriteria: []string{"works"}}
+
+	expectFailure(t, v.Verify(context.Background(), task, "", Baseline{}, nil), CheckAcceptance)
+
+	reviewer.exitCode = 2
+	failure := expectFailure(t, v.Verify(context.Background(), task, "", Baseline{}, nil), CheckAcceptance)
+	if !strings.Contains(failure.Reason, "code 2") {
+		t.Errorf("expected exit code in reason, got %q", failure.Reason)
+	}
+}
+
+func TestVerify_AcceptanceSkippedWithoutCriteria(t *testing.T) {
+	called := false
+	v, _ := New(Config{AcceptanceCheck: true}, func(agent.Config) agent.Agent {
+		called = true
+		return &fakeAgent{}
+	})
+
+	if err := v.Verify(context.Background(), testTask, "", Baseline{}, nil); err != nil {
+		t.Errorf("expected pass without acceptance criteria, got %v", err)
+	}
+	if called {
+		t.Error("expected no reviewer pass when the task has no acceptance criteria")
+	}
+}
+
+func TestFindVerdict(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"VERDICT: PASS", "PASS"},
+		{"**VERDICT: PASS**", "PASS"},
+		{"verdict: fail: missing tests", "FAIL: missing tests"},
+		{`{"type":"result","result":"Done.\nVERDICT: FAIL - broken build"}`, "FAIL - broken build"},
+		{`{"type":"assistant","message":{}}`, ""},
+		{"no verdict here", ""},
+		{"VERDICT: maybe", ""},
+	}
+	for _, tt := range tests {
+		if got := findVerdict(tt.line); got != tt.want {
+			t.Errorf("findVerdict(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
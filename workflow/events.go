@@ -0,0 +1,100 @@
+package workflow
+
+import (
+	"errors"
+	"time"
+)
+
+// Event records one status change made through a Workflow, or reported to
+// it with Record.
+type Event struct {
+	Time   time.Time `json:"time"`
+	TaskID string    `json:"task_id"`
+	Title  string    `json:"title,omitempty"`
+	// From is the status before the change, or "" if it was not looked up
+	From string `json:"from,omitempty"`
+	To   string `json:"to"`
+	// Reason says why the task moved, e.g. "agent exited with code 1"
+	Reason string `json:"reason,omitempty"`
+	// Actor is who moved the task, e.g. the Momentum instance
+	Actor string `json:"actor,omitempty"`
+	// Error is set when the change failed and the task did not move
+	Error string `json:"error,omitempty"`
+}
+
+// Observer is told about every transition a Workflow makes. Observe is
+// called synchronously after the transition, so it should not block.
+type Observer interface {
+	Observe(Event)
+}
+
+// ObserverFunc adapts a function to an Observer.
+type ObserverFunc func(Event)
+
+// Observe calls f(e).
+func (f ObserverFunc) Observe(e Event) {
+	f(e)
+}
+
+// AddObserver registers an observer for every transition. Observers must be
+// added before the workflow is shared between goroutines, and are shared by
+// its copies.
+func (w *Workflow) AddObserver(o Observer) {
+	w.observers = append(w.observers, o)
+}
+
+// SetActor names who the workflow's transitions are attributed to.
+func (w *Workflow) SetActor(actor string) {
+	w.actor = actor
+}
+
+// WithReason returns a shallow copy of the workflow whose transitions are
+// recorded with reason.
+func (w *Workflow) WithReason(reason string) *Workflow {
+	wc := *w
+	wc.reason = reason
+	return &wc
+}
+
+// Record tells the observers about a transition made outside the workflow,
+// e.g. a claim made under a lease. Missing times, reasons and actors are
+// filled in from the workflow.
+func (w *Workflow) Record(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Reason == "" {
+		e.Reason = w.reason
+	}
+	if e.Actor == "" {
+		e.Actor = w.actor
+	}
+	for _, o := range w.observers {
+		o.Observe(e)
+	}
+}
+
+// observe reports the outcome of a call to the observers. Rolled back moves
+// are reported as the move and the move back; transitions that were never
+// attempted are left out.
+func (w *Workflow) observe(results []TransitionResult) {
+	if len(w.observers) == 0 {
+		return
+	}
+	for _, r := range results {
+		if errors.Is(r.Err, ErrAborted) {
+			continue
+		}
+		e := Event{TaskID: r.TaskID, From: r.From, To: r.To}
+		if r.Task != nil {
+			e.Title = r.Task.Title
+		}
+		if r.Err != nil && !r.RolledBack {
+			e.Error = errors.Unwrap(r.Err).Error()
+		}
+		w.Record(e)
+		if r.RolledBack {
+			w.Record(Event{TaskID: r.TaskID, Title: e.Title, From: r.To, To: r.From, Reason: "rolled back: another task in the same change failed"})
+		}
+	}
+}
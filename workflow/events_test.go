@@ -0,0 +1,109 @@
+package workflow
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sirsjg/momentum/client"
+)
+
+// eventLog collects the events a workflow reports.
+type eventLog []Event
+
+func (l *eventLog) Observe(e Event) {
+	*l = append(*l, e)
+}
+
+func TestObserver_Transitions(t *testing.T) {
+	flux, wf, ids := newFluxWorkflow(t)
+	var events eventLog
+	wf.AddObserver(&events)
+	wf.SetActor("momentum host:1")
+
+	task, _ := flux.Task(ids[0])
+	wf.WithFrom(task).WithReason("picked up").StartWorking([]string{ids[0], "task-missing"})
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", events)
+	}
+	moved := events[0]
+	if moved.TaskID != ids[0] || moved.Title != "one" || moved.From != StatusTodo || moved.To != StatusInProgress ||
+		moved.Reason != "picked up" || moved.Actor != "momentum host:1" || moved.Time.IsZero() || moved.Error != "" {
+		t.Errorf("unexpected event %+v", moved)
+	}
+	if failed := events[1]; failed.TaskID != "task-missing" || failed.Error == "" {
+		t.Errorf("expected a failed event for the missing task, got %+v", failed)
+	}
+}
+
+func TestObserver_FromWithoutLookup(t *testing.T) {
+	flux, wf, ids := newFluxWorkflow(t)
+	var events eventLog
+	wf.AddObserver(&events)
+
+	// The caller's view is trusted rather than checked against Flux
+	task, _ := flux.Task(ids[0])
+	task.Status = StatusPlanning
+	wf.WithFrom(task).MarkComplete(ids)
+
+	if len(events) != 2 || events[0].From != StatusPlanning || events[1].From != "" {
+		t.Errorf("expected only the given task's status as From, got %+v", events)
+	}
+
+	// A task given without a status is looked up in its project
+	events = nil
+	wf.WithFrom(client.Task{ID: ids[1], ProjectID: task.ProjectID}).ResetTask(ids[1:])
+	if len(events) != 1 || events[0].From != StatusDone {
+		t.Errorf("expected the looked up status as From, got %+v", events)
+	}
+}
+
+func TestObserver_RolledBack(t *testing.T) {
+	_, wf, ids := newFluxWorkflow(t)
+	var events eventLog
+	wf.AddObserver(&events)
+
+	wf.AllOrNothing().StartWorking([]string{ids[0], "task-missing"})
+
+	if len(events) != 3 {
+		t.Fatalf("expected the move, the move back and the failure, got %+v", events)
+	}
+	if events[0].To != StatusInProgress || events[1].From != StatusInProgress || events[1].To != StatusTodo {
+		t.Errorf("expected the move and the move back, got %+v", events[:2])
+	}
+}
+
+func TestObserver_Record(t *testing.T) {
+	_, wf, _ := newFluxWorkflow(t)
+	var got []Event
+	wf.AddObserver(ObserverFunc(func(e Event) { got = append(got, e) }))
+	wf.SetActor("momentum host:1")
+
+	wf.WithReason("claimed under lease").Record(Event{TaskID: "task-1", From: StatusTodo, To: StatusInProgress})
+
+	if len(got) != 1 || got[0].Reason != "claimed under lease" || got[0].Actor != "momentum host:1" || got[0].Time.IsZero() {
+		t.Errorf("expected the recorded event with defaults filled in, got %+v", got)
+	}
+}
+
+func TestObserver_RefusedMoves(t *testing.T) {
+	_, wf, ids := newFluxWorkflow(t)
+	states, err := NewStateMachine(States{Transitions: map[string][]string{
+		StatusTodo:       {StatusInProgress},
+		StatusInProgress: {StatusDone, StatusPlanning, StatusTodo},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wf.SetStates(states)
+	var events eventLog
+	wf.AddObserver(&events)
+
+	_, err = wf.AllOrNothing().MarkComplete(ids)
+	if !errors.Is(err, ErrTransitionNotAllowed) {
+		t.Fatalf("expected the moves to be refused, got %v", err)
+	}
+	if len(events) != 2 || events[0].Error == "" || events[1].Error == "" {
+		t.Errorf("expected a failed event per refused move, got %+v", events)
+	}
+}
@@ -0,0 +1,273 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/sirsjg/momentum/client"
+)
+
+// Flux's built-in task statuses, used unless a board configures its own.
+const (
+	StatusPlanning   = "planning"
+	StatusTodo       = "todo"
+	StatusInProgress = "in_progress"
+	StatusDone       = "done"
+)
+
+// BuiltinStatuses are the statuses every Flux board has.
+var BuiltinStatuses = []string{StatusPlanning, StatusTodo, StatusInProgress, StatusDone}
+
+// ErrTransitionNotAllowed is returned when a status change is not one of the
+// configured transitions.
+var ErrTransitionNotAllowed = errors.New("status transition not allowed")
+
+// States names the board statuses Momentum moves tasks through. Empty fields
+// take Flux's built-in statuses.
+type States struct {
+	// Pickup lists the statuses tasks are selected from; the first is where
+	// tasks are reset to (default ["todo"])
+	Pickup []string `json:"pickup,omitempty"`
+	// Running is the status of tasks an agent is working on (default "in_progress")
+	Running string `json:"running,omitempty"`
+	// Success is where tasks go when their run succeeds (default "done")
+	Success string `json:"success,omitempty"`
+	// Failure is where tasks go when their agent fails; empty leaves them in
+	// the running status for investigation
+	Failure string `json:"failure,omitempty"`
+	// Stopped is where tasks go when the user stops their agent (default "planning")
+	Stopped string `json:"stopped,omitempty"`
+	// Timeout is where tasks go when their agent runs out of time; empty
+	// treats a timeout as a failure
+	Timeout string `json:"timeout,omitempty"`
+	// Transitions lists the statuses each status may move to; empty allows
+	// any move
+	Transitions map[string][]string `json:"transitions,omitempty"`
+}
+
+// StateMachine applies a States config. A nil *StateMachine uses Flux's
+// built-in statuses and allows any transition.
+type StateMachine struct {
+	states States
+	// extra holds the statuses of moves added with Require
+	extra []string
+}
+
+// Move is a status change Momentum makes.
+type Move struct {
+	From, To string
+}
+
+// NewStateMachine fills in defaults for states and checks that the
+// transitions allow every move Momentum makes.
+func NewStateMachine(states States) (*StateMachine, error) {
+	if len(states.Pickup) == 0 {
+		states.Pickup = []string{StatusTodo}
+	}
+	if states.Running == "" {
+		states.Running = StatusInProgress
+	}
+	if states.Success == "" {
+		states.Success = StatusDone
+	}
+	if states.Stopped == "" {
+		states.Stopped = StatusPlanning
+	}
+	m := &StateMachine{states: states}
+
+	if slices.Contains(states.Pickup, states.Running) {
+		return nil, fmt.Errorf("running status %q cannot also be a pickup status", states.Running)
+	}
+	if len(states.Transitions) == 0 {
+		return m, nil
+	}
+	required := []Move{
+		{states.Running, states.Success},
+		{states.Running, states.Stopped},
+		{states.Running, states.Pickup[0]},
+	}
+	for _, pickup := range states.Pickup {
+		required = append(required, Move{pickup, states.Running})
+	}
+	for _, to := range []string{states.Failure, states.Timeout} {
+		if to != "" {
+			required = append(required, Move{states.Running, to})
+		}
+	}
+	if err := m.allowsAll(required); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Require adds moves Momentum makes outside the States config, such as
+// holding tasks for review. It returns an error if the transitions do not
+// allow one of them, and Check also checks their statuses.
+func (m *StateMachine) Require(moves ...Move) error {
+	if m == nil {
+		return nil
+	}
+	for _, move := range moves {
+		m.extra = append(m.extra, move.From, move.To)
+	}
+	return m.allowsAll(moves)
+}
+
+func (m *StateMachine) allowsAll(moves []Move) error {
+	for _, move := range moves {
+		if !m.Allows(move.From, move.To) {
+			return fmt.Errorf("transitions must allow %s -> %s", move.From, move.To)
+		}
+	}
+	return nil
+}
+
+func (m *StateMachine) get() States {
+	if m == nil {
+		return States{
+			Pickup:  []string{StatusTodo},
+			Running: StatusInProgress,
+			Success: StatusDone,
+			Stopped: StatusPlanning,
+		}
+	}
+	return m.states
+}
+
+// Pickup returns the statuses tasks are selected from.
+func (m *StateMachine) Pickup() []string {
+	return m.get().Pickup
+}
+
+// IsPickup reports whether tasks with status can be selected.
+func (m *StateMachine) IsPickup(status string) bool {
+	return slices.Contains(m.get().Pickup, status)
+}
+
+// Reset returns the status tasks are put back in the queue with.
+func (m *StateMachine) Reset() string {
+	return m.get().Pickup[0]
+}
+
+// Running returns the status of tasks an agent is working on.
+func (m *StateMachine) Running() string {
+	return m.get().Running
+}
+
+// Success returns the status of successfully finished tasks.
+func (m *StateMachine) Success() string {
+	return m.get().Success
+}
+
+// Failure returns where failed tasks go, or "" to leave them running.
+func (m *StateMachine) Failure() string {
+	return m.get().Failure
+}
+
+// Stopped returns where tasks go when the user stops their agent.
+func (m *StateMachine) Stopped() string {
+	return m.get().Stopped
+}
+
+// Timeout returns where timed out tasks go, falling back to Failure.
+func (m *StateMachine) Timeout() string {
+	if s := m.get(); s.Timeout != "" {
+		return s.Timeout
+	}
+	return m.Failure()
+}
+
+// Restricted reports whether only the configured transitions are allowed.
+func (m *StateMachine) Restricted() bool {
+	return len(m.get().Transitions) > 0
+}
+
+// Allows reports whether a task may move from one status to another.
+// Staying in the same status is always allowed.
+func (m *StateMachine) Allows(from, to string) bool {
+	transitions := m.get().Transitions
+	if len(transitions) == 0 || from == to {
+		return true
+	}
+	return slices.Contains(transitions[from], to)
+}
+
+// Statuses returns every status the machine refers to, including those of
+// required moves, sorted.
+func (m *StateMachine) Statuses() []string {
+	s := m.get()
+	seen := make(map[string]bool)
+	add := func(statuses ...string) {
+		for _, status := range statuses {
+			if status != "" {
+				seen[status] = true
+			}
+		}
+	}
+	add(s.Pickup...)
+	add(s.Running, s.Success, s.Failure, s.Stopped, s.Timeout)
+	if m != nil {
+		add(m.extra...)
+	}
+	for from, to := range s.Transitions {
+		add(from)
+		add(to...)
+	}
+	statuses := make([]string, 0, len(seen))
+	for status := range seen {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	return statuses
+}
+
+// Check returns an error naming any status the machine refers to that is
+// not in present, the statuses found on the board.
+func (m *StateMachine) Check(present []string) error {
+	var missing []string
+	for _, status := range m.Statuses() {
+		if !slices.Contains(present, status) {
+			missing = append(missing, status)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("workflow statuses not found in Flux: %s (known: %s)", strings.Join(missing, ", "), strings.Join(present, ", "))
+	}
+	return nil
+}
+
+// BoardStatuses returns Flux's built-in statuses plus every status a task in
+// the project has, or a task in any project when projectID is empty. Flux
+// has no endpoint listing a board's columns, so a status no task is in yet
+// cannot be seen.
+func BoardStatuses(c *client.Client, projectID string) ([]string, error) {
+	projectIDs := []string{projectID}
+	if projectID == "" {
+		projects, err := c.ListProjects()
+		if err != nil {
+			return nil, err
+		}
+		projectIDs = projectIDs[:0]
+		for _, project := range projects {
+			projectIDs = append(projectIDs, project.ID)
+		}
+	}
+
+	statuses := slices.Clone(BuiltinStatuses)
+	for _, id := range projectIDs {
+		tasks, err := c.ListTasks(id, client.TaskFilters{})
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			if task.Status != "" && !slices.Contains(statuses, task.Status) {
+				statuses = append(statuses, task.Status)
+			}
+		}
+	}
+	sort.Strings(statuses)
+	return statuses, nil
+}
@@ -0,0 +1,148 @@
+package workflow
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStateMachine_Nil(t *testing.T) {
+	var m *StateMachine
+	if !m.IsPickup("todo") || m.IsPickup("in_progress") {
+		t.Error("nil machine should pick up todo tasks only")
+	}
+	if m.Running() != StatusInProgress || m.Success() != StatusDone || m.Stopped() != StatusPlanning || m.Reset() != StatusTodo {
+		t.Errorf("unexpected default statuses %+v", m.get())
+	}
+	if m.Failure() != "" || m.Timeout() != "" {
+		t.Error("nil machine should leave failed tasks where they are")
+	}
+	if m.Restricted() || !m.Allows("done", "todo") {
+		t.Error("nil machine should allow any transition")
+	}
+}
+
+func TestNewStateMachine_Defaults(t *testing.T) {
+	m, err := NewStateMachine(States{Pickup: []string{"ready", "todo"}, Failure: "blocked"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !m.IsPickup("todo") || m.Reset() != "ready" || m.Running() != StatusInProgress {
+		t.Errorf("unexpected statuses %+v", m.get())
+	}
+	if m.Timeout() != "blocked" {
+		t.Errorf("expected timeout to fall back to failure, got %q", m.Timeout())
+	}
+	want := []string{"blocked", "done", "in_progress", "planning", "ready", "todo"}
+	if got := m.Statuses(); !slices.Equal(got, want) {
+		t.Errorf("Statuses() = %v, want %v", got, want)
+	}
+}
+
+func TestNewStateMachine_Invalid(t *testing.T) {
+	if _, err := NewStateMachine(States{Pickup: []string{"doing"}, Running: "doing"}); err == nil {
+		t.Error("expected an error when the running status is also a pickup status")
+	}
+
+	_, err := NewStateMachine(States{
+		Failure: "blocked",
+		Transitions: map[string][]string{
+			"todo":        {"in_progress"},
+			"in_progress": {"done", "planning", "todo"},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "in_progress -> blocked") {
+		t.Errorf("expected the missing failure transition to be reported, got %v", err)
+	}
+}
+
+func TestStateMachine_Allows(t *testing.T) {
+	m, err := NewStateMachine(States{
+		Pickup:  []string{"ready"},
+		Running: "doing",
+		Success: "review",
+		Stopped: "ready",
+		Transitions: map[string][]string{
+			"ready": {"doing"},
+			"doing": {"review", "ready"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !m.Allows("ready", "doing") || !m.Allows("review", "review") {
+		t.Error("expected configured moves and staying put to be allowed")
+	}
+	if m.Allows("ready", "review") || m.Allows("review", "ready") {
+		t.Error("expected unlisted moves to be refused")
+	}
+}
+
+func TestStateMachine_Require(t *testing.T) {
+	m, err := NewStateMachine(States{Transitions: map[string][]string{
+		"todo":        {"in_progress"},
+		"in_progress": {"done", "planning", "todo", "review"},
+		"review":      {"done"},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := m.Require(Move{"in_progress", "review"}, Move{"review", "done"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err = m.Require(Move{"review", "todo"})
+	if err == nil || !strings.Contains(err.Error(), "review -> todo") {
+		t.Errorf("expected the missing review transition to be reported, got %v", err)
+	}
+	if err := m.Check(BuiltinStatuses); err == nil || !strings.Contains(err.Error(), "review") {
+		t.Errorf("expected the required review status to be checked, got %v", err)
+	}
+
+	var nilMachine *StateMachine
+	if err := nilMachine.Require(Move{"in_progress", "review"}); err != nil {
+		t.Errorf("expected a nil machine to allow any move, got %v", err)
+	}
+}
+
+func TestStateMachine_Check(t *testing.T) {
+	m, _ := NewStateMachine(States{Pickup: []string{"ready"}, Failure: "blocked"})
+
+	if err := m.Check(append(BuiltinStatuses, "ready", "blocked")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := m.Check(append(BuiltinStatuses, "ready"))
+	if err == nil || !strings.Contains(err.Error(), "blocked") {
+		t.Errorf("expected blocked to be reported missing, got %v", err)
+	}
+}
+
+func TestBoardStatuses(t *testing.T) {
+	server, c := setupTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/projects":
+			json.NewEncoder(w).Encode([]map[string]string{{"id": "proj-1"}, {"id": "proj-2"}})
+		case "/api/projects/proj-1/tasks":
+			json.NewEncoder(w).Encode([]map[string]string{{"id": "task-1", "status": "ready"}})
+		default:
+			json.NewEncoder(w).Encode([]map[string]string{{"id": "task-2", "status": "blocked"}, {"id": "task-3", "status": "todo"}})
+		}
+	})
+	defer server.Close()
+
+	statuses, err := BoardStatuses(c, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"blocked", "done", "in_progress", "planning", "ready", "todo"}
+	if !slices.Equal(statuses, want) {
+		t.Errorf("BoardStatuses() = %v, want %v", statuses, want)
+	}
+
+	statuses, _ = BoardStatuses(c, "proj-1")
+	if slices.Contains(statuses, "blocked") {
+		t.Errorf("expected only proj-1's statuses, got %v", statuses)
+	}
+}
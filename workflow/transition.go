@@ -0,0 +1,299 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/tracing"
+)
+
+// maxConcurrentTransitions bounds the PATCH requests made at once when Flux
+// has no batch endpoint.
+const maxConcurrentTransitions = 4
+
+var (
+	// ErrRolledBack marks a transition that succeeded and was undone because
+	// another transition in the same all-or-nothing call failed.
+	ErrRolledBack = errors.New("transition rolled back")
+	// ErrAborted marks a transition that was not attempted because another
+	// in the same all-or-nothing call could not be made.
+	ErrAborted = errors.New("transition not attempted")
+)
+
+// TransitionResult is the outcome of moving one task.
+type TransitionResult struct {
+	TaskID string
+	// From is the task's status before the move. It comes from the tasks
+	// given to WithFrom, and is otherwise only looked up when transitions are
+	// restricted or the call is all-or-nothing.
+	From string
+	To   string
+	// Task is the task as Flux returned it after the move, or after the
+	// rollback; nil if the move failed
+	Task *client.Task
+	// Err is a *TransitionError when the task is not in To after the call
+	Err error
+	// RolledBack is set when the move succeeded and was then undone
+	RolledBack bool
+	// RollbackErr is why undoing the move failed; the task stays in To
+	RollbackErr error
+}
+
+// Moved reports whether the task ended up in the requested status.
+func (r TransitionResult) Moved() bool {
+	return r.Err == nil
+}
+
+// TransitionError is one task's failed transition. It wraps the cause, which
+// is a *client.APIError when Flux refused the change.
+type TransitionError struct {
+	TaskID string
+	Status string
+	Err    error
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("task %s: %v", e.TaskID, e.Err)
+}
+
+func (e *TransitionError) Unwrap() error {
+	return e.Err
+}
+
+// BatchError is returned when any transition in a call fails. Results holds
+// every task's outcome, including the tasks that moved, and the error
+// unwraps to each failed task's *TransitionError.
+type BatchError struct {
+	Results []TransitionResult
+}
+
+func (e *BatchError) Error() string {
+	var msgs []string
+	rolledBack := 0
+	for _, r := range e.Results {
+		switch {
+		case r.RolledBack:
+			rolledBack++
+		case errors.Is(r.Err, ErrAborted):
+		case r.Err != nil:
+			msgs = append(msgs, r.Err.Error())
+		}
+		if r.RollbackErr != nil {
+			msgs = append(msgs, fmt.Sprintf("task %s: rollback failed: %v", r.TaskID, r.RollbackErr))
+		}
+	}
+	msg := "failed to update tasks: " + strings.Join(msgs, "; ")
+	if rolledBack > 0 {
+		msg += fmt.Sprintf(" (rolled back %d)", rolledBack)
+	}
+	return msg
+}
+
+func (e *BatchError) Unwrap() []error {
+	var errs []error
+	for _, r := range e.Results {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+	return errs
+}
+
+// batchSupport tracks whether Flux has the batch status endpoint. It is
+// shared by a workflow's copies so a server without it is probed only once.
+type batchSupport struct {
+	unsupported atomic.Bool
+}
+
+// transition moves every task in results to status, filling in each result.
+// Checks against the state machine happen up front; in all-or-nothing mode
+// a failed check or move leaves every task where it was.
+func (w *Workflow) transition(results []TransitionResult, status string) {
+	c := w.client
+	if w.ctx != nil {
+		c = c.WithContext(w.ctx)
+	}
+
+	// Tasks the caller has not told us about are looked up when the move
+	// must be checked or may be undone; observers alone never cost a lookup
+	required := w.states.Restricted() || w.atomic
+	current := make(map[string]client.Task, len(results))
+	lookups := make(map[string][]string)
+	for _, r := range results {
+		task, ok := w.from[r.TaskID]
+		switch {
+		case ok && task.Status != "":
+			current[r.TaskID] = task
+		case ok || required:
+			// A known task is only looked for in its own project
+			lookups[task.ProjectID] = append(lookups[task.ProjectID], r.TaskID)
+		}
+	}
+	failed := make(map[string]error)
+	for projectID, ids := range lookups {
+		found, err := c.FindTasks(projectID, ids)
+		for _, id := range ids {
+			if task, ok := found[id]; ok {
+				current[id] = task
+			} else if err != nil {
+				failed[id] = err
+			}
+		}
+	}
+
+	for i := range results {
+		r := &results[i]
+		task, ok := current[r.TaskID]
+		switch {
+		case failed[r.TaskID] != nil && required:
+			r.Err = &TransitionError{TaskID: r.TaskID, Status: status, Err: failed[r.TaskID]}
+		case !ok:
+			continue
+		case w.states.Restricted() && !w.states.Allows(task.Status, status):
+			r.Err = &TransitionError{TaskID: r.TaskID, Status: status,
+				Err: fmt.Errorf("%w: %s -> %s", ErrTransitionNotAllowed, task.Status, status)}
+		default:
+			r.From = task.Status
+		}
+	}
+	if w.atomic && anyFailed(results) {
+		abort(results, status)
+		return
+	}
+
+	var pending []int
+	for i, r := range results {
+		if r.Err == nil {
+			pending = append(pending, i)
+		}
+	}
+	w.move(c, results, pending, status)
+
+	if w.atomic && anyFailed(results) {
+		w.rollback(results)
+	}
+}
+
+// move makes the transitions for results[pending], in one request when Flux
+// has the batch endpoint and otherwise a few at a time.
+func (w *Workflow) move(c *client.Client, results []TransitionResult, pending []int, status string) {
+	if !w.batch.unsupported.Load() && len(pending) > 1 {
+		if w.moveBatch(c, results, pending, status) {
+			return
+		}
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentTransitions)
+	for _, i := range pending {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(r *TransitionResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			task, err := w.moveTaskStatus(r.TaskID, status)
+			if err != nil {
+				r.Err = &TransitionError{TaskID: r.TaskID, Status: status, Err: err}
+				return
+			}
+			r.Task = task
+		}(&results[i])
+	}
+	wg.Wait()
+}
+
+// moveBatch makes the transitions with Flux's batch endpoint. It returns
+// false, having changed nothing, when the server does not have it.
+func (w *Workflow) moveBatch(c *client.Client, results []TransitionResult, pending []int, status string) bool {
+	ids := make([]string, len(pending))
+	for j, i := range pending {
+		ids[j] = results[i].TaskID
+	}
+
+	var span *tracing.Span
+	if w.ctx != nil {
+		var ctx context.Context
+		ctx, span = tracing.Start(w.ctx, "workflow.transition",
+			tracing.Int("task.count", len(ids)),
+			tracing.String("task.status", status),
+		)
+		c = c.WithContext(ctx)
+		defer span.End()
+	}
+
+	moved, err := c.MoveTasksStatus(ids, status)
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusMethodNotAllowed) {
+		w.batch.unsupported.Store(true)
+		return false
+	}
+	span.SetError(err)
+
+	for j, i := range pending {
+		r := &results[i]
+		switch {
+		case err != nil:
+			r.Err = &TransitionError{TaskID: r.TaskID, Status: status, Err: err}
+		case moved[j].Err() != nil:
+			r.Err = &TransitionError{TaskID: r.TaskID, Status: status, Err: moved[j].Err()}
+		default:
+			r.Task = moved[j].Task
+		}
+	}
+	return true
+}
+
+// rollback moves every task that did move back to where it was.
+func (w *Workflow) rollback(results []TransitionResult) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentTransitions)
+	for i := range results {
+		r := &results[i]
+		if r.Err != nil || r.From == r.To {
+			continue
+		}
+		if r.From == "" {
+			r.RollbackErr = errors.New("previous status unknown")
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			task, err := w.moveTaskStatus(r.TaskID, r.From)
+			if err != nil {
+				r.RollbackErr = err
+				return
+			}
+			r.Task = task
+			r.RolledBack = true
+			r.Err = &TransitionError{TaskID: r.TaskID, Status: r.To, Err: ErrRolledBack}
+		}()
+	}
+	wg.Wait()
+}
+
+// abort marks every task not already failed as not attempted.
+func abort(results []TransitionResult, status string) {
+	for i := range results {
+		if results[i].Err == nil {
+			results[i].Err = &TransitionError{TaskID: results[i].TaskID, Status: status, Err: ErrAborted}
+		}
+	}
+}
+
+func anyFailed(results []TransitionResult) bool {
+	for _, r := range results {
+		if r.Err != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package workflow
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/fluxtest"
+)
+
+// newFluxWorkflow returns a workflow against a fake Flux server holding two
+// todo tasks.
+func newFluxWorkflow(t *testing.T) (*fluxtest.Server, *Workflow, []string) {
+	t.Helper()
+	flux := fluxtest.NewServer()
+	t.Cleanup(flux.Close)
+	project := flux.AddProject(client.Project{Name: "demo"})
+	first := flux.AddTask(client.Task{Title: "one", ProjectID: project.ID})
+	second := flux.AddTask(client.Task{Title: "two", ProjectID: project.ID})
+
+	wf := NewWorkflow(client.NewClient(flux.URL))
+	wf.SetOutput(io.Discard)
+	return flux, wf, []string{first.ID, second.ID}
+}
+
+func TestTransition_Results(t *testing.T) {
+	flux, wf, ids := newFluxWorkflow(t)
+
+	results, err := wf.StartWorking([]string{ids[0], "task-missing", ids[1]})
+	if len(results) != 3 {
+		t.Fatalf("expected a result per task, got %d", len(results))
+	}
+	if !results[0].Moved() || results[1].Moved() || !results[2].Moved() {
+		t.Errorf("expected only the missing task to fail, got %+v", results)
+	}
+	if results[0].Task == nil || results[0].Task.Status != StatusInProgress {
+		t.Errorf("expected the moved task in the result, got %+v", results[0].Task)
+	}
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Results) != 3 {
+		t.Fatalf("expected a BatchError, got %v", err)
+	}
+	var transitionErr *TransitionError
+	if !errors.As(err, &transitionErr) || transitionErr.TaskID != "task-missing" {
+		t.Errorf("expected a TransitionError for the missing task, got %v", err)
+	}
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected the API error to be wrapped, got %v", err)
+	}
+	if got := flux.StatusHistory(ids[1]); !slices.Equal(got, []string{StatusTodo, StatusInProgress}) {
+		t.Errorf("unexpected status history %v", got)
+	}
+}
+
+func TestTransition_Batch(t *testing.T) {
+	flux, wf, ids := newFluxWorkflow(t)
+
+	results, err := wf.MarkComplete(ids)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, r := range results {
+		if !r.Moved() || r.Task == nil || r.Task.Status != StatusDone {
+			t.Errorf("expected %s to be done, got %+v", r.TaskID, r)
+		}
+	}
+	if got := flux.StatusHistory(ids[0]); !slices.Equal(got, []string{StatusTodo, StatusDone}) {
+		t.Errorf("unexpected status history %v", got)
+	}
+}
+
+func TestTransition_BatchFallback(t *testing.T) {
+	var batches, patches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			batches.Add(1)
+			http.NotFound(w, r)
+			return
+		}
+		patches.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"task","status":"done"}`))
+	}))
+	defer server.Close()
+
+	wf := NewWorkflow(client.NewClient(server.URL))
+	wf.SetOutput(io.Discard)
+	for range 2 {
+		if _, err := wf.MarkComplete([]string{"task-1", "task-2", "task-3"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// The missing endpoint is only probed once
+	if batches.Load() != 1 || patches.Load() != 6 {
+		t.Errorf("expected 1 batch request and 6 single updates, got %d and %d", batches.Load(), patches.Load())
+	}
+}
+
+func TestTransition_AllOrNothing(t *testing.T) {
+	flux, wf, ids := newFluxWorkflow(t)
+
+	results, err := wf.AllOrNothing().StartWorking([]string{ids[0], ids[1], "task-missing"})
+	if !errors.Is(err, ErrRolledBack) {
+		t.Fatalf("expected the moves to be rolled back, got %v", err)
+	}
+	for _, r := range results[:2] {
+		if r.Moved() || !r.RolledBack || r.From != StatusTodo {
+			t.Errorf("expected %s to be rolled back, got %+v", r.TaskID, r)
+		}
+	}
+	for _, id := range ids {
+		if got := flux.StatusHistory(id); !slices.Equal(got, []string{StatusTodo, StatusInProgress, StatusTodo}) {
+			t.Errorf("unexpected status history for %s: %v", id, got)
+		}
+	}
+}
+
+func TestTransition_AllOrNothingRefused(t *testing.T) {
+	flux, wf, ids := newFluxWorkflow(t)
+	states, err := NewStateMachine(States{Transitions: map[string][]string{
+		StatusTodo:       {StatusInProgress},
+		StatusInProgress: {StatusDone, StatusPlanning, StatusTodo},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wf.SetStates(states)
+	if _, err := wf.StartWorking(ids[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	// ids[1] is still todo, so it cannot go straight to done
+	results, err := wf.AllOrNothing().MarkComplete(ids)
+	if !errors.Is(err, ErrTransitionNotAllowed) || !errors.Is(results[0].Err, ErrAborted) {
+		t.Fatalf("expected the call to be refused before any move, got %v, %+v", err, results)
+	}
+	if got := flux.StatusHistory(ids[0]); !slices.Equal(got, []string{StatusTodo, StatusInProgress}) {
+		t.Errorf("expected no moves, got %v", got)
+	}
+}
@@ -4,26 +4,44 @@
 package workflow
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
-	"strings"
 
 	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/tracing"
 )
 
 // Workflow provides methods for managing task status transitions.
 type Workflow struct {
 	client *client.Client
 	out    io.Writer
+	ctx    context.Context
+	// states names the board's statuses; nil uses Flux's built-in ones
+	states *StateMachine
+	// batch records whether Flux has the batch status endpoint
+	batch *batchSupport
+	// atomic undoes every move in a call if any of them fails
+	atomic bool
+	// from is the caller's view of tasks before they move, by ID
+	from map[string]client.Task
+	// observers are told about every transition, attributed to actor
+	observers []Observer
+	actor     string
+	reason    string
 }
 
 // NewWorkflow creates a new Workflow instance with the provided client.
+// Multi-task moves use Flux's batch status endpoint; servers without it are
+// detected on the first attempt, after which tasks are moved with concurrent
+// single-task updates.
 func NewWorkflow(client *client.Client) *Workflow {
 	return &Workflow{
 		client: client,
 		out:    os.Stdout,
+		batch:  &batchSupport{},
 	}
 }
 
@@ -33,69 +51,174 @@ func (w *Workflow) SetOutput(out io.Writer) {
 	w.out = out
 }
 
-// StartWorking transitions the specified tasks to "in_progress" status.
-// It iterates through all provided task IDs, attempting to update each one.
-// If any task fails to update, it continues with the remaining tasks and
-// returns an aggregate error describing all failures.
-func (w *Workflow) StartWorking(taskIDs []string) error {
-	return w.updateTasksStatus(taskIDs, "in_progress", "Starting work on")
+// SetStates configures the board statuses tasks are moved through and the
+// transitions allowed between them.
+func (w *Workflow) SetStates(states *StateMachine) {
+	w.states = states
 }
 
-// MarkComplete transitions the specified tasks to "done" status.
-// It iterates through all provided task IDs, attempting to update each one.
-// If any task fails to update, it continues with the remaining tasks and
-// returns an aggregate error describing all failures.
-func (w *Workflow) MarkComplete(taskIDs []string) error {
-	return w.updateTasksStatus(taskIDs, "done", "Marking complete")
+// AllOrNothing returns a shallow copy of the workflow whose transitions are
+// all-or-nothing: when any task in a call cannot be moved, the tasks that did
+// move are moved back to their previous status.
+func (w *Workflow) AllOrNothing() *Workflow {
+	wc := *w
+	wc.atomic = true
+	return &wc
 }
 
-// ResetTask transitions the specified tasks back to "todo" status.
-// It iterates through all provided task IDs, attempting to update each one.
-// If any task fails to update, it continues with the remaining tasks and
-// returns an aggregate error describing all failures.
-func (w *Workflow) ResetTask(taskIDs []string) error {
-	return w.updateTasksStatus(taskIDs, "todo", "Resetting")
+// WithFrom returns a shallow copy of the workflow that takes the given tasks
+// to be as the caller last saw them. Their status is where their transitions
+// start from, saving a lookup in Flux; a task without a status is looked up
+// in its own project only.
+func (w *Workflow) WithFrom(tasks ...client.Task) *Workflow {
+	wc := *w
+	wc.from = make(map[string]client.Task, len(w.from)+len(tasks))
+	for id, task := range w.from {
+		wc.from[id] = task
+	}
+	for _, task := range tasks {
+		wc.from[task.ID] = task
+	}
+	return &wc
+}
+
+// WithContext returns a shallow copy of the workflow whose transitions use ctx
+// for cancellation and trace propagation.
+func (w *Workflow) WithContext(ctx context.Context) *Workflow {
+	wc := *w
+	wc.ctx = ctx
+	return &wc
+}
+
+// StartWorking transitions the specified tasks to the running status
+// ("in_progress" by default).
+// It returns each task's result; if any task fails to update, the others
+// are still moved and the error is a *BatchError.
+func (w *Workflow) StartWorking(taskIDs []string) ([]TransitionResult, error) {
+	return w.updateTasksStatus(taskIDs, w.states.Running(), "Starting work on")
+}
+
+// MarkComplete transitions the specified tasks to the success status
+// ("done" by default).
+// It returns each task's result; if any task fails to update, the others
+// are still moved and the error is a *BatchError.
+func (w *Workflow) MarkComplete(taskIDs []string) ([]TransitionResult, error) {
+	return w.updateTasksStatus(taskIDs, w.states.Success(), "Marking complete")
 }
 
-// ResetToPlanning transitions the specified tasks back to "planning" status.
+// ResetTask transitions the specified tasks back to the first pickup status
+// ("todo" by default).
+// It returns each task's result; if any task fails to update, the others
+// are still moved and the error is a *BatchError.
+func (w *Workflow) ResetTask(taskIDs []string) ([]TransitionResult, error) {
+	return w.updateTasksStatus(taskIDs, w.states.Reset(), "Resetting")
+}
+
+// ResetToPlanning transitions the specified tasks to the stopped status
+// ("planning" by default).
 // This is typically used when a user stops an agent mid-execution.
-// It iterates through all provided task IDs, attempting to update each one.
-// If any task fails to update, it continues with the remaining tasks and
-// returns an aggregate error describing all failures.
-func (w *Workflow) ResetToPlanning(taskIDs []string) error {
-	return w.updateTasksStatus(taskIDs, "planning", "Resetting to planning")
+// It returns each task's result; if any task fails to update, the others
+// are still moved and the error is a *BatchError.
+func (w *Workflow) ResetToPlanning(taskIDs []string) ([]TransitionResult, error) {
+	return w.updateTasksStatus(taskIDs, w.states.Stopped(), "Resetting to "+w.states.Stopped())
 }
 
-// updateTasksStatus is the internal method that handles status updates for all tasks.
-// It processes each task ID, prints status messages, handles errors gracefully,
-// and returns an aggregate error if any updates failed.
-func (w *Workflow) updateTasksStatus(taskIDs []string, status, actionVerb string) error {
-	if len(taskIDs) == 0 {
-		return nil
+// MarkFailed transitions the specified tasks to the failure status. Without
+// one configured, the tasks stay where they are for investigation.
+// It returns each task's result; if any task fails to update, the others
+// are still moved and the error is a *BatchError.
+func (w *Workflow) MarkFailed(taskIDs []string) ([]TransitionResult, error) {
+	if w.states.Failure() == "" {
+		return nil, nil
+	}
+	return w.updateTasksStatus(taskIDs, w.states.Failure(), "Marking failed")
+}
+
+// MarkTimedOut transitions the specified tasks to the timeout status,
+// falling back to the failure status. Without either configured, the tasks
+// stay where they are.
+// It returns each task's result; if any task fails to update, the others
+// are still moved and the error is a *BatchError.
+func (w *Workflow) MarkTimedOut(taskIDs []string) ([]TransitionResult, error) {
+	if w.states.Timeout() == "" {
+		return nil, nil
 	}
+	return w.updateTasksStatus(taskIDs, w.states.Timeout(), "Marking timed out")
+}
 
-	var failedTasks []string
-	var errorMessages []string
+// MoveTo transitions the specified tasks to an arbitrary status, e.g. a
+// "review" column used when verification fails.
+// It returns each task's result; if any task fails to update, the others
+// are still moved and the error is a *BatchError.
+func (w *Workflow) MoveTo(taskIDs []string, status string) ([]TransitionResult, error) {
+	return w.updateTasksStatus(taskIDs, status, "Moving to "+status)
+}
 
-	for _, taskID := range taskIDs {
-		w.printf("%s task %s...\n", actionVerb, taskID)
+// Comment adds a comment to the specified task, e.g. to record why it was
+// moved back.
+func (w *Workflow) Comment(taskID, body string) error {
+	c := w.client
+	if w.ctx != nil {
+		c = c.WithContext(w.ctx)
+	}
+	if _, err := c.AddComment(taskID, body); err != nil {
+		w.printf("  Failed to comment on task %s: %v\n", taskID, err)
+		return err
+	}
+	return nil
+}
 
-		task, err := w.client.MoveTaskStatus(taskID, status)
-		if err != nil {
-			w.printf("  Failed to update task %s: %v\n", taskID, err)
-			failedTasks = append(failedTasks, taskID)
-			errorMessages = append(errorMessages, fmt.Sprintf("task %s: %v", taskID, err))
-			continue
+// updateTasksStatus is the internal method that handles status updates for all tasks.
+// It moves every task, prints status messages, and returns each task's
+// result with a *BatchError if any updates failed.
+func (w *Workflow) updateTasksStatus(taskIDs []string, status, actionVerb string) ([]TransitionResult, error) {
+	if len(taskIDs) == 0 {
+		return nil, nil
+	}
+
+	results := make([]TransitionResult, len(taskIDs))
+	for i, taskID := range taskIDs {
+		results[i] = TransitionResult{TaskID: taskID, To: status}
+	}
+	w.transition(results, status)
+	w.observe(results)
+
+	failed := false
+	for _, r := range results {
+		w.printf("%s task %s...\n", actionVerb, r.TaskID)
+		switch {
+		case r.RolledBack:
+			w.printf("  Task %s moved back to %s\n", r.TaskID, r.From)
+		case r.Err != nil:
+			w.printf("  Failed to update task %s: %v\n", r.TaskID, errors.Unwrap(r.Err))
+		default:
+			w.printf("  Task %s (%s) -> %s\n", r.TaskID, r.Task.Title, status)
 		}
+		failed = failed || r.Err != nil || r.RollbackErr != nil
+	}
 
-		w.printf("  Task %s (%s) -> %s\n", taskID, task.Title, status)
+	if failed {
+		return results, &BatchError{Results: results}
 	}
+	return results, nil
+}
 
-	if len(failedTasks) > 0 {
-		return errors.New("failed to update tasks: " + strings.Join(errorMessages, "; "))
+// moveTaskStatus performs a single transition, wrapped in a trace span when
+// the workflow carries a context.
+func (w *Workflow) moveTaskStatus(taskID, status string) (*client.Task, error) {
+	if w.ctx == nil {
+		return w.client.MoveTaskStatus(taskID, status)
 	}
 
-	return nil
+	ctx, span := tracing.Start(w.ctx, "workflow.transition",
+		tracing.String("task.id", taskID),
+		tracing.String("task.status", status),
+	)
+	defer span.End()
+
+	task, err := w.client.WithContext(ctx).MoveTaskStatus(taskID, status)
+	span.SetError(err)
+	return task, err
 }
 
 func (w *Workflow) printf(format string, args ...any) {
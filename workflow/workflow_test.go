@@ -1,17 +1,30 @@
 package workflow
 
 import (
+	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/tracing"
 )
 
+// setupTestServer serves handler as a Flux without the batch status endpoint,
+// so multi-task moves are made one PATCH at a time.
 func setupTestServer(handler http.HandlerFunc) (*httptest.Server, *client.Client) {
-	server := httptest.NewServer(handler)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/api/tasks/status" {
+			http.NotFound(w, r)
+			return
+		}
+		handler(w, r)
+	}))
 	c := client.NewClient(server.URL)
 	return server, c
 }
@@ -32,7 +45,7 @@ func TestWorkflow_StartWorking_EmptyList(t *testing.T) {
 	c := client.NewClient("http://localhost:3000")
 	wf := NewWorkflow(c)
 
-	err := wf.StartWorking([]string{})
+	_, err := wf.StartWorking([]string{})
 	if err != nil {
 		t.Errorf("expected no error for empty list, got %v", err)
 	}
@@ -65,19 +78,19 @@ func TestWorkflow_StartWorking_SingleTask(t *testing.T) {
 	defer server.Close()
 
 	wf := NewWorkflow(c)
-	err := wf.StartWorking([]string{"task-1"})
+	_, err := wf.StartWorking([]string{"task-1"})
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
 }
 
 func TestWorkflow_StartWorking_MultipleTasks(t *testing.T) {
-	callCount := 0
+	var callCount atomic.Int32
 	server, c := setupTestServer(func(w http.ResponseWriter, r *http.Request) {
-		callCount++
+		n := callCount.Add(1)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"id":     "task-" + string(rune('0'+callCount)),
+			"id":     "task-" + string(rune('0'+n)),
 			"title":  "Test Task",
 			"status": "in_progress",
 		})
@@ -85,19 +98,19 @@ func TestWorkflow_StartWorking_MultipleTasks(t *testing.T) {
 	defer server.Close()
 
 	wf := NewWorkflow(c)
-	err := wf.StartWorking([]string{"task-1", "task-2", "task-3"})
+	_, err := wf.StartWorking([]string{"task-1", "task-2", "task-3"})
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
-	if callCount != 3 {
-		t.Errorf("expected 3 API calls, got %d", callCount)
+	if callCount.Load() != 3 {
+		t.Errorf("expected 3 API calls, got %d", callCount.Load())
 	}
 }
 
 func TestWorkflow_StartWorking_PartialFailure(t *testing.T) {
-	callCount := 0
+	var callCount atomic.Int32
 	server, c := setupTestServer(func(w http.ResponseWriter, r *http.Request) {
-		callCount++
+		callCount.Add(1)
 		if strings.Contains(r.URL.Path, "task-2") {
 			http.Error(w, "not found", http.StatusNotFound)
 			return
@@ -112,7 +125,7 @@ func TestWorkflow_StartWorking_PartialFailure(t *testing.T) {
 	defer server.Close()
 
 	wf := NewWorkflow(c)
-	err := wf.StartWorking([]string{"task-1", "task-2", "task-3"})
+	_, err := wf.StartWorking([]string{"task-1", "task-2", "task-3"})
 	if err == nil {
 		t.Error("expected error for partial failure")
 	}
@@ -120,8 +133,8 @@ func TestWorkflow_StartWorking_PartialFailure(t *testing.T) {
 		t.Errorf("error should mention failed task: %v", err)
 	}
 	// Should still have called all 3
-	if callCount != 3 {
-		t.Errorf("expected 3 API calls despite failure, got %d", callCount)
+	if callCount.Load() != 3 {
+		t.Errorf("expected 3 API calls despite failure, got %d", callCount.Load())
 	}
 }
 
@@ -129,7 +142,7 @@ func TestWorkflow_MarkComplete_EmptyList(t *testing.T) {
 	c := client.NewClient("http://localhost:3000")
 	wf := NewWorkflow(c)
 
-	err := wf.MarkComplete([]string{})
+	_, err := wf.MarkComplete([]string{})
 	if err != nil {
 		t.Errorf("expected no error for empty list, got %v", err)
 	}
@@ -153,7 +166,7 @@ func TestWorkflow_MarkComplete_SingleTask(t *testing.T) {
 	defer server.Close()
 
 	wf := NewWorkflow(c)
-	err := wf.MarkComplete([]string{"task-1"})
+	_, err := wf.MarkComplete([]string{"task-1"})
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
@@ -166,7 +179,7 @@ func TestWorkflow_MarkComplete_AllFail(t *testing.T) {
 	defer server.Close()
 
 	wf := NewWorkflow(c)
-	err := wf.MarkComplete([]string{"task-1", "task-2"})
+	_, err := wf.MarkComplete([]string{"task-1", "task-2"})
 	if err == nil {
 		t.Error("expected error when all tasks fail")
 	}
@@ -179,7 +192,7 @@ func TestWorkflow_ResetTask_EmptyList(t *testing.T) {
 	c := client.NewClient("http://localhost:3000")
 	wf := NewWorkflow(c)
 
-	err := wf.ResetTask([]string{})
+	_, err := wf.ResetTask([]string{})
 	if err != nil {
 		t.Errorf("expected no error for empty list, got %v", err)
 	}
@@ -203,16 +216,16 @@ func TestWorkflow_ResetTask_SingleTask(t *testing.T) {
 	defer server.Close()
 
 	wf := NewWorkflow(c)
-	err := wf.ResetTask([]string{"task-1"})
+	_, err := wf.ResetTask([]string{"task-1"})
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
 }
 
 func TestWorkflow_ResetTask_MultipleTasks(t *testing.T) {
-	callCount := 0
+	var callCount atomic.Int32
 	server, c := setupTestServer(func(w http.ResponseWriter, r *http.Request) {
-		callCount++
+		callCount.Add(1)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"id":     "task",
@@ -223,26 +236,26 @@ func TestWorkflow_ResetTask_MultipleTasks(t *testing.T) {
 	defer server.Close()
 
 	wf := NewWorkflow(c)
-	err := wf.ResetTask([]string{"task-1", "task-2"})
+	_, err := wf.ResetTask([]string{"task-1", "task-2"})
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
-	if callCount != 2 {
-		t.Errorf("expected 2 API calls, got %d", callCount)
+	if callCount.Load() != 2 {
+		t.Errorf("expected 2 API calls, got %d", callCount.Load())
 	}
 }
 
 func TestWorkflow_ErrorAggregation(t *testing.T) {
-	callCount := 0
+	var callCount atomic.Int32
 	server, c := setupTestServer(func(w http.ResponseWriter, r *http.Request) {
-		callCount++
+		callCount.Add(1)
 		// All requests fail
 		http.Error(w, "error", http.StatusInternalServerError)
 	})
 	defer server.Close()
 
 	wf := NewWorkflow(c)
-	err := wf.StartWorking([]string{"task-1", "task-2", "task-3"})
+	_, err := wf.StartWorking([]string{"task-1", "task-2", "task-3"})
 
 	if err == nil {
 		t.Fatal("expected error")
@@ -261,7 +274,190 @@ func TestWorkflow_ErrorAggregation(t *testing.T) {
 	}
 
 	// All tasks should have been attempted
-	if callCount != 3 {
-		t.Errorf("expected 3 calls, got %d", callCount)
+	if callCount.Load() != 3 {
+		t.Errorf("expected 3 calls, got %d", callCount.Load())
+	}
+}
+
+func TestWorkflow_MoveTo(t *testing.T) {
+	server, c := setupTestServer(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["status"] != "review" {
+			t.Errorf("expected status 'review', got %q", body["status"])
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":     "task-1",
+			"title":  "Test Task",
+			"status": "review",
+		})
+	})
+	defer server.Close()
+
+	wf := NewWorkflow(c)
+	wf.SetOutput(nil)
+	if _, err := wf.MoveTo([]string{"task-1"}, "review"); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestWorkflow_Comment(t *testing.T) {
+	var gotPath, gotBody string
+	server, c := setupTestServer(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		gotPath, gotBody = r.URL.Path, body["body"]
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": "c-1", "body": body["body"]})
+	})
+	defer server.Close()
+
+	wf := NewWorkflow(c)
+	wf.SetOutput(nil)
+	if err := wf.Comment("task-1", "hook failed"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if gotPath != "/api/tasks/task-1/comments" || gotBody != "hook failed" {
+		t.Errorf("unexpected request: %s %q", gotPath, gotBody)
+	}
+}
+
+func TestWorkflow_WithContext_TracesTransitions(t *testing.T) {
+	server, c := setupTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "task-bad") {
+			http.Error(w, "nope", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": "task-1", "status": "done"})
+	})
+	defer server.Close()
+
+	exp := tracing.NewMemoryExporter()
+	ctx, root := tracing.NewTracer(exp).Start(context.Background(), "root")
+
+	wf := NewWorkflow(c)
+	wf.SetOutput(nil)
+	wf.WithContext(ctx).MarkComplete([]string{"task-1", "task-bad"})
+	root.End()
+
+	taskOf := func(span tracing.SpanData) string {
+		for _, a := range span.Attributes {
+			if a.Key == "task.id" {
+				return a.Value.(string)
+			}
+		}
+		return ""
+	}
+	var transitions []tracing.SpanData
+	requests := 0
+	for _, span := range exp.Spans() {
+		switch {
+		// The probe for the batch endpoint is traced too, without a task ID
+		case span.Name == "workflow.transition" && taskOf(span) != "":
+			transitions = append(transitions, span)
+		case span.Name == "flux PATCH":
+			requests++
+		}
+	}
+	if len(transitions) != 2 {
+		t.Fatalf("expected 2 transition spans, got %d", len(transitions))
+	}
+	// Transitions run concurrently, so put the spans in task order
+	slices.SortFunc(transitions, func(a, b tracing.SpanData) int { return strings.Compare(taskOf(a), taskOf(b)) })
+	if requests != 2 {
+		t.Errorf("expected a request span per transition, got %d", requests)
+	}
+	if transitions[0].ParentSpanID != root.SpanID() {
+		t.Error("expected transition span to be a child of the context span")
+	}
+	if transitions[0].Status == tracing.StatusError || transitions[1].Status != tracing.StatusError {
+		t.Errorf("expected only the failed transition to be marked as an error: %v, %v",
+			transitions[0].Status, transitions[1].Status)
+	}
+}
+
+func TestWorkflow_SetStates(t *testing.T) {
+	var got []string
+	server, c := setupTestServer(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		got = append(got, body["status"])
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": "task-1", "status": body["status"]})
+	})
+	defer server.Close()
+
+	states, err := NewStateMachine(States{Pickup: []string{"ready"}, Running: "doing", Success: "shipped", Failure: "blocked", Stopped: "ready"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wf := NewWorkflow(c)
+	wf.SetOutput(io.Discard)
+	wf.SetStates(states)
+
+	wf.StartWorking([]string{"task-1"})
+	wf.MarkComplete([]string{"task-1"})
+	wf.ResetTask([]string{"task-1"})
+	wf.ResetToPlanning([]string{"task-1"})
+	wf.MarkFailed([]string{"task-1"})
+	wf.MarkTimedOut([]string{"task-1"})
+
+	want := []string{"doing", "shipped", "ready", "ready", "blocked", "blocked"}
+	if !slices.Equal(got, want) {
+		t.Errorf("moved to %v, want %v", got, want)
+	}
+}
+
+func TestWorkflow_MarkFailed_NoFailureStatus(t *testing.T) {
+	server, c := setupTestServer(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	})
+	defer server.Close()
+
+	if _, err := NewWorkflow(c).MarkFailed([]string{"task-1"}); err != nil {
+		t.Errorf("expected failed tasks to stay put, got %v", err)
+	}
+}
+
+func TestWorkflow_RestrictedTransitions(t *testing.T) {
+	moved := false
+	server, c := setupTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/projects":
+			json.NewEncoder(w).Encode([]map[string]string{{"id": "proj-1"}})
+		case r.Method == http.MethodGet:
+			json.NewEncoder(w).Encode([]map[string]string{{"id": "task-1", "status": "todo"}})
+		default:
+			moved = true
+			json.NewEncoder(w).Encode(map[string]string{"id": "task-1", "status": "done"})
+		}
+	})
+	defer server.Close()
+
+	states, err := NewStateMachine(States{Transitions: map[string][]string{
+		"todo":        {"in_progress"},
+		"in_progress": {"done", "planning", "todo"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wf := NewWorkflow(c)
+	wf.SetOutput(io.Discard)
+	wf.SetStates(states)
+
+	_, err = wf.MarkComplete([]string{"task-1"})
+	if err == nil || !strings.Contains(err.Error(), "todo -> done") {
+		t.Errorf("expected todo -> done to be refused, got %v", err)
+	}
+	if moved {
+		t.Error("expected no status change for a refused transition")
+	}
+	if _, err := wf.StartWorking([]string{"task-1"}); err != nil || !moved {
+		t.Errorf("expected todo -> in_progress to be allowed, got %v", err)
 	}
 }
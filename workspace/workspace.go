@@ -0,0 +1,370 @@
+// Package workspace decides which directory each task's agent works in.
+//
+// Tasks run in Momentum's working directory (--workdir) unless their
+// project lives somewhere else. A task's directory is the first of:
+//
+//  1. its epic's entry in the config's epics map
+//  2. its project's entry, by ID or name, in the config's projects map
+//  3. a "workdir:" line in the Flux project's description
+//  4. the default working directory
+//
+// Relative paths are resolved against the default working directory, so a
+// monorepo's projects can be mapped to its subdirectories.
+//
+// A project can also name its git repository, in the config's repositories
+// map or with a "repository:" line in its description. When it has no local
+// checkout, Prepare clones the repository into its mapped directory or, if
+// it has none, into a managed cache directory, and before each later run
+// fetches the repository and fast-forwards the checkout.
+package workspace
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirsjg/momentum/client"
+	"github.com/sirsjg/momentum/internal/git"
+)
+
+// projectsTTL is how long the Flux project list is reused before it is
+// fetched again, so descriptions edited in Flux take effect.
+const projectsTTL = time.Minute
+
+// ErrNotUpdated is returned by Prepare when a checkout exists but could not
+// be brought up to date, for example because the remote is unreachable or
+// the checkout has local changes. Tasks can still run in it.
+var ErrNotUpdated = errors.New("checkout not updated")
+
+// Config maps Flux projects and epics to the directories their tasks run in.
+type Config struct {
+	// Projects maps a project ID or name to a directory
+	Projects map[string]string `json:"projects,omitempty"`
+	// Epics maps an epic ID to a directory, overriding its project's
+	Epics map[string]string `json:"epics,omitempty"`
+	// Repositories maps a project ID or name to the git URL of its
+	// repository, which is cloned when the project has no local checkout
+	Repositories map[string]string `json:"repositories,omitempty"`
+	// CacheDir holds the checkouts of repositories without a mapped
+	// directory (default <user cache dir>/momentum/repos)
+	CacheDir string `json:"cache-dir,omitempty"`
+}
+
+// Workspace is where a task runs.
+type Workspace struct {
+	Dir string
+	// Repository is the git URL Dir is checked out from, if Momentum keeps
+	// the checkout up to date
+	Repository string
+}
+
+// ProjectLister lists the Flux projects, for matching project names and
+// reading descriptions. *client.Client implements it.
+type ProjectLister interface {
+	ListProjects() ([]client.Project, error)
+}
+
+// Resolver picks each task's working directory. A nil *Resolver runs every
+// task in the default directory.
+type Resolver struct {
+	cfg      Config
+	projects ProjectLister
+	now      func() time.Time
+	// cacheDir holds managed checkouts; "" if it cannot be determined
+	cacheDir string
+
+	mu      sync.Mutex
+	known   map[string]client.Project
+	fetched time.Time
+
+	// checkouts serialises Prepare calls for each directory
+	checkoutsMu sync.Mutex
+	checkouts   map[string]*sync.Mutex
+}
+
+// New validates cfg and returns a resolver that looks projects up with
+// projects.
+func New(cfg Config, projects ProjectLister) (*Resolver, error) {
+	for key, dir := range cfg.Projects {
+		if strings.TrimSpace(dir) == "" {
+			return nil, fmt.Errorf("project %q has no working directory", key)
+		}
+	}
+	for key, dir := range cfg.Epics {
+		if strings.TrimSpace(dir) == "" {
+			return nil, fmt.Errorf("epic %q has no working directory", key)
+		}
+	}
+	for key, url := range cfg.Repositories {
+		if strings.TrimSpace(url) == "" {
+			return nil, fmt.Errorf("project %q has no repository URL", key)
+		}
+	}
+	r := &Resolver{
+		cfg:       cfg,
+		projects:  projects,
+		now:       time.Now,
+		cacheDir:  expandHome(cfg.CacheDir),
+		checkouts: make(map[string]*sync.Mutex),
+	}
+	if r.cacheDir == "" {
+		if dir, err := os.UserCacheDir(); err == nil {
+			r.cacheDir = filepath.Join(dir, "momentum", "repos")
+		}
+	}
+	return r, nil
+}
+
+// Resolve returns where task runs, falling back to defaultDir. It fails
+// when the task is mapped to a directory that does not exist and has no
+// repository to clone into it, rather than running the agent in the wrong
+// place.
+func (r *Resolver) Resolve(task *client.Task, defaultDir string) (Workspace, error) {
+	if defaultDir == "" {
+		defaultDir = "."
+	}
+	if r == nil {
+		return Workspace{Dir: defaultDir}, nil
+	}
+	m, err := r.lookup(task)
+	if err != nil {
+		return Workspace{}, fmt.Errorf("no working directory for task %s: %w", task.ID, err)
+	}
+	ws := Workspace{Repository: m.repository}
+	switch {
+	case m.dir == "" && m.repository == "":
+		return Workspace{Dir: defaultDir}, nil
+	case m.dir == "":
+		ws.Dir, err = r.cachePath(m.repository)
+		if err != nil {
+			return Workspace{}, fmt.Errorf("no working directory for task %s: %w", task.ID, err)
+		}
+		return ws, nil
+	}
+
+	ws.Dir = expandHome(m.dir)
+	if !filepath.IsAbs(ws.Dir) {
+		ws.Dir = filepath.Join(defaultDir, ws.Dir)
+	}
+	info, err := os.Stat(ws.Dir)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && ws.Repository != "":
+		// Prepare clones the repository there
+		return ws, nil
+	case err != nil:
+		return Workspace{}, fmt.Errorf("working directory for %s: %w", m.source, err)
+	case !info.IsDir():
+		return Workspace{}, fmt.Errorf("working directory %s for %s is not a directory", ws.Dir, m.source)
+	}
+	return ws, nil
+}
+
+// mapping is what a task's epic and project are mapped to.
+type mapping struct {
+	dir        string
+	repository string
+	// source says where dir came from, for error messages
+	source string
+}
+
+// lookup finds the directory and repository task is mapped to. Both are
+// "" for tasks with no mapping.
+func (r *Resolver) lookup(task *client.Task) (mapping, error) {
+	var m mapping
+	if dir, ok := r.cfg.Epics[task.EpicID]; ok && task.EpicID != "" {
+		m.dir, m.source = dir, "epic "+task.EpicID
+	} else if dir, ok := r.cfg.Projects[task.ProjectID]; ok && task.ProjectID != "" {
+		m.dir, m.source = dir, "project "+task.ProjectID
+	}
+	if task.ProjectID != "" {
+		m.repository = r.cfg.Repositories[task.ProjectID]
+	}
+	if (m.dir != "" && m.repository != "") || task.ProjectID == "" || r.projects == nil {
+		return m, nil
+	}
+
+	project, err := r.project(task.ProjectID)
+	if err != nil || project == nil {
+		return m, err
+	}
+	if m.dir == "" {
+		if dir, ok := r.cfg.Projects[project.Name]; ok {
+			m.dir, m.source = dir, "project "+project.Name
+		} else if dir := DescriptionDir(project.Description); dir != "" {
+			m.dir, m.source = dir, fmt.Sprintf("project %s's description", project.Name)
+		}
+	}
+	if m.repository == "" {
+		if url, ok := r.cfg.Repositories[project.Name]; ok {
+			m.repository = url
+		} else {
+			m.repository = DescriptionRepository(project.Description)
+		}
+	}
+	return m, nil
+}
+
+// project returns the Flux project with id, or nil if there is none. The
+// project list is cached; a stale list is used when Flux cannot be reached.
+func (r *Resolver) project(id string) (*client.Project, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.known == nil || r.now().Sub(r.fetched) >= projectsTTL {
+		projects, err := r.projects.ListProjects()
+		switch {
+		case err == nil:
+			r.known = make(map[string]client.Project, len(projects))
+			for _, p := range projects {
+				r.known[p.ID] = p
+			}
+			r.fetched = r.now()
+		case r.known == nil:
+			return nil, fmt.Errorf("failed to look up project %s: %w", id, err)
+		}
+	}
+	if p, ok := r.known[id]; ok {
+		return &p, nil
+	}
+	return nil, nil
+}
+
+var (
+	descriptionDir        = regexp.MustCompile(`(?im)^\s*workdir:[ \t]*(\S.*?)\s*$`)
+	descriptionRepository = regexp.MustCompile(`(?im)^\s*repository:[ \t]*(\S.*?)\s*$`)
+)
+
+// DescriptionDir returns the directory named by a "workdir:" line in a
+// project description, or "" if there is none.
+func DescriptionDir(description string) string {
+	return descriptionField(descriptionDir, description)
+}
+
+// DescriptionRepository returns the git URL named by a "repository:" line
+// in a project description, or "" if there is none.
+func DescriptionRepository(description string) string {
+	return descriptionField(descriptionRepository, description)
+}
+
+func descriptionField(field *regexp.Regexp, description string) string {
+	m := field.FindStringSubmatch(description)
+	if m == nil {
+		return ""
+	}
+	return strings.Trim(m[1], "`<>")
+}
+
+// cachePath returns the managed checkout directory for a repository: its
+// name followed by a hash of the URL, so different remotes with the same
+// name do not collide.
+func (r *Resolver) cachePath(repository string) (string, error) {
+	if r.cacheDir == "" {
+		return "", errors.New("no cache directory for repository checkouts; set cache-dir in the workdirs config")
+	}
+	name := strings.TrimSuffix(path.Base(strings.TrimRight(filepath.ToSlash(repository), "/")), ".git")
+	name = strings.Trim(unsafeName.ReplaceAllString(name, "-"), "-")
+	if name == "" {
+		name = "repo"
+	}
+	sum := sha256.Sum256([]byte(repository))
+	return filepath.Join(r.cacheDir, name+"-"+hex.EncodeToString(sum[:4])), nil
+}
+
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Prepare makes sure a workspace with a repository has an up-to-date
+// checkout: it clones the repository if the directory has none, and
+// otherwise fetches it and fast-forwards the checked-out branch. Checkouts
+// with local changes are left alone. A checkout that exists but could not
+// be updated fails with ErrNotUpdated.
+func (r *Resolver) Prepare(ctx context.Context, ws Workspace) error {
+	if r == nil || ws.Repository == "" {
+		return nil
+	}
+	lock := r.checkoutLock(ws.Dir)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, err := os.Stat(filepath.Join(ws.Dir, ".git")); errors.Is(err, fs.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(ws.Dir), 0o755); err != nil {
+			return fmt.Errorf("failed to clone %s: %w", ws.Repository, err)
+		}
+		if _, err := git.Run(ctx, "", "clone", "--quiet", "--", ws.Repository, ws.Dir); err != nil {
+			return fmt.Errorf("failed to clone %s: %w", ws.Repository, err)
+		}
+		return nil
+	}
+
+	if _, err := git.Run(ctx, ws.Dir, "fetch", "--quiet", "--prune", "origin"); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrNotUpdated, ws.Dir, err)
+	}
+	status, err := git.Run(ctx, ws.Dir, "status", "--porcelain")
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrNotUpdated, ws.Dir, err)
+	}
+	if strings.TrimSpace(status) != "" {
+		return fmt.Errorf("%w: %s has uncommitted changes", ErrNotUpdated, ws.Dir)
+	}
+	// A branch without an upstream, such as a local task branch, has
+	// nothing to fast-forward to
+	if _, err := git.Run(ctx, ws.Dir, "rev-parse", "--verify", "--quiet", "@{upstream}"); err != nil {
+		return nil
+	}
+	if _, err := git.Run(ctx, ws.Dir, "merge", "--ff-only", "--quiet", "@{upstream}"); err != nil {
+		return fmt.Errorf("%w: %s has diverged from its upstream: %v", ErrNotUpdated, ws.Dir, err)
+	}
+	return nil
+}
+
+// Checkout prepares the managed checkout of repository and returns its
+// directory. Remote runners use it for assignments that name a repository.
+// Like Prepare, it fails with ErrNotUpdated when the checkout is usable but
+// stale; the directory is returned with that error.
+func (r *Resolver) Checkout(ctx context.Context, repository string) (string, error) {
+	if r == nil {
+		return "", errors.New("no workspace resolver")
+	}
+	dir, err := r.cachePath(repository)
+	if err != nil {
+		return "", err
+	}
+	if err := r.Prepare(ctx, Workspace{Dir: dir, Repository: repository}); err != nil {
+		if errors.Is(err, ErrNotUpdated) {
+			return dir, err
+		}
+		return "", err
+	}
+	return dir, nil
+}
+
+func (r *Resolver) checkoutLock(dir string) *sync.Mutex {
+	r.checkoutsMu.Lock()
+	defer r.checkoutsMu.Unlock()
+	lock, ok := r.checkouts[dir]
+	if !ok {
+		lock = &sync.Mutex{}
+		r.checkouts[dir] = lock
+	}
+	return lock
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return path
+		}
+		return filepath.Join(home, path[1:])
+	}
+	return path
+}
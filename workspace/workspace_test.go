@@ -0,0 +1,277 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirsjg/momentum/client"
+)
+
+// fakeProjects lists a fixed set of projects, counting the calls.
+type fakeProjects struct {
+	projects []client.Project
+	err      error
+	calls    int
+}
+
+func (f *fakeProjects) ListProjects() ([]client.Project, error) {
+	f.calls++
+	return f.projects, f.err
+}
+
+func mkdir(t *testing.T, parent, name string) string {
+	t.Helper()
+	dir := filepath.Join(parent, name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestResolve(t *testing.T) {
+	root := t.TempDir()
+	api := mkdir(t, root, "api")
+	web := mkdir(t, root, "web")
+	docs := mkdir(t, root, "docs")
+	mkdir(t, root, "monorepo/tools")
+
+	projects := &fakeProjects{projects: []client.Project{
+		{ID: "p-api", Name: "API"},
+		{ID: "p-web", Name: "Web"},
+		{ID: "p-docs", Name: "Docs", Description: "The handbook.\n\nWorkDir: " + docs + "\n"},
+		{ID: "p-tools", Name: "Tools", Description: "workdir: monorepo/tools"},
+		{ID: "p-other", Name: "Other"},
+	}}
+	r, err := New(Config{
+		Projects: map[string]string{"p-api": api, "Web": web},
+		Epics:    map[string]string{"e-docs": docs},
+	}, projects)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		task client.Task
+		want string
+	}{
+		{"project ID", client.Task{ID: "t1", ProjectID: "p-api"}, api},
+		{"project name", client.Task{ID: "t2", ProjectID: "p-web"}, web},
+		{"epic overrides project", client.Task{ID: "t3", ProjectID: "p-api", EpicID: "e-docs"}, docs},
+		{"description", client.Task{ID: "t4", ProjectID: "p-docs"}, docs},
+		{"relative to default", client.Task{ID: "t5", ProjectID: "p-tools"}, filepath.Join(root, "monorepo/tools")},
+		{"unmapped", client.Task{ID: "t6", ProjectID: "p-other"}, root},
+		{"unknown project", client.Task{ID: "t7", ProjectID: "p-gone"}, root},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Resolve(&tt.task, root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Dir != tt.want || got.Repository != "" {
+				t.Errorf("expected %s, got %+v", tt.want, got)
+			}
+		})
+	}
+	if projects.calls != 1 {
+		t.Errorf("expected the project list to be fetched once, got %d", projects.calls)
+	}
+}
+
+func TestResolve_MissingDir(t *testing.T) {
+	root := t.TempDir()
+	r, err := New(Config{Projects: map[string]string{"p1": "gone"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Resolve(&client.Task{ID: "t1", ProjectID: "p1"}, root); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a missing directory error, got %v", err)
+	}
+}
+
+func TestResolve_ProjectCache(t *testing.T) {
+	root := t.TempDir()
+	mkdir(t, root, "app")
+	projects := &fakeProjects{projects: []client.Project{{ID: "p1", Name: "App", Description: "workdir: app"}}}
+	r, _ := New(Config{}, projects)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	task := &client.Task{ID: "t1", ProjectID: "p1"}
+
+	if _, err := r.Resolve(task, root); err != nil {
+		t.Fatal(err)
+	}
+	// An expired list is fetched again; while Flux is down the old one is used
+	now = now.Add(projectsTTL)
+	projects.err = errors.New("connection refused")
+	got, err := r.Resolve(task, root)
+	if err != nil || got.Dir != filepath.Join(root, "app") || projects.calls != 2 {
+		t.Errorf("expected the cached directory after %d fetches, got %+v, %v", projects.calls, got, err)
+	}
+
+	// Without any list, the lookup fails rather than guessing
+	r, _ = New(Config{}, projects)
+	if _, err := r.Resolve(task, root); err == nil {
+		t.Error("expected an error when projects cannot be listed")
+	}
+}
+
+func TestResolve_Nil(t *testing.T) {
+	var r *Resolver
+	got, err := r.Resolve(&client.Task{ID: "t1", ProjectID: "p1"}, "")
+	if err != nil || got.Dir != "." {
+		t.Errorf("expected the current directory, got %+v, %v", got, err)
+	}
+}
+
+func TestNew_EmptyDir(t *testing.T) {
+	if _, err := New(Config{Projects: map[string]string{"p1": " "}}, nil); err == nil {
+		t.Error("expected an error for a project without a directory")
+	}
+}
+
+func TestDescriptionDir(t *testing.T) {
+	tests := map[string]string{
+		"":                                   "",
+		"Just a project":                     "",
+		"workdir: ~/src/app":                 "~/src/app",
+		"Intro\n  Workdir:   `/srv/app`  \n": "/srv/app",
+		"our workdir: is not a field":        "",
+		"workdir:\nnext line":                "",
+	}
+	for description, want := range tests {
+		if got := DescriptionDir(description); got != want {
+			t.Errorf("DescriptionDir(%q) = %q, want %q", description, got, want)
+		}
+	}
+}
+
+func TestDescriptionRepository(t *testing.T) {
+	description := "API service\nrepository: <https://github.com/acme/api.git>\nworkdir: api"
+	if got := DescriptionRepository(description); got != "https://github.com/acme/api.git" {
+		t.Errorf("unexpected repository %q", got)
+	}
+	if got := DescriptionRepository("no repository here"); got != "" {
+		t.Errorf("expected no repository, got %q", got)
+	}
+}
+
+// initRemote returns the file:// URL of a repository with one commit on
+// main, and a function that adds a commit to it.
+func initRemote(t *testing.T) (url string, commit func(name string)) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	src := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = src
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	run("init", "-q", "-b", "main")
+	run("config", "user.email", "test@example.com")
+	run("config", "user.name", "Test")
+	commit = func(name string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(src, name), []byte(name+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		run("add", ".")
+		run("commit", "-q", "-m", "add "+name)
+	}
+	commit("README")
+	return "file://" + filepath.ToSlash(src), commit
+}
+
+func TestResolve_Repository(t *testing.T) {
+	root := t.TempDir()
+	cache := t.TempDir()
+	projects := &fakeProjects{projects: []client.Project{
+		{ID: "p-api", Name: "API", Description: "repository: https://example.com/acme/api.git"},
+		{ID: "p-web", Name: "Web"},
+	}}
+	r, err := New(Config{
+		Projects:     map[string]string{"p-web": "web"},
+		Repositories: map[string]string{"Web": "https://example.com/acme/web.git"},
+		CacheDir:     cache,
+	}, projects)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A repository without a directory is checked out in the cache
+	api, err := r.Resolve(&client.Task{ID: "t1", ProjectID: "p-api"}, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if api.Repository != "https://example.com/acme/api.git" || filepath.Dir(api.Dir) != cache || !strings.HasPrefix(filepath.Base(api.Dir), "api-") {
+		t.Errorf("unexpected workspace %+v", api)
+	}
+	// A missing mapped directory is where the repository will be cloned
+	web, err := r.Resolve(&client.Task{ID: "t2", ProjectID: "p-web"}, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if web.Dir != filepath.Join(root, "web") || web.Repository != "https://example.com/acme/web.git" {
+		t.Errorf("unexpected workspace %+v", web)
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	url, commit := initRemote(t)
+	r, err := New(Config{CacheDir: t.TempDir()}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// The first run clones the repository
+	dir, err := r.Checkout(ctx, url)
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "README")); err != nil {
+		t.Fatalf("expected a clone: %v", err)
+	}
+
+	// Later runs pick up new commits
+	commit("CHANGES")
+	if again, err := r.Checkout(ctx, url); err != nil || again != dir {
+		t.Fatalf("expected the same checkout updated, got %s, %v", again, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "CHANGES")); err != nil {
+		t.Errorf("expected the new commit to be checked out: %v", err)
+	}
+
+	// Local changes are never overwritten
+	os.WriteFile(filepath.Join(dir, "README"), []byte("edited\n"), 0o644)
+	commit("NEWS")
+	if got, err := r.Checkout(ctx, url); !errors.Is(err, ErrNotUpdated) || got != dir {
+		t.Errorf("expected ErrNotUpdated with the directory, got %s, %v", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "NEWS")); err == nil {
+		t.Error("expected a checkout with local changes to be left alone")
+	}
+}
+
+func TestPrepare_CloneFails(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	r, _ := New(Config{CacheDir: t.TempDir()}, nil)
+	missing := "file://" + filepath.ToSlash(filepath.Join(t.TempDir(), "missing.git"))
+	if _, err := r.Checkout(context.Background(), missing); err == nil || errors.Is(err, ErrNotUpdated) {
+		t.Errorf("expected the clone to fail, got %v", err)
+	}
+}